@@ -0,0 +1,222 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"aperture-science-network/internal/alert"
+	"aperture-science-network/internal/api"
+	"aperture-science-network/internal/audit"
+	"aperture-science-network/internal/auth"
+	"aperture-science-network/internal/autoupdate"
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/history"
+	"aperture-science-network/internal/jobs"
+	"aperture-science-network/internal/mock"
+	"aperture-science-network/internal/registry"
+	"aperture-science-network/internal/stack"
+	"aperture-science-network/internal/stats"
+	"aperture-science-network/internal/updates"
+	"aperture-science-network/internal/version"
+)
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	stacksPath := os.Getenv("STACKS_PATH")
+	if stacksPath == "" {
+		stacksPath = "/home/share/docker/dockge/stacks"
+	}
+
+	staticPath := os.Getenv("STATIC_PATH")
+	if staticPath == "" {
+		staticPath = "./static"
+	}
+
+	dataPath := os.Getenv("DATA_PATH")
+	if dataPath == "" {
+		dataPath = "./data"
+	}
+
+	sessionTTL := 24 * time.Hour
+	if v := os.Getenv("SESSION_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid SESSION_TTL %q: %v", v, err)
+		}
+		sessionTTL = ttl
+	}
+
+	jobWorkers := jobs.DefaultWorkers
+	if v := os.Getenv("JOB_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Fatalf("Invalid JOB_WORKERS %q: must be a positive integer", v)
+		}
+		jobWorkers = n
+	}
+
+	execShell := os.Getenv("EXEC_SHELL")
+	if execShell == "" {
+		execShell = "/bin/sh"
+	}
+
+	execRole := auth.RoleAdmin
+	if v := os.Getenv("EXEC_ROLE"); v != "" {
+		execRole = auth.Role(v)
+		if execRole != auth.RoleOperator && execRole != auth.RoleAdmin {
+			log.Fatalf("Invalid EXEC_ROLE %q: must be operator or admin", v)
+		}
+	}
+
+	updateInterval := updates.DefaultInterval
+	if v := os.Getenv("UPDATE_CHECK_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval < 0 {
+			log.Fatalf("Invalid UPDATE_CHECK_INTERVAL %q: must be a duration, or 0 to disable", v)
+		}
+		updateInterval = interval
+	}
+
+	// Reverse proxies allowed to report the client address, comma separated
+	var trustedProxies []string
+	for proxy := range strings.SplitSeq(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+
+	debugMode := os.Getenv("DEBUG_MODE") == "true"
+
+	if err := os.MkdirAll(dataPath, 0700); err != nil {
+		log.Fatalf("Failed to create data directory: %v", err)
+	}
+
+	// Registry credentials are encrypted with a key kept beside them unless
+	// CREDENTIALS_KEY_FILE points elsewhere. Beside them, the key only keeps
+	// the credentials out of backups and tools that skip it, not out of a
+	// leaked data directory.
+	credentialsKeyFile := os.Getenv("CREDENTIALS_KEY_FILE")
+	if credentialsKeyFile == "" {
+		credentialsKeyFile = filepath.Join(dataPath, "credentials.key")
+	}
+	credentialsKey, err := auth.LoadOrCreateSecret(credentialsKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load registry credentials key: %v", err)
+	}
+	credentialStore, err := registry.NewFileCredentialStore(filepath.Join(dataPath, "credentials.enc"), credentialsKey)
+	if err != nil {
+		log.Fatalf("Failed to load registry credentials: %v", err)
+	}
+
+	var dockerClient docker.DockerClient
+	var statsProvider stats.Provider
+	var stackProvider stack.Provider
+	var registryResolver registry.Resolver
+
+	if debugMode {
+		log.Println("[DEBUG MODE] Using mock data providers")
+		dockerClient = mock.NewDockerClient()
+		statsProvider = mock.NewStatsProvider()
+		stackProvider = mock.NewStackProvider()
+		registryResolver = mock.NewRegistry()
+	} else {
+		var err error
+		dockerClient, err = docker.NewClient()
+		if err != nil {
+			log.Fatalf("Failed to create Docker client: %v", err)
+		}
+		statsProvider = stats.NewDefaultProvider()
+		stackProvider = stack.NewFilesystemProvider(stacksPath, dockerClient)
+		registryResolver = registry.NewClient(registry.Options{
+			Insecure:    strings.Split(os.Getenv("INSECURE_REGISTRIES"), ","),
+			Credentials: credentialStore,
+		})
+	}
+
+	authStore, err := auth.NewFileStore(filepath.Join(dataPath, "users.json"))
+	if err != nil {
+		log.Fatalf("Failed to load users: %v", err)
+	}
+
+	sessionSecret, err := auth.LoadOrCreateSecret(filepath.Join(dataPath, "session.key"))
+	if err != nil {
+		log.Fatalf("Failed to load session secret: %v", err)
+	}
+	sessions := auth.NewSessions(sessionSecret, sessionTTL, authStore)
+
+	// Optionally create the admin account from the environment on first run
+	if username := os.Getenv("ADMIN_USERNAME"); username != "" {
+		if _, err := authStore.Bootstrap(username, os.Getenv("ADMIN_PASSWORD")); err == nil {
+			log.Printf("Created admin account %q from environment", username)
+		} else if err != auth.ErrSetupComplete {
+			log.Fatalf("Failed to create admin account: %v", err)
+		}
+	}
+	if hasUsers, _ := authStore.HasUsers(); !hasUsers {
+		log.Println("No user accounts exist yet - open the web interface to create the admin account")
+	}
+
+	auditLog, err := audit.NewFileLog(filepath.Join(dataPath, "audit"), audit.DefaultMaxSize, audit.DefaultMaxFiles)
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer auditLog.Close()
+
+	historyStore, err := history.NewFileStore(filepath.Join(dataPath, "history"), history.DefaultMaxRevisions)
+	if err != nil {
+		log.Fatalf("Failed to open compose history: %v", err)
+	}
+
+	alertStore, err := alert.NewFileStore(filepath.Join(dataPath, "alerts.json"))
+	if err != nil {
+		log.Fatalf("Failed to load alerting configuration: %v", err)
+	}
+
+	updatePolicies, err := autoupdate.NewFileStore(filepath.Join(dataPath, "update-policies.json"))
+	if err != nil {
+		log.Fatalf("Failed to load update policies: %v", err)
+	}
+
+	server := api.NewServer(api.ServerOptions{
+		StaticPath:     staticPath,
+		DockerClient:   dockerClient,
+		StatsProvider:  statsProvider,
+		StackProvider:  stackProvider,
+		AuthStore:      authStore,
+		Sessions:       sessions,
+		AuditLog:       auditLog,
+		History:        historyStore,
+		Alerts:         alertStore,
+		JobWorkers:     jobWorkers,
+		ExecShell:      execShell,
+		ExecRole:       execRole,
+		MetricsToken:   os.Getenv("METRICS_TOKEN"),
+		Registry:       registryResolver,
+		UpdateInterval: updateInterval,
+		UpdatePolicies: updatePolicies,
+		Credentials:    credentialStore,
+		TrustedProxies: trustedProxies,
+	})
+
+	log.Printf("Aperture Science Network v%s starting on port %s", version.Version, port)
+	log.Printf("Stacks path: %s", stacksPath)
+	log.Printf("Data path: %s", dataPath)
+	if os.Getenv("METRICS_TOKEN") == "" {
+		log.Println("Warning: METRICS_TOKEN is not set, /metrics is only served to signed-in users with access to all stacks")
+	}
+	if debugMode {
+		log.Println("[DEBUG MODE] Mock data active - Docker not required")
+	}
+
+	if err := server.Run(":" + port); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
@@ -0,0 +1,635 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/stats"
+)
+
+const (
+	// DefaultInterval is how often the engine evaluates host stats,
+	// pending alerts and silences
+	DefaultInterval = 10 * time.Second
+
+	// requestedExitWindow is how long after a kill a container's exit is
+	// considered requested rather than unexpected
+	requestedExitWindow = time.Minute
+
+	eventRetryMin = time.Second
+	eventRetryMax = 30 * time.Second
+)
+
+// Engine evaluates alert rules against host stats and Docker events and
+// sends notifications when alerts fire and resolve
+type Engine struct {
+	store         Store
+	dockerClient  docker.DockerClient
+	statsProvider stats.Provider
+	interval      time.Duration
+
+	mu     sync.Mutex
+	alerts map[string]*trackedAlert
+	// kills holds the last kill of each container, exits the times of its
+	// recent unexpected exits
+	kills map[string]time.Time
+	exits map[string][]time.Time
+	// notified holds when each alert was last notified, for cooldowns
+	notified    map[string]time.Time
+	subscribers []func(Alert)
+}
+
+type trackedAlert struct {
+	alert Alert
+	// published is set once the firing was passed to subscribers, and
+	// delivered once its notification was sent or skipped for a cooldown.
+	// notified records that a firing notification went out, so that only
+	// then is the resolution notified too.
+	published bool
+	delivered bool
+	notified  bool
+}
+
+// subject identifies what an alert is about: the host or a container
+type subject struct {
+	id    string
+	name  string
+	stack string
+}
+
+var hostSubject = subject{id: "host"}
+
+// NewEngine creates an engine; call Run to start it
+func NewEngine(store Store, dockerClient docker.DockerClient, statsProvider stats.Provider, interval time.Duration) *Engine {
+	return &Engine{
+		store:         store,
+		dockerClient:  dockerClient,
+		statsProvider: statsProvider,
+		interval:      interval,
+		alerts:        make(map[string]*trackedAlert),
+		kills:         make(map[string]time.Time),
+		exits:         make(map[string][]time.Time),
+		notified:      make(map[string]time.Time),
+	}
+}
+
+// Subscribe registers a callback invoked whenever an alert fires or
+// resolves
+func (e *Engine) Subscribe(fn func(Alert)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.subscribers = append(e.subscribers, fn)
+}
+
+// Alerts returns the pending and firing alerts, oldest first
+func (e *Engine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, t := range e.alerts {
+		alerts = append(alerts, t.alert)
+	}
+	sortAlerts(alerts)
+	return alerts
+}
+
+// Run evaluates rules until the process exits
+func (e *Engine) Run() {
+	go e.followEvents()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		e.evaluate(now)
+	}
+}
+
+// followEvents feeds Docker events to the engine, reconnecting with
+// backoff when the stream fails
+func (e *Engine) followEvents() {
+	delay := eventRetryMin
+	for {
+		connected := time.Now()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		events, errs := e.dockerClient.Events(ctx, time.Time{})
+		err := e.consume(events, errs)
+		cancel()
+
+		// Only back off further if the stream failed straight away
+		if time.Since(connected) > eventRetryMax {
+			delay = eventRetryMin
+		}
+		log.Printf("Alerting event stream interrupted: %v (retrying in %s)", err, delay)
+		time.Sleep(delay)
+		delay = min(delay*2, eventRetryMax)
+	}
+}
+
+func (e *Engine) consume(events <-chan docker.Event, errs <-chan error) error {
+	for {
+		select {
+		case event := <-events:
+			if event.Type == docker.EventContainer {
+				e.handleEvent(event)
+			}
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// handleEvent applies a container event to the container conditions
+func (e *Engine) handleEvent(event docker.Event) {
+	rules, err := e.store.ListRules()
+	if err != nil {
+		log.Printf("Error loading alert rules: %v", err)
+		return
+	}
+	now := event.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	ctr := subject{id: event.ID, name: event.Name, stack: event.Stack}
+
+	e.mu.Lock()
+	var changed []*trackedAlert
+
+	switch {
+	case event.Action == "kill":
+		e.kills[ctr.id] = now
+
+	case event.Action == "die":
+		killed, ok := e.kills[ctr.id]
+		requested := ok && now.Sub(killed) < requestedExitWindow
+		delete(e.kills, ctr.id)
+		exitCode := event.Attributes["exitCode"]
+
+		if !requested {
+			e.exits[ctr.id] = append(e.exits[ctr.id], now)
+		}
+		for _, rule := range rules {
+			if !rule.Enabled || !rule.matchesContainer(ctr) {
+				continue
+			}
+			switch rule.Condition {
+			case ConditionContainerExited:
+				if !requested && exitCode != "" && exitCode != "0" {
+					msg := fmt.Sprintf("Container %s exited unexpectedly with code %s", ctr.name, exitCode)
+					changed = append(changed, e.raiseLocked(rule, ctr, now, 0, msg)...)
+				}
+			case ConditionRestartLoop:
+				count := e.recentExitsLocked(ctr.id, now, time.Duration(rule.Window))
+				if !requested && float64(count) >= rule.Threshold {
+					msg := fmt.Sprintf("Container %s exited unexpectedly %d times in %s", ctr.name, count, time.Duration(rule.Window))
+					changed = append(changed, e.raiseLocked(rule, ctr, now, float64(count), msg)...)
+				}
+			case ConditionContainerUnhealthy:
+				// A stopped container has no health status
+				changed = append(changed, e.resolveLocked(rule.ID, ctr, now)...)
+			}
+		}
+
+	case event.Action == "start":
+		for _, rule := range rules {
+			if rule.Condition == ConditionContainerExited {
+				changed = append(changed, e.resolveLocked(rule.ID, ctr, now)...)
+			}
+		}
+
+	case event.Action == "destroy":
+		delete(e.kills, ctr.id)
+		delete(e.exits, ctr.id)
+		for _, rule := range rules {
+			changed = append(changed, e.resolveLocked(rule.ID, ctr, now)...)
+		}
+
+	case strings.HasPrefix(event.Action, "health_status"):
+		status := strings.TrimSpace(strings.TrimPrefix(event.Action, "health_status:"))
+		for _, rule := range rules {
+			if !rule.Enabled || rule.Condition != ConditionContainerUnhealthy || !rule.matchesContainer(ctr) {
+				continue
+			}
+			if status == "unhealthy" {
+				msg := fmt.Sprintf("Container %s is unhealthy", ctr.name)
+				changed = append(changed, e.raiseLocked(rule, ctr, now, 0, msg)...)
+			} else {
+				changed = append(changed, e.resolveLocked(rule.ID, ctr, now)...)
+			}
+		}
+	}
+
+	e.mu.Unlock()
+	e.dispatch(changed, rules)
+}
+
+// evaluate checks host thresholds, health statuses and restart loops,
+// promotes pending alerts that have lasted long enough and retries
+// notifications held back by silences
+func (e *Engine) evaluate(now time.Time) {
+	rules, err := e.store.ListRules()
+	if err != nil {
+		log.Printf("Error loading alert rules: %v", err)
+		return
+	}
+
+	var sys *stats.SystemStats
+	var containers []docker.ContainerInfo
+	listed := false
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		if rule.Condition.host() && sys == nil {
+			if sys, err = e.statsProvider.GetSystemStats(); err != nil {
+				log.Printf("Error getting system stats for alerts: %v", err)
+			}
+		}
+		if rule.Condition == ConditionContainerUnhealthy && !listed {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			containers, err = e.dockerClient.ListContainers(ctx, false)
+			cancel()
+			if err != nil {
+				log.Printf("Error listing containers for alerts: %v", err)
+			} else {
+				listed = true
+			}
+		}
+	}
+
+	e.mu.Lock()
+	var changed []*trackedAlert
+	active := make(map[string]bool)
+
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		active[rule.ID] = true
+
+		switch {
+		case rule.Condition.host():
+			if sys == nil {
+				continue
+			}
+			value, label := hostValue(rule.Condition, sys)
+			if value > rule.Threshold {
+				msg := fmt.Sprintf("Host %s usage is %.1f%%, above %g%%", label, value, rule.Threshold)
+				changed = append(changed, e.raiseLocked(rule, hostSubject, now, value, msg)...)
+			} else {
+				changed = append(changed, e.resolveLocked(rule.ID, hostSubject, now)...)
+			}
+
+		case rule.Condition == ConditionContainerUnhealthy && listed:
+			// Catch containers that were unhealthy before the engine
+			// started or whose health event was missed
+			running := make(map[string]bool)
+			for _, ctr := range containers {
+				s := subject{id: ctr.ID, name: ctr.Name, stack: ctr.Labels["com.docker.compose.project"]}
+				running[ctr.ID] = true
+				if !rule.matchesContainer(s) {
+					continue
+				}
+				if strings.Contains(ctr.Status, "(unhealthy)") {
+					msg := fmt.Sprintf("Container %s is unhealthy", ctr.Name)
+					changed = append(changed, e.raiseLocked(rule, s, now, 0, msg)...)
+				} else {
+					changed = append(changed, e.resolveLocked(rule.ID, s, now)...)
+				}
+			}
+			for _, t := range e.alerts {
+				if t.alert.RuleID == rule.ID && !running[t.alert.ContainerID] {
+					changed = append(changed, e.resolveLocked(rule.ID, t.subject(), now)...)
+				}
+			}
+
+		case rule.Condition == ConditionRestartLoop:
+			for _, t := range e.alerts {
+				if t.alert.RuleID != rule.ID {
+					continue
+				}
+				if float64(e.recentExitsLocked(t.alert.ContainerID, now, time.Duration(rule.Window))) < rule.Threshold {
+					changed = append(changed, e.resolveLocked(rule.ID, t.subject(), now)...)
+				}
+			}
+		}
+	}
+
+	// Alerts of deleted or disabled rules are dropped without notice
+	for key, t := range e.alerts {
+		if !active[t.alert.RuleID] {
+			delete(e.alerts, key)
+		}
+	}
+
+	// Promote pending alerts whose condition has held for long enough
+	byID := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		byID[rule.ID] = rule
+	}
+	for _, t := range e.alerts {
+		if t.alert.State == StatePending && now.Sub(t.alert.Since) >= time.Duration(byID[t.alert.RuleID].For) {
+			e.fireLocked(t, now)
+			changed = append(changed, t)
+		} else if t.alert.State == StateFiring && !t.delivered {
+			changed = append(changed, t)
+		}
+	}
+
+	// Forget old kills, exits and notification times
+	for id, kill := range e.kills {
+		if now.Sub(kill) > requestedExitWindow {
+			delete(e.kills, id)
+		}
+	}
+	window := maxWindow(rules)
+	for id, exits := range e.exits {
+		recent := exits[:0]
+		for _, t := range exits {
+			if now.Sub(t) <= window {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) == 0 {
+			delete(e.exits, id)
+		} else {
+			e.exits[id] = recent
+		}
+	}
+	var cooldown time.Duration
+	for _, rule := range rules {
+		cooldown = max(cooldown, time.Duration(rule.Cooldown))
+	}
+	for key, last := range e.notified {
+		if now.Sub(last) > cooldown {
+			delete(e.notified, key)
+		}
+	}
+
+	e.mu.Unlock()
+	e.dispatch(changed, rules)
+}
+
+// raiseLocked records that a rule's condition holds for a subject. Exits
+// and restart loops fire straight away, other conditions once they have
+// held for the rule's For duration. It returns the alert if it fired.
+// Callers must hold mu.
+func (e *Engine) raiseLocked(rule Rule, s subject, now time.Time, value float64, message string) []*trackedAlert {
+	key := alertKey(rule.ID, s)
+	t, ok := e.alerts[key]
+	if ok {
+		t.alert.Value = value
+		t.alert.Message = message
+		// Every unexpected exit is worth announcing, subject to the
+		// cooldown
+		if rule.Condition == ConditionContainerExited {
+			e.fireLocked(t, now)
+			return []*trackedAlert{t}
+		}
+		return nil
+	}
+
+	t = &trackedAlert{alert: Alert{
+		ID:        key,
+		RuleID:    rule.ID,
+		RuleName:  rule.Name,
+		Condition: rule.Condition,
+		State:     StatePending,
+		Message:   message,
+		Value:     value,
+		Since:     now,
+	}}
+	if s != hostSubject {
+		t.alert.ContainerID = s.id
+		t.alert.Container = s.name
+		t.alert.Stack = s.stack
+	}
+	e.alerts[key] = t
+
+	if rule.For == 0 || rule.Condition == ConditionContainerExited || rule.Condition == ConditionRestartLoop {
+		e.fireLocked(t, now)
+		return []*trackedAlert{t}
+	}
+	return nil
+}
+
+func (e *Engine) fireLocked(t *trackedAlert, now time.Time) {
+	fired := now
+	t.alert.State = StateFiring
+	t.alert.FiredAt = &fired
+	t.published = false
+	t.delivered = false
+}
+
+// resolveLocked ends a subject's alert for a rule, returning it if it had
+// fired. Pending alerts are dropped silently. Callers must hold mu.
+func (e *Engine) resolveLocked(ruleID string, s subject, now time.Time) []*trackedAlert {
+	key := alertKey(ruleID, s)
+	t, ok := e.alerts[key]
+	if !ok {
+		return nil
+	}
+	delete(e.alerts, key)
+	if t.alert.State != StateFiring {
+		return nil
+	}
+	resolved := now
+	t.alert.State = StateResolved
+	t.alert.ResolvedAt = &resolved
+	return []*trackedAlert{t}
+}
+
+// recentExitsLocked counts a container's unexpected exits within window
+// before now. Callers must hold mu.
+func (e *Engine) recentExitsLocked(id string, now time.Time, window time.Duration) int {
+	exits := e.exits[id]
+	count := 0
+	for _, t := range exits {
+		if now.Sub(t) <= window {
+			count++
+		}
+	}
+	return count
+}
+
+// dispatch publishes alert changes to subscribers and sends notifications
+// for those not silenced or in their cooldown
+func (e *Engine) dispatch(changed []*trackedAlert, rules []Rule) {
+	if len(changed) == 0 {
+		return
+	}
+
+	silences, err := e.store.ListSilences()
+	if err != nil {
+		log.Printf("Error loading silences: %v", err)
+	}
+	byID := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		byID[rule.ID] = rule
+	}
+
+	type delivery struct {
+		alert    Alert
+		channels []string
+	}
+	var deliveries []delivery
+	var published []Alert
+
+	e.mu.Lock()
+	now := time.Now()
+	for _, t := range changed {
+		rule := byID[t.alert.RuleID]
+		t.alert.Silenced = silenced(silences, t.alert)
+
+		switch t.alert.State {
+		case StateFiring:
+			if !t.published {
+				t.published = true
+				published = append(published, t.alert)
+			}
+			// Silenced alerts are retried on every evaluation until the
+			// silence ends
+			if t.delivered || t.alert.Silenced {
+				continue
+			}
+			t.delivered = true
+			if last, ok := e.notified[t.alert.ID]; ok && now.Sub(last) < time.Duration(rule.Cooldown) {
+				continue
+			}
+			e.notified[t.alert.ID] = now
+			t.notified = true
+			deliveries = append(deliveries, delivery{alert: t.alert, channels: rule.Channels})
+
+		case StateResolved:
+			published = append(published, t.alert)
+			if t.notified && !t.alert.Silenced {
+				deliveries = append(deliveries, delivery{alert: t.alert, channels: rule.Channels})
+			}
+		}
+	}
+	subscribers := e.subscribers
+	e.mu.Unlock()
+
+	for _, a := range published {
+		for _, fn := range subscribers {
+			fn(a)
+		}
+	}
+	for _, d := range deliveries {
+		go e.deliver(d.alert, d.channels)
+	}
+}
+
+// deliver sends a notification for an alert to each of the channels
+func (e *Engine) deliver(a Alert, channels []string) {
+	notification := newNotification(a)
+	for _, id := range channels {
+		ch, err := e.store.GetChannel(id)
+		if err != nil {
+			log.Printf("Error loading notification channel %s: %v", id, err)
+			continue
+		}
+		if err := Send(*ch, notification); err != nil {
+			log.Printf("Error sending alert %q to channel %s: %v", a.RuleName, ch.Name, err)
+		}
+	}
+}
+
+// Send delivers a notification to a channel
+func Send(ch Channel, n Notification) error {
+	notifier, err := NewNotifier(ch)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	return notifier.Notify(ctx, n)
+}
+
+func (t *trackedAlert) subject() subject {
+	id := t.alert.ContainerID
+	if id == "" {
+		id = hostSubject.id
+	}
+	return subject{id: id, name: t.alert.Container, stack: t.alert.Stack}
+}
+
+// matchesContainer reports whether a container condition applies to s
+func (r Rule) matchesContainer(s subject) bool {
+	if r.Condition.host() {
+		return false
+	}
+	if r.Stack != "" {
+		if ok, _ := path.Match(r.Stack, s.stack); !ok {
+			return false
+		}
+	}
+	if r.Container != "" {
+		if ok, _ := path.Match(r.Container, s.name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// silenced reports whether any silence matches an alert
+func silenced(silences []Silence, a Alert) bool {
+	for _, s := range silences {
+		if s.RuleID != "" && s.RuleID != a.RuleID {
+			continue
+		}
+		if s.Stack != "" {
+			if ok, _ := path.Match(s.Stack, a.Stack); !ok {
+				continue
+			}
+		}
+		if s.Container != "" {
+			if ok, _ := path.Match(s.Container, a.Container); !ok {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func hostValue(c Condition, sys *stats.SystemStats) (float64, string) {
+	switch c {
+	case ConditionHostCPU:
+		return sys.CPUUsage, "CPU"
+	case ConditionHostMemory:
+		return sys.MemoryPercent, "memory"
+	default:
+		return sys.DiskPercent, "disk"
+	}
+}
+
+func alertKey(ruleID string, s subject) string {
+	return ruleID + ":" + s.id
+}
+
+// maxWindow returns the longest restart loop window of the rules
+func maxWindow(rules []Rule) time.Duration {
+	window := defaultRestartWindow
+	for _, rule := range rules {
+		window = max(window, time.Duration(rule.Window))
+	}
+	return window
+}
+
+func sortAlerts(alerts []Alert) {
+	sort.Slice(alerts, func(i, j int) bool {
+		if !alerts[i].Since.Equal(alerts[j].Since) {
+			return alerts[i].Since.Before(alerts[j].Since)
+		}
+		return alerts[i].ID < alerts[j].ID
+	})
+}
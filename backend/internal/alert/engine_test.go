@@ -0,0 +1,427 @@
+package alert
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/mock"
+	"aperture-science-network/internal/stats"
+)
+
+// fixedStats reports host stats set by the test
+type fixedStats struct {
+	mu  sync.Mutex
+	cpu float64
+}
+
+func (p *fixedStats) GetSystemStats() (*stats.SystemStats, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &stats.SystemStats{CPUUsage: p.cpu}, nil
+}
+
+func (p *fixedStats) set(cpu float64) {
+	p.mu.Lock()
+	p.cpu = cpu
+	p.mu.Unlock()
+}
+
+type engineHarness struct {
+	engine  *Engine
+	store   *FileStore
+	stats   *fixedStats
+	channel string
+
+	mu        sync.Mutex
+	published []Alert
+	// sent receives every notification delivered to the webhook channel
+	sent chan Notification
+}
+
+func newEngineHarness(t *testing.T) *engineHarness {
+	t.Helper()
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "alerts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &engineHarness{store: store, stats: &fixedStats{}, sent: make(chan Notification, 16)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		h.sent <- n
+	}))
+	t.Cleanup(srv.Close)
+
+	ch, err := store.CreateChannel(Channel{Name: "hook", Type: ChannelWebhook, URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.channel = ch.ID
+
+	h.engine = NewEngine(store, mock.NewDockerClient(), h.stats, time.Minute)
+	h.engine.Subscribe(func(a Alert) {
+		h.mu.Lock()
+		h.published = append(h.published, a)
+		h.mu.Unlock()
+	})
+	return h
+}
+
+func (h *engineHarness) rule(t *testing.T, rule Rule) Rule {
+	t.Helper()
+	rule.Enabled = true
+	rule.Channels = []string{h.channel}
+	created, err := h.store.CreateRule(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *created
+}
+
+// states returns the states of the alerts published so far and forgets
+// them
+func (h *engineHarness) states() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	states := make([]string, 0, len(h.published))
+	for _, a := range h.published {
+		states = append(states, a.State)
+	}
+	h.published = nil
+	return states
+}
+
+// expectSent waits for a notification with the given state
+func (h *engineHarness) expectSent(t *testing.T, state string) Notification {
+	t.Helper()
+	select {
+	case n := <-h.sent:
+		if n.Alert.State != state {
+			t.Fatalf("notification state = %q, want %q", n.Alert.State, state)
+		}
+		return n
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no %s notification sent", state)
+		return Notification{}
+	}
+}
+
+// expectSentStates waits for one notification per state. Deliveries run
+// concurrently, so their order is not checked.
+func (h *engineHarness) expectSentStates(t *testing.T, states ...string) {
+	t.Helper()
+	want := make(map[string]int)
+	for _, state := range states {
+		want[state]++
+	}
+	for range states {
+		select {
+		case n := <-h.sent:
+			want[n.Alert.State]--
+		case <-time.After(5 * time.Second):
+			t.Fatalf("notifications missing, want %v", states)
+		}
+	}
+	for state, count := range want {
+		if count != 0 {
+			t.Fatalf("notification states differ from %v for %q", states, state)
+		}
+	}
+}
+
+// expectNothingSent checks that no notification arrives for a moment
+func (h *engineHarness) expectNothingSent(t *testing.T) {
+	t.Helper()
+	select {
+	case n := <-h.sent:
+		t.Fatalf("unexpected notification %q", n.Title)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func containerEvent(action string, at time.Time, exitCode string) docker.Event {
+	e := docker.Event{
+		Type:   docker.EventContainer,
+		Action: action,
+		ID:     "c0ffee",
+		Name:   "web-1",
+		Stack:  "web",
+		Time:   at,
+	}
+	if exitCode != "" {
+		e.Attributes = map[string]string{"exitCode": exitCode}
+	}
+	return e
+}
+
+func TestEngineContainerExited(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []docker.Event
+		want   []string
+	}{
+		{
+			name:   "unexpected exit",
+			events: []docker.Event{containerEvent("die", time.Unix(1000, 0), "1")},
+			want:   []string{StateFiring},
+		},
+		{
+			name:   "clean exit",
+			events: []docker.Event{containerEvent("die", time.Unix(1000, 0), "0")},
+			want:   []string{},
+		},
+		{
+			name: "stopped through docker",
+			events: []docker.Event{
+				containerEvent("kill", time.Unix(1000, 0), ""),
+				containerEvent("die", time.Unix(1010, 0), "137"),
+			},
+			want: []string{},
+		},
+		{
+			name: "exit long after a kill",
+			events: []docker.Event{
+				containerEvent("kill", time.Unix(1000, 0), ""),
+				containerEvent("die", time.Unix(1000, 0).Add(2*requestedExitWindow), "137"),
+			},
+			want: []string{StateFiring},
+		},
+		{
+			name: "restarted",
+			events: []docker.Event{
+				containerEvent("die", time.Unix(1000, 0), "1"),
+				containerEvent("start", time.Unix(1005, 0), ""),
+			},
+			want: []string{StateFiring, StateResolved},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newEngineHarness(t)
+			h.rule(t, Rule{Name: "Crashes", Condition: ConditionContainerExited, Stack: "web"})
+
+			for _, e := range tt.events {
+				h.engine.handleEvent(e)
+			}
+			if got := h.states(); !slices.Equal(got, tt.want) {
+				t.Errorf("published = %v, want %v", got, tt.want)
+			}
+			h.expectSentStates(t, tt.want...)
+		})
+	}
+}
+
+func TestEngineRuleScope(t *testing.T) {
+	h := newEngineHarness(t)
+	h.rule(t, Rule{Name: "Crashes", Condition: ConditionContainerExited, Stack: "db*"})
+
+	h.engine.handleEvent(containerEvent("die", time.Unix(1000, 0), "1"))
+	if got := h.states(); len(got) != 0 {
+		t.Errorf("published = %v for a container outside the rule's stacks", got)
+	}
+}
+
+func TestEngineUnhealthy(t *testing.T) {
+	h := newEngineHarness(t)
+	h.rule(t, Rule{Name: "Health", Condition: ConditionContainerUnhealthy})
+
+	h.engine.handleEvent(containerEvent("health_status: unhealthy", time.Unix(1000, 0), ""))
+	h.engine.handleEvent(containerEvent("health_status: unhealthy", time.Unix(1030, 0), ""))
+	if got, want := h.states(), []string{StateFiring}; !slices.Equal(got, want) {
+		t.Fatalf("published = %v, want %v", got, want)
+	}
+	n := h.expectSent(t, StateFiring)
+	if want := "Container web-1 is unhealthy"; n.Message != want {
+		t.Errorf("Message = %q, want %q", n.Message, want)
+	}
+
+	h.engine.handleEvent(containerEvent("health_status: healthy", time.Unix(1060, 0), ""))
+	if got, want := h.states(), []string{StateResolved}; !slices.Equal(got, want) {
+		t.Fatalf("published = %v, want %v", got, want)
+	}
+	h.expectSent(t, StateResolved)
+}
+
+func TestEngineUnhealthyContainerGone(t *testing.T) {
+	h := newEngineHarness(t)
+	h.rule(t, Rule{Name: "Health", Condition: ConditionContainerUnhealthy})
+
+	// The mock client lists no container with the event's ID, so the
+	// next evaluation resolves the alert
+	h.engine.handleEvent(containerEvent("health_status: unhealthy", time.Unix(1000, 0), ""))
+	h.engine.evaluate(time.Unix(1010, 0))
+	if got, want := h.states(), []string{StateFiring, StateResolved}; !slices.Equal(got, want) {
+		t.Errorf("published = %v, want %v", got, want)
+	}
+	h.expectSentStates(t, StateFiring, StateResolved)
+	if alerts := h.engine.Alerts(); len(alerts) != 0 {
+		t.Errorf("Alerts() = %+v, want none", alerts)
+	}
+}
+
+func TestEngineRestartLoop(t *testing.T) {
+	h := newEngineHarness(t)
+	h.rule(t, Rule{Name: "Loop", Condition: ConditionRestartLoop, Threshold: 3, Window: Duration(5 * time.Minute)})
+
+	start := time.Unix(1000, 0)
+	for i := 0; i < 2; i++ {
+		h.engine.handleEvent(containerEvent("die", start.Add(time.Duration(i)*time.Minute), "1"))
+	}
+	if got := h.states(); len(got) != 0 {
+		t.Fatalf("published = %v after two exits, want nothing", got)
+	}
+
+	// A requested stop does not count towards the loop
+	h.engine.handleEvent(containerEvent("kill", start.Add(2*time.Minute), ""))
+	h.engine.handleEvent(containerEvent("die", start.Add(2*time.Minute), "137"))
+	if got := h.states(); len(got) != 0 {
+		t.Fatalf("published = %v after a requested stop, want nothing", got)
+	}
+
+	h.engine.handleEvent(containerEvent("die", start.Add(3*time.Minute), "1"))
+	if got, want := h.states(), []string{StateFiring}; !slices.Equal(got, want) {
+		t.Fatalf("published = %v, want %v", got, want)
+	}
+	alerts := h.engine.Alerts()
+	if len(alerts) != 1 || alerts[0].Value != 3 {
+		t.Fatalf("Alerts() = %+v, want one alert with value 3", alerts)
+	}
+	h.expectSent(t, StateFiring)
+
+	// Once the first exits leave the window the loop is over
+	h.engine.evaluate(start.Add(7 * time.Minute))
+	if got, want := h.states(), []string{StateResolved}; !slices.Equal(got, want) {
+		t.Errorf("published = %v, want %v", got, want)
+	}
+	h.expectSent(t, StateResolved)
+}
+
+func TestEngineHostThresholdFor(t *testing.T) {
+	h := newEngineHarness(t)
+	h.rule(t, Rule{Name: "CPU", Condition: ConditionHostCPU, Threshold: 80, For: Duration(time.Minute)})
+
+	start := time.Unix(1000, 0)
+	h.stats.set(95)
+	h.engine.evaluate(start)
+	h.engine.evaluate(start.Add(30 * time.Second))
+	alerts := h.engine.Alerts()
+	if len(alerts) != 1 || alerts[0].State != StatePending {
+		t.Fatalf("Alerts() = %+v, want one pending alert", alerts)
+	}
+	if got := h.states(); len(got) != 0 {
+		t.Fatalf("published = %v while pending, want nothing", got)
+	}
+
+	h.engine.evaluate(start.Add(time.Minute))
+	if got, want := h.states(), []string{StateFiring}; !slices.Equal(got, want) {
+		t.Fatalf("published = %v, want %v", got, want)
+	}
+	n := h.expectSent(t, StateFiring)
+	if n.Alert.Value != 95 || !n.Alert.Since.Equal(start) {
+		t.Errorf("alert = %+v, want value 95 since %v", n.Alert, start)
+	}
+
+	h.stats.set(40)
+	h.engine.evaluate(start.Add(2 * time.Minute))
+	if got, want := h.states(), []string{StateResolved}; !slices.Equal(got, want) {
+		t.Fatalf("published = %v, want %v", got, want)
+	}
+	h.expectSent(t, StateResolved)
+}
+
+func TestEngineHostThresholdDropsPending(t *testing.T) {
+	h := newEngineHarness(t)
+	h.rule(t, Rule{Name: "CPU", Condition: ConditionHostCPU, Threshold: 80, For: Duration(time.Minute)})
+
+	start := time.Unix(1000, 0)
+	h.stats.set(95)
+	h.engine.evaluate(start)
+	h.stats.set(40)
+	h.engine.evaluate(start.Add(30 * time.Second))
+	h.stats.set(95)
+	h.engine.evaluate(start.Add(time.Minute))
+
+	// The condition restarted at the last evaluation, so it has not held
+	// for the full minute
+	alerts := h.engine.Alerts()
+	if len(alerts) != 1 || alerts[0].State != StatePending || !alerts[0].Since.Equal(start.Add(time.Minute)) {
+		t.Fatalf("Alerts() = %+v, want one alert pending since the last evaluation", alerts)
+	}
+	if got := h.states(); len(got) != 0 {
+		t.Errorf("published = %v, want nothing", got)
+	}
+}
+
+func TestEngineCooldown(t *testing.T) {
+	h := newEngineHarness(t)
+	h.rule(t, Rule{Name: "Crashes", Condition: ConditionContainerExited, Cooldown: Duration(time.Hour)})
+
+	h.engine.handleEvent(containerEvent("die", time.Unix(1000, 0), "1"))
+	h.expectSent(t, StateFiring)
+
+	// The second exit is published but not notified within the cooldown
+	h.engine.handleEvent(containerEvent("die", time.Unix(1010, 0), "1"))
+	if got, want := h.states(), []string{StateFiring, StateFiring}; !slices.Equal(got, want) {
+		t.Fatalf("published = %v, want %v", got, want)
+	}
+	h.expectNothingSent(t)
+
+	// The resolution still follows the notified firing
+	h.engine.handleEvent(containerEvent("start", time.Unix(1020, 0), ""))
+	h.expectSent(t, StateResolved)
+}
+
+func TestEngineSilence(t *testing.T) {
+	h := newEngineHarness(t)
+	rule := h.rule(t, Rule{Name: "Crashes", Condition: ConditionContainerExited})
+	silence, err := h.store.CreateSilence(Silence{RuleID: rule.ID, Container: "web-*", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.engine.handleEvent(containerEvent("die", time.Unix(1000, 0), "1"))
+	alerts := h.engine.Alerts()
+	if len(alerts) != 1 || !alerts[0].Silenced {
+		t.Fatalf("Alerts() = %+v, want one silenced alert", alerts)
+	}
+	h.expectNothingSent(t)
+
+	// Evaluations keep holding the notification back while the silence
+	// lasts and send it once the silence is gone
+	h.engine.evaluate(time.Unix(1010, 0))
+	h.expectNothingSent(t)
+
+	if err := h.store.DeleteSilence(silence.ID); err != nil {
+		t.Fatal(err)
+	}
+	h.engine.evaluate(time.Unix(1020, 0))
+	n := h.expectSent(t, StateFiring)
+	if n.Alert.Silenced {
+		t.Errorf("notification alert is still marked silenced")
+	}
+	if got, want := h.states(), []string{StateFiring}; !slices.Equal(got, want) {
+		t.Errorf("published = %v, want the firing published once, %v", got, want)
+	}
+}
+
+func TestEngineSilencedResolution(t *testing.T) {
+	h := newEngineHarness(t)
+	h.rule(t, Rule{Name: "Crashes", Condition: ConditionContainerExited})
+
+	h.engine.handleEvent(containerEvent("die", time.Unix(1000, 0), "1"))
+	h.expectSent(t, StateFiring)
+
+	if _, err := h.store.CreateSilence(Silence{Stack: "web", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	h.engine.handleEvent(containerEvent("start", time.Unix(1010, 0), ""))
+	h.expectNothingSent(t)
+}
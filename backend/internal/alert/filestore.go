@@ -0,0 +1,487 @@
+package alert
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aperture-science-network/internal/fsutil"
+)
+
+// RedactedSecret replaces channel secrets in API responses
+const RedactedSecret = "********"
+
+const (
+	// defaultRestartThreshold and defaultRestartWindow apply to restart
+	// loop rules that leave them unset
+	defaultRestartThreshold = 3
+	defaultRestartWindow    = 10 * time.Minute
+)
+
+type alertsFile struct {
+	Rules    []Rule    `json:"rules"`
+	Channels []Channel `json:"channels"`
+	Silences []Silence `json:"silences"`
+}
+
+// FileStore implements Store as a single JSON file
+type FileStore struct {
+	path     string
+	mu       sync.RWMutex
+	rules    map[string]*Rule
+	channels map[string]*Channel
+	silences map[string]*Silence
+}
+
+// NewFileStore loads (or initializes) the alerting configuration at path
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:     path,
+		rules:    make(map[string]*Rule),
+		channels: make(map[string]*Channel),
+		silences: make(map[string]*Silence),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	var file alertsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Rules {
+		s.rules[file.Rules[i].ID] = &file.Rules[i]
+	}
+	for i := range file.Channels {
+		s.channels[file.Channels[i].ID] = &file.Channels[i]
+	}
+	for i := range file.Silences {
+		s.silences[file.Silences[i].ID] = &file.Silences[i]
+	}
+	return s, nil
+}
+
+// Ensure FileStore implements Store
+var _ Store = (*FileStore)(nil)
+
+func (s *FileStore) ListRules() ([]Rule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := make([]Rule, 0, len(s.rules))
+	for _, r := range s.rules {
+		rules = append(rules, *r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules, nil
+}
+
+func (s *FileStore) CreateRule(rule Rule) (*Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validateRuleLocked(&rule); err != nil {
+		return nil, err
+	}
+	rule.ID = newID()
+	rule.CreatedAt = time.Now().UTC()
+	rule.UpdatedAt = rule.CreatedAt
+
+	s.rules[rule.ID] = &rule
+	if err := s.saveLocked(); err != nil {
+		delete(s.rules, rule.ID)
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *FileStore) UpdateRule(id string, rule Rule) (*Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.rules[id]
+	if !ok {
+		return nil, ErrRuleNotFound
+	}
+	if err := s.validateRuleLocked(&rule); err != nil {
+		return nil, err
+	}
+	rule.ID = id
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now().UTC()
+
+	s.rules[id] = &rule
+	if err := s.saveLocked(); err != nil {
+		s.rules[id] = existing
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *FileStore) DeleteRule(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.rules[id]
+	if !ok {
+		return ErrRuleNotFound
+	}
+	delete(s.rules, id)
+	if err := s.saveLocked(); err != nil {
+		s.rules[id] = existing
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) ListChannels() ([]Channel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channels := make([]Channel, 0, len(s.channels))
+	for _, ch := range s.channels {
+		channels = append(channels, *ch)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Name < channels[j].Name })
+	return channels, nil
+}
+
+func (s *FileStore) GetChannel(id string) (*Channel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ch, ok := s.channels[id]
+	if !ok {
+		return nil, ErrChannelNotFound
+	}
+	channel := *ch
+	return &channel, nil
+}
+
+func (s *FileStore) CreateChannel(channel Channel) (*Channel, error) {
+	if err := validateChannel(&channel); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel.ID = newID()
+	channel.CreatedAt = time.Now().UTC()
+	channel.UpdatedAt = channel.CreatedAt
+
+	s.channels[channel.ID] = &channel
+	if err := s.saveLocked(); err != nil {
+		delete(s.channels, channel.ID)
+		return nil, err
+	}
+	return &channel, nil
+}
+
+func (s *FileStore) UpdateChannel(id string, channel Channel) (*Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.channels[id]
+	if !ok {
+		return nil, ErrChannelNotFound
+	}
+	keepSecrets(&channel, existing)
+	if err := validateChannel(&channel); err != nil {
+		return nil, err
+	}
+	channel.ID = id
+	channel.CreatedAt = existing.CreatedAt
+	channel.UpdatedAt = time.Now().UTC()
+
+	s.channels[id] = &channel
+	if err := s.saveLocked(); err != nil {
+		s.channels[id] = existing
+		return nil, err
+	}
+	return &channel, nil
+}
+
+func (s *FileStore) DeleteChannel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.channels[id]
+	if !ok {
+		return ErrChannelNotFound
+	}
+	for _, r := range s.rules {
+		for _, chID := range r.Channels {
+			if chID == id {
+				return fmt.Errorf("%w: %s", ErrChannelInUse, r.Name)
+			}
+		}
+	}
+	delete(s.channels, id)
+	if err := s.saveLocked(); err != nil {
+		s.channels[id] = existing
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) ListSilences() ([]Silence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	silences := make([]Silence, 0, len(s.silences))
+	for _, sl := range s.silences {
+		if sl.ExpiresAt.After(now) {
+			silences = append(silences, *sl)
+		}
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].ExpiresAt.Before(silences[j].ExpiresAt) })
+	return silences, nil
+}
+
+func (s *FileStore) CreateSilence(silence Silence) (*Silence, error) {
+	now := time.Now().UTC()
+	if !silence.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", ErrInvalidSilence)
+	}
+	for _, pattern := range []string{silence.Stack, silence.Container} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: bad pattern %q", ErrInvalidSilence, pattern)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if silence.RuleID != "" {
+		if _, ok := s.rules[silence.RuleID]; !ok {
+			return nil, fmt.Errorf("%w: unknown rule %s", ErrInvalidSilence, silence.RuleID)
+		}
+	}
+	silence.ID = newID()
+	silence.CreatedAt = now
+	silence.ExpiresAt = silence.ExpiresAt.UTC()
+
+	// Expired silences are dropped whenever a new one is added
+	for id, sl := range s.silences {
+		if !sl.ExpiresAt.After(now) {
+			delete(s.silences, id)
+		}
+	}
+	s.silences[silence.ID] = &silence
+	if err := s.saveLocked(); err != nil {
+		delete(s.silences, silence.ID)
+		return nil, err
+	}
+	return &silence, nil
+}
+
+func (s *FileStore) DeleteSilence(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.silences[id]
+	if !ok {
+		return ErrSilenceNotFound
+	}
+	delete(s.silences, id)
+	if err := s.saveLocked(); err != nil {
+		s.silences[id] = existing
+		return err
+	}
+	return nil
+}
+
+// saveLocked writes the configuration to disk. Callers must hold mu.
+func (s *FileStore) saveLocked() error {
+	file := alertsFile{
+		Rules:    make([]Rule, 0, len(s.rules)),
+		Channels: make([]Channel, 0, len(s.channels)),
+		Silences: make([]Silence, 0, len(s.silences)),
+	}
+	for _, r := range s.rules {
+		file.Rules = append(file.Rules, *r)
+	}
+	for _, ch := range s.channels {
+		file.Channels = append(file.Channels, *ch)
+	}
+	for _, sl := range s.silences {
+		file.Silences = append(file.Silences, *sl)
+	}
+	sort.Slice(file.Rules, func(i, j int) bool { return file.Rules[i].ID < file.Rules[j].ID })
+	sort.Slice(file.Channels, func(i, j int) bool { return file.Channels[i].ID < file.Channels[j].ID })
+	sort.Slice(file.Silences, func(i, j int) bool { return file.Silences[i].ID < file.Silences[j].ID })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(s.path, data, 0600)
+}
+
+// validateRuleLocked checks a rule and fills in defaults. Callers must hold mu.
+func (s *FileStore) validateRuleLocked(r *Rule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if !r.Condition.valid() {
+		return fmt.Errorf("%w: unknown condition %q", ErrInvalidRule, r.Condition)
+	}
+	if r.For < 0 || r.Window < 0 || r.Cooldown < 0 {
+		return fmt.Errorf("%w: durations cannot be negative", ErrInvalidRule)
+	}
+
+	switch {
+	case r.Condition.host():
+		if r.Threshold <= 0 || r.Threshold > 100 {
+			return fmt.Errorf("%w: threshold must be a percentage above 0", ErrInvalidRule)
+		}
+		if r.Stack != "" || r.Container != "" {
+			return fmt.Errorf("%w: host conditions do not match stacks or containers", ErrInvalidRule)
+		}
+	case r.Condition == ConditionRestartLoop:
+		if r.Threshold == 0 {
+			r.Threshold = defaultRestartThreshold
+		}
+		if r.Threshold < 1 || r.Threshold != float64(int(r.Threshold)) {
+			return fmt.Errorf("%w: threshold must be a whole number of exits", ErrInvalidRule)
+		}
+		if r.Window == 0 {
+			r.Window = Duration(defaultRestartWindow)
+		}
+	default:
+		r.Threshold = 0
+	}
+
+	for _, pattern := range []string{r.Stack, r.Container} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: bad pattern %q", ErrInvalidRule, pattern)
+		}
+	}
+
+	if r.Channels == nil {
+		r.Channels = []string{}
+	}
+	for _, id := range r.Channels {
+		if _, ok := s.channels[id]; !ok {
+			return fmt.Errorf("%w: unknown channel %s", ErrInvalidRule, id)
+		}
+	}
+	return nil
+}
+
+func validateChannel(ch *Channel) error {
+	ch.Name = strings.TrimSpace(ch.Name)
+	if ch.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidChannel)
+	}
+
+	switch ch.Type {
+	case ChannelWebhook, ChannelNtfy, ChannelGotify:
+		u, err := url.Parse(ch.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: url must be an http or https URL", ErrInvalidChannel)
+		}
+		if ch.Type == ChannelGotify && ch.Token == "" {
+			return fmt.Errorf("%w: gotify needs an application token", ErrInvalidChannel)
+		}
+		ch.SMTP = nil
+	case ChannelSMTP:
+		cfg := ch.SMTP
+		if cfg == nil || cfg.Host == "" {
+			return fmt.Errorf("%w: smtp host is required", ErrInvalidChannel)
+		}
+		if cfg.Security == "" {
+			cfg.Security = SMTPStartTLS
+		}
+		if cfg.Security != SMTPStartTLS && cfg.Security != SMTPTLS && cfg.Security != SMTPNone {
+			return fmt.Errorf("%w: smtp security must be starttls, tls or none", ErrInvalidChannel)
+		}
+		if cfg.Port < 0 || cfg.Port > 65535 {
+			return fmt.Errorf("%w: invalid smtp port", ErrInvalidChannel)
+		}
+		if _, err := mail.ParseAddress(cfg.From); err != nil {
+			return fmt.Errorf("%w: invalid from address", ErrInvalidChannel)
+		}
+		if len(cfg.To) == 0 {
+			return fmt.Errorf("%w: at least one recipient is required", ErrInvalidChannel)
+		}
+		for _, to := range cfg.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("%w: invalid recipient %q", ErrInvalidChannel, to)
+			}
+		}
+		ch.URL = ""
+		ch.Token = ""
+		ch.Headers = nil
+	default:
+		return fmt.Errorf("%w: type must be webhook, smtp, ntfy or gotify", ErrInvalidChannel)
+	}
+
+	if ch.Priority < 0 || (ch.Type == ChannelNtfy && ch.Priority > 5) || ch.Priority > 10 {
+		return fmt.Errorf("%w: priority out of range", ErrInvalidChannel)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the channel with its secrets replaced by
+// RedactedSecret
+func (ch Channel) Redacted() Channel {
+	if ch.Token != "" {
+		ch.Token = RedactedSecret
+	}
+	if len(ch.Headers) > 0 {
+		headers := make(map[string]string, len(ch.Headers))
+		for k := range ch.Headers {
+			headers[k] = RedactedSecret
+		}
+		ch.Headers = headers
+	}
+	if ch.SMTP != nil && ch.SMTP.Password != "" {
+		smtp := *ch.SMTP
+		smtp.Password = RedactedSecret
+		ch.SMTP = &smtp
+	}
+	return ch
+}
+
+// keepSecrets carries over secrets an update left empty or redacted, as
+// long as the channel type is unchanged
+func keepSecrets(ch *Channel, existing *Channel) {
+	if ch.Type != existing.Type {
+		return
+	}
+	if ch.Token == "" || ch.Token == RedactedSecret {
+		ch.Token = existing.Token
+	}
+	for k, v := range ch.Headers {
+		if v == RedactedSecret {
+			ch.Headers[k] = existing.Headers[k]
+		}
+	}
+	if ch.SMTP != nil && existing.SMTP != nil && (ch.SMTP.Password == "" || ch.SMTP.Password == RedactedSecret) {
+		ch.SMTP.Password = existing.SMTP.Password
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
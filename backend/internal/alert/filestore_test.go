@@ -0,0 +1,104 @@
+package alert
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreRedactedSecretRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		channel Channel
+	}{
+		{
+			name:    "ntfy token",
+			channel: Channel{Name: "ntfy", Type: ChannelNtfy, URL: "https://ntfy.sh/alerts", Token: "tk_secret"},
+		},
+		{
+			name: "webhook headers",
+			channel: Channel{Name: "hook", Type: ChannelWebhook, URL: "https://example.com/hook",
+				Headers: map[string]string{"Authorization": "Bearer abc", "X-Team": "ops"}},
+		},
+		{
+			name: "smtp password",
+			channel: Channel{Name: "mail", Type: ChannelSMTP, SMTP: &SMTPSettings{
+				Host: "smtp.example.com", Username: "alerts", Password: "hunter2",
+				From: "alerts@example.com", To: []string{"ops@example.com"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := store.CreateChannel(tt.channel)
+			if err != nil {
+				t.Fatalf("CreateChannel() error = %v", err)
+			}
+
+			// A client edits the redacted copy it was given and sends it back
+			update := created.Redacted()
+			if update.Token != "" && update.Token != RedactedSecret {
+				t.Fatalf("Redacted() Token = %q", update.Token)
+			}
+			update.Name += " renamed"
+			if update.Headers != nil {
+				update.Headers["X-Team"] = "platform"
+			}
+			if _, err := store.UpdateChannel(created.ID, update); err != nil {
+				t.Fatalf("UpdateChannel() error = %v", err)
+			}
+
+			// Secrets survive the round trip, also after reloading the file
+			reloaded, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range []*FileStore{store, reloaded} {
+				got, err := s.GetChannel(created.ID)
+				if err != nil {
+					t.Fatalf("GetChannel() error = %v", err)
+				}
+				if got.Name != tt.channel.Name+" renamed" {
+					t.Errorf("Name = %q, want the update applied", got.Name)
+				}
+				if got.Token != tt.channel.Token {
+					t.Errorf("Token = %q, want %q", got.Token, tt.channel.Token)
+				}
+				if tt.channel.Headers != nil {
+					if got.Headers["Authorization"] != "Bearer abc" || got.Headers["X-Team"] != "platform" {
+						t.Errorf("Headers = %v, want the stored Authorization and the new X-Team", got.Headers)
+					}
+				}
+				if tt.channel.SMTP != nil && got.SMTP.Password != "hunter2" {
+					t.Errorf("SMTP.Password = %q, want hunter2", got.SMTP.Password)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreChangedSecret(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "alerts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := store.CreateChannel(Channel{Name: "gotify", Type: ChannelGotify, URL: "https://gotify.example.com", Token: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := created.Redacted()
+	update.Token = "new"
+	got, err := store.UpdateChannel(created.ID, update)
+	if err != nil {
+		t.Fatalf("UpdateChannel() error = %v", err)
+	}
+	if got.Token != "new" {
+		t.Errorf("Token = %q, want new", got.Token)
+	}
+}
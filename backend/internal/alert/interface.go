@@ -0,0 +1,253 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrRuleNotFound    = errors.New("alert rule not found")
+	ErrChannelNotFound = errors.New("notification channel not found")
+	ErrSilenceNotFound = errors.New("silence not found")
+	ErrChannelInUse    = errors.New("notification channel is used by an alert rule")
+	ErrInvalidRule     = errors.New("invalid alert rule")
+	ErrInvalidChannel  = errors.New("invalid notification channel")
+	ErrInvalidSilence  = errors.New("invalid silence")
+)
+
+// Condition is what a rule watches for
+type Condition string
+
+const (
+	// ConditionContainerExited fires when a container dies with a non-zero
+	// exit code without having been stopped or killed through Docker
+	ConditionContainerExited Condition = "container_exited"
+	// ConditionContainerUnhealthy fires when a container's health check
+	// reports it unhealthy
+	ConditionContainerUnhealthy Condition = "container_unhealthy"
+	// ConditionRestartLoop fires when a container dies unexpectedly at
+	// least Threshold times within Window
+	ConditionRestartLoop Condition = "container_restart_loop"
+	// ConditionHostCPU fires when host CPU usage is above Threshold percent
+	ConditionHostCPU Condition = "host_cpu_percent"
+	// ConditionHostMemory fires when host memory usage is above Threshold
+	// percent
+	ConditionHostMemory Condition = "host_memory_percent"
+	// ConditionHostDisk fires when root filesystem usage is above Threshold
+	// percent
+	ConditionHostDisk Condition = "host_disk_percent"
+)
+
+// host reports whether the condition is evaluated against host stats
+// rather than containers
+func (c Condition) host() bool {
+	return c == ConditionHostCPU || c == ConditionHostMemory || c == ConditionHostDisk
+}
+
+func (c Condition) valid() bool {
+	switch c {
+	case ConditionContainerExited, ConditionContainerUnhealthy, ConditionRestartLoop:
+		return true
+	}
+	return c.host()
+}
+
+// Duration is a time.Duration written in JSON as a Go duration string such
+// as "90s" or "5m"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	if d == 0 {
+		return json.Marshal("")
+	}
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*d = 0
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Rule describes a condition to alert on and where to send notifications
+type Rule struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Enabled   bool      `json:"enabled"`
+	Condition Condition `json:"condition"`
+
+	// Threshold is a percentage for host conditions and a number of exits
+	// for restart loops
+	Threshold float64 `json:"threshold,omitempty"`
+	// For is how long a host threshold or an unhealthy status must last
+	// before the alert fires
+	For Duration `json:"for,omitempty"`
+	// Window is the period over which restart loop exits are counted
+	Window Duration `json:"window,omitempty"`
+	// Cooldown is the minimum time between two notifications for the same
+	// rule and container
+	Cooldown Duration `json:"cooldown,omitempty"`
+
+	// Stack and Container limit container conditions to matching compose
+	// projects and container names; both accept path.Match patterns
+	Stack     string `json:"stack,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	// Channels are the IDs of the channels notified when the alert fires
+	// and resolves
+	Channels []string `json:"channels"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ChannelType selects how a channel delivers notifications
+type ChannelType string
+
+const (
+	// ChannelWebhook POSTs the notification as JSON to URL
+	ChannelWebhook ChannelType = "webhook"
+	// ChannelSMTP sends an email
+	ChannelSMTP ChannelType = "smtp"
+	// ChannelNtfy publishes to the ntfy topic at URL
+	ChannelNtfy ChannelType = "ntfy"
+	// ChannelGotify posts a message to the Gotify server at URL
+	ChannelGotify ChannelType = "gotify"
+)
+
+// Channel is a destination for notifications
+type Channel struct {
+	ID   string      `json:"id"`
+	Name string      `json:"name"`
+	Type ChannelType `json:"type"`
+
+	// URL is the webhook endpoint, the ntfy topic URL or the Gotify server
+	URL string `json:"url,omitempty"`
+	// Headers are sent with webhook requests
+	Headers map[string]string `json:"headers,omitempty"`
+	// Token is the ntfy access token or the Gotify application token
+	Token string `json:"token,omitempty"`
+	// Priority is passed to ntfy (1-5) and Gotify (0-10); zero leaves the
+	// server's default
+	Priority int `json:"priority,omitempty"`
+
+	SMTP *SMTPSettings `json:"smtp,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SMTP security modes
+const (
+	SMTPStartTLS = "starttls"
+	SMTPTLS      = "tls"
+	SMTPNone     = "none"
+)
+
+// SMTPSettings configure an email channel
+type SMTPSettings struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	// Security is starttls (the default), tls or none
+	Security string `json:"security,omitempty"`
+}
+
+// Silence mutes notifications for alerts it matches until it expires.
+// Empty fields match everything.
+type Silence struct {
+	ID        string `json:"id"`
+	RuleID    string `json:"ruleId,omitempty"`
+	Stack     string `json:"stack,omitempty"`
+	Container string `json:"container,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Alert states
+const (
+	StatePending  = "pending"
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Alert is a rule's condition holding for the host or one container
+type Alert struct {
+	ID        string    `json:"id"`
+	RuleID    string    `json:"ruleId"`
+	RuleName  string    `json:"ruleName"`
+	Condition Condition `json:"condition"`
+	State     string    `json:"state"`
+	Message   string    `json:"message"`
+	// Value is the measured percentage or the number of exits
+	Value float64 `json:"value,omitempty"`
+
+	ContainerID string `json:"containerId,omitempty"`
+	Container   string `json:"container,omitempty"`
+	Stack       string `json:"stack,omitempty"`
+
+	// Since is when the condition started holding
+	Since      time.Time  `json:"since"`
+	FiredAt    *time.Time `json:"firedAt,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	Silenced   bool       `json:"silenced"`
+}
+
+// Store defines the interface for alert rule, channel and silence storage
+type Store interface {
+	// ListRules returns all rules ordered by name
+	ListRules() ([]Rule, error)
+
+	// CreateRule validates and saves a new rule, assigning its ID
+	CreateRule(rule Rule) (*Rule, error)
+
+	// UpdateRule replaces an existing rule
+	UpdateRule(id string, rule Rule) (*Rule, error)
+
+	// DeleteRule removes a rule
+	DeleteRule(id string) error
+
+	// ListChannels returns all channels ordered by name
+	ListChannels() ([]Channel, error)
+
+	// GetChannel returns a channel by ID
+	GetChannel(id string) (*Channel, error)
+
+	// CreateChannel validates and saves a new channel, assigning its ID
+	CreateChannel(channel Channel) (*Channel, error)
+
+	// UpdateChannel replaces an existing channel. Secrets left empty or
+	// set to RedactedSecret keep their stored value.
+	UpdateChannel(id string, channel Channel) (*Channel, error)
+
+	// DeleteChannel removes a channel no rule uses
+	DeleteChannel(id string) error
+
+	// ListSilences returns silences that have not expired, soonest to
+	// expire first
+	ListSilences() ([]Silence, error)
+
+	// CreateSilence saves a new silence, assigning its ID
+	CreateSilence(silence Silence) (*Silence, error)
+
+	// DeleteSilence removes a silence
+	DeleteSilence(id string) error
+}
@@ -0,0 +1,175 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sendTimeout bounds the delivery of one notification
+const sendTimeout = 15 * time.Second
+
+// httpClient is shared by the HTTP-based notifiers
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+// Notification is a message about an alert firing or resolving
+type Notification struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Alert   Alert  `json:"alert"`
+}
+
+// Notifier delivers notifications to one channel
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NewNotifier returns the notifier for a channel's type
+func NewNotifier(ch Channel) (Notifier, error) {
+	switch ch.Type {
+	case ChannelWebhook:
+		return &webhookNotifier{url: ch.URL, headers: ch.Headers}, nil
+	case ChannelNtfy:
+		return &ntfyNotifier{url: ch.URL, token: ch.Token, priority: ch.Priority}, nil
+	case ChannelGotify:
+		return &gotifyNotifier{url: ch.URL, token: ch.Token, priority: ch.Priority}, nil
+	case ChannelSMTP:
+		if ch.SMTP == nil {
+			return nil, fmt.Errorf("%w: smtp settings missing", ErrInvalidChannel)
+		}
+		return &smtpNotifier{settings: *ch.SMTP}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidChannel, ch.Type)
+}
+
+// newNotification words a notification for an alert's current state
+func newNotification(a Alert) Notification {
+	subject := "host"
+	if a.Container != "" {
+		subject = a.Container
+	}
+	return Notification{
+		Title:   fmt.Sprintf("[%s] %s: %s", strings.ToUpper(a.State), a.RuleName, subject),
+		Message: a.Message,
+		Alert:   a,
+	}
+}
+
+// TestNotification is sent when a channel is tested from the API
+func TestNotification() Notification {
+	now := time.Now().UTC()
+	return Notification{
+		Title:   "[TEST] Celeste notification channel",
+		Message: "This is a test notification. If you can read it, the channel works.",
+		Alert: Alert{
+			ID:       "test",
+			RuleName: "Test",
+			State:    StateFiring,
+			Message:  "Test notification",
+			Since:    now,
+			FiredAt:  &now,
+		},
+	}
+}
+
+// webhookNotifier POSTs the notification as JSON
+type webhookNotifier struct {
+	url     string
+	headers map[string]string
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.headers {
+		req.Header.Set(k, v)
+	}
+	return doRequest(req)
+}
+
+// ntfyNotifier publishes the message to an ntfy topic, with the title,
+// priority and tags passed as headers
+type ntfyNotifier struct {
+	url      string
+	token    string
+	priority int
+}
+
+func (n *ntfyNotifier) Notify(ctx context.Context, notification Notification) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, strings.NewReader(notification.Message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", notification.Title)
+	if notification.Alert.State == StateResolved {
+		req.Header.Set("Tags", "white_check_mark")
+	} else {
+		req.Header.Set("Tags", "warning")
+	}
+	if n.priority > 0 {
+		req.Header.Set("Priority", strconv.Itoa(n.priority))
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	return doRequest(req)
+}
+
+// gotifyNotifier posts the message to a Gotify server's message API
+type gotifyNotifier struct {
+	url      string
+	token    string
+	priority int
+}
+
+func (n *gotifyNotifier) Notify(ctx context.Context, notification Notification) error {
+	message := map[string]interface{}{
+		"title":   notification.Title,
+		"message": notification.Message,
+	}
+	if n.priority > 0 {
+		message["priority"] = n.priority
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(n.url, "/")+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", n.token)
+	return doRequest(req)
+}
+
+// doRequest sends req and turns non-2xx responses into errors carrying the
+// start of the response body
+func doRequest(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(body)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,170 @@
+package alert
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// capture records the last request a test server received
+type capture struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newCaptureServer(t *testing.T, status int) (*httptest.Server, *capture) {
+	t.Helper()
+	got := &capture{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.method = r.Method
+		got.path = r.URL.Path
+		got.header = r.Header.Clone()
+		got.body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		io.WriteString(w, "server says no")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func testAlertNotification(state string) Notification {
+	return newNotification(Alert{
+		ID:        "rule1:abc",
+		RuleID:    "rule1",
+		RuleName:  "Crashes",
+		Condition: ConditionContainerExited,
+		State:     state,
+		Message:   "Container web-1 exited unexpectedly with code 137",
+		Container: "web-1",
+		Stack:     "web",
+	})
+}
+
+func TestNewNotification(t *testing.T) {
+	n := testAlertNotification(StateFiring)
+	if want := "[FIRING] Crashes: web-1"; n.Title != want {
+		t.Errorf("Title = %q, want %q", n.Title, want)
+	}
+	host := newNotification(Alert{RuleName: "CPU", State: StateResolved})
+	if want := "[RESOLVED] CPU: host"; host.Title != want {
+		t.Errorf("Title = %q, want %q", host.Title, want)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	srv, got := newCaptureServer(t, http.StatusNoContent)
+	ch := Channel{Type: ChannelWebhook, URL: srv.URL + "/hook", Headers: map[string]string{"X-Secret": "s3cret"}}
+
+	n := testAlertNotification(StateFiring)
+	if err := Send(ch, n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got.method != http.MethodPost || got.path != "/hook" {
+		t.Errorf("request = %s %s, want POST /hook", got.method, got.path)
+	}
+	if ct := got.header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if v := got.header.Get("X-Secret"); v != "s3cret" {
+		t.Errorf("X-Secret = %q, want s3cret", v)
+	}
+	var body Notification
+	if err := json.Unmarshal(got.body, &body); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if body.Title != n.Title || body.Message != n.Message || body.Alert.ID != n.Alert.ID || body.Alert.Stack != "web" {
+		t.Errorf("body = %+v, want %+v", body, n)
+	}
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string
+		token    string
+		priority int
+		wantTags string
+		wantPrio string
+		wantAuth string
+	}{
+		{name: "firing", state: StateFiring, token: "tk_abc", priority: 4, wantTags: "warning", wantPrio: "4", wantAuth: "Bearer tk_abc"},
+		{name: "resolved", state: StateResolved, wantTags: "white_check_mark"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, got := newCaptureServer(t, http.StatusOK)
+			ch := Channel{Type: ChannelNtfy, URL: srv.URL + "/alerts", Token: tt.token, Priority: tt.priority}
+
+			n := testAlertNotification(tt.state)
+			if err := Send(ch, n); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got.path != "/alerts" {
+				t.Errorf("path = %q, want /alerts", got.path)
+			}
+			if string(got.body) != n.Message {
+				t.Errorf("body = %q, want %q", got.body, n.Message)
+			}
+			if v := got.header.Get("Title"); v != n.Title {
+				t.Errorf("Title = %q, want %q", v, n.Title)
+			}
+			if v := got.header.Get("Tags"); v != tt.wantTags {
+				t.Errorf("Tags = %q, want %q", v, tt.wantTags)
+			}
+			if v := got.header.Get("Priority"); v != tt.wantPrio {
+				t.Errorf("Priority = %q, want %q", v, tt.wantPrio)
+			}
+			if v := got.header.Get("Authorization"); v != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", v, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestGotifyNotifier(t *testing.T) {
+	srv, got := newCaptureServer(t, http.StatusOK)
+	ch := Channel{Type: ChannelGotify, URL: srv.URL + "/", Token: "app-token", Priority: 8}
+
+	n := testAlertNotification(StateFiring)
+	if err := Send(ch, n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got.path != "/message" {
+		t.Errorf("path = %q, want /message", got.path)
+	}
+	if v := got.header.Get("X-Gotify-Key"); v != "app-token" {
+		t.Errorf("X-Gotify-Key = %q, want app-token", v)
+	}
+	var body struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}
+	if err := json.Unmarshal(got.body, &body); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if body.Title != n.Title || body.Message != n.Message || body.Priority != 8 {
+		t.Errorf("body = %+v, want title %q, message %q, priority 8", body, n.Title, n.Message)
+	}
+}
+
+func TestNotifierErrorStatus(t *testing.T) {
+	srv, _ := newCaptureServer(t, http.StatusUnauthorized)
+	ch := Channel{Type: ChannelWebhook, URL: srv.URL}
+
+	err := Send(ch, testAlertNotification(StateFiring))
+	if err == nil {
+		t.Fatal("Send() error = nil, want an error for 401")
+	}
+	if !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "server says no") {
+		t.Errorf("Send() error = %q, want status and body", err)
+	}
+}
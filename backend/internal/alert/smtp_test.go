@@ -0,0 +1,158 @@
+package alert
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpSession is what the fake SMTP server received in one session
+type smtpSession struct {
+	commands []string
+	data     string
+	err      error
+}
+
+// serveSMTP accepts one connection on ln and plays a minimal SMTP server
+// without extensions, reporting the session once the client quits
+func serveSMTP(ln net.Listener) <-chan smtpSession {
+	done := make(chan smtpSession, 1)
+	go func() {
+		var s smtpSession
+		defer func() { done <- s }()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			s.err = err
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+		reply("220 localhost ESMTP test")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				s.err = err
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			s.commands = append(s.commands, cmd)
+
+			switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL", "RCPT":
+				reply("250 OK")
+			case "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						s.err = err
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				s.data = data.String()
+				reply("250 OK queued")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return done
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	done := serveSMTP(ln)
+
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	ch := Channel{Type: ChannelSMTP, SMTP: &SMTPSettings{
+		Host:     host,
+		Port:     port,
+		From:     "Celeste <alerts@example.com>",
+		To:       []string{"ops@example.com", "Oncall <oncall@example.com>"},
+		Security: SMTPNone,
+	}}
+
+	n := testAlertNotification(StateFiring)
+	if err := Send(ch, n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	s := <-done
+	if s.err != nil {
+		t.Fatalf("server error = %v", s.err)
+	}
+
+	var envelope []string
+	for _, cmd := range s.commands {
+		if strings.HasPrefix(cmd, "MAIL") || strings.HasPrefix(cmd, "RCPT") {
+			envelope = append(envelope, cmd)
+		}
+	}
+	wantEnvelope := []string{
+		"MAIL FROM:<alerts@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<oncall@example.com>",
+	}
+	if strings.Join(envelope, "\n") != strings.Join(wantEnvelope, "\n") {
+		t.Errorf("envelope = %q, want %q", envelope, wantEnvelope)
+	}
+
+	for _, want := range []string{
+		"From: Celeste <alerts@example.com>\r\n",
+		"To: ops@example.com, Oncall <oncall@example.com>\r\n",
+		"Subject: [FIRING] Crashes: web-1\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n" + n.Message + "\r\n",
+		"Container: web-1\r\n",
+		"Stack: web\r\n",
+		"State: firing\r\n",
+	} {
+		if !strings.Contains(s.data, want) {
+			t.Errorf("message is missing %q:\n%s", want, s.data)
+		}
+	}
+}
+
+func TestSMTPNotifierStartTLSUnsupported(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	serveSMTP(ln)
+
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	ch := Channel{Type: ChannelSMTP, SMTP: &SMTPSettings{
+		Host:     host,
+		Port:     port,
+		From:     "alerts@example.com",
+		To:       []string{"ops@example.com"},
+		Security: SMTPStartTLS,
+	}}
+
+	err = Send(ch, testAlertNotification(StateFiring))
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Send() error = %v, want STARTTLS not supported", err)
+	}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/alert"
+)
+
+// Alerts
+
+// ListAlerts returns the pending and firing alerts the user can see. Host
+// alerts and alerts for containers outside any stack are only visible to
+// unrestricted users.
+func ListAlerts(engine *alert.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		result := make([]alert.Alert, 0)
+		for _, a := range engine.Alerts() {
+			if !user.Restricted() || (a.Stack != "" && user.CanAccessStack(a.Stack)) {
+				result = append(result, a)
+			}
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// Alert rules
+func ListAlertRules(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := store.ListRules()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rules)
+	}
+}
+
+func CreateAlertRule(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body alert.Rule
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rule, err := store.CreateRule(body)
+		if err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, rule)
+	}
+}
+
+func UpdateAlertRule(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body alert.Rule
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rule, err := store.UpdateRule(c.Param("id"), body)
+		if err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rule)
+	}
+}
+
+func DeleteAlertRule(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := store.DeleteRule(c.Param("id")); err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+	}
+}
+
+// Notification channels. Secrets are never returned; send them back
+// unchanged (or empty) to keep them on update.
+func ListAlertChannels(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		channels, err := store.ListChannels()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i := range channels {
+			channels[i] = channels[i].Redacted()
+		}
+		c.JSON(http.StatusOK, channels)
+	}
+}
+
+func CreateAlertChannel(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body alert.Channel
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		channel, err := store.CreateChannel(body)
+		if err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, channel.Redacted())
+	}
+}
+
+func UpdateAlertChannel(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body alert.Channel
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		channel, err := store.UpdateChannel(c.Param("id"), body)
+		if err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, channel.Redacted())
+	}
+}
+
+func DeleteAlertChannel(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := store.DeleteChannel(c.Param("id")); err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+	}
+}
+
+// TestAlertChannel sends a test notification through a channel and reports
+// the delivery error, if any
+func TestAlertChannel(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		channel, err := store.GetChannel(c.Param("id"))
+		if err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := alert.Send(*channel, alert.TestNotification()); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "sent"})
+	}
+}
+
+// Silences
+func ListSilences(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		silences, err := store.ListSilences()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, silences)
+	}
+}
+
+// CreateSilence mutes matching alerts for a duration (e.g. "2h") or until a
+// time. Restricted users must name a stack they can access.
+func CreateSilence(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			RuleID    string    `json:"ruleId"`
+			Stack     string    `json:"stack"`
+			Container string    `json:"container"`
+			Comment   string    `json:"comment"`
+			Duration  string    `json:"duration"`
+			ExpiresAt time.Time `json:"expiresAt"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user := CurrentUser(c)
+		if user.Restricted() && (body.Stack == "" || !user.CanAccessStack(body.Stack)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to this stack is not granted"})
+			return
+		}
+
+		expires := body.ExpiresAt
+		if body.Duration != "" {
+			d, err := time.ParseDuration(body.Duration)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration: " + err.Error()})
+				return
+			}
+			expires = time.Now().Add(d)
+		}
+
+		silence, err := store.CreateSilence(alert.Silence{
+			RuleID:    body.RuleID,
+			Stack:     body.Stack,
+			Container: body.Container,
+			Comment:   body.Comment,
+			CreatedBy: user.Username,
+			ExpiresAt: expires,
+		})
+		if err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, silence)
+	}
+}
+
+func DeleteSilence(store alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user := CurrentUser(c); user.Restricted() {
+			silences, err := store.ListSilences()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			for _, s := range silences {
+				if s.ID == c.Param("id") && (s.Stack == "" || !user.CanAccessStack(s.Stack)) {
+					c.JSON(http.StatusForbidden, gin.H{"error": "Access to this stack is not granted"})
+					return
+				}
+			}
+		}
+
+		if err := store.DeleteSilence(c.Param("id")); err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+	}
+}
+
+func alertErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, alert.ErrRuleNotFound), errors.Is(err, alert.ErrChannelNotFound), errors.Is(err, alert.ErrSilenceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, alert.ErrChannelInUse):
+		return http.StatusConflict
+	case errors.Is(err, alert.ErrInvalidRule), errors.Is(err, alert.ErrInvalidChannel), errors.Is(err, alert.ErrInvalidSilence):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/audit"
+	"aperture-science-network/internal/logs"
+)
+
+const (
+	auditDeferredKey = "auditDeferred"
+	auditErrorKey    = "auditError"
+)
+
+// maxAuditBody is the largest request body whose fields are recorded
+const maxAuditBody = 64 * 1024
+
+// redactedParams are body fields never written to the audit log, at any
+// depth. Large document fields are recorded by size only.
+var (
+	redactedParams = map[string]bool{"password": true, "currentPassword": true, "newPassword": true, "token": true, "headers": true}
+	sizedParams    = map[string]bool{"content": true, "compose": true, "env": true}
+)
+
+// auditWriter captures error response bodies so the error text can be recorded
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(b []byte) (int, error) {
+	if w.Status() >= 400 && w.body.Len() < maxAuditBody {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	if w.Status() >= 400 && w.body.Len() < maxAuditBody {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Audited records the outcome of the wrapped handler as an audit entry
+func Audited(auditLog audit.Log, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		params := auditParams(c)
+
+		// The target is the first path parameter, or the name of the
+		// resource being created
+		target := ""
+		if len(c.Params) > 0 {
+			target = c.Params[0].Value
+		} else if name, ok := params["name"].(string); ok {
+			target = name
+		} else if username, ok := params["username"].(string); ok {
+			target = username
+		} else if reference, ok := params["reference"].(string); ok {
+			target = reference
+		}
+
+		writer := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		if c.GetBool(auditDeferredKey) {
+			return
+		}
+
+		entry := audit.Entry{
+			Time:       start.UTC(),
+			Action:     action,
+			Target:     target,
+			Params:     params,
+			Outcome:    audit.OutcomeSuccess,
+			Status:     writer.Status(),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if user := CurrentUser(c); user != nil {
+			entry.User = user.Username
+		}
+		if entry.Status >= 400 {
+			entry.Outcome = audit.OutcomeFailure
+			var body struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(writer.body.Bytes(), &body) == nil {
+				entry.Error = body.Error
+			}
+		} else if msg := c.GetString(auditErrorKey); msg != "" {
+			entry.Outcome = audit.OutcomeFailure
+			entry.Error = msg
+		}
+
+		if _, err := auditLog.Record(entry); err != nil {
+			log.Printf("Error writing audit entry: %v", err)
+		}
+	}
+}
+
+// deferAudit tells Audited not to record the request because the handler
+// started a job that records its own outcome on completion
+func deferAudit(c *gin.Context) {
+	c.Set(auditDeferredKey, true)
+}
+
+// failAudit records the request as failed even though the response status
+// was successful, for streams that report their errors in-band
+func failAudit(c *gin.Context, err error) {
+	c.Set(auditErrorKey, err.Error())
+}
+
+// auditParams collects path, query and JSON body parameters of the request.
+// The body is restored so the handler can still bind it.
+func auditParams(c *gin.Context) map[string]interface{} {
+	params := make(map[string]interface{})
+
+	for _, p := range c.Params {
+		params[p.Key] = p.Value
+	}
+	for key, values := range c.Request.URL.Query() {
+		if len(values) == 1 {
+			params[key] = values[0]
+		} else {
+			params[key] = values
+		}
+	}
+
+	if c.Request.Body == nil {
+		return params
+	}
+	if c.Request.ContentLength > maxAuditBody {
+		params["truncated"] = true
+		return params
+	}
+
+	// Chunked bodies have no length, so at most one byte past the limit is
+	// read and the rest is left for the handler
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBody+1))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), c.Request.Body), c.Request.Body}
+	if err != nil || len(data) == 0 {
+		return params
+	}
+	if len(data) > maxAuditBody {
+		params["truncated"] = true
+		return params
+	}
+
+	var body map[string]interface{}
+	if json.Unmarshal(data, &body) != nil {
+		return params
+	}
+	for key, value := range body {
+		if sizedParams[key] {
+			if s, ok := value.(string); ok {
+				params[key] = fmt.Sprintf("[%d bytes]", len(s))
+			}
+			continue
+		}
+		params[key] = redactParam(key, value)
+	}
+	return params
+}
+
+// redactParam replaces secret fields in a body value, descending into
+// nested objects and arrays
+func redactParam(key string, value interface{}) interface{} {
+	if redactedParams[key] {
+		return "[redacted]"
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			result[k] = redactParam(k, nested)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, nested := range v {
+			result[i] = redactParam("", nested)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// Audit
+func QueryAudit(auditLog audit.Log) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := audit.Query{
+			User:    c.Query("user"),
+			Action:  c.Query("action"),
+			Target:  c.Query("target"),
+			Outcome: c.Query("outcome"),
+		}
+
+		now := time.Now()
+		var err error
+		if q.Since, err = logs.ParseTime(c.Query("since"), now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since: " + err.Error()})
+			return
+		}
+		if q.Until, err = logs.ParseTime(c.Query("until"), now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until: " + err.Error()})
+			return
+		}
+		if v := c.Query("limit"); v != "" {
+			if q.Limit, err = strconv.Atoi(v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+				return
+			}
+		}
+		if v := c.Query("offset"); v != "" {
+			if q.Offset, err = strconv.Atoi(v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+				return
+			}
+		}
+
+		page, err := auditLog.Query(q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
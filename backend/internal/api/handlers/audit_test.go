@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func auditContext(body string, contentLength int64) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/things?force=true", strings.NewReader(body))
+	c.Request.ContentLength = contentLength
+	return c
+}
+
+func TestAuditParams(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want map[string]interface{}
+	}{
+		{
+			name: "plain fields",
+			body: `{"name":"web","up":true}`,
+			want: map[string]interface{}{"force": "true", "name": "web", "up": true},
+		},
+		{
+			name: "top level secrets",
+			body: `{"username":"bob","password":"hunter22","token":"abc"}`,
+			want: map[string]interface{}{"force": "true", "username": "bob", "password": "[redacted]", "token": "[redacted]"},
+		},
+		{
+			name: "nested secrets",
+			body: `{"smtp":{"host":"mail","password":"hunter22"},"headers":{"Authorization":"Bearer x"}}`,
+			want: map[string]interface{}{
+				"force":   "true",
+				"smtp":    map[string]interface{}{"host": "mail", "password": "[redacted]"},
+				"headers": "[redacted]",
+			},
+		},
+		{
+			name: "secrets in arrays",
+			body: `{"channels":[{"name":"a","token":"abc"}]}`,
+			want: map[string]interface{}{
+				"force":    "true",
+				"channels": []interface{}{map[string]interface{}{"name": "a", "token": "[redacted]"}},
+			},
+		},
+		{
+			name: "documents by size",
+			body: `{"content":"services: {}\n"}`,
+			want: map[string]interface{}{"force": "true", "content": "[13 bytes]"},
+		},
+		{
+			name: "not json",
+			body: `name=web`,
+			want: map[string]interface{}{"force": "true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := auditContext(tt.body, int64(len(tt.body)))
+			if got := auditParams(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditParams() = %v, want %v", got, tt.want)
+			}
+			if rest, _ := io.ReadAll(c.Request.Body); string(rest) != tt.body {
+				t.Errorf("body not restored: %q", rest)
+			}
+		})
+	}
+}
+
+func TestAuditParamsLargeBody(t *testing.T) {
+	body := `{"name":"` + strings.Repeat("a", maxAuditBody) + `"}`
+
+	for _, length := range []int64{int64(len(body)), -1} {
+		c := auditContext(body, length)
+		got := auditParams(c)
+		if got["truncated"] != true || got["name"] != nil {
+			t.Errorf("ContentLength %d: auditParams() = %v, want truncated", length, got)
+		}
+		rest, _ := io.ReadAll(c.Request.Body)
+		if !bytes.Equal(rest, []byte(body)) {
+			t.Errorf("ContentLength %d: body not restored, got %d bytes", length, len(rest))
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/auth"
+	"aperture-science-network/internal/docker"
+)
+
+// SessionCookie is the name of the cookie carrying the session token
+const SessionCookie = "celeste_session"
+
+const userContextKey = "user"
+
+// CurrentUser returns the authenticated user for the request, if any
+func CurrentUser(c *gin.Context) *auth.User {
+	if v, ok := c.Get(userContextKey); ok {
+		if user, ok := v.(*auth.User); ok {
+			return user
+		}
+	}
+	return nil
+}
+
+// sessionToken extracts the session token from the cookie, the Authorization
+// header or, for WebSocket upgrades only, the "token" query parameter
+func sessionToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if cookie, err := c.Cookie(SessionCookie); err == nil && cookie != "" {
+		return cookie
+	}
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		return c.Query("token")
+	}
+	return ""
+}
+
+func setSessionCookie(c *gin.Context, token string, expires time.Time) {
+	maxAge := int(time.Until(expires).Seconds())
+	if token == "" {
+		maxAge = -1
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// RequireAuth rejects requests that do not carry a valid session
+func RequireAuth(store auth.Store, sessions *auth.Sessions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := sessionToken(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		claims, err := sessions.Validate(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Reload the account so deleted users lose access immediately
+		user, err := store.GetUser(claims.Subject)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": auth.ErrInvalidSession.Error()})
+			return
+		}
+
+		c.Set(userContextKey, user)
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests from users below the given role
+func RequireRole(role auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil || !user.Role.Includes(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Requires " + string(role) + " role"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireStackAccess rejects requests for a stack outside the user's team grants
+func RequireStackAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil || !user.CanAccessStack(c.Param("name")) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access to this stack is not granted"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireUnrestricted rejects requests from users limited to their teams'
+// stacks
+func RequireUnrestricted() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil || user.Restricted() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Requires access to all stacks"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireContainerAccess rejects requests for a container whose compose
+// project is outside the user's team grants. Containers that do not belong
+// to any stack are only visible to unrestricted users.
+func RequireContainerAccess(dockerClient docker.DockerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+		if !user.Restricted() {
+			c.Next()
+			return
+		}
+
+		ctr, err := dockerClient.GetContainer(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if !canAccessContainer(user, ctr) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access to this container is not granted"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func canAccessContainer(user *auth.User, ctr *docker.ContainerInfo) bool {
+	if !user.Restricted() {
+		return true
+	}
+	project, ok := ctr.Labels["com.docker.compose.project"]
+	return ok && user.CanAccessStack(project)
+}
+
+// Auth
+func GetSetupStatus(store auth.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hasUsers, err := store.HasUsers()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"setupRequired": !hasUsers})
+	}
+}
+
+func Setup(store auth.Store, sessions *auth.Sessions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := store.Bootstrap(body.Username, body.Password)
+		if err != nil {
+			c.JSON(authErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		issueSession(c, sessions, user, http.StatusCreated)
+	}
+}
+
+func Login(store auth.Store, sessions *auth.Sessions, throttle *auth.Throttle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ip := c.ClientIP()
+		if wait := throttle.Wait(body.Username, ip, time.Now()); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": auth.ErrTooManyAttempts.Error()})
+			return
+		}
+
+		user, err := store.Authenticate(body.Username, body.Password)
+		if err != nil {
+			throttle.Fail(body.Username, ip, time.Now())
+			c.JSON(http.StatusUnauthorized, gin.H{"error": auth.ErrInvalidCredentials.Error()})
+			return
+		}
+		throttle.Succeed(body.Username)
+
+		issueSession(c, sessions, user, http.StatusOK)
+	}
+}
+
+func Logout(sessions *auth.Sessions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := sessionToken(c); token != "" {
+			if err := sessions.Revoke(token); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		setSessionCookie(c, "", time.Time{})
+		c.JSON(http.StatusOK, gin.H{"status": "logged out"})
+	}
+}
+
+func GetSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user": CurrentUser(c)})
+	}
+}
+
+func ChangePassword(store auth.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			CurrentPassword string `json:"currentPassword"`
+			NewPassword     string `json:"newPassword"`
+		}
+
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user := CurrentUser(c)
+		if _, err := store.Authenticate(user.Username, body.CurrentPassword); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+
+		if _, err := store.UpdateUser(user.Username, auth.UserUpdate{Password: &body.NewPassword}); err != nil {
+			c.JSON(authErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "updated"})
+	}
+}
+
+func issueSession(c *gin.Context, sessions *auth.Sessions, user *auth.User, status int) {
+	token, expires, err := sessions.Issue(user.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	setSessionCookie(c, token, expires)
+	c.JSON(status, gin.H{
+		"user":      user,
+		"token":     token,
+		"expiresAt": expires.Unix(),
+	})
+}
+
+// authErrorStatus maps auth store errors to HTTP status codes
+func authErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound), errors.Is(err, auth.ErrTeamNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, auth.ErrUserExists), errors.Is(err, auth.ErrSetupComplete), errors.Is(err, auth.ErrLastAdmin),
+		errors.Is(err, auth.ErrTeamInUse):
+		return http.StatusConflict
+	case errors.Is(err, auth.ErrInvalidUsername), errors.Is(err, auth.ErrInvalidTeamName),
+		errors.Is(err, auth.ErrWeakPassword), errors.Is(err, auth.ErrInvalidRole), errors.Is(err, auth.ErrInvalidPattern):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
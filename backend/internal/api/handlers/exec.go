@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/ws"
+)
+
+// Default terminal size when the client does not send one
+const (
+	defaultExecRows = 24
+	defaultExecCols = 80
+)
+
+// Exec
+
+// ExecContainer opens an interactive terminal in a running container over a
+// WebSocket; see ws.HandleExec for the protocol. The command is the shell
+// query parameter, split on spaces, or defaultShell. The user, rows and cols
+// query parameters set the user the command runs as and the initial
+// terminal size.
+func ExecContainer(dockerClient docker.DockerClient, hub *ws.Hub, defaultShell string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !websocket.IsWebSocketUpgrade(c.Request) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "WebSocket upgrade required"})
+			return
+		}
+
+		cmd := strings.Fields(c.DefaultQuery("shell", defaultShell))
+		if len(cmd) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "shell must not be empty"})
+			return
+		}
+		size := docker.TerminalSize{Rows: defaultExecRows, Cols: defaultExecCols}
+		if !terminalDimension(c, "rows", &size.Rows) || !terminalDimension(c, "cols", &size.Cols) {
+			return
+		}
+
+		ctx := c.Request.Context()
+		ctr, err := dockerClient.GetContainer(ctx, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if ctr.State != "running" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Container is not running"})
+			return
+		}
+
+		execID, err := dockerClient.CreateExec(ctx, ctr.ID, docker.ExecOptions{
+			Cmd:  cmd,
+			User: c.Query("user"),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ws.HandleExec(hub, c.Writer, c.Request, dockerClient, execID, size, CurrentUser(c))
+	}
+}
+
+// terminalDimension reads an optional terminal row or column count
+func terminalDimension(c *gin.Context, name string, value *uint) bool {
+	v := c.Query(name)
+	if v == "" {
+		return true
+	}
+	n, err := strconv.ParseUint(v, 10, 16)
+	if err != nil || n == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a positive number"})
+		return false
+	}
+	*value = uint(n)
+	return true
+}
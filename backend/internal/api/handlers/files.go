@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/stack"
+)
+
+// Stack files
+func ListStackFiles(stackProvider stack.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entries, err := stackProvider.ListFiles(c.Param("name"))
+		if err != nil {
+			c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+func ReadStackFile(stackProvider stack.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		file, err := stackProvider.ReadFile(c.Param("name"), c.Query("path"))
+		if err != nil {
+			c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, file)
+	}
+}
+
+func WriteStackFile(stackProvider stack.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Content string `json:"content"`
+		}
+
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		entry, err := stackProvider.WriteFile(c.Param("name"), c.Query("path"), body.Content)
+		if err != nil {
+			c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+func CreateStackFile(stackProvider stack.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Content   string `json:"content"`
+			Directory bool   `json:"directory"`
+		}
+
+		if c.Request.ContentLength != 0 {
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		entry, err := stackProvider.CreateFile(c.Param("name"), c.Query("path"), body.Content, body.Directory)
+		if err != nil {
+			c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, entry)
+	}
+}
+
+func DeleteStackFile(stackProvider stack.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := stackProvider.DeleteFile(c.Param("name"), c.Query("path")); err != nil {
+			c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+	}
+}
+
+// fileErrorStatus maps stack file errors to HTTP status codes
+func fileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, stack.ErrStackNotFound), errors.Is(err, stack.ErrFileNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, stack.ErrFileExists), errors.Is(err, stack.ErrDirectoryNotEmpty):
+		return http.StatusConflict
+	case errors.Is(err, stack.ErrFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, stack.ErrInvalidPath), errors.Is(err, stack.ErrNotAFile), errors.Is(err, stack.ErrBinaryContent):
+		return http.StatusBadRequest
+	case errors.Is(err, stack.ErrProtectedFile):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
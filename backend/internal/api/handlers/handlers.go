@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -9,8 +10,11 @@ import (
 
 	"aperture-science-network/internal/compose"
 	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/history"
+	"aperture-science-network/internal/jobs"
 	"aperture-science-network/internal/stack"
 	"aperture-science-network/internal/stats"
+	"aperture-science-network/internal/updates"
 )
 
 // System Stats
@@ -26,18 +30,27 @@ func GetSystemStats(statsProvider stats.Provider) gin.HandlerFunc {
 }
 
 // Stacks
-func ListStacks(stackProvider stack.Provider) gin.HandlerFunc {
+func ListStacks(stackProvider stack.Provider, checker *updates.Checker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		stacks, err := stackProvider.ListStacks()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, stacks)
+
+		user := CurrentUser(c)
+		visible := make([]stack.StackInfo, 0, len(stacks))
+		for _, s := range stacks {
+			if user.CanAccessStack(s.Name) {
+				s.UpdatesAvailable = checker.StackUpdatesAvailable(s.Name)
+				visible = append(visible, s)
+			}
+		}
+		c.JSON(http.StatusOK, visible)
 	}
 }
 
-func GetStack(stackProvider stack.Provider) gin.HandlerFunc {
+func GetStack(stackProvider stack.Provider, checker *updates.Checker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		name := c.Param("name")
 		s, err := stackProvider.GetStack(name)
@@ -45,32 +58,31 @@ func GetStack(stackProvider stack.Provider) gin.HandlerFunc {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Stack not found"})
 			return
 		}
+		s.UpdatesAvailable = checker.StackUpdatesAvailable(s.Name)
 		c.JSON(http.StatusOK, s)
 	}
 }
 
-func StartStack(stackProvider stack.Provider, composeManager *compose.Manager) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		name := c.Param("name")
+func StartStack(stackProvider stack.Provider, composeManager *compose.Manager, jobManager *jobs.Manager) gin.HandlerFunc {
+	return stackJob(stackProvider, jobManager, "start", 2*time.Minute, composeManager.Up)
+}
 
-		if !stackProvider.StackExists(name) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Stack not found"})
-			return
-		}
+func StopStack(stackProvider stack.Provider, composeManager *compose.Manager, jobManager *jobs.Manager) gin.HandlerFunc {
+	return stackJob(stackProvider, jobManager, "stop", 2*time.Minute, composeManager.Down)
+}
 
-		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
-		defer cancel()
+func RestartStack(stackProvider stack.Provider, composeManager *compose.Manager, jobManager *jobs.Manager) gin.HandlerFunc {
+	return stackJob(stackProvider, jobManager, "restart", 2*time.Minute, composeManager.Restart)
+}
 
-		stackPath := stackProvider.GetStackPath(name)
-		if err := composeManager.Up(ctx, stackPath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"status": "started"})
-	}
+func PullStack(stackProvider stack.Provider, composeManager *compose.Manager, jobManager *jobs.Manager) gin.HandlerFunc {
+	return stackJob(stackProvider, jobManager, "pull", 5*time.Minute, composeManager.Pull)
 }
 
-func StopStack(stackProvider stack.Provider, composeManager *compose.Manager) gin.HandlerFunc {
+// stackJob queues a compose operation as a background job and responds with
+// the job immediately. Output is streamed to WebSocket clients as it arrives.
+func stackJob(stackProvider stack.Provider, jobManager *jobs.Manager, action string, timeout time.Duration,
+	op func(ctx context.Context, stackPath string, output compose.OutputFunc) error) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		name := c.Param("name")
 
@@ -79,80 +91,101 @@ func StopStack(stackProvider stack.Provider, composeManager *compose.Manager) gi
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
-		defer cancel()
-
 		stackPath := stackProvider.GetStackPath(name)
-		if err := composeManager.Down(ctx, stackPath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"status": "stopped"})
+		job := jobManager.Enqueue(jobs.Spec{
+			Stack:   name,
+			Action:  action,
+			User:    CurrentUser(c).Username,
+			Timeout: timeout,
+			Run: func(ctx context.Context, output func(stream string, line string)) error {
+				return op(ctx, stackPath, output)
+			},
+		})
+
+		// The job records its own audit entry when it finishes
+		deferAudit(c)
+		c.JSON(http.StatusAccepted, gin.H{"status": "accepted", "job": job})
 	}
 }
 
-func RestartStack(stackProvider stack.Provider, composeManager *compose.Manager) gin.HandlerFunc {
+func GetComposeFile(stackProvider stack.Provider) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		name := c.Param("name")
-
-		if !stackProvider.StackExists(name) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Stack not found"})
-			return
-		}
-
-		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
-		defer cancel()
-
-		stackPath := stackProvider.GetStackPath(name)
-		if err := composeManager.Restart(ctx, stackPath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		content, err := stackProvider.GetComposeFile(name)
+		if err != nil {
+			if errors.Is(err, compose.ErrOutsideProject) {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "Compose file not found"})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"status": "restarted"})
+		c.JSON(http.StatusOK, gin.H{
+			"content": content,
+		})
 	}
 }
 
-func PullStack(stackProvider stack.Provider, composeManager *compose.Manager) gin.HandlerFunc {
+// UpdateComposeFile validates and saves a stack's main compose file,
+// recording the new content as a revision. An invalid file is refused with
+// 422 and the list of issues unless force is set. With verify, the content
+// is also checked with `docker compose config`.
+func UpdateComposeFile(stackProvider stack.Provider, composeManager *compose.Manager, historyStore history.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		name := c.Param("name")
 
-		if !stackProvider.StackExists(name) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Stack not found"})
-			return
+		var body struct {
+			Content string `json:"content"`
+			Message string `json:"message"`
+			Force   bool   `json:"force"`
+			Verify  bool   `json:"verify"`
 		}
 
-		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
-		defer cancel()
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		stackPath := stackProvider.GetStackPath(name)
-		if err := composeManager.Pull(ctx, stackPath); err != nil {
+		issues, err := checkCompose(c.Request.Context(), stackProvider, composeManager, name, body.Content, body.Verify)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"status": "pulled"})
-	}
-}
+		if compose.HasErrors(issues) && !body.Force {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":  "Compose file is invalid",
+				"issues": issues,
+			})
+			return
+		}
 
-func GetComposeFile(stackProvider stack.Provider) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		name := c.Param("name")
-		content, err := stackProvider.GetComposeFile(name)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Compose file not found"})
+		previous, _ := stackProvider.GetComposeFile(name)
+		if err := stackProvider.UpdateComposeFile(name, body.Content); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, compose.ErrOutsideProject) {
+				status = http.StatusUnprocessableEntity
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
 			return
 		}
+		rev := recordRevision(historyStore, previous, name, body.Content, CurrentUser(c).Username, body.Message)
+
 		c.JSON(http.StatusOK, gin.H{
-			"content": content,
+			"status":   "updated",
+			"issues":   issues,
+			"revision": rev,
 		})
 	}
 }
 
-func UpdateComposeFile(stackProvider stack.Provider) gin.HandlerFunc {
+// ValidateComposeFile checks compose content for a stack without saving it
+func ValidateComposeFile(stackProvider stack.Provider, composeManager *compose.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		name := c.Param("name")
 
 		var body struct {
 			Content string `json:"content"`
+			Verify  bool   `json:"verify"`
 		}
 
 		if err := c.BindJSON(&body); err != nil {
@@ -160,17 +193,45 @@ func UpdateComposeFile(stackProvider stack.Provider) gin.HandlerFunc {
 			return
 		}
 
-		if err := stackProvider.UpdateComposeFile(name, body.Content); err != nil {
+		issues, err := checkCompose(c.Request.Context(), stackProvider, composeManager, name, body.Content, body.Verify)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"status": "updated"})
+		c.JSON(http.StatusOK, gin.H{
+			"valid":  !compose.HasErrors(issues),
+			"issues": issues,
+		})
+	}
+}
+
+// checkCompose validates content as the main compose file of a stack. The
+// CLI dry run only happens when the document passes the built-in checks.
+func checkCompose(ctx context.Context, stackProvider stack.Provider, composeManager *compose.Manager,
+	name string, content string, verify bool) ([]compose.Issue, error) {
+	partial := false
+	if info, err := stackProvider.GetStack(name); err == nil {
+		partial = len(info.ComposeFiles) > 1
+	}
+
+	issues := compose.Validate(content, partial)
+	if !verify || compose.HasErrors(issues) {
+		return issues, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cliIssues, err := composeManager.Config(ctx, stackProvider.GetStackPath(name), content)
+	if err != nil {
+		return nil, err
 	}
+	return append(issues, cliIssues...), nil
 }
 
 // Containers
-func ListContainers(dockerClient docker.DockerClient) gin.HandlerFunc {
+func ListContainers(dockerClient docker.DockerClient, checker *updates.Checker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		all := c.Query("all") == "true"
 		containers, err := dockerClient.ListContainers(c.Request.Context(), all)
@@ -178,11 +239,20 @@ func ListContainers(dockerClient docker.DockerClient) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, containers)
+
+		user := CurrentUser(c)
+		visible := make([]docker.ContainerInfo, 0, len(containers))
+		for i := range containers {
+			if canAccessContainer(user, &containers[i]) {
+				containers[i].UpdateAvailable = checker.ContainerUpdateAvailable(containers[i].ID)
+				visible = append(visible, containers[i])
+			}
+		}
+		c.JSON(http.StatusOK, visible)
 	}
 }
 
-func GetContainer(dockerClient docker.DockerClient) gin.HandlerFunc {
+func GetContainer(dockerClient docker.DockerClient, checker *updates.Checker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		container, err := dockerClient.GetContainer(c.Request.Context(), id)
@@ -190,6 +260,7 @@ func GetContainer(dockerClient docker.DockerClient) gin.HandlerFunc {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		container.UpdateAvailable = checker.ContainerUpdateAvailable(container.ID)
 		c.JSON(http.StatusOK, container)
 	}
 }
@@ -227,20 +298,6 @@ func RestartContainer(dockerClient docker.DockerClient) gin.HandlerFunc {
 	}
 }
 
-func GetContainerLogs(dockerClient docker.DockerClient) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		id := c.Param("id")
-		tail := c.DefaultQuery("tail", "100")
-
-		logs, err := dockerClient.GetContainerLogs(c.Request.Context(), id, tail)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"logs": logs})
-	}
-}
-
 func GetContainerStats(dockerClient docker.DockerClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
@@ -352,15 +409,3 @@ func DeleteNetwork(dockerClient docker.DockerClient) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"status": "deleted"})
 	}
 }
-
-// Images
-func ListImages(dockerClient docker.DockerClient) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		images, err := dockerClient.ListImages(c.Request.Context())
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusOK, images)
-	}
-}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/compose"
+	"aperture-science-network/internal/history"
+	"aperture-science-network/internal/jobs"
+	"aperture-science-network/internal/stack"
+)
+
+// recordRevision saves content to a stack's history after a successful
+// write. The first time a stack is saved, the content it had before is
+// recorded too so the original can always be restored.
+func recordRevision(historyStore history.Store, previous string, name string, content string, author string, message string) *history.Revision {
+	if previous != "" {
+		if revisions, err := historyStore.List(name); err == nil && len(revisions) == 0 {
+			if _, err := historyStore.Record(name, previous, "", "Existing compose file"); err != nil {
+				log.Printf("Error recording revision for %s: %v", name, err)
+			}
+		}
+	}
+
+	rev, err := historyStore.Record(name, content, author, message)
+	if err != nil {
+		log.Printf("Error recording revision for %s: %v", name, err)
+		return nil
+	}
+	return rev
+}
+
+// History
+func ListRevisions(historyStore history.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		revisions, err := historyStore.List(c.Param("name"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, revisions)
+	}
+}
+
+func GetRevision(historyStore history.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rev, content, ok := loadRevision(c, historyStore, c.Param("rev"))
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"revision": rev,
+			"content":  content,
+		})
+	}
+}
+
+// DiffRevisions returns a unified diff between two revisions given by the
+// from and to query parameters. Either may be "current" for the compose file
+// on disk; to defaults to "current".
+func DiffRevisions(stackProvider stack.Provider, historyStore history.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+
+		side := func(param string) (string, string, bool) {
+			value := c.DefaultQuery(param, "current")
+			if value == "current" {
+				content, err := stackProvider.GetComposeFile(name)
+				if err != nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Compose file not found"})
+					return "", "", false
+				}
+				return content, "current", true
+			}
+			rev, content, ok := loadRevision(c, historyStore, value)
+			if !ok {
+				return "", "", false
+			}
+			return content, fmt.Sprintf("revision %d", rev.ID), true
+		}
+
+		if c.Query("from") == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from is required"})
+			return
+		}
+		from, fromLabel, ok := side("from")
+		if !ok {
+			return
+		}
+		to, toLabel, ok := side("to")
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from": fromLabel,
+			"to":   toLabel,
+			"diff": history.Diff(from, to, fromLabel, toLabel),
+		})
+	}
+}
+
+// RollbackRevision restores a revision as the stack's compose file, recording
+// the restore as a new revision. With up set, `compose up` is queued
+// afterwards and the job is returned.
+func RollbackRevision(stackProvider stack.Provider, composeManager *compose.Manager, historyStore history.Store, jobManager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+
+		var body struct {
+			Up      bool   `json:"up"`
+			Message string `json:"message"`
+		}
+
+		if c.Request.ContentLength != 0 {
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		rev, content, ok := loadRevision(c, historyStore, c.Param("rev"))
+		if !ok {
+			return
+		}
+
+		previous, _ := stackProvider.GetComposeFile(name)
+		if err := stackProvider.UpdateComposeFile(name, content); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		message := body.Message
+		if message == "" {
+			message = fmt.Sprintf("Rollback to revision %d", rev.ID)
+		}
+		user := CurrentUser(c).Username
+		restored := recordRevision(historyStore, previous, name, content, user, message)
+
+		if !body.Up {
+			c.JSON(http.StatusOK, gin.H{
+				"status":   "restored",
+				"revision": restored,
+			})
+			return
+		}
+
+		stackPath := stackProvider.GetStackPath(name)
+		job := jobManager.Enqueue(jobs.Spec{
+			Stack:   name,
+			Action:  "start",
+			User:    user,
+			Params:  map[string]string{"revision": strconv.Itoa(rev.ID)},
+			Timeout: 2 * time.Minute,
+			Run: func(ctx context.Context, output func(stream string, line string)) error {
+				return composeManager.Up(ctx, stackPath, output)
+			},
+		})
+
+		deferAudit(c)
+		c.JSON(http.StatusAccepted, gin.H{
+			"status":   "restored",
+			"revision": restored,
+			"job":      job,
+		})
+	}
+}
+
+func loadRevision(c *gin.Context, historyStore history.Store, value string) (*history.Revision, string, bool) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision: " + value})
+		return nil, "", false
+	}
+
+	rev, content, err := historyStore.Get(c.Param("name"), id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, history.ErrRevisionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	return rev, content, true
+}
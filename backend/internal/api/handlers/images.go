@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/auth"
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/registry"
+)
+
+// Images
+func ListImages(dockerClient docker.DockerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		images, err := dockerClient.ListImages(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		user := CurrentUser(c)
+		for i := range images {
+			images[i] = visibleImageUsers(user, images[i])
+		}
+		c.JSON(http.StatusOK, images)
+	}
+}
+
+func GetImage(dockerClient docker.DockerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		details, err := dockerClient.InspectImage(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.JSON(imageErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		details.ImageInfo = visibleImageUsers(CurrentUser(c), details.ImageInfo)
+		c.JSON(http.StatusOK, details)
+	}
+}
+
+func GetImageHistory(dockerClient docker.DockerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		layers, err := dockerClient.ImageHistory(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.JSON(imageErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, layers)
+	}
+}
+
+// PullImage pulls an image and streams the daemon's progress as server-sent
+// "progress" events, followed by an "end" event with an error if the pull
+// failed. Errors before any progress is reported are returned as JSON. A
+// stored credential for the image's registry is presented if there is one.
+func PullImage(dockerClient docker.DockerClient, credentials registry.CredentialStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Reference string `json:"reference" binding:"required"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		reference := strings.TrimSpace(body.Reference)
+
+		// The pull is not tied to the request, so a client that goes away
+		// doesn't leave a half-pulled image behind
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		progress := make(chan docker.PullProgress, 64)
+		done := make(chan error, 1)
+		go func() {
+			defer cancel()
+			done <- dockerClient.PullImage(ctx, reference, pullAuth(credentials, reference), func(p docker.PullProgress) {
+				select {
+				case progress <- p:
+				case <-ctx.Done():
+				}
+			})
+			close(progress)
+		}()
+
+		first, ok := <-progress
+		if !ok {
+			if err := <-done; err != nil {
+				c.JSON(imageErrorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		if ok {
+			c.SSEvent("progress", first)
+		}
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(logHeartbeat)
+		defer heartbeat.Stop()
+
+		clientGone := c.Request.Context().Done()
+		for {
+			select {
+			case <-clientGone:
+				// Keep draining so the pull can finish
+				clientGone = nil
+
+			case <-heartbeat.C:
+				if clientGone != nil {
+					c.Writer.WriteString(": keepalive\n\n")
+					c.Writer.Flush()
+				}
+
+			case p, ok := <-progress:
+				if ok {
+					if clientGone != nil {
+						c.SSEvent("progress", p)
+						c.Writer.Flush()
+					}
+					continue
+				}
+
+				end := gin.H{}
+				if err := <-done; err != nil {
+					end["error"] = err.Error()
+					failAudit(c, err)
+				}
+				if clientGone != nil {
+					c.SSEvent("end", end)
+					c.Writer.Flush()
+				}
+				return
+			}
+		}
+	}
+}
+
+// DeleteImage removes an image by ID. With a tag query parameter only that
+// tag is removed, and the image with it if it was the last one.
+func DeleteImage(dockerClient docker.DockerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		ref := c.Param("id")
+		force := c.Query("force") == "true"
+
+		if tag := c.Query("tag"); tag != "" {
+			details, err := dockerClient.InspectImage(ctx, ref)
+			if err != nil {
+				c.JSON(imageErrorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			found := false
+			for _, t := range details.Tags {
+				found = found || t == tag
+			}
+			if !found {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Image is not tagged " + tag})
+				return
+			}
+			ref = tag
+		}
+
+		removed, err := dockerClient.RemoveImage(ctx, ref, force)
+		if err != nil {
+			c.JSON(imageErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, removed)
+	}
+}
+
+// PruneImages removes dangling images, or every unused image with all=true.
+// With dryRun=true it reports what would be removed instead.
+func PruneImages(dockerClient docker.DockerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := dockerClient.PruneImages(c.Request.Context(), docker.ImagePruneOptions{
+			All:    c.Query("all") == "true",
+			DryRun: c.Query("dryRun") == "true",
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		user := CurrentUser(c)
+		for i := range report.Images {
+			report.Images[i] = visibleImageUsers(user, report.Images[i])
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// visibleImageUsers hides the containers and stacks a restricted user can't
+// access. Images themselves are shared by all stacks and stay visible.
+func visibleImageUsers(user *auth.User, img docker.ImageInfo) docker.ImageInfo {
+	if !user.Restricted() {
+		return img
+	}
+
+	containers := make([]docker.ImageContainer, 0, len(img.Containers))
+	for _, ctr := range img.Containers {
+		if ctr.Stack != "" && user.CanAccessStack(ctr.Stack) {
+			containers = append(containers, ctr)
+		}
+	}
+	stacks := make([]string, 0, len(img.Stacks))
+	for _, s := range img.Stacks {
+		if user.CanAccessStack(s) {
+			stacks = append(stacks, s)
+		}
+	}
+	img.Containers = containers
+	img.Stacks = stacks
+	return img
+}
+
+// pullAuth returns the stored credential for the registry of ref, or nil to
+// pull anonymously
+func pullAuth(credentials registry.CredentialStore, ref string) *docker.RegistryAuth {
+	r, err := registry.ParseReference(ref)
+	if err != nil {
+		return nil
+	}
+	cred, err := credentials.Lookup(r.Domain)
+	if err != nil {
+		return nil
+	}
+	return &docker.RegistryAuth{
+		Username:      cred.Username,
+		Password:      cred.Password,
+		ServerAddress: registry.ServerAddress(cred.Registry),
+	}
+}
+
+func imageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, docker.ErrImageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, docker.ErrImageConflict):
+		return http.StatusConflict
+	case errors.Is(err, docker.ErrInvalidReference):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
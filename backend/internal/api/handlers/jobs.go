@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/jobs"
+)
+
+// Jobs
+func GetJob(jobManager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := jobManager.Get(c.Param("id"))
+		if !ok || !CurrentUser(c).CanAccessStack(job.Stack) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+
+		output, _ := jobManager.Output(job.ID, 0)
+		c.JSON(http.StatusOK, gin.H{
+			"job":    job,
+			"output": output,
+		})
+	}
+}
+
+func GetJobOutput(jobManager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := jobManager.Get(c.Param("id"))
+		if !ok || !CurrentUser(c).CanAccessStack(job.Stack) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+
+		after, _ := strconv.Atoi(c.DefaultQuery("after", "0"))
+		output, _ := jobManager.Output(job.ID, after)
+		c.JSON(http.StatusOK, output)
+	}
+}
+
+func ListJobs(jobManager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := jobs.Filter{
+			Stack:  c.Query("stack"),
+			Status: jobs.Status(c.Query("status")),
+		}
+
+		user := CurrentUser(c)
+		result := make([]jobs.Job, 0)
+		for _, job := range jobManager.List(filter) {
+			if user.CanAccessStack(job.Stack) {
+				result = append(result, job)
+			}
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func CancelJob(jobManager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := jobManager.Get(c.Param("id"))
+		if !ok || !CurrentUser(c).CanAccessStack(job.Stack) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+
+		job, err := jobManager.Cancel(job.ID)
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, jobs.ErrJobNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, jobs.ErrJobFinished):
+				status = http.StatusConflict
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	}
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/logs"
+)
+
+const (
+	// logHeartbeat is how often an idle log stream sends a comment to keep
+	// proxies from closing the connection
+	logHeartbeat = 15 * time.Second
+
+	// maxLogEntries bounds the lines returned by GetContainerLogs
+	maxLogEntries = 10000
+)
+
+// Logs
+
+// GetContainerLogs returns a container's log lines as structured entries.
+// It takes the same since, until and tail parameters as the log streams;
+// tail is capped at maxLogEntries.
+func GetContainerLogs(dockerClient docker.DockerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts, ok := logOptions(c)
+		if !ok {
+			return
+		}
+		if n, err := strconv.Atoi(opts.Tail); err != nil || n > maxLogEntries {
+			opts.Tail = strconv.Itoa(maxLogEntries)
+		}
+
+		logs, err := dockerClient.GetContainerLogs(c.Request.Context(), c.Param("id"), opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, logs)
+	}
+}
+
+// StreamContainerLogs streams a container's logs as Server-Sent Events.
+// See streamLogs for the query parameters and events.
+func StreamContainerLogs(dockerClient docker.DockerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts, ok := logOptions(c)
+		if !ok {
+			return
+		}
+
+		ctr, err := dockerClient.GetContainer(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		streamLogs(c, dockerClient, []logs.Source{containerSource(ctr)}, opts)
+	}
+}
+
+// StreamStackLogs streams the logs of a stack's containers as Server-Sent
+// Events, interleaved by timestamp. The services query parameter limits the
+// stream to a comma-separated list of services. Containers created after
+// the stream starts are not included.
+func StreamStackLogs(dockerClient docker.DockerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts, ok := logOptions(c)
+		if !ok {
+			return
+		}
+
+		var services []string
+		if value := c.Query("services"); value != "" {
+			services = strings.Split(value, ",")
+		}
+
+		sources, err := stackSources(c, dockerClient, c.Param("name"), services)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(sources) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No containers found for this stack"})
+			return
+		}
+
+		streamLogs(c, dockerClient, sources, opts)
+	}
+}
+
+// SearchLogs searches the logs of a stack's containers, or of the given
+// containers, over a time range. since and until take the same formats as
+// the log streams.
+func SearchLogs(dockerClient docker.DockerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Stack         string   `json:"stack"`
+			Services      []string `json:"services"`
+			Containers    []string `json:"containers"`
+			Query         string   `json:"query"`
+			Regex         bool     `json:"regex"`
+			CaseSensitive bool     `json:"caseSensitive"`
+			Levels        []string `json:"levels"`
+			Since         string   `json:"since"`
+			Until         string   `json:"until"`
+			Context       int      `json:"context"`
+			Offset        int      `json:"offset"`
+			Limit         int      `json:"limit"`
+		}
+
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now := time.Now()
+		query := logs.Query{
+			Text:          body.Query,
+			Regex:         body.Regex,
+			CaseSensitive: body.CaseSensitive,
+			Levels:        body.Levels,
+			Context:       body.Context,
+			Offset:        body.Offset,
+			Limit:         body.Limit,
+		}
+		var err error
+		if query.Since, err = logs.ParseTime(body.Since, now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since: " + err.Error()})
+			return
+		}
+		if query.Until, err = logs.ParseTime(body.Until, now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until: " + err.Error()})
+			return
+		}
+
+		user := CurrentUser(c)
+		var sources []logs.Source
+		switch {
+		case body.Stack != "":
+			if !user.CanAccessStack(body.Stack) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Access to this stack is not granted"})
+				return
+			}
+			sources, err = stackSources(c, dockerClient, body.Stack, body.Services)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		case len(body.Containers) > 0:
+			for _, id := range body.Containers {
+				ctr, err := dockerClient.GetContainer(c.Request.Context(), id)
+				if err != nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+					return
+				}
+				if !canAccessContainer(user, ctr) {
+					c.JSON(http.StatusForbidden, gin.H{"error": "Access to this container is not granted"})
+					return
+				}
+				sources = append(sources, containerSource(ctr))
+			}
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stack or containers is required"})
+			return
+		}
+
+		result, err := logs.Search(c.Request.Context(), dockerClient, sources, query)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, logs.ErrInvalidPattern) || errors.Is(err, logs.ErrInvalidLevel) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// logOptions reads the since, until, tail and follow query parameters. since
+// and until accept RFC 3339, Unix timestamps or durations before now; tail
+// defaults to 100 lines and follow to true.
+func logOptions(c *gin.Context) (docker.LogOptions, bool) {
+	now := time.Now()
+	opts := docker.LogOptions{
+		Tail:   c.DefaultQuery("tail", "100"),
+		Follow: c.DefaultQuery("follow", "true") != "false",
+	}
+
+	var err error
+	if opts.Since, err = logs.ParseTime(c.Query("since"), now); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since: " + err.Error()})
+		return opts, false
+	}
+	if opts.Until, err = logs.ParseTime(c.Query("until"), now); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until: " + err.Error()})
+		return opts, false
+	}
+	if opts.Tail != "all" {
+		if n, err := strconv.Atoi(opts.Tail); err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tail must be a number of lines or \"all\""})
+			return opts, false
+		}
+	}
+	return opts, true
+}
+
+func containerSource(ctr *docker.ContainerInfo) logs.Source {
+	return logs.Source{ID: ctr.ID, Service: ctr.Labels["com.docker.compose.service"]}
+}
+
+// stackSources returns the stack's containers, limited to services if any
+// are given
+func stackSources(c *gin.Context, dockerClient docker.DockerClient, name string, services []string) ([]logs.Source, error) {
+	containers, err := dockerClient.ListContainers(c.Request.Context(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, service := range services {
+		wanted[strings.TrimSpace(service)] = true
+	}
+
+	sources := make([]logs.Source, 0)
+	for _, ctr := range containers {
+		if ctr.Labels["com.docker.compose.project"] != name {
+			continue
+		}
+		source := containerSource(&ctr)
+		if len(wanted) > 0 && !wanted[source.Service] {
+			continue
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// streamLogs writes log entries as "log" events until the selected logs are
+// exhausted or the client disconnects. A "dropped" event with a count is sent
+// when the client reads too slowly to keep up, and an "end" event, with an
+// error if a container's stream failed, when the logs are exhausted.
+func streamLogs(c *gin.Context, dockerClient docker.DockerClient, sources []logs.Source, opts docker.LogOptions) {
+	ctx := c.Request.Context()
+	stream := logs.Follow(ctx, dockerClient, sources, opts)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(logHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-heartbeat.C:
+			c.Writer.WriteString(": keepalive\n\n")
+			c.Writer.Flush()
+
+		case <-stream.Ready():
+			batch, more := stream.Take()
+			if batch.Dropped > 0 {
+				c.SSEvent("dropped", gin.H{"count": batch.Dropped})
+			}
+			for _, entry := range batch.Entries {
+				c.SSEvent("log", entry)
+			}
+
+			if !more {
+				end := gin.H{}
+				if err := stream.Err(); err != nil {
+					end["error"] = err.Error()
+				}
+				c.SSEvent("end", end)
+				c.Writer.Flush()
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/exporter"
+	"aperture-science-network/internal/logs"
+	"aperture-science-network/internal/metrics"
+)
+
+// Metrics
+
+// QueryMetrics returns recorded metrics over a time range. With a container
+// (ID or name) or stack query parameter it returns the series of those
+// containers, including ones since removed; otherwise it returns the host's.
+// since and until take the same formats as the log streams, since
+// defaulting to an hour ago. resolution is raw, 1m or 10m and defaults to
+// the finest one still retained at since.
+func QueryMetrics(store *metrics.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		query := metrics.Query{Resolution: c.Query("resolution")}
+
+		var err error
+		if query.Since, err = logs.ParseTime(c.DefaultQuery("since", "1h"), now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since: " + err.Error()})
+			return
+		}
+		if query.Until, err = logs.ParseTime(c.Query("until"), now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until: " + err.Error()})
+			return
+		}
+
+		user := CurrentUser(c)
+		container := c.Query("container")
+		stack := c.Query("stack")
+		if stack != "" && !user.CanAccessStack(stack) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to this stack is not granted"})
+			return
+		}
+
+		if container == "" && stack == "" {
+			query.Match = func(s metrics.Series) bool {
+				return s.Target == metrics.TargetSystem
+			}
+		} else {
+			query.Match = func(s metrics.Series) bool {
+				if s.Target != metrics.TargetContainer {
+					return false
+				}
+				if container != "" && s.ID != container && s.Name != container {
+					return false
+				}
+				if stack != "" && s.Stack != stack {
+					return false
+				}
+				// Containers outside any stack are only visible to
+				// unrestricted users
+				return !user.Restricted() || (s.Stack != "" && user.CanAccessStack(s.Stack))
+			}
+		}
+
+		result, err := store.Query(query)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// PrometheusMetrics serves metrics in the Prometheus text format. If token
+// is set, scrapers must send it as a bearer token.
+func PrometheusMetrics(exp *exporter.Exporter, token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+			return
+		}
+
+		c.Header("Content-Type", exporter.ContentType)
+		c.Status(http.StatusOK)
+		if err := exp.Write(c.Writer); err != nil {
+			log.Printf("Error writing metrics: %v", err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/registry"
+)
+
+// Registry credentials
+
+// ListRegistryCredentials returns the stored credentials without their
+// passwords
+func ListRegistryCredentials(store registry.CredentialStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		creds, err := store.ListCredentials()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, creds)
+	}
+}
+
+// SetRegistryCredential creates or replaces the credential of a registry.
+// Leaving the password out keeps the stored one.
+func SetRegistryCredential(store registry.CredentialStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		cred, err := store.SetCredential(registry.Credential{
+			Registry: c.Param("registry"),
+			Username: body.Username,
+			Password: body.Password,
+		})
+		if err != nil {
+			c.JSON(registryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, cred)
+	}
+}
+
+func DeleteRegistryCredential(store registry.CredentialStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := store.DeleteCredential(c.Param("registry")); err != nil {
+			c.JSON(registryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+	}
+}
+
+// registryErrorStatus maps credential store errors to HTTP status codes
+func registryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, registry.ErrCredentialNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, registry.ErrInvalidCredential):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/autoupdate"
+	"aperture-science-network/internal/compose"
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/history"
+	"aperture-science-network/internal/jobs"
+	"aperture-science-network/internal/stack"
+)
+
+// Stack lifecycle
+func CreateStack(stackProvider stack.Provider, historyStore history.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Name    string `json:"name"`
+			Compose string `json:"compose"`
+			Env     string `json:"env"`
+			Force   bool   `json:"force"`
+		}
+
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !CurrentUser(c).CanAccessStack(body.Name) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to this stack is not granted"})
+			return
+		}
+
+		issues := compose.Validate(body.Compose, false)
+		if compose.HasErrors(issues) && !body.Force {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":  "Compose file is invalid",
+				"issues": issues,
+			})
+			return
+		}
+
+		info, err := stackProvider.CreateStack(body.Name, body.Compose, body.Env)
+		if err != nil {
+			c.JSON(stackErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		recordRevision(historyStore, "", body.Name, body.Compose, CurrentUser(c).Username, "Created stack")
+		c.JSON(http.StatusCreated, info)
+	}
+}
+
+// RenameStack moves a stack, its compose history and its update policy to a
+// new name as a background job. Since the compose project name follows the
+// directory name, a running project is brought down under the old name and
+// started again under the new one. Named volumes belong to the old project
+// and are not carried over, so a stack owning any is refused with 409 unless
+// abandonVolumes is set.
+func RenameStack(stackProvider stack.Provider, dockerClient docker.DockerClient, composeManager *compose.Manager, historyStore history.Store, policyStore autoupdate.Store, jobManager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+
+		var body struct {
+			NewName        string `json:"newName"`
+			AbandonVolumes bool   `json:"abandonVolumes"`
+		}
+
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !stackProvider.StackExists(name) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Stack not found"})
+			return
+		}
+		if !stack.ValidName(body.NewName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": stack.ErrInvalidStackName.Error()})
+			return
+		}
+		if stackProvider.StackExists(body.NewName) {
+			c.JSON(http.StatusConflict, gin.H{"error": stack.ErrStackExists.Error()})
+			return
+		}
+		if !CurrentUser(c).CanAccessStack(body.NewName) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to the new stack name is not granted"})
+			return
+		}
+
+		volumes, err := projectVolumes(c.Request.Context(), dockerClient, name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(volumes) > 0 && !body.AbandonVolumes {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Stack has named volumes that will not follow the rename; set abandonVolumes to rename it with empty volumes",
+				"volumes": volumes,
+			})
+			return
+		}
+
+		newName := body.NewName
+		params := map[string]string{"newName": newName}
+		if len(volumes) > 0 {
+			params["abandonedVolumes"] = strings.Join(volumes, ",")
+		}
+		job := jobManager.Enqueue(jobs.Spec{
+			Stack:   name,
+			Action:  "rename",
+			User:    CurrentUser(c).Username,
+			Params:  params,
+			Timeout: 5 * time.Minute,
+			Run: func(ctx context.Context, output func(stream string, line string)) error {
+				info, err := stackProvider.GetStack(name)
+				if err != nil {
+					return err
+				}
+				oldPath := stackProvider.GetStackPath(name)
+
+				active := info.Status != "stopped"
+				if active {
+					if err := composeManager.Down(ctx, oldPath, output); err != nil {
+						return err
+					}
+				}
+
+				if _, err := stackProvider.RenameStack(name, newName); err != nil {
+					if active {
+						// Bring the project back up where it was
+						if upErr := composeManager.Up(ctx, oldPath, output); upErr != nil {
+							output("stderr", "Failed to restart stack after rename error: "+upErr.Error())
+						}
+					}
+					return err
+				}
+				output("stdout", "Renamed "+name+" to "+newName)
+				if len(volumes) > 0 {
+					output("stderr", "Volumes left with the old project: "+strings.Join(volumes, ", "))
+				}
+				if err := historyStore.Rename(name, newName); err != nil {
+					output("stderr", "Failed to move compose history: "+err.Error())
+				}
+				if err := policyStore.RenameStack(name, newName); err != nil {
+					output("stderr", "Failed to move update policy: "+err.Error())
+				}
+
+				if active {
+					return composeManager.Up(ctx, stackProvider.GetStackPath(newName), output)
+				}
+				return nil
+			},
+		})
+
+		deferAudit(c)
+		c.JSON(http.StatusAccepted, gin.H{"status": "accepted", "job": job, "abandonedVolumes": volumes})
+	}
+}
+
+// projectVolumes returns the names of the volumes docker compose created for
+// a project
+func projectVolumes(ctx context.Context, dockerClient docker.DockerClient, project string) ([]string, error) {
+	all, err := dockerClient.ListVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	volumes := make([]string, 0)
+	for _, v := range all {
+		if v.Labels["com.docker.compose.project"] == project {
+			volumes = append(volumes, v.Name)
+		}
+	}
+	sort.Strings(volumes)
+	return volumes, nil
+}
+
+// DeleteStack removes a stack as a background job. With ?down=true the
+// project is first taken down with its volumes; otherwise a stack that still
+// has containers is refused.
+func DeleteStack(stackProvider stack.Provider, composeManager *compose.Manager, historyStore history.Store, policyStore autoupdate.Store, jobManager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		down := c.Query("down") == "true"
+
+		info, err := stackProvider.GetStack(name)
+		if err != nil {
+			c.JSON(stackErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		if !down && info.Services > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Stack still has containers; stop it first or delete with down=true"})
+			return
+		}
+
+		stackPath := stackProvider.GetStackPath(name)
+		params := map[string]string{}
+		if down {
+			params["down"] = "true"
+		}
+		job := jobManager.Enqueue(jobs.Spec{
+			Stack:   name,
+			Action:  "delete",
+			User:    CurrentUser(c).Username,
+			Params:  params,
+			Timeout: 5 * time.Minute,
+			Run: func(ctx context.Context, output func(stream string, line string)) error {
+				if down {
+					if err := composeManager.DownVolumes(ctx, stackPath, output); err != nil {
+						return err
+					}
+				}
+				if err := stackProvider.DeleteStack(name); err != nil {
+					return err
+				}
+				output("stdout", "Deleted stack "+name)
+				if err := historyStore.Delete(name); err != nil {
+					output("stderr", "Failed to delete compose history: "+err.Error())
+				}
+				if err := policyStore.DeletePolicy(name); err != nil && !errors.Is(err, autoupdate.ErrPolicyNotFound) {
+					output("stderr", "Failed to delete update policy: "+err.Error())
+				}
+				return nil
+			},
+		})
+
+		deferAudit(c)
+		c.JSON(http.StatusAccepted, gin.H{"status": "accepted", "job": job})
+	}
+}
+
+// stackErrorStatus maps stack provider errors to HTTP status codes
+func stackErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, stack.ErrStackNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, stack.ErrStackExists):
+		return http.StatusConflict
+	case errors.Is(err, stack.ErrInvalidStackName):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/auth"
+	"aperture-science-network/internal/autoupdate"
+	"aperture-science-network/internal/compose"
+	"aperture-science-network/internal/history"
+	"aperture-science-network/internal/jobs"
+	"aperture-science-network/internal/mock"
+)
+
+func TestRenameStackVolumes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	historyStore, err := history.NewFileStore(filepath.Join(dir, "history"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policyStore, err := autoupdate.NewFileStore(filepath.Join(dir, "policies.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stackProvider := mock.NewStackProvider()
+	jobManager := jobs.NewManager(1)
+
+	router := gin.New()
+	router.POST("/stacks/:name/rename", func(c *gin.Context) {
+		c.Set(userContextKey, &auth.User{Username: "admin", Role: auth.RoleAdmin})
+	}, RenameStack(stackProvider, mock.NewDockerClient(), compose.NewManager(nil), historyStore, policyStore, jobManager))
+
+	rename := func(name string, body string) (int, map[string]json.RawMessage) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/stacks/"+name+"/rename", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		var resp map[string]json.RawMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+		}
+		return w.Code, resp
+	}
+
+	tests := []struct {
+		name        string
+		stack       string
+		body        string
+		wantStatus  int
+		wantVolumes []string
+	}{
+		{"volumes refused", "celeste", `{"newName":"portal"}`, http.StatusConflict, []string{"celeste_data"}},
+		{"volumes abandoned", "celeste", `{"newName":"portal","abandonVolumes":true}`, http.StatusAccepted, []string{"celeste_data"}},
+		{"no volumes", "database", `{"newName":"db"}`, http.StatusAccepted, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := rename(tt.stack, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %v", status, tt.wantStatus, resp)
+			}
+
+			key := "abandonedVolumes"
+			if status == http.StatusConflict {
+				key = "volumes"
+				if _, ok := resp["job"]; ok {
+					t.Error("refused rename started a job")
+				}
+			}
+			var volumes []string
+			if err := json.Unmarshal(resp[key], &volumes); err != nil {
+				t.Fatalf("%s: %v", key, err)
+			}
+			if !reflect.DeepEqual(volumes, tt.wantVolumes) {
+				t.Errorf("%s = %v, want %v", key, volumes, tt.wantVolumes)
+			}
+		})
+	}
+
+	// The stopped stack without volumes is renamed by its job
+	deadline := time.Now().Add(5 * time.Second)
+	for !stackProvider.StackExists("db") {
+		if time.Now().After(deadline) {
+			t.Fatal("database was not renamed to db")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
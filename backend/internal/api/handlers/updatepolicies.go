@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/alert"
+	"aperture-science-network/internal/autoupdate"
+	"aperture-science-network/internal/jobs"
+	"aperture-science-network/internal/stack"
+)
+
+// Update policies
+
+// ListUpdatePolicies returns the update policies of the stacks the user can
+// access
+func ListUpdatePolicies(store autoupdate.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policies, err := store.ListPolicies()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		user := CurrentUser(c)
+		result := make([]autoupdate.Policy, 0, len(policies))
+		for _, p := range policies {
+			if user.CanAccessStack(p.Stack) {
+				result = append(result, p)
+			}
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetUpdatePolicy returns a stack's update policy and the updates waiting
+// for its maintenance window
+func GetUpdatePolicy(store autoupdate.Store, scheduler *autoupdate.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		policy, err := store.GetPolicy(name)
+		if err != nil {
+			c.JSON(updatePolicyErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"policy": policy, "pending": scheduler.Pending(name)})
+	}
+}
+
+// SetUpdatePolicy creates or replaces a stack's update policy
+func SetUpdatePolicy(store autoupdate.Store, stackProvider stack.Provider, alerts alert.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+
+		var body autoupdate.Policy
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !stackProvider.StackExists(name) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Stack not found"})
+			return
+		}
+		for _, id := range body.Channels {
+			if _, err := alerts.GetChannel(id); err != nil {
+				if errors.Is(err, alert.ErrChannelNotFound) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification channel " + id})
+				} else {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				}
+				return
+			}
+		}
+
+		body.Stack = name
+		policy, err := store.SetPolicy(body)
+		if err != nil {
+			c.JSON(updatePolicyErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, policy)
+	}
+}
+
+func DeleteUpdatePolicy(store autoupdate.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := store.DeletePolicy(c.Param("name")); err != nil {
+			c.JSON(updatePolicyErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+	}
+}
+
+// RunUpdatePolicy checks a stack for updates now and handles them according
+// to its policy, applying them even outside the maintenance window. When an
+// update job is started, it is returned along with the run.
+func RunUpdatePolicy(scheduler *autoupdate.Scheduler, jobManager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		run, err := scheduler.RunNow(c.Param("name"), CurrentUser(c).Username)
+		if err != nil {
+			c.JSON(updatePolicyErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		if job, ok := jobManager.Get(run.JobID); ok {
+			deferAudit(c)
+			c.JSON(http.StatusAccepted, gin.H{"status": "accepted", "run": run, "job": job})
+			return
+		}
+		if run.Outcome == autoupdate.OutcomeFailed {
+			failAudit(c, errors.New(run.Error))
+		}
+		c.JSON(http.StatusOK, gin.H{"run": run})
+	}
+}
+
+func ListUpdateRuns(store autoupdate.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		runs, err := store.ListRuns(c.Param("name"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, runs)
+	}
+}
+
+// updatePolicyErrorStatus maps update policy errors to HTTP status codes
+func updatePolicyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, autoupdate.ErrPolicyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, autoupdate.ErrInvalidPolicy):
+		return http.StatusBadRequest
+	case errors.Is(err, autoupdate.ErrRunInProgress):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/updates"
+)
+
+// Image updates
+type updatesResponse struct {
+	// LastCheck is null until the registries have been queried once
+	LastCheck *time.Time       `json:"lastCheck"`
+	Services  []updates.Status `json:"services"`
+}
+
+// ListUpdates returns the cached update check results for the stacks the
+// user can access
+func ListUpdates(checker *updates.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, visibleUpdates(c, checker))
+	}
+}
+
+// CheckUpdates queries the registries now instead of waiting for the next
+// periodic check
+func CheckUpdates(checker *updates.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+		defer cancel()
+
+		if err := checker.Check(ctx); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, visibleUpdates(c, checker))
+	}
+}
+
+func visibleUpdates(c *gin.Context, checker *updates.Checker) updatesResponse {
+	user := CurrentUser(c)
+	resp := updatesResponse{Services: make([]updates.Status, 0)}
+	if last := checker.LastCheck(); !last.IsZero() {
+		resp.LastCheck = &last
+	}
+	for _, s := range checker.Statuses() {
+		if user.CanAccessStack(s.Stack) {
+			resp.Services = append(resp.Services, s)
+		}
+	}
+	return resp
+}
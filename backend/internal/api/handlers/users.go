@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"aperture-science-network/internal/auth"
+)
+
+// Users
+func ListUsers(store auth.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		users, err := store.ListUsers()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, users)
+	}
+}
+
+func CreateUser(store auth.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Username string    `json:"username"`
+			Password string    `json:"password"`
+			Role     auth.Role `json:"role"`
+			Teams    []string  `json:"teams"`
+		}
+
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if body.Role == "" {
+			body.Role = auth.RoleViewer
+		}
+
+		user, err := store.CreateUser(body.Username, body.Password, body.Role, body.Teams)
+		if err != nil {
+			c.JSON(authErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, user)
+	}
+}
+
+func UpdateUser(store auth.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username := c.Param("username")
+
+		var body auth.UserUpdate
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := store.UpdateUser(username, body)
+		if err != nil {
+			c.JSON(authErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+func DeleteUser(store auth.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username := c.Param("username")
+
+		if current := CurrentUser(c); current != nil && current.Username == username {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete your own account"})
+			return
+		}
+
+		if err := store.DeleteUser(username); err != nil {
+			c.JSON(authErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+	}
+}
+
+// Teams
+func ListTeams(store auth.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		teams, err := store.ListTeams()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, teams)
+	}
+}
+
+func SaveTeam(store auth.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Stacks []string `json:"stacks"`
+		}
+
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		team, err := store.SaveTeam(auth.Team{Name: c.Param("team"), Stacks: body.Stacks})
+		if err != nil {
+			c.JSON(authErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, team)
+	}
+}
+
+func DeleteTeam(store auth.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := store.DeleteTeam(c.Param("team")); err != nil {
+			c.JSON(authErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+	}
+}
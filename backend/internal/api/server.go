@@ -3,28 +3,61 @@ package api
 import (
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 
+	"aperture-science-network/internal/alert"
 	"aperture-science-network/internal/api/handlers"
+	"aperture-science-network/internal/audit"
+	"aperture-science-network/internal/auth"
+	"aperture-science-network/internal/autoupdate"
 	"aperture-science-network/internal/compose"
 	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/exporter"
+	"aperture-science-network/internal/history"
+	"aperture-science-network/internal/jobs"
+	"aperture-science-network/internal/metrics"
+	"aperture-science-network/internal/registry"
 	"aperture-science-network/internal/stack"
 	"aperture-science-network/internal/stats"
+	"aperture-science-network/internal/updates"
 	"aperture-science-network/internal/version"
 	"aperture-science-network/internal/ws"
 )
 
+// allowedOrigins lists the development frontends permitted to call the API
+// cross-origin. Production traffic is served same-origin.
+var allowedOrigins = []string{"http://localhost:3001", "http://localhost:5173"}
+
 type Server struct {
 	router         *gin.Engine
+	authStore      auth.Store
+	sessions       *auth.Sessions
+	loginThrottle  *auth.Throttle
+	auditLog       audit.Log
+	historyStore   history.Store
 	dockerClient   docker.DockerClient
 	statsProvider  stats.Provider
 	stackProvider  stack.Provider
 	composeManager *compose.Manager
+	jobManager     *jobs.Manager
+	metricsStore   *metrics.Store
+	exporter       *exporter.Exporter
+	alertStore     alert.Store
+	alertEngine    *alert.Engine
+	updateChecker  *updates.Checker
+	credentials    registry.CredentialStore
+	updatePolicies autoupdate.Store
+	updater        *autoupdate.Scheduler
+	metricsToken   string
 	wsHub          *ws.Hub
 	staticPath     string
+	execShell      string
+	execRole       auth.Role
 }
 
 // ServerOptions contains the dependencies for creating a new server
@@ -33,24 +66,124 @@ type ServerOptions struct {
 	DockerClient  docker.DockerClient
 	StatsProvider stats.Provider
 	StackProvider stack.Provider
+	AuthStore     auth.Store
+	Sessions      *auth.Sessions
+	AuditLog      audit.Log
+	History       history.Store
+	Alerts        alert.Store
+	JobWorkers    int
+	// ExecShell is the command exec terminals run unless the client asks
+	// for another one
+	ExecShell string
+	// ExecRole is the minimum role allowed to open exec terminals
+	ExecRole auth.Role
+	// MetricsToken, if set, is the bearer token Prometheus must send to
+	// scrape /metrics. Otherwise /metrics requires the session of a user
+	// without team restrictions.
+	MetricsToken string
+	// Registry resolves tags to digests for image update checks, run every
+	// UpdateInterval (never if zero)
+	Registry       registry.Resolver
+	UpdateInterval time.Duration
+	// Credentials authenticate image pulls from private registries
+	Credentials registry.CredentialStore
+	// UpdatePolicies holds the per-stack automatic update policies
+	UpdatePolicies autoupdate.Store
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For header gives the client address that failed
+	// logins are throttled by. Without any, the connecting address is used.
+	TrustedProxies []string
 }
 
 func NewServer(opts ServerOptions) *Server {
 	gin.SetMode(gin.ReleaseMode)
 
-	wsHub := ws.NewHub(opts.DockerClient, opts.StatsProvider)
+	// One stats stream per running container feeds the live stats and the
+	// metrics history
+	containerStats := stats.NewContainerWatcher(opts.DockerClient)
+	go containerStats.Run()
+
+	wsHub := ws.NewHub(opts.DockerClient, opts.StatsProvider, containerStats)
+	wsHub.SetAllowedOrigins(allowedOrigins)
 	go wsHub.Run()
 
-	composeManager := compose.NewManager()
+	exp := exporter.New(opts.StatsProvider, containerStats, opts.StackProvider, wsHub)
+
+	// Clients keep the user they connected as, so make them reconnect when
+	// the account changes
+	opts.AuthStore.Subscribe(wsHub.DisconnectUser)
+
+	// Push audit entries live to admins
+	opts.AuditLog.Subscribe(func(e audit.Entry) {
+		wsHub.PublishToRole("audit", e, auth.RoleAdmin, ws.TopicAudit)
+	})
+
+	// Record metrics history for charts
+	metricsStore := metrics.NewStore(metrics.DefaultInterval)
+	go metrics.NewCollector(metricsStore, containerStats, opts.StatsProvider, metrics.DefaultInterval).Run()
+
+	// Evaluate alert rules and push alert changes live
+	alertEngine := alert.NewEngine(opts.Alerts, opts.DockerClient, opts.StatsProvider, alert.DefaultInterval)
+	alertEngine.Subscribe(func(a alert.Alert) {
+		if a.Stack == "" {
+			wsHub.PublishUnrestricted("alert", a, ws.TopicAlerts)
+		} else {
+			wsHub.PublishForStack("alert", a, a.Stack, ws.TopicAlerts)
+		}
+	})
+	go alertEngine.Run()
+
+	// Look for newer images of stack services
+	updateChecker := updates.NewChecker(opts.DockerClient, opts.Registry, opts.UpdateInterval)
+	go updateChecker.Run()
+
+	composeManager := compose.NewManager(opts.Credentials)
+	jobManager := jobs.NewManager(opts.JobWorkers)
+	jobManager.Subscribe(func(e jobs.Event) {
+		switch e.Kind {
+		case jobs.EventOutput:
+			wsHub.PublishForStack("job_output", JobOutputPayload{JobID: e.Job.ID, Stack: e.Job.Stack, Line: *e.Line}, e.Job.Stack, ws.JobTopic(e.Job.ID))
+		case jobs.EventStatus:
+			wsHub.PublishForStack("job", e.Job, e.Job.Stack, ws.JobTopic(e.Job.ID), ws.StackTopic(e.Job.Stack))
+			if e.Job.Finished() {
+				recordJobAudit(opts.AuditLog, e.Job)
+			}
+		}
+	})
+
+	// Run automatic update policies
+	updater := autoupdate.NewScheduler(opts.UpdatePolicies, opts.DockerClient, opts.StackProvider, composeManager, jobManager, updateChecker, opts.Alerts)
+	go updater.Run()
 
 	s := &Server{
 		router:         gin.New(),
+		authStore:      opts.AuthStore,
+		sessions:       opts.Sessions,
+		loginThrottle:  auth.NewThrottle(),
+		auditLog:       opts.AuditLog,
+		historyStore:   opts.History,
 		dockerClient:   opts.DockerClient,
 		statsProvider:  opts.StatsProvider,
 		stackProvider:  opts.StackProvider,
 		composeManager: composeManager,
+		jobManager:     jobManager,
+		metricsStore:   metricsStore,
+		exporter:       exp,
+		alertStore:     opts.Alerts,
+		alertEngine:    alertEngine,
+		updateChecker:  updateChecker,
+		credentials:    opts.Credentials,
+		updatePolicies: opts.UpdatePolicies,
+		updater:        updater,
+		metricsToken:   opts.MetricsToken,
 		wsHub:          wsHub,
 		staticPath:     opts.StaticPath,
+		execShell:      opts.ExecShell,
+		execRole:       opts.ExecRole,
+	}
+
+	if err := s.router.SetTrustedProxies(opts.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
 	}
 
 	s.setupMiddleware()
@@ -59,6 +192,41 @@ func NewServer(opts ServerOptions) *Server {
 	return s
 }
 
+// JobOutputPayload carries one line of job output over the WebSocket
+type JobOutputPayload struct {
+	JobID string    `json:"jobId"`
+	Stack string    `json:"stack"`
+	Line  jobs.Line `json:"line"`
+}
+
+// recordJobAudit writes the audit entry for a finished job
+func recordJobAudit(auditLog audit.Log, job jobs.Job) {
+	entry := audit.Entry{
+		Time:    job.CreatedAt,
+		User:    job.User,
+		Action:  "stack." + job.Action,
+		Target:  job.Stack,
+		Params:  map[string]interface{}{"jobId": job.ID},
+		Outcome: audit.OutcomeSuccess,
+		Status:  http.StatusOK,
+		Error:   job.Error,
+	}
+	for k, v := range job.Params {
+		entry.Params[k] = v
+	}
+	if job.FinishedAt != nil {
+		entry.DurationMs = job.FinishedAt.Sub(job.CreatedAt).Milliseconds()
+	}
+	if job.Status != jobs.StatusSucceeded {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Status = http.StatusInternalServerError
+	}
+
+	if _, err := auditLog.Record(entry); err != nil {
+		log.Printf("Error writing audit entry: %v", err)
+	}
+}
+
 func (s *Server) setupMiddleware() {
 	// Custom logger that only logs errors (4xx and 5xx responses)
 	s.router.Use(func(c *gin.Context) {
@@ -83,8 +251,24 @@ func (s *Server) setupMiddleware() {
 	})
 	s.router.Use(gin.Recovery())
 
+	// Record request latencies for /metrics. WebSocket sessions and event
+	// streams last as long as the client stays, so they are left out.
+	s.router.Use(func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		if websocket.IsWebSocketUpgrade(c.Request) || strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "text/event-stream") {
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		s.exporter.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	})
+
 	s.router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3001", "http://localhost:5173"},
+		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
@@ -94,6 +278,21 @@ func (s *Server) setupMiddleware() {
 }
 
 func (s *Server) setupRoutes() {
+	requireAuth := handlers.RequireAuth(s.authStore, s.sessions)
+	requireOperator := handlers.RequireRole(auth.RoleOperator)
+	requireAdmin := handlers.RequireRole(auth.RoleAdmin)
+	audited := func(action string) gin.HandlerFunc {
+		return handlers.Audited(s.auditLog, action)
+	}
+
+	// Prometheus metrics cover every stack, so without a scrape token they
+	// are limited to signed-in users who can see all of them
+	if s.metricsToken != "" {
+		s.router.GET("/metrics", handlers.PrometheusMetrics(s.exporter, s.metricsToken))
+	} else {
+		s.router.GET("/metrics", requireAuth, handlers.RequireUnrestricted(), handlers.PrometheusMetrics(s.exporter, ""))
+	}
+
 	// Health check
 	s.router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -102,8 +301,19 @@ func (s *Server) setupRoutes() {
 		})
 	})
 
+	// Authentication (public)
+	authRoutes := s.router.Group("/api/auth")
+	{
+		authRoutes.GET("/setup", handlers.GetSetupStatus(s.authStore))
+		authRoutes.POST("/setup", handlers.Setup(s.authStore, s.sessions))
+		authRoutes.POST("/login", handlers.Login(s.authStore, s.sessions, s.loginThrottle))
+		authRoutes.POST("/logout", handlers.Logout(s.sessions))
+		authRoutes.GET("/session", requireAuth, handlers.GetSession())
+		authRoutes.PUT("/password", requireAuth, handlers.ChangePassword(s.authStore))
+	}
+
 	// API routes
-	api := s.router.Group("/api")
+	api := s.router.Group("/api", requireAuth)
 	{
 		// System stats
 		api.GET("/stats", handlers.GetSystemStats(s.statsProvider))
@@ -111,52 +321,147 @@ func (s *Server) setupRoutes() {
 		// Stacks
 		stacks := api.Group("/stacks")
 		{
-			stacks.GET("", handlers.ListStacks(s.stackProvider))
-			stacks.GET("/:name", handlers.GetStack(s.stackProvider))
-			stacks.POST("/:name/start", handlers.StartStack(s.stackProvider, s.composeManager))
-			stacks.POST("/:name/stop", handlers.StopStack(s.stackProvider, s.composeManager))
-			stacks.POST("/:name/restart", handlers.RestartStack(s.stackProvider, s.composeManager))
-			stacks.POST("/:name/pull", handlers.PullStack(s.stackProvider, s.composeManager))
-			stacks.GET("/:name/compose", handlers.GetComposeFile(s.stackProvider))
-			stacks.PUT("/:name/compose", handlers.UpdateComposeFile(s.stackProvider))
+			stacks.GET("", handlers.ListStacks(s.stackProvider, s.updateChecker))
+			stacks.POST("", audited("stack.create"), requireOperator, handlers.CreateStack(s.stackProvider, s.historyStore))
+
+			stackRoutes := stacks.Group("/:name", handlers.RequireStackAccess())
+			stackRoutes.GET("", handlers.GetStack(s.stackProvider, s.updateChecker))
+			stackRoutes.DELETE("", audited("stack.delete"), requireAdmin, handlers.DeleteStack(s.stackProvider, s.composeManager, s.historyStore, s.updatePolicies, s.jobManager))
+			stackRoutes.POST("/rename", audited("stack.rename"), requireOperator, handlers.RenameStack(s.stackProvider, s.dockerClient, s.composeManager, s.historyStore, s.updatePolicies, s.jobManager))
+			stackRoutes.POST("/start", audited("stack.start"), requireOperator, handlers.StartStack(s.stackProvider, s.composeManager, s.jobManager))
+			stackRoutes.POST("/stop", audited("stack.stop"), requireOperator, handlers.StopStack(s.stackProvider, s.composeManager, s.jobManager))
+			stackRoutes.POST("/restart", audited("stack.restart"), requireOperator, handlers.RestartStack(s.stackProvider, s.composeManager, s.jobManager))
+			stackRoutes.POST("/pull", audited("stack.pull"), requireOperator, handlers.PullStack(s.stackProvider, s.composeManager, s.jobManager))
+			stackRoutes.GET("/logs/stream", handlers.StreamStackLogs(s.dockerClient))
+			stackRoutes.GET("/compose", handlers.GetComposeFile(s.stackProvider))
+			stackRoutes.PUT("/compose", audited("stack.compose.update"), requireOperator, handlers.UpdateComposeFile(s.stackProvider, s.composeManager, s.historyStore))
+			stackRoutes.POST("/compose/validate", requireOperator, handlers.ValidateComposeFile(s.stackProvider, s.composeManager))
+			stackRoutes.GET("/files", handlers.ListStackFiles(s.stackProvider))
+			stackRoutes.GET("/file", requireOperator, handlers.ReadStackFile(s.stackProvider))
+			stackRoutes.PUT("/file", audited("stack.file.update"), requireOperator, handlers.WriteStackFile(s.stackProvider))
+			stackRoutes.POST("/file", audited("stack.file.create"), requireOperator, handlers.CreateStackFile(s.stackProvider))
+			stackRoutes.DELETE("/file", audited("stack.file.delete"), requireOperator, handlers.DeleteStackFile(s.stackProvider))
+			stackRoutes.GET("/history", handlers.ListRevisions(s.historyStore))
+			stackRoutes.GET("/history/diff", handlers.DiffRevisions(s.stackProvider, s.historyStore))
+			stackRoutes.GET("/history/:rev", handlers.GetRevision(s.historyStore))
+			stackRoutes.GET("/update-policy", handlers.GetUpdatePolicy(s.updatePolicies, s.updater))
+			stackRoutes.PUT("/update-policy", audited("stack.update-policy.set"), requireOperator, handlers.SetUpdatePolicy(s.updatePolicies, s.stackProvider, s.alertStore))
+			stackRoutes.DELETE("/update-policy", audited("stack.update-policy.delete"), requireOperator, handlers.DeleteUpdatePolicy(s.updatePolicies))
+			stackRoutes.POST("/update-policy/run", audited("stack.update-policy.run"), requireOperator, handlers.RunUpdatePolicy(s.updater, s.jobManager))
+			stackRoutes.GET("/update-policy/runs", handlers.ListUpdateRuns(s.updatePolicies))
+			stackRoutes.POST("/history/:rev/rollback", audited("stack.compose.rollback"), requireOperator, handlers.RollbackRevision(s.stackProvider, s.composeManager, s.historyStore, s.jobManager))
 		}
 
 		// Containers
 		containers := api.Group("/containers")
 		{
-			containers.GET("", handlers.ListContainers(s.dockerClient))
-			containers.GET("/:id", handlers.GetContainer(s.dockerClient))
-			containers.POST("/:id/start", handlers.StartContainer(s.dockerClient))
-			containers.POST("/:id/stop", handlers.StopContainer(s.dockerClient))
-			containers.POST("/:id/restart", handlers.RestartContainer(s.dockerClient))
-			containers.GET("/:id/logs", handlers.GetContainerLogs(s.dockerClient))
-			containers.GET("/:id/stats", handlers.GetContainerStats(s.dockerClient))
+			containers.GET("", handlers.ListContainers(s.dockerClient, s.updateChecker))
+
+			containerRoutes := containers.Group("/:id", handlers.RequireContainerAccess(s.dockerClient))
+			containerRoutes.GET("", handlers.GetContainer(s.dockerClient, s.updateChecker))
+			containerRoutes.POST("/start", audited("container.start"), requireOperator, handlers.StartContainer(s.dockerClient))
+			containerRoutes.POST("/stop", audited("container.stop"), requireOperator, handlers.StopContainer(s.dockerClient))
+			containerRoutes.POST("/restart", audited("container.restart"), requireOperator, handlers.RestartContainer(s.dockerClient))
+			containerRoutes.GET("/logs", handlers.GetContainerLogs(s.dockerClient))
+			containerRoutes.GET("/logs/stream", handlers.StreamContainerLogs(s.dockerClient))
+			containerRoutes.GET("/stats", handlers.GetContainerStats(s.dockerClient))
+		}
+
+		// Logs
+		api.POST("/logs/search", handlers.SearchLogs(s.dockerClient))
+
+		// Metrics history
+		api.GET("/metrics", handlers.QueryMetrics(s.metricsStore))
+
+		// Alerts
+		alerts := api.Group("/alerts")
+		{
+			alerts.GET("", handlers.ListAlerts(s.alertEngine))
+			alerts.GET("/rules", requireAdmin, handlers.ListAlertRules(s.alertStore))
+			alerts.POST("/rules", audited("alert.rule.create"), requireAdmin, handlers.CreateAlertRule(s.alertStore))
+			alerts.PUT("/rules/:id", audited("alert.rule.update"), requireAdmin, handlers.UpdateAlertRule(s.alertStore))
+			alerts.DELETE("/rules/:id", audited("alert.rule.delete"), requireAdmin, handlers.DeleteAlertRule(s.alertStore))
+			alerts.GET("/channels", requireAdmin, handlers.ListAlertChannels(s.alertStore))
+			alerts.POST("/channels", audited("alert.channel.create"), requireAdmin, handlers.CreateAlertChannel(s.alertStore))
+			alerts.PUT("/channels/:id", audited("alert.channel.update"), requireAdmin, handlers.UpdateAlertChannel(s.alertStore))
+			alerts.DELETE("/channels/:id", audited("alert.channel.delete"), requireAdmin, handlers.DeleteAlertChannel(s.alertStore))
+			alerts.POST("/channels/:id/test", requireAdmin, handlers.TestAlertChannel(s.alertStore))
+			alerts.GET("/silences", handlers.ListSilences(s.alertStore))
+			alerts.POST("/silences", audited("alert.silence.create"), requireOperator, handlers.CreateSilence(s.alertStore))
+			alerts.DELETE("/silences/:id", audited("alert.silence.delete"), requireOperator, handlers.DeleteSilence(s.alertStore))
 		}
 
 		// Volumes
 		volumes := api.Group("/volumes")
 		{
 			volumes.GET("", handlers.ListVolumes(s.dockerClient))
-			volumes.POST("", handlers.CreateVolume(s.dockerClient))
-			volumes.DELETE("/:name", handlers.DeleteVolume(s.dockerClient))
+			volumes.POST("", audited("volume.create"), requireAdmin, handlers.CreateVolume(s.dockerClient))
+			volumes.DELETE("/:name", audited("volume.delete"), requireAdmin, handlers.DeleteVolume(s.dockerClient))
 		}
 
 		// Networks
 		networks := api.Group("/networks")
 		{
 			networks.GET("", handlers.ListNetworks(s.dockerClient))
-			networks.POST("", handlers.CreateNetwork(s.dockerClient))
-			networks.DELETE("/:id", handlers.DeleteNetwork(s.dockerClient))
+			networks.POST("", audited("network.create"), requireAdmin, handlers.CreateNetwork(s.dockerClient))
+			networks.DELETE("/:id", audited("network.delete"), requireAdmin, handlers.DeleteNetwork(s.dockerClient))
 		}
 
 		// Images
-		api.GET("/images", handlers.ListImages(s.dockerClient))
+		images := api.Group("/images")
+		{
+			images.GET("", handlers.ListImages(s.dockerClient))
+			images.POST("/pull", audited("image.pull"), requireOperator, handlers.PullImage(s.dockerClient, s.credentials))
+			images.POST("/prune", audited("image.prune"), requireAdmin, handlers.PruneImages(s.dockerClient))
+			images.GET("/:id", handlers.GetImage(s.dockerClient))
+			images.GET("/:id/history", handlers.GetImageHistory(s.dockerClient))
+			images.DELETE("/:id", audited("image.delete"), requireAdmin, handlers.DeleteImage(s.dockerClient))
+		}
+
+		// Image updates
+		api.GET("/updates", handlers.ListUpdates(s.updateChecker))
+		api.POST("/updates/check", audited("updates.check"), requireOperator, handlers.CheckUpdates(s.updateChecker))
+		api.GET("/update-policies", handlers.ListUpdatePolicies(s.updatePolicies))
+
+		// Registry credentials
+		credentials := api.Group("/registry-credentials", requireAdmin)
+		{
+			credentials.GET("", handlers.ListRegistryCredentials(s.credentials))
+			credentials.PUT("/:registry", audited("registry.credential.set"), handlers.SetRegistryCredential(s.credentials))
+			credentials.DELETE("/:registry", audited("registry.credential.delete"), handlers.DeleteRegistryCredential(s.credentials))
+		}
+
+		// Users and teams
+		users := api.Group("/users", requireAdmin)
+		{
+			users.GET("", handlers.ListUsers(s.authStore))
+			users.POST("", audited("user.create"), handlers.CreateUser(s.authStore))
+			users.PUT("/:username", audited("user.update"), handlers.UpdateUser(s.authStore))
+			users.DELETE("/:username", audited("user.delete"), handlers.DeleteUser(s.authStore))
+		}
+
+		teams := api.Group("/teams", requireAdmin)
+		{
+			teams.GET("", handlers.ListTeams(s.authStore))
+			teams.PUT("/:team", audited("team.save"), handlers.SaveTeam(s.authStore))
+			teams.DELETE("/:team", audited("team.delete"), handlers.DeleteTeam(s.authStore))
+		}
+
+		// Jobs
+		api.GET("/jobs", handlers.ListJobs(s.jobManager))
+		api.GET("/jobs/:id", handlers.GetJob(s.jobManager))
+		api.GET("/jobs/:id/output", handlers.GetJobOutput(s.jobManager))
+		api.POST("/jobs/:id/cancel", audited("job.cancel"), requireOperator, handlers.CancelJob(s.jobManager))
+
+		// Audit log
+		api.GET("/audit", requireAdmin, handlers.QueryAudit(s.auditLog))
 	}
 
 	// WebSocket
-	s.router.GET("/ws", func(c *gin.Context) {
-		ws.HandleWebSocket(s.wsHub, c.Writer, c.Request)
+	s.router.GET("/ws", requireAuth, func(c *gin.Context) {
+		ws.HandleWebSocket(s.wsHub, c.Writer, c.Request, handlers.CurrentUser(c))
 	})
+	s.router.GET("/ws/containers/:id/exec", requireAuth, handlers.RequireContainerAccess(s.dockerClient), audited("container.exec"), handlers.RequireRole(s.execRole), handlers.ExecContainer(s.dockerClient, s.wsHub, s.execShell))
 
 	// Serve static files (SvelteKit build output)
 	s.router.Static("/_app", s.staticPath+"/_app")
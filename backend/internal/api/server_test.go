@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"aperture-science-network/internal/alert"
+	"aperture-science-network/internal/api/handlers"
+	"aperture-science-network/internal/audit"
+	"aperture-science-network/internal/auth"
+	"aperture-science-network/internal/autoupdate"
+	"aperture-science-network/internal/exporter"
+	"aperture-science-network/internal/history"
+	"aperture-science-network/internal/mock"
+	"aperture-science-network/internal/registry"
+)
+
+// newTestServer creates a server on mock Docker data with its stores in a
+// temporary directory, and an admin, an unrestricted viewer and an operator
+// limited to a team
+func newTestServer(t *testing.T, metricsToken string) (*Server, *auth.Sessions) {
+	t.Helper()
+	dir := t.TempDir()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	authStore, err := auth.NewFileStore(filepath.Join(dir, "users.json"))
+	must(err)
+	_, err = authStore.CreateUser("admin", "password1", auth.RoleAdmin, nil)
+	must(err)
+	_, err = authStore.CreateUser("viewer", "password1", auth.RoleViewer, nil)
+	must(err)
+	_, err = authStore.SaveTeam(auth.Team{Name: "billing", Stacks: []string{"billing-*"}})
+	must(err)
+	_, err = authStore.CreateUser("restricted", "password1", auth.RoleOperator, []string{"billing"})
+	must(err)
+	sessions := auth.NewSessions([]byte("0123456789abcdef0123456789abcdef"), time.Hour, authStore)
+
+	auditLog, err := audit.NewFileLog(filepath.Join(dir, "audit"), 0, 0)
+	must(err)
+	t.Cleanup(func() { auditLog.Close() })
+	historyStore, err := history.NewFileStore(filepath.Join(dir, "history"), 0)
+	must(err)
+	alertStore, err := alert.NewFileStore(filepath.Join(dir, "alerts.json"))
+	must(err)
+	policies, err := autoupdate.NewFileStore(filepath.Join(dir, "policies.json"))
+	must(err)
+	credentials, err := registry.NewFileCredentialStore(filepath.Join(dir, "credentials.enc"), []byte("0123456789abcdef0123456789abcdef"))
+	must(err)
+
+	server := NewServer(ServerOptions{
+		DockerClient:   mock.NewDockerClient(),
+		StatsProvider:  mock.NewStatsProvider(),
+		StackProvider:  mock.NewStackProvider(),
+		AuthStore:      authStore,
+		Sessions:       sessions,
+		AuditLog:       auditLog,
+		History:        historyStore,
+		Alerts:         alertStore,
+		JobWorkers:     1,
+		ExecShell:      "/bin/sh",
+		ExecRole:       auth.RoleAdmin,
+		MetricsToken:   metricsToken,
+		Registry:       mock.NewRegistry(),
+		UpdatePolicies: policies,
+		Credentials:    credentials,
+	})
+	return server, sessions
+}
+
+func TestPrometheusMetricsAccess(t *testing.T) {
+	tests := []struct {
+		name          string
+		metricsToken  string
+		authorization string
+		session       string
+		wantStatus    int
+	}{
+		{name: "token, none sent", metricsToken: "scrape-secret", wantStatus: http.StatusUnauthorized},
+		{name: "token, wrong one", metricsToken: "scrape-secret", authorization: "Bearer scrape-secrets", wantStatus: http.StatusUnauthorized},
+		{name: "token, without scheme", metricsToken: "scrape-secret", authorization: "scrape-secret", wantStatus: http.StatusUnauthorized},
+		{name: "token, admin session instead", metricsToken: "scrape-secret", session: "admin", wantStatus: http.StatusUnauthorized},
+		{name: "token", metricsToken: "scrape-secret", authorization: "Bearer scrape-secret", wantStatus: http.StatusOK},
+		{name: "no token, signed out", wantStatus: http.StatusUnauthorized},
+		{name: "no token, bearer token that is no session", authorization: "Bearer scrape-secret", wantStatus: http.StatusUnauthorized},
+		{name: "no token, restricted operator", session: "restricted", wantStatus: http.StatusForbidden},
+		{name: "no token, unrestricted viewer", session: "viewer", wantStatus: http.StatusOK},
+		{name: "no token, admin", session: "admin", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, sessions := newTestServer(t, tt.metricsToken)
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.session != "" {
+				token, _, err := sessions.Issue(tt.session)
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.AddCookie(&http.Cookie{Name: handlers.SessionCookie, Value: token})
+			}
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if strings.Contains(w.Body.String(), "# TYPE") {
+					t.Errorf("metrics served with status %d", w.Code)
+				}
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != exporter.ContentType {
+				t.Errorf("Content-Type = %q, want %q", got, exporter.ContentType)
+			}
+			if !strings.Contains(w.Body.String(), "# TYPE celeste_build_info gauge\n") {
+				t.Errorf("body lacks the build info:\n%s", w.Body.String())
+			}
+		})
+	}
+}
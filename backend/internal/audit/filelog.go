@@ -0,0 +1,269 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	currentFile = "audit.log"
+
+	// DefaultMaxSize is the size at which the current file is rotated
+	DefaultMaxSize = 10 * 1024 * 1024
+	// DefaultMaxFiles is the number of rotated files kept on disk
+	DefaultMaxFiles = 10
+
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// FileLog implements Log as append-only JSON lines files with size-based rotation
+type FileLog struct {
+	dir         string
+	maxSize     int64
+	maxFiles    int
+	mu          sync.Mutex
+	file        *os.File
+	size        int64
+	subscribers []func(Entry)
+}
+
+// NewFileLog opens (or creates) the audit log in dir
+func NewFileLog(dir string, maxSize int64, maxFiles int) (*FileLog, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	l := &FileLog{
+		dir:      dir,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Ensure FileLog implements Log
+var _ Log = (*FileLog)(nil)
+
+func (l *FileLog) Record(entry Entry) (*Entry, error) {
+	if entry.ID == "" {
+		entry.ID = newID()
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	if l.size+int64(len(data)) > l.maxSize && l.size > 0 {
+		if err := l.rotate(); err != nil {
+			l.mu.Unlock()
+			return nil, err
+		}
+	}
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	subscribers := l.subscribers
+	l.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fn := range subscribers {
+		fn(entry)
+	}
+	return &entry, nil
+}
+
+func (l *FileLog) Query(q Query) (*Page, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultLimit
+	}
+	if q.Limit > maxLimit {
+		q.Limit = maxLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	l.mu.Lock()
+	files, err := l.files()
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Entries: make([]Entry, 0), Limit: q.Limit, Offset: q.Offset}
+
+	// Files are ordered newest first; entries within a file oldest first
+	for _, path := range files {
+		entries, err := readEntries(path)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			if !q.matches(&entries[i]) {
+				continue
+			}
+			if page.Total >= q.Offset && len(page.Entries) < q.Limit {
+				page.Entries = append(page.Entries, entries[i])
+			}
+			page.Total++
+		}
+	}
+
+	return page, nil
+}
+
+func (l *FileLog) Subscribe(fn func(Entry)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subscribers = append(l.subscribers, fn)
+}
+
+// Close closes the current log file
+func (l *FileLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+func (l *FileLog) open() error {
+	f, err := os.OpenFile(filepath.Join(l.dir, currentFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file aside, opens a fresh one and prunes old
+// rotated files. Callers must hold mu.
+func (l *FileLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	rotated := fmt.Sprintf("audit-%s.log", time.Now().UTC().Format("20060102T150405.000000000"))
+	if err := os.Rename(filepath.Join(l.dir, currentFile), filepath.Join(l.dir, rotated)); err != nil {
+		return err
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+
+	files, err := l.files()
+	if err != nil {
+		return err
+	}
+	// files[0] is the current file; keep maxFiles rotated files after it
+	for i := l.maxFiles + 1; i < len(files); i++ {
+		os.Remove(files[i])
+	}
+	return nil
+}
+
+// files returns the current file followed by rotated files, newest first
+func (l *FileLog) files() ([]string, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := make([]string, 0)
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, "audit-") && strings.HasSuffix(name, ".log") {
+			rotated = append(rotated, name)
+		}
+	}
+	// Timestamped names sort chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(rotated)))
+
+	files := []string{filepath.Join(l.dir, currentFile)}
+	for _, name := range rotated {
+		files = append(files, filepath.Join(l.dir, name))
+	}
+	return files, nil
+}
+
+func readEntries(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // Skip a torn line left by a crash
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func (q *Query) matches(e *Entry) bool {
+	if q.User != "" && e.User != q.User {
+		return false
+	}
+	if q.Action != "" && e.Action != q.Action && !strings.HasPrefix(e.Action, q.Action+".") {
+		return false
+	}
+	if q.Target != "" && e.Target != q.Target {
+		return false
+	}
+	if q.Outcome != "" && e.Outcome != q.Outcome {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && e.Time.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+func newID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%d-%s", time.Now().UnixNano(), hex.EncodeToString(b))
+}
@@ -0,0 +1,55 @@
+package audit
+
+import "time"
+
+// Outcome values recorded for an action
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Entry is a single audit record describing a mutating action
+type Entry struct {
+	ID         string                 `json:"id"`
+	Time       time.Time              `json:"time"`
+	User       string                 `json:"user"`
+	Action     string                 `json:"action"`
+	Target     string                 `json:"target"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Outcome    string                 `json:"outcome"`
+	Status     int                    `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"durationMs"`
+}
+
+// Query filters audit entries. Zero values match everything.
+type Query struct {
+	User    string
+	Action  string
+	Target  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+	Offset  int
+}
+
+// Page is a slice of query results, newest first
+type Page struct {
+	Entries []Entry `json:"entries"`
+	Total   int     `json:"total"`
+	Limit   int     `json:"limit"`
+	Offset  int     `json:"offset"`
+}
+
+// Log defines the interface for audit storage
+type Log interface {
+	// Record appends an entry, assigning its ID and time if unset
+	Record(entry Entry) (*Entry, error)
+
+	// Query returns matching entries, newest first
+	Query(q Query) (*Page, error)
+
+	// Subscribe registers a callback invoked for every recorded entry
+	Subscribe(fn func(Entry))
+}
@@ -0,0 +1,493 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"aperture-science-network/internal/fsutil"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// dummyHash is compared against when a username does not exist so that
+// failed logins take the same time whether or not the account exists.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("celeste-dummy-password"), bcrypt.DefaultCost)
+
+type storedUser struct {
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"passwordHash"`
+	Role         Role      `json:"role"`
+	Teams        []string  `json:"teams,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	// RevokedSessions maps the IDs of logged out session tokens to their
+	// expiry, so they stay rejected across restarts
+	RevokedSessions map[string]time.Time `json:"revokedSessions,omitempty"`
+}
+
+type credentialsFile struct {
+	Users []storedUser `json:"users"`
+	Teams []Team       `json:"teams,omitempty"`
+}
+
+// FileStore implements Store using a JSON file of bcrypt-hashed credentials
+type FileStore struct {
+	path        string
+	mu          sync.RWMutex
+	users       map[string]*storedUser
+	teams       map[string]*Team
+	subscribers []func(string)
+}
+
+// NewFileStore loads (or initializes) the credentials file at path
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:  path,
+		users: make(map[string]*storedUser),
+		teams: make(map[string]*Team),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	var file credentialsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Users {
+		u := file.Users[i]
+		// Accounts created before roles existed were the bootstrap admin
+		if u.Role == "" {
+			u.Role = RoleAdmin
+		}
+		s.users[u.Username] = &u
+	}
+	for i := range file.Teams {
+		t := file.Teams[i]
+		s.teams[t.Name] = &t
+	}
+	return s, nil
+}
+
+// Ensure FileStore implements Store
+var _ Store = (*FileStore)(nil)
+
+func (s *FileStore) HasUsers() (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.users) > 0, nil
+}
+
+func (s *FileStore) ListUsers() ([]User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, s.publicLocked(u))
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+func (s *FileStore) GetUser(username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user := s.publicLocked(u)
+	return &user, nil
+}
+
+func (s *FileStore) CreateUser(username string, password string, role Role, teams []string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createLocked(username, password, role, teams)
+}
+
+func (s *FileStore) UpdateUser(username string, update UserUpdate) (*User, error) {
+	user, err := s.updateUser(username, update)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(username)
+	return user, nil
+}
+
+func (s *FileStore) updateUser(username string, update UserUpdate) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	updated := *u
+
+	if update.Password != nil {
+		hash, err := hashPassword(*update.Password)
+		if err != nil {
+			return nil, err
+		}
+		updated.PasswordHash = hash
+	}
+	if update.Role != nil {
+		if !update.Role.Valid() {
+			return nil, ErrInvalidRole
+		}
+		if u.Role == RoleAdmin && *update.Role != RoleAdmin && s.adminCountLocked() == 1 {
+			return nil, ErrLastAdmin
+		}
+		updated.Role = *update.Role
+	}
+	if update.Teams != nil {
+		if err := s.checkTeamsLocked(*update.Teams); err != nil {
+			return nil, err
+		}
+		updated.Teams = slices.Clone(*update.Teams)
+	}
+
+	s.users[username] = &updated
+	if err := s.saveLocked(); err != nil {
+		s.users[username] = u
+		return nil, err
+	}
+
+	user := s.publicLocked(&updated)
+	return &user, nil
+}
+
+func (s *FileStore) DeleteUser(username string) error {
+	if err := s.deleteUser(username); err != nil {
+		return err
+	}
+	s.publish(username)
+	return nil
+}
+
+func (s *FileStore) deleteUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if u.Role == RoleAdmin && s.adminCountLocked() == 1 {
+		return ErrLastAdmin
+	}
+
+	delete(s.users, username)
+	if err := s.saveLocked(); err != nil {
+		s.users[username] = u
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Bootstrap(username string, password string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.users) > 0 {
+		return nil, ErrSetupComplete
+	}
+	return s.createLocked(username, password, RoleAdmin, nil)
+}
+
+func (s *FileStore) Authenticate(username string, password string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	user := s.publicLocked(u)
+	return &user, nil
+}
+
+func (s *FileStore) ListTeams() ([]Team, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	teams := make([]Team, 0, len(s.teams))
+	for _, t := range s.teams {
+		teams = append(teams, *t)
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
+	return teams, nil
+}
+
+func (s *FileStore) SaveTeam(team Team) (*Team, error) {
+	saved, err := s.saveTeam(team)
+	if err != nil {
+		return nil, err
+	}
+	// The members' access follows the team's stacks
+	s.publish(s.membersOf(team.Name)...)
+	return saved, nil
+}
+
+func (s *FileStore) saveTeam(team Team) (*Team, error) {
+	if !namePattern.MatchString(team.Name) {
+		return nil, ErrInvalidTeamName
+	}
+	for _, pattern := range team.Stacks {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPattern, pattern)
+		}
+	}
+	if team.Stacks == nil {
+		team.Stacks = []string{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.teams[team.Name]
+	s.teams[team.Name] = &team
+	if err := s.saveLocked(); err != nil {
+		if previous != nil {
+			s.teams[team.Name] = previous
+		} else {
+			delete(s.teams, team.Name)
+		}
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (s *FileStore) DeleteTeam(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	team, ok := s.teams[name]
+	if !ok {
+		return ErrTeamNotFound
+	}
+	// Dropping a user's last team would lift their restriction, so members
+	// must be moved out explicitly first
+	for _, u := range s.users {
+		if slices.Contains(u.Teams, name) {
+			return fmt.Errorf("%w: %s is still a member", ErrTeamInUse, u.Username)
+		}
+	}
+
+	delete(s.teams, name)
+	if err := s.saveLocked(); err != nil {
+		s.teams[name] = team
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) RevokeSession(username string, id string, expires time.Time) error {
+	if err := s.revokeSession(username, id, expires); err != nil {
+		return err
+	}
+	s.publish(username)
+	return nil
+}
+
+func (s *FileStore) revokeSession(username string, id string, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	// Revocations of tokens that have expired since are dropped
+	now := time.Now()
+	updated := *u
+	updated.RevokedSessions = make(map[string]time.Time, len(u.RevokedSessions)+1)
+	for revoked, exp := range u.RevokedSessions {
+		if exp.After(now) {
+			updated.RevokedSessions[revoked] = exp
+		}
+	}
+	updated.RevokedSessions[id] = expires.UTC()
+
+	s.users[username] = &updated
+	if err := s.saveLocked(); err != nil {
+		s.users[username] = u
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) SessionRevoked(username string, id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return false
+	}
+	_, revoked := u.RevokedSessions[id]
+	return revoked
+}
+
+func (s *FileStore) Subscribe(fn func(username string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// publish tells subscribers that the accounts of usernames changed
+func (s *FileStore) publish(usernames ...string) {
+	s.mu.RLock()
+	subscribers := s.subscribers
+	s.mu.RUnlock()
+
+	for _, username := range usernames {
+		for _, fn := range subscribers {
+			fn(username)
+		}
+	}
+}
+
+// membersOf returns the usernames of the members of team
+func (s *FileStore) membersOf(team string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var members []string
+	for _, u := range s.users {
+		if slices.Contains(u.Teams, team) {
+			members = append(members, u.Username)
+		}
+	}
+	return members
+}
+
+func (s *FileStore) createLocked(username string, password string, role Role, teams []string) (*User, error) {
+	if !namePattern.MatchString(username) {
+		return nil, ErrInvalidUsername
+	}
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if _, ok := s.users[username]; ok {
+		return nil, ErrUserExists
+	}
+	if err := s.checkTeamsLocked(teams); err != nil {
+		return nil, err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &storedUser{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+		Teams:        slices.Clone(teams),
+		CreatedAt:    time.Now().UTC(),
+	}
+	s.users[username] = u
+	if err := s.saveLocked(); err != nil {
+		delete(s.users, username)
+		return nil, err
+	}
+
+	user := s.publicLocked(u)
+	return &user, nil
+}
+
+func (s *FileStore) checkTeamsLocked(teams []string) error {
+	for _, name := range teams {
+		if _, ok := s.teams[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrTeamNotFound, name)
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) adminCountLocked() int {
+	count := 0
+	for _, u := range s.users {
+		if u.Role == RoleAdmin {
+			count++
+		}
+	}
+	return count
+}
+
+// saveLocked atomically rewrites the credentials file. Callers must hold mu.
+func (s *FileStore) saveLocked() error {
+	file := credentialsFile{
+		Users: make([]storedUser, 0, len(s.users)),
+		Teams: make([]Team, 0, len(s.teams)),
+	}
+	for _, u := range s.users {
+		file.Users = append(file.Users, *u)
+	}
+	for _, t := range s.teams {
+		file.Teams = append(file.Teams, *t)
+	}
+	sort.Slice(file.Users, func(i, j int) bool { return file.Users[i].Username < file.Users[j].Username })
+	sort.Slice(file.Teams, func(i, j int) bool { return file.Teams[i].Name < file.Teams[j].Name })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(s.path, data, 0600)
+}
+
+// publicLocked converts a stored account to its exposed form, resolving team
+// grants. Callers must hold mu.
+func (s *FileStore) publicLocked(u *storedUser) User {
+	user := User{
+		Username:  u.Username,
+		Role:      u.Role,
+		Teams:     slices.Clone(u.Teams),
+		CreatedAt: u.CreatedAt,
+		Stacks:    []string{},
+	}
+	if user.Teams == nil {
+		user.Teams = []string{}
+	}
+	for _, name := range u.Teams {
+		if t, ok := s.teams[name]; ok {
+			user.Stacks = append(user.Stacks, t.Stacks...)
+		}
+	}
+	return user
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < 8 {
+		return "", ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
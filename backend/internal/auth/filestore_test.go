@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFileStoreDeleteTeamInUse(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveTeam(Team{Name: "billing", Stacks: []string{"billing-*"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateUser("alice", "password1", RoleOperator, []string{"billing"}); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := store.GetUser("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.CanAccessStack("billing-api") || user.CanAccessStack("payroll") {
+		t.Fatalf("team grants not applied: %v", user.Stacks)
+	}
+
+	if err := store.DeleteTeam("billing"); !errors.Is(err, ErrTeamInUse) {
+		t.Fatalf("DeleteTeam() with a member = %v, want %v", err, ErrTeamInUse)
+	}
+	if user, _ := store.GetUser("alice"); !user.Restricted() {
+		t.Fatal("member lost its restriction")
+	}
+
+	noTeams := []string{}
+	if _, err := store.UpdateUser("alice", UserUpdate{Teams: &noTeams}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteTeam("billing"); err != nil {
+		t.Fatalf("DeleteTeam() without members = %v", err)
+	}
+	if err := store.DeleteTeam("billing"); !errors.Is(err, ErrTeamNotFound) {
+		t.Fatalf("DeleteTeam() twice = %v, want %v", err, ErrTeamNotFound)
+	}
+}
+
+func TestFileStoreSubscribe(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changed []string
+	store.Subscribe(func(username string) { changed = append(changed, username) })
+
+	if _, err := store.SaveTeam(Team{Name: "billing", Stacks: []string{"billing-*"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateUser("alice", "password1", RoleAdmin, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateUser("bob", "password1", RoleOperator, []string{"billing"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Fatalf("creating accounts and an empty team published %v", changed)
+	}
+
+	viewer := RoleViewer
+	steps := []struct {
+		name   string
+		change func() error
+		want   []string
+	}{
+		{"update", func() error { _, err := store.UpdateUser("bob", UserUpdate{Role: &viewer}); return err }, []string{"bob"}},
+		{"team stacks", func() error {
+			_, err := store.SaveTeam(Team{Name: "billing", Stacks: []string{"payroll"}})
+			return err
+		}, []string{"bob"}},
+		{"revoke session", func() error { return store.RevokeSession("alice", "id", time.Now().Add(time.Hour)) }, []string{"alice"}},
+		{"delete", func() error { return store.DeleteUser("bob") }, []string{"bob"}},
+		{"failed update", func() error { _, err := store.UpdateUser("bob", UserUpdate{Role: &viewer}); return err }, nil},
+	}
+	for _, step := range steps {
+		changed = nil
+		err := step.change()
+		if step.want != nil && err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if !slices.Equal(changed, step.want) {
+			t.Errorf("%s published %v, want %v", step.name, changed, step.want)
+		}
+	}
+}
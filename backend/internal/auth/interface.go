@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"errors"
+	"path"
+	"time"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = errors.New("user already exists")
+	ErrTeamNotFound       = errors.New("team not found")
+	ErrTeamInUse          = errors.New("team still has members")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrSetupComplete      = errors.New("initial setup already completed")
+	ErrInvalidUsername    = errors.New("username must be 1-64 characters of letters, digits, '.', '_' or '-'")
+	ErrInvalidTeamName    = errors.New("team name must be 1-64 characters of letters, digits, '.', '_' or '-'")
+	ErrWeakPassword       = errors.New("password must be at least 8 characters")
+	ErrInvalidRole        = errors.New("role must be one of viewer, operator or admin")
+	ErrInvalidPattern     = errors.New("invalid stack pattern")
+	ErrLastAdmin          = errors.New("at least one admin account must remain")
+)
+
+// Role determines which operations a user may perform
+type Role string
+
+const (
+	// RoleViewer can read stacks, containers, logs and stats
+	RoleViewer Role = "viewer"
+	// RoleOperator can additionally create, rename, start, stop, pull and edit
+	// stacks and containers
+	RoleOperator Role = "operator"
+	// RoleAdmin can additionally delete stacks and manage volumes, networks,
+	// users and teams
+	RoleAdmin Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// Includes reports whether r grants at least the permissions of other
+func (r Role) Includes(other Role) bool {
+	return roleRank[r] >= roleRank[other]
+}
+
+// User represents a local account. The password hash is never exposed.
+type User struct {
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	Teams     []string  `json:"teams"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Stacks holds the stack name patterns granted through the user's teams.
+	// It is resolved by the store and only meaningful when Teams is non-empty.
+	Stacks []string `json:"stacks"`
+}
+
+// Restricted reports whether the user's stack access is limited by team grants
+func (u *User) Restricted() bool {
+	return u.Role != RoleAdmin && len(u.Teams) > 0
+}
+
+// CanAccessStack reports whether the user may see and manage the named stack.
+// Admins and users without teams can access every stack.
+func (u *User) CanAccessStack(name string) bool {
+	if !u.Restricted() {
+		return true
+	}
+	for _, pattern := range u.Stacks {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Team groups stack grants. Stacks are names or path.Match patterns
+// (e.g. "billing-*") relative to STACKS_PATH.
+type Team struct {
+	Name   string   `json:"name"`
+	Stacks []string `json:"stacks"`
+}
+
+// UserUpdate holds the optional fields of an account update
+type UserUpdate struct {
+	Password *string   `json:"password,omitempty"`
+	Role     *Role     `json:"role,omitempty"`
+	Teams    *[]string `json:"teams,omitempty"`
+}
+
+// Store defines the interface for user credential storage
+type Store interface {
+	// HasUsers reports whether at least one account exists
+	HasUsers() (bool, error)
+
+	// ListUsers returns all accounts
+	ListUsers() ([]User, error)
+
+	// GetUser returns a specific account by username
+	GetUser(username string) (*User, error)
+
+	// CreateUser adds a new account with the given password, role and teams
+	CreateUser(username string, password string, role Role, teams []string) (*User, error)
+
+	// UpdateUser changes an account's password, role or teams
+	UpdateUser(username string, update UserUpdate) (*User, error)
+
+	// DeleteUser removes an account
+	DeleteUser(username string) error
+
+	// Bootstrap creates the first admin account, failing if any account already exists
+	Bootstrap(username string, password string) (*User, error)
+
+	// Authenticate verifies a username/password pair
+	Authenticate(username string, password string) (*User, error)
+
+	// ListTeams returns all teams
+	ListTeams() ([]Team, error)
+
+	// SaveTeam creates or replaces a team
+	SaveTeam(team Team) (*Team, error)
+
+	// DeleteTeam removes a team that no user is a member of
+	DeleteTeam(name string) error
+
+	// RevokeSession records that a user's session token is revoked until
+	// expires, when it would have lapsed anyway
+	RevokeSession(username string, id string, expires time.Time) error
+
+	// SessionRevoked reports whether a user's session token was revoked
+	SessionRevoked(username string, id string) bool
+
+	// Subscribe registers a callback invoked after an account was updated,
+	// deleted or had a session revoked, or the stacks of one of its teams
+	// changed
+	Subscribe(fn func(username string))
+}
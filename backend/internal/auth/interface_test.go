@@ -0,0 +1,70 @@
+package auth
+
+import "testing"
+
+func TestUserRestricted(t *testing.T) {
+	tests := []struct {
+		name string
+		user User
+		want bool
+	}{
+		{"admin without teams", User{Role: RoleAdmin}, false},
+		{"admin with teams", User{Role: RoleAdmin, Teams: []string{"billing"}}, false},
+		{"operator without teams", User{Role: RoleOperator}, false},
+		{"operator with teams", User{Role: RoleOperator, Teams: []string{"billing"}}, true},
+		{"viewer with teams", User{Role: RoleViewer, Teams: []string{"billing"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.Restricted(); got != tt.want {
+				t.Errorf("Restricted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserCanAccessStack(t *testing.T) {
+	restricted := User{Role: RoleOperator, Teams: []string{"billing"}, Stacks: []string{"billing-*", "shared"}}
+
+	tests := []struct {
+		name  string
+		user  User
+		stack string
+		want  bool
+	}{
+		{"unrestricted user", User{Role: RoleViewer}, "anything", true},
+		{"admin ignores grants", User{Role: RoleAdmin, Teams: []string{"billing"}}, "anything", true},
+		{"pattern match", restricted, "billing-api", true},
+		{"exact match", restricted, "shared", true},
+		{"no match", restricted, "payroll", false},
+		{"pattern does not cross prefix", restricted, "old-billing-api", false},
+		{"exact name is not a prefix", restricted, "shared-db", false},
+		{"team without stacks", User{Role: RoleViewer, Teams: []string{"empty"}}, "shared", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.CanAccessStack(tt.stack); got != tt.want {
+				t.Errorf("CanAccessStack(%q) = %v, want %v", tt.stack, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleIncludes(t *testing.T) {
+	tests := []struct {
+		role  Role
+		other Role
+		want  bool
+	}{
+		{RoleAdmin, RoleOperator, true},
+		{RoleOperator, RoleOperator, true},
+		{RoleOperator, RoleAdmin, false},
+		{RoleViewer, RoleOperator, false},
+		{Role("root"), RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Includes(tt.other); got != tt.want {
+			t.Errorf("%s.Includes(%s) = %v, want %v", tt.role, tt.other, got, tt.want)
+		}
+	}
+}
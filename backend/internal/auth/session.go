@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"aperture-science-network/internal/fsutil"
+)
+
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// Claims is the signed payload carried by a session token
+type Claims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+}
+
+// Sessions issues and validates HMAC-signed session tokens. Tokens are
+// stateless; logout is handled by recording revoked token IDs with the
+// user in the store until they would have expired anyway.
+type Sessions struct {
+	secret []byte
+	ttl    time.Duration
+	store  Store
+}
+
+// NewSessions creates a session manager signing tokens with secret and
+// keeping revocations in store
+func NewSessions(secret []byte, ttl time.Duration, store Store) *Sessions {
+	return &Sessions{
+		secret: secret,
+		ttl:    ttl,
+		store:  store,
+	}
+}
+
+// LoadOrCreateSecret reads the random key at path, generating a new one if
+// the file does not exist yet
+func LoadOrCreateSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		secret, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(secret) < 32 {
+			return nil, errors.New("secret file is corrupt: " + path)
+		}
+		return secret, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := fsutil.WriteFileAtomic(path, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// TTL returns the lifetime of newly issued tokens
+func (s *Sessions) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue creates a signed token for username
+func (s *Sessions) Issue(username string) (string, time.Time, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expires := time.Now().Add(s.ttl)
+	claims := Claims{
+		Subject:   username,
+		ExpiresAt: expires.Unix(),
+		ID:        hex.EncodeToString(id),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded), expires, nil
+}
+
+// Validate checks a token's signature, expiry and revocation status
+func (s *Sessions) Validate(token string) (*Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidSession
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return nil, ErrInvalidSession
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidSession
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidSession
+	}
+
+	if s.store.SessionRevoked(claims.Subject, claims.ID) {
+		return nil, ErrInvalidSession
+	}
+
+	return &claims, nil
+}
+
+// Revoke invalidates a token before its natural expiry. Invalid tokens
+// and tokens of deleted users need no revocation.
+func (s *Sessions) Revoke(token string) error {
+	claims, err := s.Validate(token)
+	if err != nil {
+		return nil
+	}
+
+	err = s.store.RevokeSession(claims.Subject, claims.ID, time.Unix(claims.ExpiresAt, 0))
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *Sessions) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionRevokeSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateUser("alice", "password1", RoleAdmin, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	sessions := NewSessions(secret, time.Hour, store)
+	revoked, _, err := sessions.Issue("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := sessions.Issue("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.Revoke(revoked); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	// A restarted server loads the revocation from the user file; the
+	// user's other sessions stay valid
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions = NewSessions(secret, time.Hour, store)
+	if _, err := sessions.Validate(revoked); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Validate(revoked) error = %v, want %v", err, ErrInvalidSession)
+	}
+	if _, err := sessions.Validate(other); err != nil {
+		t.Errorf("Validate(other) error = %v", err)
+	}
+
+	// Revoking again, or revoking garbage, is not an error
+	if err := sessions.Revoke(revoked); err != nil {
+		t.Errorf("Revoke() of a revoked token error = %v", err)
+	}
+	if err := sessions.Revoke("not-a-token"); err != nil {
+		t.Errorf("Revoke() of an invalid token error = %v", err)
+	}
+}
+
+func TestFileStoreRevokeSessionPrunesExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateUser("alice", "password1", RoleAdmin, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := store.RevokeSession("alice", "old", now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RevokeSession("alice", "new", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if store.SessionRevoked("alice", "old") {
+		t.Errorf("expired revocation was kept")
+	}
+	if !store.SessionRevoked("alice", "new") {
+		t.Errorf("SessionRevoked(new) = false, want true")
+	}
+	if store.SessionRevoked("bob", "new") {
+		t.Errorf("revocation applies to another user")
+	}
+
+	if err := store.RevokeSession("bob", "x", now.Add(time.Hour)); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("RevokeSession() for an unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTooManyAttempts is returned while logins are throttled
+var ErrTooManyAttempts = errors.New("too many failed logins, try again later")
+
+const (
+	// userFreeFailures and ipFreeFailures are the failed logins allowed for
+	// a username and a client address before each further attempt must
+	// wait. Addresses get more leeway as they may be shared behind NAT.
+	userFreeFailures = 3
+	ipFreeFailures   = 10
+
+	// throttleBase is the first wait, doubled by every further failure up
+	// to throttleMax
+	throttleBase = time.Second
+	throttleMax  = 15 * time.Minute
+
+	// throttleForget is how long after its last failure a username or
+	// address starts from scratch
+	throttleForget = time.Hour
+)
+
+// Throttle slows down password guessing by making further login attempts
+// wait after repeated failures, separately for each username and for each
+// client address
+type Throttle struct {
+	mu       sync.Mutex
+	failures map[string]*failures
+	pruned   time.Time
+}
+
+type failures struct {
+	count int
+	last  time.Time
+}
+
+// NewThrottle creates an empty login throttle
+func NewThrottle() *Throttle {
+	return &Throttle{failures: make(map[string]*failures)}
+}
+
+// Wait returns how long a login for username from ip must wait, or zero if
+// it may proceed
+func (t *Throttle) Wait(username string, ip string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return max(t.waitLocked(userKey(username), userFreeFailures, now), t.waitLocked(ipKey(ip), ipFreeFailures, now))
+}
+
+// Fail records a failed login for username from ip
+func (t *Throttle) Fail(username string, ip string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Forget stale entries now and then so guesses at many usernames do
+	// not grow the map forever
+	if now.Sub(t.pruned) > throttleForget {
+		for key, f := range t.failures {
+			if now.Sub(f.last) > throttleForget {
+				delete(t.failures, key)
+			}
+		}
+		t.pruned = now
+	}
+
+	for _, key := range []string{userKey(username), ipKey(ip)} {
+		f, ok := t.failures[key]
+		if !ok || now.Sub(f.last) > throttleForget {
+			f = &failures{}
+			t.failures[key] = f
+		}
+		f.count++
+		f.last = now
+	}
+}
+
+// Succeed clears the failures of username after a successful login. Those
+// of the address remain, so that signing in to one account does not reset
+// guessing at others.
+func (t *Throttle) Succeed(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, userKey(username))
+}
+
+func (t *Throttle) waitLocked(key string, free int, now time.Time) time.Duration {
+	f, ok := t.failures[key]
+	if !ok || f.count < free || now.Sub(f.last) > throttleForget {
+		return 0
+	}
+	delay := throttleBase
+	for i := free; i < f.count && delay < throttleMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, throttleMax)
+	return max(f.last.Add(delay).Sub(now), 0)
+}
+
+func userKey(username string) string {
+	return "user:" + username
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestThrottleUser(t *testing.T) {
+	throttle := NewThrottle()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Each client address is different so only the user entry counts
+	fail := func(n int) {
+		for i := range n {
+			throttle.Fail("alice", fmt.Sprint("10.0.0.", i), now)
+		}
+	}
+
+	fail(userFreeFailures - 1)
+	if wait := throttle.Wait("alice", "10.0.1.1", now); wait != 0 {
+		t.Fatalf("Wait() after %d failures = %v, want 0", userFreeFailures-1, wait)
+	}
+
+	fail(1)
+	if wait := throttle.Wait("alice", "10.0.1.1", now); wait != throttleBase {
+		t.Errorf("Wait() after %d failures = %v, want %v", userFreeFailures, wait, throttleBase)
+	}
+	if wait := throttle.Wait("alice", "10.0.1.1", now.Add(throttleBase)); wait != 0 {
+		t.Errorf("Wait() once the delay passed = %v, want 0", wait)
+	}
+	if wait := throttle.Wait("bob", "10.0.1.1", now); wait != 0 {
+		t.Errorf("Wait() for another user = %v, want 0", wait)
+	}
+
+	// Every further failure doubles the delay, up to the maximum
+	fail(2)
+	if wait := throttle.Wait("alice", "10.0.1.1", now); wait != 4*throttleBase {
+		t.Errorf("Wait() after %d failures = %v, want %v", userFreeFailures+2, wait, 4*throttleBase)
+	}
+	fail(20)
+	if wait := throttle.Wait("alice", "10.0.1.1", now); wait != throttleMax {
+		t.Errorf("Wait() after many failures = %v, want %v", wait, throttleMax)
+	}
+
+	// A successful login clears the user
+	throttle.Succeed("alice")
+	if wait := throttle.Wait("alice", "10.0.1.1", now); wait != 0 {
+		t.Errorf("Wait() after a success = %v, want 0", wait)
+	}
+}
+
+func TestThrottleAddress(t *testing.T) {
+	throttle := NewThrottle()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Guessing at many usernames from one address
+	for i := range ipFreeFailures {
+		throttle.Fail(fmt.Sprint("user", i), "10.0.0.1", now)
+	}
+	if wait := throttle.Wait("someone", "10.0.0.1", now); wait != throttleBase {
+		t.Errorf("Wait() from the address = %v, want %v", wait, throttleBase)
+	}
+	if wait := throttle.Wait("someone", "10.0.0.2", now); wait != 0 {
+		t.Errorf("Wait() from another address = %v, want 0", wait)
+	}
+
+	// Signing in to one account leaves the address throttled
+	throttle.Succeed("user0")
+	if wait := throttle.Wait("user0", "10.0.0.1", now); wait != throttleBase {
+		t.Errorf("Wait() after a success = %v, want %v", wait, throttleBase)
+	}
+}
+
+func TestThrottleForgets(t *testing.T) {
+	throttle := NewThrottle()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for range 10 {
+		throttle.Fail("alice", "10.0.0.1", now)
+	}
+
+	later := now.Add(throttleForget + time.Second)
+	if wait := throttle.Wait("alice", "10.0.0.1", later); wait != 0 {
+		t.Errorf("Wait() after an idle hour = %v, want 0", wait)
+	}
+
+	// A new failure starts the count from scratch and prunes stale entries
+	throttle.Fail("alice", "10.0.0.1", later)
+	if wait := throttle.Wait("alice", "10.0.0.1", later); wait != 0 {
+		t.Errorf("Wait() after one new failure = %v, want 0", wait)
+	}
+	throttle.Fail("bob", "10.0.0.2", now)
+	throttle.Fail("carol", "10.0.0.3", later.Add(throttleForget+time.Second))
+	if _, ok := throttle.failures[userKey("bob")]; ok {
+		t.Errorf("stale entry was not pruned")
+	}
+	if len(throttle.failures) != 2 {
+		t.Errorf("%d entries kept, want 2", len(throttle.failures))
+	}
+}
@@ -0,0 +1,262 @@
+package autoupdate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aperture-science-network/internal/alert"
+	"aperture-science-network/internal/fsutil"
+)
+
+const (
+	// DefaultHealthTimeout applies to policies that leave it unset
+	DefaultHealthTimeout = 2 * time.Minute
+
+	// maxRuns is the number of runs retained per stack
+	maxRuns = 20
+)
+
+type policiesFile struct {
+	Policies []Policy `json:"policies"`
+	Runs     []Run    `json:"runs"`
+}
+
+// FileStore implements Store as a single JSON file
+type FileStore struct {
+	path     string
+	mu       sync.RWMutex
+	policies map[string]*Policy
+	// runs holds each stack's runs, newest first
+	runs map[string][]Run
+}
+
+// NewFileStore loads (or initializes) the update policies at path
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:     path,
+		policies: make(map[string]*Policy),
+		runs:     make(map[string][]Run),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	var file policiesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Policies {
+		s.policies[file.Policies[i].Stack] = &file.Policies[i]
+	}
+	for _, run := range file.Runs {
+		s.runs[run.Stack] = append(s.runs[run.Stack], run)
+	}
+	return s, nil
+}
+
+// Ensure FileStore implements Store
+var _ Store = (*FileStore)(nil)
+
+func (s *FileStore) ListPolicies() ([]Policy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	policies := make([]Policy, 0, len(s.policies))
+	for _, p := range s.policies {
+		policies = append(policies, *p)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Stack < policies[j].Stack })
+	return policies, nil
+}
+
+func (s *FileStore) GetPolicy(stack string) (*Policy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.policies[stack]
+	if !ok {
+		return nil, ErrPolicyNotFound
+	}
+	policy := *p
+	return &policy, nil
+}
+
+func (s *FileStore) SetPolicy(policy Policy) (*Policy, error) {
+	if err := validatePolicy(&policy); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.policies[policy.Stack]
+	policy.UpdatedAt = time.Now().UTC()
+	policy.CreatedAt = policy.UpdatedAt
+	if ok {
+		policy.CreatedAt = existing.CreatedAt
+	}
+
+	s.policies[policy.Stack] = &policy
+	if err := s.saveLocked(); err != nil {
+		if ok {
+			s.policies[policy.Stack] = existing
+		} else {
+			delete(s.policies, policy.Stack)
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (s *FileStore) DeletePolicy(stack string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.policies[stack]
+	if !ok {
+		return ErrPolicyNotFound
+	}
+	runs := s.runs[stack]
+	delete(s.policies, stack)
+	delete(s.runs, stack)
+	if err := s.saveLocked(); err != nil {
+		s.policies[stack] = existing
+		s.runs[stack] = runs
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) RenameStack(stack string, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.policies[stack]
+	if !ok {
+		return nil
+	}
+	runs := s.runs[stack]
+
+	moved := *existing
+	moved.Stack = newName
+	movedRuns := make([]Run, len(runs))
+	for i, run := range runs {
+		run.Stack = newName
+		movedRuns[i] = run
+	}
+
+	delete(s.policies, stack)
+	delete(s.runs, stack)
+	s.policies[newName] = &moved
+	s.runs[newName] = movedRuns
+	if err := s.saveLocked(); err != nil {
+		delete(s.policies, newName)
+		delete(s.runs, newName)
+		s.policies[stack] = existing
+		s.runs[stack] = runs
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) ListRuns(stack string) ([]Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs := make([]Run, len(s.runs[stack]))
+	copy(runs, s.runs[stack])
+	return runs, nil
+}
+
+func (s *FileStore) RecordRun(run Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if run.ID == "" {
+		run.ID = newID()
+	}
+	previous := s.runs[run.Stack]
+	runs := append([]Run{run}, previous...)
+	if len(runs) > maxRuns {
+		runs = runs[:maxRuns]
+	}
+
+	s.runs[run.Stack] = runs
+	if err := s.saveLocked(); err != nil {
+		s.runs[run.Stack] = previous
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) saveLocked() error {
+	file := policiesFile{
+		Policies: make([]Policy, 0, len(s.policies)),
+		Runs:     make([]Run, 0),
+	}
+	for _, p := range s.policies {
+		file.Policies = append(file.Policies, *p)
+	}
+	sort.Slice(file.Policies, func(i, j int) bool { return file.Policies[i].Stack < file.Policies[j].Stack })
+	for _, p := range file.Policies {
+		file.Runs = append(file.Runs, s.runs[p.Stack]...)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(s.path, data, 0600)
+}
+
+// validatePolicy checks a policy and fills in defaults
+func validatePolicy(p *Policy) error {
+	if p.Stack == "" {
+		return fmt.Errorf("%w: stack is required", ErrInvalidPolicy)
+	}
+	if p.Mode == "" {
+		p.Mode = ModeNotify
+	}
+	if p.Mode != ModeNotify && p.Mode != ModeApply {
+		return fmt.Errorf("%w: mode must be %q or %q", ErrInvalidPolicy, ModeNotify, ModeApply)
+	}
+
+	p.Schedule = strings.TrimSpace(p.Schedule)
+	if _, err := ParseSchedule(p.Schedule); err != nil {
+		return fmt.Errorf("%w: schedule: %v", ErrInvalidPolicy, err)
+	}
+	if p.Window != nil {
+		if err := p.Window.validate(); err != nil {
+			return fmt.Errorf("%w: window: %v", ErrInvalidPolicy, err)
+		}
+	}
+
+	if p.HealthTimeout < 0 {
+		return fmt.Errorf("%w: health timeout cannot be negative", ErrInvalidPolicy)
+	}
+	if p.HealthTimeout == 0 {
+		p.HealthTimeout = alert.Duration(DefaultHealthTimeout)
+	}
+	if p.Channels == nil {
+		p.Channels = []string{}
+	}
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,117 @@
+package autoupdate
+
+import (
+	"errors"
+	"time"
+
+	"aperture-science-network/internal/alert"
+)
+
+var (
+	ErrPolicyNotFound = errors.New("update policy not found")
+	ErrInvalidPolicy  = errors.New("invalid update policy")
+)
+
+// Mode is what a policy does when its schedule finds newer images
+type Mode string
+
+const (
+	// ModeNotify only sends a notification listing the updates
+	ModeNotify Mode = "notify"
+	// ModeApply pulls the new images and recreates the changed services,
+	// rolling back if they don't become healthy
+	ModeApply Mode = "apply"
+)
+
+// Outcome is the result of one policy run
+type Outcome string
+
+const (
+	OutcomeUpToDate   Outcome = "up-to-date"
+	OutcomeNotified   Outcome = "notified"
+	OutcomeDeferred   Outcome = "deferred"
+	OutcomeApplied    Outcome = "applied"
+	OutcomeRolledBack Outcome = "rolled-back"
+	OutcomeFailed     Outcome = "failed"
+)
+
+// Window is a maintenance window, in the server's time zone. A window whose
+// end is before its start spans midnight.
+type Window struct {
+	// Start and End are "HH:MM"
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Days restricts the window to some weekdays ("mon" to "sun"), by the
+	// day it starts on. Empty means every day.
+	Days []string `json:"days,omitempty"`
+}
+
+// Policy controls automatic updates of one stack
+type Policy struct {
+	Stack   string `json:"stack"`
+	Enabled bool   `json:"enabled"`
+	Mode    Mode   `json:"mode"`
+	// Schedule is a five-field cron expression (minute hour day-of-month
+	// month day-of-week) or a shorthand such as "@daily", for when to look
+	// for updates
+	Schedule string `json:"schedule"`
+	// Window, if set, limits when updates are applied. Updates found
+	// outside it are applied when it next opens.
+	Window *Window `json:"window,omitempty"`
+	// HealthTimeout is how long recreated containers have to become
+	// healthy before the update is rolled back
+	HealthTimeout alert.Duration `json:"healthTimeout"`
+	// Channels are the alert notification channels told about updates
+	Channels  []string  `json:"channels"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ServiceUpdate is a service whose image changed, or would change
+type ServiceUpdate struct {
+	Service string `json:"service"`
+	Image   string `json:"image"`
+	// FromDigest and ToDigest are registry digests where known
+	FromDigest string `json:"fromDigest,omitempty"`
+	ToDigest   string `json:"toDigest,omitempty"`
+}
+
+// Run records one execution of a policy
+type Run struct {
+	ID    string `json:"id"`
+	Stack string `json:"stack"`
+	// Trigger is "schedule", "window" for a deferred update applied when
+	// the maintenance window opened, or "manual"
+	Trigger    string          `json:"trigger"`
+	Mode       Mode            `json:"mode"`
+	Outcome    Outcome         `json:"outcome"`
+	Services   []ServiceUpdate `json:"services"`
+	JobID      string          `json:"jobId,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+}
+
+// Store persists update policies and their recent runs
+type Store interface {
+	// ListPolicies returns all policies, ordered by stack
+	ListPolicies() ([]Policy, error)
+
+	// GetPolicy returns a stack's policy
+	GetPolicy(stack string) (*Policy, error)
+
+	// SetPolicy creates or replaces a stack's policy
+	SetPolicy(policy Policy) (*Policy, error)
+
+	// DeletePolicy removes a stack's policy and its runs
+	DeletePolicy(stack string) error
+
+	// RenameStack moves a policy and its runs to a renamed stack
+	RenameStack(stack string, newName string) error
+
+	// ListRuns returns a stack's recent runs, newest first
+	ListRuns(stack string) ([]Run, error)
+
+	// RecordRun adds a run, dropping the oldest beyond the retention limit
+	RecordRun(run Run) error
+}
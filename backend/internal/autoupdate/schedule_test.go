@@ -0,0 +1,153 @@
+package autoupdate
+
+import (
+	"testing"
+	"time"
+)
+
+// monday is a Monday at midnight
+var monday = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(day int, hour int, minute int) time.Time {
+	return monday.AddDate(0, 0, day).Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@often",
+	}
+	for _, expr := range tests {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded", expr)
+		}
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	tests := []struct {
+		expr  string
+		time  time.Time
+		match bool
+	}{
+		{"* * * * *", at(0, 13, 37), true},
+		{"@daily", at(2, 0, 0), true},
+		{"@daily", at(2, 0, 1), false},
+		{"@hourly", at(0, 5, 0), true},
+		{"*/15 * * * *", at(0, 1, 45), true},
+		{"*/15 * * * *", at(0, 1, 46), false},
+		{"5/20 * * * *", at(0, 1, 45), true},
+		{"0-30/10 * * * *", at(0, 1, 40), false},
+		{"0 3 * * mon-fri", at(4, 3, 0), true},
+		{"0 3 * * mon-fri", at(5, 3, 0), false},
+		{"0 3 * * 7", at(6, 3, 0), true},
+		{"0 3 * * sun", at(6, 3, 0), true},
+		{"0 0 1 jan *", monday, true},
+		{"0 0 1 feb *", monday, false},
+		{"0 0 1,15 * *", at(14, 0, 0), true},
+		// With both day fields restricted either may match
+		{"0 0 15 * mon", at(7, 0, 0), true},
+		{"0 0 15 * mon", at(14, 0, 0), true},
+		{"0 0 15 * mon", at(15, 0, 0), false},
+		// With one restricted, both must
+		{"0 0 * * mon", at(1, 0, 0), false},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q) error = %v", tt.expr, err)
+		}
+		if got := s.Matches(tt.time); got != tt.match {
+			t.Errorf("%q.Matches(%s) = %v, want %v", tt.expr, tt.time.Format("Mon 2006-01-02 15:04"), got, tt.match)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", at(0, 1, 15), at(0, 1, 30)},
+		{"@daily", at(0, 12, 0), at(1, 0, 0)},
+		{"30 4 * * sat", at(0, 12, 0), at(5, 4, 30)},
+		{"0 0 1 * *", at(0, 12, 0), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", at(0, 0, 0), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", at(0, 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q) error = %v", tt.expr, err)
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%s) = %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	tests := []struct {
+		name   string
+		window Window
+		time   time.Time
+		want   bool
+	}{
+		{"inside", Window{Start: "02:00", End: "04:00"}, at(0, 3, 0), true},
+		{"at start", Window{Start: "02:00", End: "04:00"}, at(0, 2, 0), true},
+		{"at end", Window{Start: "02:00", End: "04:00"}, at(0, 4, 0), false},
+		{"before", Window{Start: "02:00", End: "04:00"}, at(0, 1, 59), false},
+		{"overnight before midnight", Window{Start: "23:00", End: "01:00"}, at(0, 23, 30), true},
+		{"overnight after midnight", Window{Start: "23:00", End: "01:00"}, at(1, 0, 30), true},
+		{"overnight outside", Window{Start: "23:00", End: "01:00"}, at(0, 12, 0), false},
+		{"on a listed day", Window{Start: "02:00", End: "04:00", Days: []string{"mon"}}, at(0, 3, 0), true},
+		{"on another day", Window{Start: "02:00", End: "04:00", Days: []string{"mon"}}, at(1, 3, 0), false},
+		// Days apply to the day an overnight window starts on
+		{"overnight from a listed day", Window{Start: "23:00", End: "01:00", Days: []string{"sun"}}, at(7, 0, 30), true},
+		{"overnight into a listed day", Window{Start: "23:00", End: "01:00", Days: []string{"mon"}}, at(7, 0, 30), false},
+		{"invalid times", Window{Start: "25:00", End: "01:00"}, at(0, 0, 30), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Contains(tt.time); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.time.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  Window
+		wantErr bool
+	}{
+		{"valid", Window{Start: "02:00", End: "04:00", Days: []string{" Sat ", "sun"}}, false},
+		{"bad start", Window{Start: "2am", End: "04:00"}, true},
+		{"bad end", Window{Start: "02:00", End: "24:00"}, true},
+		{"empty window", Window{Start: "02:00", End: "02:00"}, true},
+		{"unknown day", Window{Start: "02:00", End: "04:00", Days: []string{"someday"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.window.validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.window.Days[0] != "sat" {
+				t.Errorf("days not normalized: %v", tt.window.Days)
+			}
+		})
+	}
+}
@@ -0,0 +1,558 @@
+package autoupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aperture-science-network/internal/alert"
+	"aperture-science-network/internal/compose"
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/jobs"
+	"aperture-science-network/internal/registry"
+	"aperture-science-network/internal/stack"
+	"aperture-science-network/internal/updates"
+)
+
+const (
+	// SchedulerUser is the user recorded for jobs the scheduler starts
+	SchedulerUser = "scheduler"
+
+	// JobAction is the action of update jobs
+	JobAction = "update"
+
+	// checkTimeout bounds the registry check that starts a run
+	checkTimeout = 2 * time.Minute
+	// jobTimeout bounds an update job, on top of its health timeouts
+	jobTimeout = 15 * time.Minute
+	// rollbackTimeout bounds restoring the previous images, on top of the
+	// health timeout
+	rollbackTimeout = 5 * time.Minute
+	// healthPoll is how often recreated containers are inspected
+	healthPoll = 2 * time.Second
+	// settleTime is how long containers without a healthcheck must stay
+	// running to count as healthy
+	settleTime = 10 * time.Second
+)
+
+var (
+	ErrRunInProgress = errors.New("an update is already running for this stack")
+	errRolledBack    = errors.New("update rolled back")
+)
+
+// Scheduler runs update policies on their schedules
+type Scheduler struct {
+	store          Store
+	dockerClient   docker.DockerClient
+	stackProvider  stack.Provider
+	composeManager *compose.Manager
+	jobManager     *jobs.Manager
+	checker        *updates.Checker
+	alerts         alert.Store
+
+	mu sync.Mutex
+	// pending holds updates found outside a stack's maintenance window
+	pending map[string][]ServiceUpdate
+	// checking holds the stacks whose registries are being queried
+	checking map[string]bool
+	// updating holds the ID of each stack's last update job
+	updating map[string]string
+}
+
+// NewScheduler creates a scheduler; call Run to start it
+func NewScheduler(store Store, dockerClient docker.DockerClient, stackProvider stack.Provider, composeManager *compose.Manager,
+	jobManager *jobs.Manager, checker *updates.Checker, alerts alert.Store) *Scheduler {
+	return &Scheduler{
+		store:          store,
+		dockerClient:   dockerClient,
+		stackProvider:  stackProvider,
+		composeManager: composeManager,
+		jobManager:     jobManager,
+		checker:        checker,
+		alerts:         alerts,
+		pending:        make(map[string][]ServiceUpdate),
+		checking:       make(map[string]bool),
+		updating:       make(map[string]string),
+	}
+}
+
+// Run evaluates the policies at the start of every minute until the process
+// exits
+func (s *Scheduler) Run() {
+	for {
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		s.tick(time.Now())
+	}
+}
+
+// Pending returns the updates waiting for a stack's maintenance window
+func (s *Scheduler) Pending(stackName string) []ServiceUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ServiceUpdate{}, s.pending[stackName]...)
+}
+
+// RunNow runs a stack's policy immediately on behalf of user. Updates are
+// applied even outside the maintenance window. The run is returned once the
+// registries were checked; an update job may still be in progress.
+func (s *Scheduler) RunNow(stackName string, user string) (*Run, error) {
+	policy, err := s.store.GetPolicy(stackName)
+	if err != nil {
+		return nil, err
+	}
+	return s.execute(*policy, "manual", user)
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	policies, err := s.store.ListPolicies()
+	if err != nil {
+		log.Printf("Error loading update policies: %v", err)
+		return
+	}
+
+	for _, p := range policies {
+		if !p.Enabled || !s.stackProvider.StackExists(p.Stack) {
+			continue
+		}
+		schedule, err := ParseSchedule(p.Schedule)
+		if err != nil {
+			continue
+		}
+
+		trigger := ""
+		switch {
+		case schedule.Matches(now):
+			trigger = "schedule"
+		case p.Window != nil && p.Window.Contains(now) && len(s.Pending(p.Stack)) > 0:
+			trigger = "window"
+		default:
+			continue
+		}
+
+		go func(p Policy) {
+			if _, err := s.execute(p, trigger, SchedulerUser); err != nil && !errors.Is(err, ErrRunInProgress) {
+				log.Printf("Error running update policy for %s: %v", p.Stack, err)
+			}
+		}(p)
+	}
+}
+
+// execute checks a stack for updates and notifies, defers or applies them
+// according to its policy
+func (s *Scheduler) execute(p Policy, trigger string, user string) (*Run, error) {
+	s.mu.Lock()
+	if s.busyLocked(p.Stack) {
+		s.mu.Unlock()
+		return nil, ErrRunInProgress
+	}
+	s.checking[p.Stack] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.checking, p.Stack)
+		s.mu.Unlock()
+	}()
+
+	run := Run{
+		ID:        newID(),
+		Stack:     p.Stack,
+		Trigger:   trigger,
+		Mode:      p.Mode,
+		Services:  []ServiceUpdate{},
+		StartedAt: time.Now().UTC(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	found, err := s.findUpdates(ctx, p.Stack)
+	cancel()
+
+	switch {
+	case err != nil:
+		run.Outcome = OutcomeFailed
+		run.Error = err.Error()
+	case len(found) == 0:
+		run.Outcome = OutcomeUpToDate
+		s.setPending(p.Stack, nil)
+	case p.Mode == ModeNotify:
+		run.Services = found
+		run.Outcome = OutcomeNotified
+		s.notify(p, alert.StateFiring, fmt.Sprintf("Updates available for %s", p.Stack),
+			"Newer images are available for "+describeUpdates(found)+".")
+	case p.Window != nil && trigger != "manual" && !p.Window.Contains(time.Now()):
+		run.Services = found
+		run.Outcome = OutcomeDeferred
+		s.setPending(p.Stack, found)
+	default:
+		run.Services = found
+		s.setPending(p.Stack, nil)
+		return s.startJob(p, run, user), nil
+	}
+
+	run.FinishedAt = time.Now().UTC()
+	if err := s.store.RecordRun(run); err != nil {
+		log.Printf("Error recording update run for %s: %v", p.Stack, err)
+	}
+	return &run, nil
+}
+
+// startJob queues the update of a stack as a job, so its output is
+// streamed like other stack operations and it never overlaps them
+func (s *Scheduler) startJob(p Policy, run Run, user string) *Run {
+	stackPath := s.stackProvider.GetStackPath(p.Stack)
+	timeout := jobTimeout + 2*time.Duration(p.HealthTimeout)
+
+	// The job may start before Enqueue returns its ID
+	jobID := make(chan string, 1)
+	job := s.jobManager.Enqueue(jobs.Spec{
+		Stack:   p.Stack,
+		Action:  JobAction,
+		User:    user,
+		Params:  map[string]string{"trigger": run.Trigger},
+		Timeout: timeout,
+		Run: func(ctx context.Context, output func(stream string, line string)) error {
+			result := run
+			result.JobID = <-jobID
+			services, err := s.apply(ctx, p, stackPath, output)
+			if services != nil {
+				result.Services = services
+			}
+
+			switch {
+			case err == nil && len(services) == 0:
+				result.Outcome = OutcomeUpToDate
+			case err == nil:
+				result.Outcome = OutcomeApplied
+				s.notify(p, alert.StateResolved, fmt.Sprintf("Updated %s", p.Stack),
+					"Updated "+describeUpdates(services)+".")
+			case errors.Is(err, errRolledBack):
+				result.Outcome = OutcomeRolledBack
+				result.Error = err.Error()
+				s.notify(p, alert.StateFiring, fmt.Sprintf("Update of %s rolled back", p.Stack),
+					"The update of "+describeUpdates(services)+" was rolled back: "+err.Error())
+			default:
+				result.Outcome = OutcomeFailed
+				result.Error = err.Error()
+				s.notify(p, alert.StateFiring, fmt.Sprintf("Update of %s failed", p.Stack),
+					"The update of "+p.Stack+" failed: "+err.Error())
+			}
+
+			result.FinishedAt = time.Now().UTC()
+			if recordErr := s.store.RecordRun(result); recordErr != nil {
+				log.Printf("Error recording update run for %s: %v", p.Stack, recordErr)
+			}
+			return err
+		},
+	})
+	jobID <- job.ID
+
+	s.mu.Lock()
+	s.updating[p.Stack] = job.ID
+	s.mu.Unlock()
+
+	run.JobID = job.ID
+	return &run
+}
+
+// busyLocked reports whether a stack is being checked or has an unfinished
+// update job
+func (s *Scheduler) busyLocked(stackName string) bool {
+	if s.checking[stackName] {
+		return true
+	}
+	id, ok := s.updating[stackName]
+	if !ok {
+		return false
+	}
+	if job, found := s.jobManager.Get(id); found && !job.Finished() {
+		return true
+	}
+	delete(s.updating, stackName)
+	return false
+}
+
+// findUpdates asks the registries which of a stack's services have newer
+// images
+func (s *Scheduler) findUpdates(ctx context.Context, stackName string) ([]ServiceUpdate, error) {
+	statuses, err := s.checker.CheckStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	found := make([]ServiceUpdate, 0)
+	for _, st := range statuses {
+		if !st.UpdateAvailable || seen[st.Service] {
+			continue
+		}
+		seen[st.Service] = true
+		found = append(found, ServiceUpdate{
+			Service:    st.Service,
+			Image:      st.Image,
+			FromDigest: st.LocalDigest,
+			ToDigest:   st.RemoteDigest,
+		})
+	}
+	return found, nil
+}
+
+// serviceState is what a service ran before an update
+type serviceState struct {
+	image   string
+	imageID string
+}
+
+// apply pulls a stack's images, recreates the services whose image changed
+// and waits for them to become healthy, rolling back to the previous images
+// if they don't. It returns the services it updated.
+func (s *Scheduler) apply(ctx context.Context, p Policy, stackPath string, output func(stream string, line string)) ([]ServiceUpdate, error) {
+	before, err := s.serviceStates(ctx, p.Stack)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.composeManager.Pull(ctx, stackPath, output); err != nil {
+		return nil, err
+	}
+
+	changed := make([]ServiceUpdate, 0)
+	for service, state := range before {
+		image, err := s.dockerClient.InspectImage(ctx, state.image)
+		if err != nil {
+			output("stderr", fmt.Sprintf("Cannot inspect %s for %s: %v", state.image, service, err))
+			continue
+		}
+		if image.ID == state.imageID {
+			continue
+		}
+		update := ServiceUpdate{Service: service, Image: state.image, ToDigest: repoDigest(image.Digests, state.image)}
+		if previous, err := s.dockerClient.InspectImage(ctx, state.imageID); err == nil {
+			update.FromDigest = repoDigest(previous.Digests, state.image)
+		}
+		changed = append(changed, update)
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Service < changed[j].Service })
+	if len(changed) == 0 {
+		output("stdout", "All images are up to date")
+		return changed, nil
+	}
+
+	services := make([]string, len(changed))
+	for i, u := range changed {
+		services[i] = u.Service
+	}
+	output("stdout", "Recreating "+strings.Join(services, ", "))
+	upErr := s.composeManager.UpServices(ctx, stackPath, services, output)
+	if upErr == nil {
+		upErr = s.waitHealthy(ctx, p.Stack, services, time.Duration(p.HealthTimeout), output)
+		if upErr == nil {
+			output("stdout", "Update complete")
+			return changed, nil
+		}
+	}
+
+	output("stderr", "Rolling back: "+upErr.Error())
+	// A canceled or timed out job must not leave the services on the images
+	// being rolled back from
+	rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout+time.Duration(p.HealthTimeout))
+	defer cancel()
+	for _, service := range services {
+		state := before[service]
+		output("stdout", fmt.Sprintf("Restoring %s to image %s", state.image, state.imageID))
+		if err := s.dockerClient.TagImage(rollbackCtx, state.imageID, state.image); err != nil {
+			return changed, fmt.Errorf("%v; rollback failed: %w", upErr, err)
+		}
+	}
+	// Pulling would replace the restored tags with the images being rolled
+	// back from
+	if err := s.composeManager.UpServicesLocal(rollbackCtx, stackPath, services, output); err != nil {
+		return changed, fmt.Errorf("%v; rollback failed: %w", upErr, err)
+	}
+	if err := s.waitHealthy(rollbackCtx, p.Stack, services, time.Duration(p.HealthTimeout), output); err != nil {
+		return changed, fmt.Errorf("%v; rolled back services are not healthy either: %w", upErr, err)
+	}
+	return changed, fmt.Errorf("%w: %v", errRolledBack, upErr)
+}
+
+// serviceStates records the image reference and image ID each service of a
+// stack runs
+func (s *Scheduler) serviceStates(ctx context.Context, stackName string) (map[string]serviceState, error) {
+	containers, err := s.dockerClient.ListContainers(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]serviceState)
+	for _, ctr := range containers {
+		service := ctr.Labels["com.docker.compose.service"]
+		if ctr.Labels["com.docker.compose.project"] != stackName || service == "" {
+			continue
+		}
+		image := ctr.Image
+		if strings.HasPrefix(image, "sha256:") {
+			// The tag already moved; the container's config has its name
+			info, err := s.dockerClient.GetContainer(ctx, ctr.ID)
+			if err != nil {
+				return nil, err
+			}
+			image = info.Image
+		}
+		if ref, err := registry.ParseReference(image); err != nil || ref.Digest != "" {
+			// Pinned images never change on pull
+			continue
+		}
+		states[service] = serviceState{image: image, imageID: ctr.ImageID}
+	}
+	return states, nil
+}
+
+// waitHealthy waits for the containers of services to be running and, if
+// they have a healthcheck, healthy. Containers without one must stay up for
+// a few seconds, unless they exit cleanly.
+func (s *Scheduler) waitHealthy(ctx context.Context, stackName string, services []string, timeout time.Duration, output func(stream string, line string)) error {
+	output("stdout", fmt.Sprintf("Waiting up to %s for %s to become healthy", timeout, strings.Join(services, ", ")))
+
+	wanted := make(map[string]bool, len(services))
+	for _, service := range services {
+		wanted[service] = true
+	}
+	settle := min(settleTime, timeout/2)
+	runningSince := make(map[string]time.Time)
+	deadline := time.Now().Add(timeout)
+
+	ticker := time.NewTicker(healthPoll)
+	defer ticker.Stop()
+	for {
+		containers, err := s.dockerClient.ListContainers(ctx, true)
+		if err != nil {
+			return err
+		}
+
+		waiting := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, ctr := range containers {
+			service := ctr.Labels["com.docker.compose.service"]
+			if ctr.Labels["com.docker.compose.project"] != stackName || !wanted[service] {
+				continue
+			}
+			seen[service] = true
+
+			switch {
+			case ctr.State == "exited":
+				// One-shot services are done once they exit cleanly
+				info, err := s.dockerClient.GetContainer(ctx, ctr.ID)
+				if err != nil {
+					return err
+				}
+				if info.ExitCode != 0 {
+					return fmt.Errorf("%s exited with code %d", service, info.ExitCode)
+				}
+			case ctr.State == "dead":
+				return fmt.Errorf("%s %s: %s", service, ctr.State, ctr.Status)
+			case strings.Contains(ctr.Status, "(unhealthy)"):
+				return fmt.Errorf("%s is unhealthy", service)
+			case ctr.State != "running" || strings.Contains(ctr.Status, "(health: starting)"):
+				delete(runningSince, ctr.ID)
+				waiting = append(waiting, fmt.Sprintf("%s (%s)", service, ctr.Status))
+			case strings.Contains(ctr.Status, "(healthy)"):
+			default:
+				since, ok := runningSince[ctr.ID]
+				if !ok {
+					since = time.Now()
+					runningSince[ctr.ID] = since
+				}
+				if time.Since(since) < settle {
+					waiting = append(waiting, service+" (starting)")
+				}
+			}
+		}
+		for _, service := range services {
+			if !seen[service] {
+				waiting = append(waiting, service+" (no container)")
+			}
+		}
+
+		if len(waiting) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not healthy after %s: %s", timeout, strings.Join(waiting, ", "))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) setPending(stackName string, found []ServiceUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(found) == 0 {
+		delete(s.pending, stackName)
+	} else {
+		s.pending[stackName] = found
+	}
+}
+
+// notify sends a message about a stack's updates to the policy's channels
+func (s *Scheduler) notify(p Policy, state string, title string, message string) {
+	now := time.Now().UTC()
+	n := alert.Notification{
+		Title:   title,
+		Message: message,
+		Alert: alert.Alert{
+			ID:       "update-" + p.Stack,
+			RuleName: "Stack updates",
+			State:    state,
+			Message:  message,
+			Stack:    p.Stack,
+			Since:    now,
+			FiredAt:  &now,
+		},
+	}
+	if state == alert.StateResolved {
+		n.Alert.ResolvedAt = &now
+	}
+
+	for _, id := range p.Channels {
+		ch, err := s.alerts.GetChannel(id)
+		if err != nil {
+			log.Printf("Update policy for %s: notification channel %s: %v", p.Stack, id, err)
+			continue
+		}
+		if err := alert.Send(*ch, n); err != nil {
+			log.Printf("Error sending update notification for %s to %s: %v", p.Stack, ch.Name, err)
+		}
+	}
+}
+
+// repoDigest returns the digest of image among RepoDigests entries for the
+// repository of ref
+func repoDigest(digests []string, ref string) string {
+	want, err := registry.ParseReference(ref)
+	if err != nil {
+		return ""
+	}
+	for _, d := range digests {
+		if r, err := registry.ParseReference(d); err == nil && r.Name == want.Name {
+			return r.Digest
+		}
+	}
+	return ""
+}
+
+func describeUpdates(found []ServiceUpdate) string {
+	parts := make([]string, len(found))
+	for i, u := range found {
+		parts[i] = fmt.Sprintf("%s (%s)", u.Service, u.Image)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,124 @@
+package autoupdate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aperture-science-network/internal/alert"
+	"aperture-science-network/internal/compose"
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/mock"
+)
+
+// pulledClient is the mock Docker client after a pull that moved the
+// celeste:latest tag to a new image
+type pulledClient struct {
+	*mock.DockerClient
+
+	mu     sync.Mutex
+	tagged []string
+}
+
+func (c *pulledClient) InspectImage(ctx context.Context, id string) (*docker.ImageDetails, error) {
+	if id == "celeste:latest" {
+		return &docker.ImageDetails{ImageInfo: docker.ImageInfo{ID: "sha256:fresh", Tags: []string{id}}}, nil
+	}
+	return c.DockerClient.InspectImage(ctx, id)
+}
+
+func (c *pulledClient) TagImage(ctx context.Context, source string, target string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.tagged = append(c.tagged, source+" "+target)
+	c.mu.Unlock()
+	return c.DockerClient.TagImage(ctx, source, target)
+}
+
+// fakeCompose puts a docker command on PATH that records its arguments
+// and succeeds, returning the file it records them to
+func fakeCompose(t *testing.T) string {
+	t.Helper()
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("/bin/sh not available")
+	}
+	bin := t.TempDir()
+	log := filepath.Join(bin, "calls.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	return log
+}
+
+func TestApplyRollsBackAfterCancel(t *testing.T) {
+	calls := fakeCompose(t)
+	stackPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(stackPath, "compose.yaml"), []byte("services: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &pulledClient{DockerClient: mock.NewDockerClient()}
+	s := &Scheduler{dockerClient: client, composeManager: compose.NewManager(nil)}
+	p := Policy{Stack: "celeste", HealthTimeout: alert.Duration(time.Second)}
+
+	// Cancel the job while it waits for the updated services, as a user
+	// or the job timeout would
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	var lines []string
+	output := func(stream string, line string) {
+		mu.Lock()
+		lines = append(lines, line)
+		mu.Unlock()
+		if strings.HasPrefix(line, "Waiting up to") {
+			cancel()
+		}
+	}
+
+	changed, err := s.apply(ctx, p, stackPath, output)
+	if !errors.Is(err, errRolledBack) {
+		t.Fatalf("apply() error = %v, want %v\noutput:\n%s", err, errRolledBack, strings.Join(lines, "\n"))
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Fatalf("job context was not canceled")
+	}
+
+	var services []string
+	for _, u := range changed {
+		services = append(services, u.Service)
+	}
+	if got := strings.Join(services, ","); got != "backend,frontend" {
+		t.Errorf("changed services = %s, want backend,frontend", got)
+	}
+
+	client.mu.Lock()
+	tagged := strings.Join(client.tagged, "\n")
+	client.mu.Unlock()
+	if want := "abc123def456 celeste:latest\nabc123def456 celeste:latest"; tagged != want {
+		t.Errorf("tagged = %q, want %q", tagged, want)
+	}
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	composeFile := filepath.Join(stackPath, "compose.yaml")
+	want := []string{
+		"compose -f " + composeFile + " pull",
+		"compose -f " + composeFile + " up -d --no-deps backend frontend",
+		"compose -f " + composeFile + " up -d --no-deps --pull never backend frontend",
+	}
+	if got := strings.TrimSpace(string(data)); got != strings.Join(want, "\n") {
+		t.Errorf("compose calls:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
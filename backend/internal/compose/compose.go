@@ -3,15 +3,23 @@ package compose
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"aperture-science-network/internal/registry"
 )
 
 // Manager handles docker-compose operations via CLI
 type Manager struct {
-	dockerCmd string
+	dockerCmd   string
+	credentials registry.CredentialStore
 }
 
 // ServiceStatus represents the status of a compose service
@@ -21,79 +29,224 @@ type ServiceStatus struct {
 	Health string `json:"health,omitempty"`
 }
 
-// NewManager creates a new compose manager, detecting the appropriate CLI command
-func NewManager() *Manager {
+// NewManager creates a new compose manager, detecting the appropriate CLI
+// command. Stored registry credentials, if any, are used by the commands
+// that may pull images.
+func NewManager(credentials registry.CredentialStore) *Manager {
 	// Check if 'container' CLI is available (macOS)
 	if _, err := exec.LookPath("container"); err == nil {
-		return &Manager{dockerCmd: "container"}
+		return &Manager{dockerCmd: "container", credentials: credentials}
 	}
-	return &Manager{dockerCmd: "docker"}
+	return &Manager{dockerCmd: "docker", credentials: credentials}
 }
 
+// OutputFunc receives each line a compose command writes, tagged with the
+// stream ("stdout" or "stderr") it came from
+type OutputFunc func(stream string, line string)
+
 // Up starts all services in a compose stack
-func (m *Manager) Up(ctx context.Context, stackPath string) error {
-	composeFile := filepath.Join(stackPath, "docker-compose.yml")
-	cmd := exec.CommandContext(ctx, m.dockerCmd, "compose", "-f", composeFile, "up", "-d")
-	cmd.Dir = stackPath
+func (m *Manager) Up(ctx context.Context, stackPath string, output OutputFunc) error {
+	if err := m.stream(ctx, stackPath, output, "up", "-d"); err != nil {
+		return fmt.Errorf("compose up failed: %w", err)
+	}
+	return nil
+}
 
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+// UpServices recreates the given services of a compose stack if their
+// configuration or image changed, leaving their dependencies alone
+func (m *Manager) UpServices(ctx context.Context, stackPath string, services []string, output OutputFunc) error {
+	args := append([]string{"up", "-d", "--no-deps"}, services...)
+	if err := m.stream(ctx, stackPath, output, args...); err != nil {
+		return fmt.Errorf("compose up failed: %w", err)
+	}
+	return nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("compose up failed: %s: %w", stderr.String(), err)
+// UpServicesLocal is UpServices without pulling, so services are recreated
+// from the images already present
+func (m *Manager) UpServicesLocal(ctx context.Context, stackPath string, services []string, output OutputFunc) error {
+	args := append([]string{"up", "-d", "--no-deps", "--pull", "never"}, services...)
+	if err := m.stream(ctx, stackPath, output, args...); err != nil {
+		return fmt.Errorf("compose up failed: %w", err)
 	}
 	return nil
 }
 
 // Down stops and removes all services in a compose stack
-func (m *Manager) Down(ctx context.Context, stackPath string) error {
-	composeFile := filepath.Join(stackPath, "docker-compose.yml")
-	cmd := exec.CommandContext(ctx, m.dockerCmd, "compose", "-f", composeFile, "down")
-	cmd.Dir = stackPath
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+func (m *Manager) Down(ctx context.Context, stackPath string, output OutputFunc) error {
+	if err := m.stream(ctx, stackPath, output, "down"); err != nil {
+		return fmt.Errorf("compose down failed: %w", err)
+	}
+	return nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("compose down failed: %s: %w", stderr.String(), err)
+// DownVolumes stops and removes all services in a compose stack together
+// with its named volumes and any orphaned containers
+func (m *Manager) DownVolumes(ctx context.Context, stackPath string, output OutputFunc) error {
+	if err := m.stream(ctx, stackPath, output, "down", "-v", "--remove-orphans"); err != nil {
+		return fmt.Errorf("compose down failed: %w", err)
 	}
 	return nil
 }
 
 // Restart restarts all services in a compose stack
-func (m *Manager) Restart(ctx context.Context, stackPath string) error {
-	composeFile := filepath.Join(stackPath, "docker-compose.yml")
-	cmd := exec.CommandContext(ctx, m.dockerCmd, "compose", "-f", composeFile, "restart")
+func (m *Manager) Restart(ctx context.Context, stackPath string, output OutputFunc) error {
+	if err := m.stream(ctx, stackPath, output, "restart"); err != nil {
+		return fmt.Errorf("compose restart failed: %w", err)
+	}
+	return nil
+}
+
+// Pull pulls the latest images for all services in a compose stack
+func (m *Manager) Pull(ctx context.Context, stackPath string, output OutputFunc) error {
+	if err := m.stream(ctx, stackPath, output, "pull"); err != nil {
+		return fmt.Errorf("compose pull failed: %w", err)
+	}
+	return nil
+}
+
+// composeArgs prefixes a compose subcommand with a -f flag for each of the
+// stack's resolved compose files
+func composeArgs(stackPath string, args ...string) ([]string, error) {
+	files, err := FindFiles(stackPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{"compose"}
+	for _, file := range files {
+		result = append(result, "-f", file)
+	}
+	return append(result, args...), nil
+}
+
+// stream runs a compose subcommand, passing each output line to output as it
+// is produced. On failure the returned error includes the last lines of
+// stderr.
+func (m *Manager) stream(ctx context.Context, stackPath string, output OutputFunc, args ...string) error {
+	args, err := composeArgs(stackPath, args...)
+	if err != nil {
+		return err
+	}
+
+	configDir, err := m.dockerConfigDir()
+	if err != nil {
+		return fmt.Errorf("cannot prepare registry credentials: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, m.dockerCmd, args...)
 	cmd.Dir = stackPath
+	if configDir != "" {
+		defer os.RemoveAll(configDir)
+		cmd.Env = append(os.Environ(), "DOCKER_CONFIG="+configDir)
+	}
+	// Interrupt rather than kill on cancellation so compose can clean up,
+	// falling back to a kill if it does not exit in time
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = 10 * time.Second
 
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	tail := &tailBuffer{max: 20}
+	stdout := newLineWriter(func(line string) {
+		if output != nil {
+			output("stdout", line)
+		}
+	})
+	stderr := newLineWriter(func(line string) {
+		tail.add(line)
+		if output != nil {
+			output("stderr", line)
+		}
+	})
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("compose restart failed: %s: %w", stderr.String(), err)
+	err = cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", tail.String(), err)
 	}
 	return nil
 }
 
-// Pull pulls the latest images for all services in a compose stack
-func (m *Manager) Pull(ctx context.Context, stackPath string) error {
-	composeFile := filepath.Join(stackPath, "docker-compose.yml")
-	cmd := exec.CommandContext(ctx, m.dockerCmd, "compose", "-f", composeFile, "pull")
+// Config checks content with `docker compose config` as if it replaced the
+// stack's main compose file, merging any override files and the stack's
+// .env as the CLI would. The file on disk is not touched. Problems reported
+// by the CLI are returned as issues; the error is only set if the CLI could
+// not be run.
+func (m *Manager) Config(ctx context.Context, stackPath string, content string) ([]Issue, error) {
+	tmp, err := os.CreateTemp("", "compose-*.yml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	mainName := "compose file"
+	args := []string{"compose", "--project-directory", stackPath, "-f", tmp.Name()}
+	if files, err := FindFiles(stackPath); err == nil {
+		mainName = filepath.Base(files[0])
+		for _, file := range files[1:] {
+			args = append(args, "-f", file)
+		}
+	}
+	args = append(args, "config", "--quiet")
+
+	cmd := exec.CommandContext(ctx, m.dockerCmd, args...)
 	cmd.Dir = stackPath
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("compose pull failed: %s: %w", stderr.String(), err)
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("compose config failed: %w", err)
 	}
-	return nil
+
+	issues := make([]Issue, 0)
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		line = strings.TrimSpace(strings.ReplaceAll(line, tmp.Name(), mainName))
+		if line == "" {
+			continue
+		}
+
+		// Anything printed by a successful run is a warning
+		issue := Issue{Message: line, Severity: SeverityError}
+		if err == nil || strings.Contains(strings.ToLower(line), "level=warning") || strings.HasPrefix(strings.ToUpper(line), "WARN") {
+			issue.Severity = SeverityWarning
+		}
+		if match := cliPositionPattern.FindStringSubmatch(line); match != nil {
+			issue.Line, _ = strconv.Atoi(match[1])
+			if match[2] != "" {
+				issue.Column, _ = strconv.Atoi(match[2])
+			}
+		}
+		issues = append(issues, issue)
+	}
+	if err != nil && !HasErrors(issues) {
+		issues = append(issues, Issue{Message: err.Error(), Severity: SeverityError})
+	}
+	return issues, nil
 }
 
+// cliPositionPattern extracts positions from YAML errors printed by the CLI
+var cliPositionPattern = regexp.MustCompile(`line (\d+)(?:,? column (\d+))?`)
+
 // Logs retrieves logs for a specific service or all services
 func (m *Manager) Logs(ctx context.Context, stackPath string, service string, tail int) (string, error) {
-	composeFile := filepath.Join(stackPath, "docker-compose.yml")
-	args := []string{"compose", "-f", composeFile, "logs", "--no-color", fmt.Sprintf("--tail=%d", tail)}
+	args, err := composeArgs(stackPath, "logs", "--no-color", fmt.Sprintf("--tail=%d", tail))
+	if err != nil {
+		return "", fmt.Errorf("compose logs failed: %w", err)
+	}
 	if service != "" {
 		args = append(args, service)
 	}
@@ -113,8 +266,12 @@ func (m *Manager) Logs(ctx context.Context, stackPath string, service string, ta
 
 // PS returns the status of services in a compose stack
 func (m *Manager) PS(ctx context.Context, stackPath string) ([]ServiceStatus, error) {
-	composeFile := filepath.Join(stackPath, "docker-compose.yml")
-	cmd := exec.CommandContext(ctx, m.dockerCmd, "compose", "-f", composeFile, "ps", "--format", "{{.Service}}|{{.State}}|{{.Health}}")
+	args, err := composeArgs(stackPath, "ps", "--format", "{{.Service}}|{{.State}}|{{.Health}}")
+	if err != nil {
+		return nil, fmt.Errorf("compose ps failed: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, m.dockerCmd, args...)
 	cmd.Dir = stackPath
 
 	var stdout, stderr bytes.Buffer
@@ -0,0 +1,96 @@
+package compose
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"aperture-science-network/internal/registry"
+)
+
+// dockerConfigDir writes a Docker CLI configuration holding the stored
+// registry credentials to a new temporary directory, for one compose
+// invocation to use as DOCKER_CONFIG. Other settings of the usual
+// configuration are carried over. It returns "" if no credentials are
+// stored; the caller removes the directory otherwise.
+func (m *Manager) dockerConfigDir() (string, error) {
+	if m.credentials == nil {
+		return "", nil
+	}
+	creds, err := m.credentials.All()
+	if err != nil || len(creds) == 0 {
+		return "", err
+	}
+
+	config := make(map[string]json.RawMessage)
+	if data, err := os.ReadFile(filepath.Join(userDockerConfigDir(), "config.json")); err == nil {
+		// An unreadable configuration is replaced rather than fatal
+		if json.Unmarshal(data, &config) != nil {
+			config = make(map[string]json.RawMessage)
+		}
+	}
+
+	auths := make(map[string]json.RawMessage)
+	if raw, ok := config["auths"]; ok {
+		json.Unmarshal(raw, &auths)
+	}
+	helpers := make(map[string]json.RawMessage)
+	if raw, ok := config["credHelpers"]; ok {
+		json.Unmarshal(raw, &helpers)
+	}
+	for _, cred := range creds {
+		address := registry.ServerAddress(cred.Registry)
+		entry, err := json.Marshal(map[string]string{
+			"auth": base64.StdEncoding.EncodeToString([]byte(cred.Username + ":" + cred.Password)),
+		})
+		if err != nil {
+			return "", err
+		}
+		auths[address] = entry
+		// Helpers take precedence over auths, so drop those that would
+		// shadow a stored credential
+		delete(helpers, address)
+		delete(helpers, cred.Registry)
+	}
+	// A global credential store would also shadow them
+	delete(config, "credsStore")
+
+	if config["auths"], err = json.Marshal(auths); err != nil {
+		return "", err
+	}
+	if len(helpers) > 0 {
+		if config["credHelpers"], err = json.Marshal(helpers); err != nil {
+			return "", err
+		}
+	} else {
+		delete(config, "credHelpers")
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "docker-config-")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), data, 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// userDockerConfigDir returns the directory the Docker CLI reads its
+// configuration from by default
+func userDockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker")
+}
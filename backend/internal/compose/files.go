@@ -0,0 +1,147 @@
+package compose
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNoComposeFile  = errors.New("no compose file found")
+	ErrOutsideProject = errors.New("compose file is outside the project directory")
+)
+
+// DefaultFileNames are the compose file names looked up in a project
+// directory, in the order the docker compose CLI prefers them
+var DefaultFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml"}
+
+// DefaultOverrideFileNames are the override file names applied on top of a
+// default compose file, in the order the docker compose CLI prefers them
+var DefaultOverrideFileNames = []string{"compose.override.yml", "compose.override.yaml", "docker-compose.override.yml", "docker-compose.override.yaml"}
+
+// FindFiles resolves the compose files of the project in dir the way the
+// docker compose CLI does: COMPOSE_FILE from the project's .env file if set,
+// otherwise the first default file name present plus the first override file
+// present. Paths are absolute; the first entry is the main compose file.
+// COMPOSE_FILE entries must stay inside dir, since the files are read and
+// written through the API.
+func FindFiles(dir string) ([]string, error) {
+	env, err := ReadEnvFile(filepath.Join(dir, ".env"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if value := env["COMPOSE_FILE"]; value != "" {
+		separator := env["COMPOSE_PATH_SEPARATOR"]
+		if separator == "" {
+			separator = string(os.PathListSeparator)
+		}
+
+		files := make([]string, 0)
+		for _, file := range strings.Split(value, separator) {
+			file = strings.TrimSpace(file)
+			if file == "" {
+				continue
+			}
+			if filepath.IsAbs(file) {
+				return nil, fmt.Errorf("%w: %s", ErrOutsideProject, file)
+			}
+			path := filepath.Join(dir, file)
+			if _, err := os.Stat(path); err != nil {
+				return nil, err
+			}
+			if !insideDir(dir, path) {
+				return nil, fmt.Errorf("%w: %s", ErrOutsideProject, file)
+			}
+			files = append(files, path)
+		}
+		if len(files) == 0 {
+			return nil, ErrNoComposeFile
+		}
+		return files, nil
+	}
+
+	main := findFirst(dir, DefaultFileNames)
+	if main == "" {
+		return nil, ErrNoComposeFile
+	}
+	files := []string{main}
+	if override := findFirst(dir, DefaultOverrideFileNames); override != "" {
+		files = append(files, override)
+	}
+	return files, nil
+}
+
+// MainFile returns the path of the main compose file of the project in dir
+func MainFile(dir string) (string, error) {
+	files, err := FindFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	return files[0], nil
+}
+
+// ReadEnvFile parses a compose .env file of KEY=VALUE lines. Blank lines,
+// comments and an "export " prefix are ignored, and quoted values are
+// unwrapped.
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+			if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+				value = value[1 : end+1]
+			}
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			// Inline comments are only recognized on unquoted values
+			value = strings.TrimSpace(value[:i])
+		}
+		env[key] = value
+	}
+	return env, scanner.Err()
+}
+
+// insideDir reports whether path, with symlinks resolved, is below dir
+func insideDir(dir string, path string) bool {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(realDir, realPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func findFirst(dir string, names []string) string {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package compose
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:  "preferred default name",
+			files: map[string]string{"compose.yaml": "", "docker-compose.yml": ""},
+			want:  []string{"compose.yaml"},
+		},
+		{
+			name:  "override applied",
+			files: map[string]string{"docker-compose.yml": "", "compose.override.yml": ""},
+			want:  []string{"docker-compose.yml", "compose.override.yml"},
+		},
+		{
+			name:    "no compose file",
+			files:   map[string]string{"README.md": ""},
+			wantErr: ErrNoComposeFile,
+		},
+		{
+			name:  "COMPOSE_FILE list",
+			files: map[string]string{".env": "COMPOSE_FILE=base.yml:prod/extra.yml\n", "base.yml": "", "prod/extra.yml": "", "compose.yaml": ""},
+			want:  []string{"base.yml", "prod/extra.yml"},
+		},
+		{
+			name:  "COMPOSE_PATH_SEPARATOR",
+			files: map[string]string{".env": "COMPOSE_PATH_SEPARATOR=,\nCOMPOSE_FILE=\"a.yml, b.yml\"\n", "a.yml": "", "b.yml": ""},
+			want:  []string{"a.yml", "b.yml"},
+		},
+		{
+			name:    "COMPOSE_FILE absolute",
+			files:   map[string]string{".env": "COMPOSE_FILE=/etc/passwd\n"},
+			wantErr: ErrOutsideProject,
+		},
+		{
+			name:    "COMPOSE_FILE escapes the stack",
+			files:   map[string]string{".env": "COMPOSE_FILE=../outside.yml\n", "../outside.yml": ""},
+			wantErr: ErrOutsideProject,
+		},
+		{
+			name:    "COMPOSE_FILE missing",
+			files:   map[string]string{".env": "COMPOSE_FILE=missing.yml\n"},
+			wantErr: os.ErrNotExist,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "stack")
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			writeFiles(t, dir, tt.files)
+
+			got, err := FindFiles(dir)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FindFiles() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindFiles() error = %v", err)
+			}
+			want := make([]string, len(tt.want))
+			for i, name := range tt.want {
+				want[i] = filepath.Join(dir, name)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("FindFiles() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFindFilesSymlinkOutside(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "stack")
+	writeFiles(t, root, map[string]string{"outside.yml": "", "stack/.env": "COMPOSE_FILE=link.yml\n"})
+	if err := os.Symlink(filepath.Join(root, "outside.yml"), filepath.Join(dir, "link.yml")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	if _, err := FindFiles(dir); !errors.Is(err, ErrOutsideProject) {
+		t.Fatalf("FindFiles() error = %v, want %v", err, ErrOutsideProject)
+	}
+}
+
+func TestReadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `# comment
+export A=1
+B = two
+C="quoted # not a comment"
+D='single'
+E=value # trailing comment
+
+invalid line
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadEnvFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"A": "1", "B": "two", "C": "quoted # not a comment", "D": "single", "E": "value"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadEnvFile() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,510 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// Severity distinguishes problems that make a compose file unusable from
+// those the CLI merely warns about
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found in a compose file. Line and Column are 1-based
+// and zero when the position is unknown.
+type Issue struct {
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Path     string   `json:"path,omitempty"`
+	Message  string   `json:"message"`
+	Severity Severity `json:"severity"`
+}
+
+// HasErrors reports whether any issue has error severity
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	serviceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+	topLevelKeys = keySet("version", "name", "include", "services", "networks", "volumes", "secrets", "configs", "models")
+
+	serviceKeys = keySet(
+		"annotations", "attach", "blkio_config", "build", "cap_add", "cap_drop", "cgroup", "cgroup_parent",
+		"command", "configs", "container_name", "cpu_count", "cpu_percent", "cpu_period", "cpu_quota",
+		"cpu_rt_period", "cpu_rt_runtime", "cpu_shares", "cpus", "cpuset", "credential_spec", "depends_on",
+		"deploy", "develop", "device_cgroup_rules", "devices", "dns", "dns_opt", "dns_search", "domainname",
+		"entrypoint", "env_file", "environment", "expose", "extends", "external_links", "extra_hosts", "gpus",
+		"group_add", "healthcheck", "hostname", "image", "init", "ipc", "isolation", "label_file", "labels",
+		"links", "logging", "mac_address", "mem_limit", "mem_reservation", "mem_swappiness", "memswap_limit",
+		"models", "network_mode", "networks", "oom_kill_disable", "oom_score_adj", "pid", "pids_limit",
+		"platform", "ports", "post_start", "pre_stop", "privileged", "profiles", "provider", "pull_policy",
+		"read_only", "restart", "runtime", "scale", "secrets", "security_opt", "shm_size", "stdin_open",
+		"stop_grace_period", "stop_signal", "storage_opt", "sysctls", "tmpfs", "tty", "ulimits",
+		"use_api_socket", "user", "userns_mode", "uts", "volumes", "volumes_from", "working_dir",
+	)
+)
+
+func keySet(keys ...string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return set
+}
+
+// Validate parses a compose document and checks it against the structural
+// rules of the Compose spec: known top-level and service keys, services that
+// define an image or build, and references to services, networks, volumes,
+// secrets and configs that exist. Values that use variable interpolation
+// are not resolved.
+//
+// A partial document is one merged with override files by the CLI; checks
+// that depend on the merged result are then reported as warnings only.
+func Validate(content string, partial bool) []Issue {
+	v := &validator{issues: make([]Issue, 0), partial: partial}
+
+	file, err := parser.ParseBytes([]byte(content), 0)
+	if err != nil {
+		v.addYAMLError(err)
+		return v.issues
+	}
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		v.add(nil, "", "compose file is empty")
+		return v.issues
+	}
+	if len(file.Docs) > 1 {
+		v.add(file.Docs[1].Body, "", "compose file must contain a single YAML document")
+	}
+
+	v.document(file.Docs[0].Body)
+	return v.issues
+}
+
+type validator struct {
+	issues  []Issue
+	partial bool
+}
+
+func (v *validator) add(node ast.Node, path string, format string, args ...interface{}) {
+	v.issues = append(v.issues, v.issue(node, path, SeverityError, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) warn(node ast.Node, path string, format string, args ...interface{}) {
+	v.issues = append(v.issues, v.issue(node, path, SeverityWarning, fmt.Sprintf(format, args...)))
+}
+
+// unresolved reports a problem that other compose files could resolve
+func (v *validator) unresolved(node ast.Node, path string, format string, args ...interface{}) {
+	if v.partial {
+		v.warn(node, path, format, args...)
+		return
+	}
+	v.add(node, path, format, args...)
+}
+
+func (v *validator) issue(node ast.Node, path string, severity Severity, message string) Issue {
+	issue := Issue{Path: path, Message: message, Severity: severity}
+	if node != nil {
+		if tk := node.GetToken(); tk != nil && tk.Position != nil {
+			issue.Line = tk.Position.Line
+			issue.Column = tk.Position.Column
+		}
+	}
+	return issue
+}
+
+func (v *validator) addYAMLError(err error) {
+	issue := Issue{Message: err.Error(), Severity: SeverityError}
+	var yamlErr yaml.Error
+	if errors.As(err, &yamlErr) {
+		issue.Message = yamlErr.GetMessage()
+		if tk := yamlErr.GetToken(); tk != nil && tk.Position != nil {
+			issue.Line = tk.Position.Line
+			issue.Column = tk.Position.Column
+		}
+	}
+	v.issues = append(v.issues, issue)
+}
+
+// entry is a mapping key with its value
+type entry struct {
+	key   string
+	node  *ast.MappingValueNode
+	value ast.Node
+}
+
+// mapping returns the entries of a mapping node, reporting duplicate keys.
+// The second result is false if node is not a mapping.
+func (v *validator) mapping(node ast.Node, path string) ([]entry, bool) {
+	var values []*ast.MappingValueNode
+	switch n := unwrap(node).(type) {
+	case *ast.MappingNode:
+		values = n.Values
+	case *ast.MappingValueNode:
+		// A mapping with a single key is represented by its only entry
+		values = []*ast.MappingValueNode{n}
+	default:
+		return nil, false
+	}
+
+	entries := make([]entry, 0, len(values))
+	seen := make(map[string]bool)
+	for _, mv := range values {
+		key := mv.Key.GetToken().Value
+		if seen[key] && key != "<<" {
+			v.add(mv.Key, join(path, key), "duplicate key %q", key)
+		}
+		seen[key] = true
+		entries = append(entries, entry{key: key, node: mv, value: mv.Value})
+	}
+	return entries, true
+}
+
+// sequence returns the items of a sequence node, or false if node is not one
+func sequence(node ast.Node) ([]ast.Node, bool) {
+	if seq, ok := unwrap(node).(*ast.SequenceNode); ok {
+		return seq.Values, true
+	}
+	return nil, false
+}
+
+// scalar returns the text of a scalar node, or false if node is not one
+func scalar(node ast.Node) (string, bool) {
+	switch n := unwrap(node).(type) {
+	case *ast.MappingNode, *ast.MappingValueNode, *ast.SequenceNode, nil:
+		return "", false
+	case *ast.NullNode:
+		return "", true
+	default:
+		return n.GetToken().Value, true
+	}
+}
+
+// unwrap strips anchors and tags from a node
+func unwrap(node ast.Node) ast.Node {
+	for {
+		switch n := node.(type) {
+		case *ast.AnchorNode:
+			node = n.Value
+		case *ast.TagNode:
+			node = n.Value
+		default:
+			return node
+		}
+	}
+}
+
+func isAlias(node ast.Node) bool {
+	_, ok := unwrap(node).(*ast.AliasNode)
+	return ok
+}
+
+func isNull(node ast.Node) bool {
+	_, ok := unwrap(node).(*ast.NullNode)
+	return node == nil || ok
+}
+
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// document holds the top-level definitions other sections refer to
+type document struct {
+	services map[string]bool
+	networks map[string]bool
+	volumes  map[string]bool
+	secrets  map[string]bool
+	configs  map[string]bool
+}
+
+func (v *validator) document(body ast.Node) {
+	entries, ok := v.mapping(body, "")
+	if !ok {
+		v.add(body, "", "top-level element must be a mapping")
+		return
+	}
+
+	doc := document{
+		services: make(map[string]bool),
+		networks: map[string]bool{"default": true},
+		volumes:  make(map[string]bool),
+		secrets:  make(map[string]bool),
+		configs:  make(map[string]bool),
+	}
+
+	sections := make(map[string]entry)
+	for _, e := range entries {
+		switch {
+		case strings.HasPrefix(e.key, "x-"):
+		case !topLevelKeys[e.key]:
+			v.add(e.node.Key, e.key, "additional property %q is not allowed", e.key)
+		case e.key == "version":
+			v.warn(e.node.Key, e.key, "the attribute %q is obsolete and will be ignored", e.key)
+		default:
+			sections[e.key] = e
+		}
+	}
+
+	for _, name := range []string{"networks", "volumes", "secrets", "configs"} {
+		e, ok := sections[name]
+		if !ok || isNull(e.value) {
+			continue
+		}
+		defs, ok := v.mapping(e.value, name)
+		if !ok {
+			v.add(e.value, name, "%s must be a mapping", name)
+			continue
+		}
+		target := map[string]map[string]bool{
+			"networks": doc.networks, "volumes": doc.volumes, "secrets": doc.secrets, "configs": doc.configs,
+		}[name]
+		for _, def := range defs {
+			target[def.key] = true
+			if !isNull(def.value) && !isAlias(def.value) {
+				if _, ok := v.mapping(def.value, join(name, def.key)); !ok {
+					v.add(def.value, join(name, def.key), "%s definition must be a mapping", strings.TrimSuffix(name, "s"))
+				}
+			}
+		}
+	}
+
+	services, ok := sections["services"]
+	if !ok {
+		if _, hasInclude := sections["include"]; !hasInclude {
+			v.add(body, "", "services must be defined")
+		}
+		return
+	}
+	if isNull(services.value) {
+		return
+	}
+	defs, ok := v.mapping(services.value, "services")
+	if !ok {
+		v.add(services.value, "services", "services must be a mapping")
+		return
+	}
+	for _, def := range defs {
+		doc.services[def.key] = true
+	}
+	for _, def := range defs {
+		v.service(def, doc)
+	}
+}
+
+func (v *validator) service(def entry, doc document) {
+	path := join("services", def.key)
+	if !serviceNamePattern.MatchString(def.key) {
+		v.add(def.node.Key, path, "service name %q must contain only letters, digits, '.', '_' or '-'", def.key)
+	}
+	if isAlias(def.value) {
+		return
+	}
+
+	entries, ok := v.mapping(def.value, path)
+	if !ok {
+		v.add(def.value, path, "service %q must be a mapping", def.key)
+		return
+	}
+
+	keys := make(map[string]entry)
+	merged := false
+	for _, e := range entries {
+		switch {
+		case e.key == "<<":
+			merged = true
+		case strings.HasPrefix(e.key, "x-"):
+		case !serviceKeys[e.key]:
+			v.add(e.node.Key, join(path, e.key), "additional property %q is not allowed", e.key)
+		default:
+			keys[e.key] = e
+		}
+	}
+
+	_, hasImage := keys["image"]
+	_, hasBuild := keys["build"]
+	_, hasExtends := keys["extends"]
+	_, hasProvider := keys["provider"]
+	if !hasImage && !hasBuild && !hasExtends && !hasProvider && !merged {
+		v.unresolved(def.node.Key, path, "service %q has neither an image nor a build context specified", def.key)
+	}
+
+	if e, ok := keys["depends_on"]; ok {
+		v.references(e, path, doc.services, "service")
+		if deps := v.names(e); deps[def.key] != nil {
+			v.add(deps[def.key], join(path, "depends_on"), "service %q depends on itself", def.key)
+		}
+	}
+	if e, ok := keys["networks"]; ok {
+		if _, hasMode := keys["network_mode"]; hasMode {
+			v.add(e.node.Key, join(path, "networks"), "networks and network_mode cannot be combined")
+		}
+		v.references(e, path, doc.networks, "network")
+	}
+	if e, ok := keys["secrets"]; ok {
+		v.mountReferences(e, path, doc.secrets, "secret")
+	}
+	if e, ok := keys["configs"]; ok {
+		v.mountReferences(e, path, doc.configs, "config")
+	}
+	if e, ok := keys["volumes"]; ok {
+		v.volumes(e, path, doc.volumes)
+	}
+
+	for _, name := range []string{"ports", "expose", "cap_add", "cap_drop", "devices", "profiles"} {
+		if e, ok := keys[name]; ok && !isNull(e.value) && !isAlias(e.value) {
+			if _, ok := sequence(e.value); !ok {
+				v.add(e.value, join(path, name), "%s must be a list", name)
+			}
+		}
+	}
+	for _, name := range []string{"environment", "labels", "extra_hosts", "sysctls"} {
+		if e, ok := keys[name]; ok && !isNull(e.value) && !isAlias(e.value) {
+			if _, isSeq := sequence(e.value); !isSeq {
+				if _, isMap := v.mapping(e.value, join(path, name)); !isMap {
+					v.add(e.value, join(path, name), "%s must be a list or a mapping", name)
+				}
+			}
+		}
+	}
+}
+
+// names collects the names listed by a field that accepts either a list of
+// names or a mapping keyed by name, such as depends_on or networks
+func (v *validator) names(e entry) map[string]ast.Node {
+	names := make(map[string]ast.Node)
+	if items, ok := sequence(e.value); ok {
+		for _, item := range items {
+			if name, ok := scalar(item); ok {
+				names[name] = item
+			}
+		}
+		return names
+	}
+	switch n := unwrap(e.value).(type) {
+	case *ast.MappingNode:
+		for _, mv := range n.Values {
+			names[mv.Key.GetToken().Value] = mv.Key
+		}
+	case *ast.MappingValueNode:
+		names[n.Key.GetToken().Value] = n.Key
+	}
+	return names
+}
+
+// references checks that every name listed by a service field is defined
+func (v *validator) references(e entry, path string, defined map[string]bool, kind string) {
+	fieldPath := join(path, e.key)
+	if isNull(e.value) || isAlias(e.value) {
+		return
+	}
+	if _, isSeq := sequence(e.value); !isSeq {
+		if _, isMap := v.mapping(e.value, fieldPath); !isMap {
+			v.add(e.value, fieldPath, "%s must be a list or a mapping", e.key)
+			return
+		}
+	}
+	for name, node := range v.names(e) {
+		if strings.Contains(name, "$") {
+			continue
+		}
+		if !defined[name] {
+			v.unresolved(node, fieldPath, "%s %q is not defined", kind, name)
+		}
+	}
+}
+
+// mountReferences checks secrets and configs, which are listed either by
+// name or as mappings with a source
+func (v *validator) mountReferences(e entry, path string, defined map[string]bool, kind string) {
+	fieldPath := join(path, e.key)
+	items, ok := sequence(e.value)
+	if !ok {
+		if !isNull(e.value) && !isAlias(e.value) {
+			v.add(e.value, fieldPath, "%s must be a list", e.key)
+		}
+		return
+	}
+	for _, item := range items {
+		name, ok := scalar(item)
+		node := item
+		if !ok {
+			entries, isMap := v.mapping(item, fieldPath)
+			if !isMap {
+				continue
+			}
+			for _, field := range entries {
+				if field.key == "source" {
+					name, _ = scalar(field.value)
+					node = field.value
+				}
+			}
+		}
+		if name != "" && !strings.Contains(name, "$") && !defined[name] {
+			v.unresolved(node, fieldPath, "%s %q is not defined", kind, name)
+		}
+	}
+}
+
+// volumes checks that named volumes mounted by a service are declared at
+// the top level. Bind mounts are recognized by their path-like source.
+func (v *validator) volumes(e entry, path string, defined map[string]bool) {
+	fieldPath := join(path, e.key)
+	items, ok := sequence(e.value)
+	if !ok {
+		if !isNull(e.value) && !isAlias(e.value) {
+			v.add(e.value, fieldPath, "volumes must be a list")
+		}
+		return
+	}
+
+	for _, item := range items {
+		source, node, named := "", item, false
+		if spec, ok := scalar(item); ok {
+			parts := strings.Split(spec, ":")
+			if len(parts) > 1 {
+				source = parts[0]
+				named = true
+			}
+		} else if entries, ok := v.mapping(item, fieldPath); ok {
+			volumeType := ""
+			for _, field := range entries {
+				switch field.key {
+				case "type":
+					volumeType, _ = scalar(field.value)
+				case "source":
+					source, _ = scalar(field.value)
+					node = field.value
+				}
+			}
+			named = volumeType == "volume"
+		}
+
+		if !named || source == "" || strings.ContainsAny(source, "/\\$") ||
+			strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~") {
+			continue
+		}
+		if !defined[source] {
+			v.unresolved(node, fieldPath, "named volume %q is not declared in the top-level volumes section", source)
+		}
+	}
+}
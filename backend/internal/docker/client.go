@@ -2,14 +2,10 @@ package docker
 
 import (
 	"context"
-	"encoding/json"
-	"io"
 	"strings"
 	"time"
 
-	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
@@ -20,15 +16,24 @@ type Client struct {
 }
 
 type ContainerInfo struct {
-	ID           string            `json:"id"`
-	Name         string            `json:"name"`
-	Image        string            `json:"image"`
-	Status       string            `json:"status"`
-	State        string            `json:"state"`
-	Created      int64             `json:"created"`
-	Ports        []PortBinding     `json:"ports"`
-	Labels       map[string]string `json:"labels"`
-	NetworkMode  string            `json:"networkMode"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Image       string            `json:"image"`
+	ImageID     string            `json:"imageId"`
+	Status      string            `json:"status"`
+	State       string            `json:"state"`
+	Created     int64             `json:"created"`
+	Ports       []PortBinding     `json:"ports"`
+	Labels      map[string]string `json:"labels"`
+	NetworkMode string            `json:"networkMode"`
+
+	// ExitCode is the last exit code of a stopped container. Only
+	// GetContainer reports it.
+	ExitCode int `json:"exitCode,omitempty"`
+
+	// UpdateAvailable is set by the API when a newer image was found in
+	// the registry for the container's tag
+	UpdateAvailable bool `json:"updateAvailable"`
 }
 
 type PortBinding struct {
@@ -37,24 +42,13 @@ type PortBinding struct {
 	Type    string `json:"type"`
 }
 
-type ContainerStats struct {
-	CPUPercent    float64 `json:"cpuPercent"`
-	MemoryUsage   uint64  `json:"memoryUsage"`
-	MemoryLimit   uint64  `json:"memoryLimit"`
-	MemoryPercent float64 `json:"memoryPercent"`
-	NetworkRx     uint64  `json:"networkRx"`
-	NetworkTx     uint64  `json:"networkTx"`
-	BlockRead     uint64  `json:"blockRead"`
-	BlockWrite    uint64  `json:"blockWrite"`
-}
-
 type VolumeInfo struct {
-	Name       string   `json:"name"`
-	Driver     string   `json:"driver"`
-	Mountpoint string   `json:"mountpoint"`
-	CreatedAt  string   `json:"createdAt"`
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	Mountpoint string            `json:"mountpoint"`
+	CreatedAt  string            `json:"createdAt"`
 	Labels     map[string]string `json:"labels"`
-	UsedBy     []string `json:"usedBy"`
+	UsedBy     []string          `json:"usedBy"`
 }
 
 type NetworkInfo struct {
@@ -66,13 +60,6 @@ type NetworkInfo struct {
 	Containers []string `json:"containers"`
 }
 
-type ImageInfo struct {
-	ID      string   `json:"id"`
-	Tags    []string `json:"tags"`
-	Size    int64    `json:"size"`
-	Created int64    `json:"created"`
-}
-
 func NewClient() (*Client, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
@@ -107,6 +94,7 @@ func (c *Client) ListContainers(ctx context.Context, all bool) ([]ContainerInfo,
 			ID:          ctr.ID[:12],
 			Name:        name,
 			Image:       ctr.Image,
+			ImageID:     shortImageID(ctr.ImageID),
 			Status:      ctr.Status,
 			State:       ctr.State,
 			Created:     ctr.Created,
@@ -150,12 +138,14 @@ func (c *Client) GetContainer(ctx context.Context, id string) (*ContainerInfo, e
 		ID:          ctr.ID[:12],
 		Name:        strings.TrimPrefix(ctr.Name, "/"),
 		Image:       ctr.Config.Image,
+		ImageID:     shortImageID(ctr.Image),
 		Status:      ctr.State.Status,
 		State:       ctr.State.Status,
 		Created:     createdUnix,
 		Ports:       ports,
 		Labels:      ctr.Config.Labels,
 		NetworkMode: string(ctr.HostConfig.NetworkMode),
+		ExitCode:    ctr.State.ExitCode,
 	}, nil
 }
 
@@ -173,71 +163,6 @@ func (c *Client) RestartContainer(ctx context.Context, id string) error {
 	return c.cli.ContainerRestart(ctx, id, container.StopOptions{Timeout: &timeout})
 }
 
-func (c *Client) GetContainerLogs(ctx context.Context, id string, tail string) (string, error) {
-	options := container.LogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Tail:       tail,
-		Timestamps: true,
-	}
-
-	reader, err := c.cli.ContainerLogs(ctx, id, options)
-	if err != nil {
-		return "", err
-	}
-	defer reader.Close()
-
-	logs, err := io.ReadAll(reader)
-	if err != nil {
-		return "", err
-	}
-
-	return string(logs), nil
-}
-
-func (c *Client) GetContainerStats(ctx context.Context, id string) (*ContainerStats, error) {
-	stats, err := c.cli.ContainerStats(ctx, id, false)
-	if err != nil {
-		return nil, err
-	}
-	defer stats.Body.Close()
-
-	var v types.StatsJSON
-	if err := json.NewDecoder(stats.Body).Decode(&v); err != nil {
-		return nil, err
-	}
-
-	// Calculate CPU percentage
-	cpuDelta := float64(v.CPUStats.CPUUsage.TotalUsage - v.PreCPUStats.CPUUsage.TotalUsage)
-	systemDelta := float64(v.CPUStats.SystemUsage - v.PreCPUStats.SystemUsage)
-	cpuPercent := 0.0
-	if systemDelta > 0 && cpuDelta > 0 {
-		cpuPercent = (cpuDelta / systemDelta) * float64(len(v.CPUStats.CPUUsage.PercpuUsage)) * 100.0
-	}
-
-	// Calculate memory percentage
-	memPercent := 0.0
-	if v.MemoryStats.Limit > 0 {
-		memPercent = float64(v.MemoryStats.Usage) / float64(v.MemoryStats.Limit) * 100.0
-	}
-
-	// Calculate network I/O
-	var rxBytes, txBytes uint64
-	for _, net := range v.Networks {
-		rxBytes += net.RxBytes
-		txBytes += net.TxBytes
-	}
-
-	return &ContainerStats{
-		CPUPercent:    cpuPercent,
-		MemoryUsage:   v.MemoryStats.Usage,
-		MemoryLimit:   v.MemoryStats.Limit,
-		MemoryPercent: memPercent,
-		NetworkRx:     rxBytes,
-		NetworkTx:     txBytes,
-	}, nil
-}
-
 func (c *Client) ListVolumes(ctx context.Context) ([]VolumeInfo, error) {
 	volumes, err := c.cli.VolumeList(ctx, volume.ListOptions{})
 	if err != nil {
@@ -325,25 +250,6 @@ func (c *Client) DeleteNetwork(ctx context.Context, id string) error {
 	return c.cli.NetworkRemove(ctx, id)
 }
 
-func (c *Client) ListImages(ctx context.Context) ([]ImageInfo, error) {
-	images, err := c.cli.ImageList(ctx, image.ListOptions{})
-	if err != nil {
-		return nil, err
-	}
-
-	result := make([]ImageInfo, len(images))
-	for i, img := range images {
-		result[i] = ImageInfo{
-			ID:      img.ID[7:19], // Remove "sha256:" prefix and truncate
-			Tags:    img.RepoTags,
-			Size:    img.Size,
-			Created: img.Created,
-		}
-	}
-
-	return result, nil
-}
-
 func (c *Client) Close() error {
 	return c.cli.Close()
 }
@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Event types forwarded by Events
+const (
+	EventContainer = "container"
+	EventImage     = "image"
+	EventVolume    = "volume"
+	EventNetwork   = "network"
+)
+
+// ErrEventStreamClosed is returned when the daemon ends the event stream
+var ErrEventStreamClosed = errors.New("docker event stream closed")
+
+// Event is a state change reported by the Docker daemon
+type Event struct {
+	Type string `json:"type"`
+	// Action is the daemon's action, e.g. "start", "die", "oom" or
+	// "health_status: healthy"
+	Action     string            `json:"action"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name,omitempty"`
+	Stack      string            `json:"stack,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Time       time.Time         `json:"time"`
+}
+
+// Events streams container, image, volume and network events. If since is
+// set, events from that time on are replayed first. Events are delivered
+// until ctx is canceled or the stream fails; either way one error is sent
+// on the error channel, which is then closed.
+func (c *Client) Events(ctx context.Context, since time.Time) (<-chan Event, <-chan error) {
+	options := events.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("type", string(events.ImageEventType)),
+			filters.Arg("type", string(events.VolumeEventType)),
+			filters.Arg("type", string(events.NetworkEventType)),
+		),
+	}
+	if !since.IsZero() {
+		options.Since = unixTimestamp(since)
+	}
+
+	messages, errs := c.cli.Events(ctx, options)
+	out := make(chan Event)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(outErrs)
+		for {
+			select {
+			case msg := <-messages:
+				select {
+				case out <- convertEvent(msg):
+				case <-ctx.Done():
+					outErrs <- ctx.Err()
+					return
+				}
+			case err := <-errs:
+				if err == nil || errors.Is(err, io.EOF) {
+					err = ErrEventStreamClosed
+				}
+				outErrs <- err
+				return
+			}
+		}
+	}()
+
+	return out, outErrs
+}
+
+func convertEvent(msg events.Message) Event {
+	event := Event{
+		Type:       string(msg.Type),
+		Action:     string(msg.Action),
+		ID:         msg.Actor.ID,
+		Name:       msg.Actor.Attributes["name"],
+		Attributes: msg.Actor.Attributes,
+		Time:       time.Unix(0, msg.TimeNano).UTC(),
+	}
+
+	// Shorten IDs the same way the listing endpoints do so events can be
+	// matched against them
+	switch msg.Type {
+	case events.ContainerEventType, events.NetworkEventType:
+		if len(event.ID) > 12 {
+			event.ID = event.ID[:12]
+		}
+	case events.ImageEventType:
+		if id, ok := strings.CutPrefix(event.ID, "sha256:"); ok && len(id) > 12 {
+			event.ID = id[:12]
+		}
+	}
+
+	if msg.Type == events.ContainerEventType {
+		event.Stack = msg.Actor.Attributes["com.docker.compose.project"]
+	}
+	return event
+}
@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// ExecOptions describes a command to run inside a running container
+type ExecOptions struct {
+	Cmd        []string
+	User       string
+	WorkingDir string
+}
+
+// TerminalSize is the size of an exec session's TTY in characters
+type TerminalSize struct {
+	Rows uint `json:"rows"`
+	Cols uint `json:"cols"`
+}
+
+// CreateExec prepares an interactive command with a TTY in a container and
+// returns its exec ID. The command does not run until it is attached.
+func (c *Client) CreateExec(ctx context.Context, containerID string, opts ExecOptions) (string, error) {
+	resp, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		User:         opts.User,
+		WorkingDir:   opts.WorkingDir,
+		Cmd:          opts.Cmd,
+		Env:          []string{"TERM=xterm-256color"},
+		Tty:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// AttachExec starts an exec created by CreateExec. Reads return the
+// terminal's output and writes are sent to its input; closing the stream
+// hangs up the terminal.
+func (c *Client) AttachExec(ctx context.Context, execID string, size TerminalSize) (io.ReadWriteCloser, error) {
+	options := container.ExecAttachOptions{Tty: true}
+	if size.Rows > 0 && size.Cols > 0 {
+		options.ConsoleSize = &[2]uint{size.Rows, size.Cols}
+	}
+
+	resp, err := c.cli.ContainerExecAttach(ctx, execID, options)
+	if err != nil {
+		return nil, err
+	}
+	return &execStream{resp: resp}, nil
+}
+
+func (c *Client) ResizeExec(ctx context.Context, execID string, size TerminalSize) error {
+	return c.cli.ContainerExecResize(ctx, execID, container.ResizeOptions{
+		Height: size.Rows,
+		Width:  size.Cols,
+	})
+}
+
+// ExecExitCode returns the exit code of an exec whose output has ended. The
+// daemon can report the exec as running for a moment after its stream
+// closes, so it is polled briefly until it has exited.
+func (c *Client) ExecExitCode(ctx context.Context, execID string) (int, error) {
+	for attempt := 0; ; attempt++ {
+		info, err := c.cli.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return 0, err
+		}
+		if !info.Running || attempt >= 20 {
+			return info.ExitCode, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// execStream reads a TTY exec's raw output from the hijacked connection,
+// which is not multiplexed, and writes input to it
+type execStream struct {
+	resp types.HijackedResponse
+}
+
+func (s *execStream) Read(p []byte) (int, error) {
+	return s.resp.Reader.Read(p)
+}
+
+func (s *execStream) Write(p []byte) (int, error) {
+	return s.resp.Conn.Write(p)
+}
+
+func (s *execStream) Close() error {
+	s.resp.Close()
+	return nil
+}
@@ -0,0 +1,429 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+var (
+	ErrImageNotFound    = errors.New("no such image")
+	ErrImageConflict    = errors.New("image cannot be removed")
+	ErrInvalidReference = errors.New("invalid image reference")
+)
+
+type ImageInfo struct {
+	ID      string   `json:"id"`
+	Tags    []string `json:"tags"`
+	Digests []string `json:"digests"`
+	Size    int64    `json:"size"`
+	// SharedSize is the part of Size in layers shared with other images,
+	// or -1 if unknown
+	SharedSize int64 `json:"sharedSize"`
+	Created    int64 `json:"created"`
+	// Dangling images have no tag, usually because a newer image took it
+	Dangling bool `json:"dangling"`
+	// Containers are the containers, running or not, created from the
+	// image, and Stacks their compose projects
+	Containers []ImageContainer `json:"containers"`
+	Stacks     []string         `json:"stacks"`
+}
+
+// ImageContainer is a container using an image
+type ImageContainer struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+	Stack string `json:"stack,omitempty"`
+}
+
+// ImageDetails is an image with its configuration and layers
+type ImageDetails struct {
+	ImageInfo
+	Architecture string            `json:"architecture"`
+	Variant      string            `json:"variant,omitempty"`
+	OS           string            `json:"os"`
+	Author       string            `json:"author,omitempty"`
+	Env          []string          `json:"env"`
+	Entrypoint   []string          `json:"entrypoint"`
+	Cmd          []string          `json:"cmd"`
+	WorkingDir   string            `json:"workingDir,omitempty"`
+	User         string            `json:"user,omitempty"`
+	ExposedPorts []string          `json:"exposedPorts"`
+	Volumes      []string          `json:"volumes"`
+	Labels       map[string]string `json:"labels"`
+	// Layers are the digests of the root filesystem layers, base first
+	Layers []string `json:"layers"`
+}
+
+// ImageLayer is one step of an image's build history, newest first
+type ImageLayer struct {
+	// ID is empty for layers pulled from a registry, which only keeps
+	// the IDs of tagged images
+	ID        string   `json:"id,omitempty"`
+	Created   int64    `json:"created"`
+	CreatedBy string   `json:"createdBy"`
+	Size      int64    `json:"size"`
+	Tags      []string `json:"tags,omitempty"`
+	Comment   string   `json:"comment,omitempty"`
+}
+
+// PullProgress is a status update from an image pull. Layer downloads and
+// extractions report Current and Total bytes.
+type PullProgress struct {
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+// RegistryAuth is the credential an image pull presents to its registry
+type RegistryAuth struct {
+	Username string
+	Password string
+	// ServerAddress is the registry's key in Docker client configurations
+	ServerAddress string
+}
+
+// ImageRemoval reports one reference untagged or one image deleted
+type ImageRemoval struct {
+	Untagged string `json:"untagged,omitempty"`
+	Deleted  string `json:"deleted,omitempty"`
+}
+
+// ImagePruneOptions selects which images PruneImages removes
+type ImagePruneOptions struct {
+	// All removes every image no container uses, not only dangling ones
+	All bool
+	// DryRun reports what would be removed without removing anything
+	DryRun bool
+}
+
+// ImagePruneReport lists the images pruned, or that would be. For a dry
+// run SpaceReclaimed is estimated from the space not shared with other
+// images.
+type ImagePruneReport struct {
+	Images         []ImageInfo `json:"images"`
+	SpaceReclaimed uint64      `json:"spaceReclaimed"`
+	DryRun         bool        `json:"dryRun"`
+}
+
+func (c *Client) ListImages(ctx context.Context) ([]ImageInfo, error) {
+	images, err := c.cli.ImageList(ctx, image.ListOptions{SharedSize: true})
+	if err != nil {
+		return nil, err
+	}
+	users, err := c.imageUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ImageInfo, len(images))
+	for i, img := range images {
+		result[i] = newImageInfo(img.ID, img.RepoTags, img.RepoDigests, img.Size, img.Created, users)
+		result[i].SharedSize = img.SharedSize
+	}
+	return result, nil
+}
+
+func (c *Client) InspectImage(ctx context.Context, id string) (*ImageDetails, error) {
+	img, _, err := c.cli.ImageInspectWithRaw(ctx, id)
+	if err != nil {
+		return nil, imageError(err, id)
+	}
+	users, err := c.imageUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var created int64
+	if t, err := time.Parse(time.RFC3339Nano, img.Created); err == nil {
+		created = t.Unix()
+	}
+	details := &ImageDetails{
+		ImageInfo:    newImageInfo(img.ID, img.RepoTags, img.RepoDigests, img.Size, created, users),
+		Architecture: img.Architecture,
+		Variant:      img.Variant,
+		OS:           img.Os,
+		Author:       img.Author,
+		Env:          []string{},
+		Entrypoint:   []string{},
+		Cmd:          []string{},
+		ExposedPorts: []string{},
+		Volumes:      []string{},
+		Labels:       map[string]string{},
+		Layers:       img.RootFS.Layers,
+	}
+	details.SharedSize = -1
+	if cfg := img.Config; cfg != nil {
+		details.Env = nonNil(cfg.Env)
+		details.Entrypoint = nonNil(cfg.Entrypoint)
+		details.Cmd = nonNil(cfg.Cmd)
+		details.WorkingDir = cfg.WorkingDir
+		details.User = cfg.User
+		if cfg.Labels != nil {
+			details.Labels = cfg.Labels
+		}
+		for port := range cfg.ExposedPorts {
+			details.ExposedPorts = append(details.ExposedPorts, string(port))
+		}
+		for volume := range cfg.Volumes {
+			details.Volumes = append(details.Volumes, volume)
+		}
+		sort.Strings(details.ExposedPorts)
+		sort.Strings(details.Volumes)
+	}
+	if details.Layers == nil {
+		details.Layers = []string{}
+	}
+	return details, nil
+}
+
+func (c *Client) ImageHistory(ctx context.Context, id string) ([]ImageLayer, error) {
+	history, err := c.cli.ImageHistory(ctx, id)
+	if err != nil {
+		return nil, imageError(err, id)
+	}
+
+	layers := make([]ImageLayer, len(history))
+	for i, h := range history {
+		layers[i] = ImageLayer{
+			Created:   h.Created,
+			CreatedBy: h.CreatedBy,
+			Size:      h.Size,
+			Tags:      h.Tags,
+			Comment:   h.Comment,
+		}
+		if h.ID != "<missing>" {
+			layers[i].ID = shortImageID(h.ID)
+		}
+	}
+	return layers, nil
+}
+
+// PullImage pulls an image by reference, defaulting to the latest tag, and
+// reports the daemon's progress messages as they arrive. auth may be nil for
+// anonymous pulls.
+func (c *Client) PullImage(ctx context.Context, ref string, auth *RegistryAuth, progress func(PullProgress)) error {
+	var opts image.PullOptions
+	if auth != nil {
+		encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			ServerAddress: auth.ServerAddress,
+		})
+		if err != nil {
+			return err
+		}
+		opts.RegistryAuth = encoded
+	}
+
+	body, err := c.cli.ImagePull(ctx, ref, opts)
+	if err != nil {
+		if errdefs.IsInvalidParameter(err) || strings.Contains(err.Error(), "invalid reference format") {
+			return fmt.Errorf("%w: %v", ErrInvalidReference, err)
+		}
+		return imageError(err, ref)
+	}
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return errors.New(msg.Error.Message)
+		}
+
+		p := PullProgress{ID: msg.ID, Status: msg.Status}
+		if msg.Progress != nil {
+			p.Current = msg.Progress.Current
+			p.Total = msg.Progress.Total
+		}
+		if progress != nil {
+			progress(p)
+		}
+	}
+}
+
+// RemoveImage removes an image by ID, or a single tag by reference; the
+// image itself is deleted once its last tag is gone. Images with several
+// tags or used by stopped containers can only be removed by ID with force.
+func (c *Client) RemoveImage(ctx context.Context, ref string, force bool) ([]ImageRemoval, error) {
+	removed, err := c.cli.ImageRemove(ctx, ref, image.RemoveOptions{Force: force, PruneChildren: true})
+	if err != nil {
+		return nil, imageError(err, ref)
+	}
+
+	result := make([]ImageRemoval, len(removed))
+	for i, r := range removed {
+		result[i] = ImageRemoval{Untagged: r.Untagged}
+		if r.Deleted != "" {
+			result[i].Deleted = shortImageID(r.Deleted)
+		}
+	}
+	return result, nil
+}
+
+// TagImage points the target reference at the source image, moving the tag
+// if it already exists
+func (c *Client) TagImage(ctx context.Context, source string, target string) error {
+	if err := c.cli.ImageTag(ctx, source, target); err != nil {
+		if errdefs.IsInvalidParameter(err) {
+			return fmt.Errorf("%w: %v", ErrInvalidReference, err)
+		}
+		return imageError(err, source)
+	}
+	return nil
+}
+
+// PruneImages removes dangling images, or with All every image no
+// container uses
+func (c *Client) PruneImages(ctx context.Context, opts ImagePruneOptions) (*ImagePruneReport, error) {
+	images, err := c.ListImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImagePruneReport{Images: []ImageInfo{}, DryRun: opts.DryRun}
+	candidates := PruneCandidates(images, opts.All)
+	if opts.DryRun {
+		report.Images = candidates
+		for _, img := range candidates {
+			report.SpaceReclaimed += uint64(img.Size - max(img.SharedSize, 0))
+		}
+		return report, nil
+	}
+
+	pruneFilters := filters.NewArgs()
+	if opts.All {
+		pruneFilters.Add("dangling", "false")
+	}
+	pruned, err := c.cli.ImagesPrune(ctx, pruneFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[string]bool)
+	for _, d := range pruned.ImagesDeleted {
+		if d.Deleted != "" {
+			deleted[shortImageID(d.Deleted)] = true
+		}
+	}
+	for _, img := range candidates {
+		if deleted[img.ID] {
+			report.Images = append(report.Images, img)
+		}
+	}
+	report.SpaceReclaimed = pruned.SpaceReclaimed
+	return report, nil
+}
+
+// PruneCandidates returns the images a prune would remove: those no
+// container uses that are dangling, or any such image with all
+func PruneCandidates(images []ImageInfo, all bool) []ImageInfo {
+	candidates := make([]ImageInfo, 0)
+	for _, img := range images {
+		if len(img.Containers) == 0 && (all || img.Dangling) {
+			candidates = append(candidates, img)
+		}
+	}
+	return candidates
+}
+
+// imageUsers maps full image IDs to the containers created from them
+func (c *Client) imageUsers(ctx context.Context) (map[string][]ImageContainer, error) {
+	containers, err := c.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string][]ImageContainer)
+	for _, ctr := range containers {
+		name := ""
+		if len(ctr.Names) > 0 {
+			name = strings.TrimPrefix(ctr.Names[0], "/")
+		}
+		users[ctr.ImageID] = append(users[ctr.ImageID], ImageContainer{
+			ID:    ctr.ID[:12],
+			Name:  name,
+			State: ctr.State,
+			Stack: ctr.Labels["com.docker.compose.project"],
+		})
+	}
+	return users, nil
+}
+
+func newImageInfo(id string, tags []string, digests []string, size int64, created int64, users map[string][]ImageContainer) ImageInfo {
+	info := ImageInfo{
+		ID:         shortImageID(id),
+		Tags:       make([]string, 0, len(tags)),
+		Digests:    nonNil(digests),
+		Size:       size,
+		Created:    created,
+		Containers: nonNil(users[id]),
+		Stacks:     []string{},
+	}
+	for _, tag := range tags {
+		if tag != "<none>:<none>" {
+			info.Tags = append(info.Tags, tag)
+		}
+	}
+	info.Dangling = len(info.Tags) == 0
+
+	seen := make(map[string]bool)
+	for _, ctr := range info.Containers {
+		if ctr.Stack != "" && !seen[ctr.Stack] {
+			seen[ctr.Stack] = true
+			info.Stacks = append(info.Stacks, ctr.Stack)
+		}
+	}
+	sort.Strings(info.Stacks)
+	return info
+}
+
+// imageError converts daemon errors about an image to ErrImageNotFound or
+// ErrImageConflict
+func imageError(err error, ref string) error {
+	switch {
+	case errdefs.IsNotFound(err):
+		return fmt.Errorf("%w: %s", ErrImageNotFound, ref)
+	case errdefs.IsConflict(err):
+		return fmt.Errorf("%w: %v", ErrImageConflict, err)
+	}
+	return err
+}
+
+// shortImageID removes the "sha256:" prefix and truncates the ID the same
+// way the image list does
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return id
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
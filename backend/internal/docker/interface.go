@@ -1,6 +1,10 @@
 package docker
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // DockerClient defines the interface for Docker operations
 type DockerClient interface {
@@ -9,8 +13,14 @@ type DockerClient interface {
 	StartContainer(ctx context.Context, id string) error
 	StopContainer(ctx context.Context, id string) error
 	RestartContainer(ctx context.Context, id string) error
-	GetContainerLogs(ctx context.Context, id string, tail string) (string, error)
+	GetContainerLogs(ctx context.Context, id string, opts LogOptions) (*ContainerLogs, error)
+	StreamContainerLogs(ctx context.Context, id string, opts LogOptions, emit func(LogEntry)) error
 	GetContainerStats(ctx context.Context, id string) (*ContainerStats, error)
+	StreamContainerStats(ctx context.Context, id string, emit func(*ContainerStats)) error
+	CreateExec(ctx context.Context, containerID string, opts ExecOptions) (string, error)
+	AttachExec(ctx context.Context, execID string, size TerminalSize) (io.ReadWriteCloser, error)
+	ResizeExec(ctx context.Context, execID string, size TerminalSize) error
+	ExecExitCode(ctx context.Context, execID string) (int, error)
 	ListVolumes(ctx context.Context) ([]VolumeInfo, error)
 	CreateVolume(ctx context.Context, name string, driver string, labels map[string]string) (*VolumeInfo, error)
 	DeleteVolume(ctx context.Context, name string, force bool) error
@@ -18,6 +28,13 @@ type DockerClient interface {
 	CreateNetwork(ctx context.Context, name string, driver string) (*NetworkInfo, error)
 	DeleteNetwork(ctx context.Context, id string) error
 	ListImages(ctx context.Context) ([]ImageInfo, error)
+	InspectImage(ctx context.Context, id string) (*ImageDetails, error)
+	ImageHistory(ctx context.Context, id string) ([]ImageLayer, error)
+	PullImage(ctx context.Context, ref string, auth *RegistryAuth, progress func(PullProgress)) error
+	RemoveImage(ctx context.Context, ref string, force bool) ([]ImageRemoval, error)
+	TagImage(ctx context.Context, source string, target string) error
+	PruneImages(ctx context.Context, opts ImagePruneOptions) (*ImagePruneReport, error)
+	Events(ctx context.Context, since time.Time) (<-chan Event, <-chan error)
 	Close() error
 }
 
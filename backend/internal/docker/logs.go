@@ -0,0 +1,174 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Log streams
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// LogEntry is one line of container output
+type LogEntry struct {
+	Time      time.Time `json:"time"`
+	Stream    string    `json:"stream"`
+	Message   string    `json:"message"`
+	Container string    `json:"container,omitempty"`
+	Service   string    `json:"service,omitempty"`
+}
+
+// LogOptions selects the log lines returned by StreamContainerLogs. Zero
+// values mean no bound; Tail is a number of lines or "all".
+type LogOptions struct {
+	Since  time.Time
+	Until  time.Time
+	Tail   string
+	Follow bool
+}
+
+// ContainerLogs is a container's log output
+type ContainerLogs struct {
+	// TTY is set for containers with a terminal, whose stdout and stderr
+	// are a single stream reported as stdout
+	TTY     bool       `json:"tty"`
+	Entries []LogEntry `json:"entries"`
+}
+
+func (c *Client) GetContainerLogs(ctx context.Context, id string, opts LogOptions) (*ContainerLogs, error) {
+	opts.Follow = false
+	logs := &ContainerLogs{Entries: make([]LogEntry, 0)}
+	tty, err := c.readLogs(ctx, id, opts, func(entry LogEntry) {
+		logs.Entries = append(logs.Entries, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logs.TTY = tty
+	return logs, nil
+}
+
+// StreamContainerLogs calls emit for each log line until the lines selected
+// by opts are exhausted or ctx is canceled
+func (c *Client) StreamContainerLogs(ctx context.Context, id string, opts LogOptions, emit func(LogEntry)) error {
+	_, err := c.readLogs(ctx, id, opts, emit)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// readLogs decodes a container's logs, reporting whether it has a TTY
+func (c *Client) readLogs(ctx context.Context, id string, opts LogOptions, emit func(LogEntry)) (bool, error) {
+	// TTY containers write a single raw stream instead of multiplexed frames
+	info, err := c.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	tty := info.Config != nil && info.Config.Tty
+
+	options := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+		Follow:     opts.Follow,
+		Tail:       opts.Tail,
+	}
+	if !opts.Since.IsZero() {
+		options.Since = unixTimestamp(opts.Since)
+	}
+	if !opts.Until.IsZero() {
+		options.Until = unixTimestamp(opts.Until)
+	}
+
+	reader, err := c.cli.ContainerLogs(ctx, id, options)
+	if err != nil {
+		return tty, err
+	}
+	defer reader.Close()
+
+	return tty, DecodeLogs(reader, tty, emit)
+}
+
+// DecodeLogs splits a log stream read with timestamps into entries. Output
+// of containers without a TTY is multiplexed into stdout and stderr frames;
+// with a TTY it is a single raw stream reported as stdout.
+func DecodeLogs(r io.Reader, tty bool, emit func(LogEntry)) error {
+	if tty {
+		w := newLogWriter(StreamStdout, emit)
+		_, err := io.Copy(w, r)
+		w.flush()
+		return err
+	}
+
+	stdout := newLogWriter(StreamStdout, emit)
+	stderr := newLogWriter(StreamStderr, emit)
+	_, err := stdcopy.StdCopy(stdout, stderr, r)
+	stdout.flush()
+	stderr.flush()
+	return err
+}
+
+// logWriter turns the output of one stream into entries, holding back
+// partial lines until they are complete
+type logWriter struct {
+	stream string
+	buf    bytes.Buffer
+	emit   func(LogEntry)
+}
+
+func newLogWriter(stream string, emit func(LogEntry)) *logWriter {
+	return &logWriter{stream: stream, emit: emit}
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		data := w.buf.Bytes()
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		w.emitLine(string(data[:i]))
+		w.buf.Next(i + 1)
+	}
+	return len(p), nil
+}
+
+func (w *logWriter) flush() {
+	if w.buf.Len() > 0 {
+		w.emitLine(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+func (w *logWriter) emitLine(line string) {
+	entry := ParseLogLine(strings.TrimSuffix(line, "\r"))
+	entry.Stream = w.stream
+	w.emit(entry)
+}
+
+// ParseLogLine splits the RFC 3339 timestamp Docker prefixes to each line
+// from the message. Lines without a timestamp are returned as is.
+func ParseLogLine(line string) LogEntry {
+	prefix, message, ok := strings.Cut(line, " ")
+	if ok {
+		if t, err := time.Parse(time.RFC3339Nano, prefix); err == nil {
+			return LogEntry{Time: t, Message: message}
+		}
+	}
+	return LogEntry{Message: line}
+}
+
+func unixTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
@@ -0,0 +1,181 @@
+package docker
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// frame is one write to a multiplexed log stream
+type frame struct {
+	stream stdcopy.StdType
+	data   string
+}
+
+func multiplex(t *testing.T, frames []frame) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, f := range frames {
+		if _, err := stdcopy.NewStdWriter(&buf, f.stream).Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeLogs(t *testing.T) {
+	t1 := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	t2 := time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC)
+	ts1 := t1.Format(time.RFC3339Nano)
+	ts2 := t2.Format(time.RFC3339Nano)
+
+	tests := []struct {
+		name   string
+		tty    bool
+		frames []frame
+		raw    string
+		want   []LogEntry
+	}{
+		{
+			name: "interleaved streams",
+			frames: []frame{
+				{stdcopy.Stdout, ts1 + " listening on :8080\n"},
+				{stdcopy.Stderr, ts2 + " warning: no config\n"},
+				{stdcopy.Stdout, ts2 + " ready\n"},
+			},
+			want: []LogEntry{
+				{Time: t1, Stream: StreamStdout, Message: "listening on :8080"},
+				{Time: t2, Stream: StreamStderr, Message: "warning: no config"},
+				{Time: t2, Stream: StreamStdout, Message: "ready"},
+			},
+		},
+		{
+			name: "line split across frames",
+			frames: []frame{
+				{stdcopy.Stdout, ts1 + " first half"},
+				{stdcopy.Stderr, ts2 + " unrelated\n"},
+				{stdcopy.Stdout, " second half\n"},
+			},
+			want: []LogEntry{
+				{Time: t2, Stream: StreamStderr, Message: "unrelated"},
+				{Time: t1, Stream: StreamStdout, Message: "first half second half"},
+			},
+		},
+		{
+			name: "several lines in one frame",
+			frames: []frame{
+				{stdcopy.Stdout, ts1 + " one\n" + ts2 + " two\n"},
+			},
+			want: []LogEntry{
+				{Time: t1, Stream: StreamStdout, Message: "one"},
+				{Time: t2, Stream: StreamStdout, Message: "two"},
+			},
+		},
+		{
+			name: "trailing partial line",
+			frames: []frame{
+				{stdcopy.Stdout, ts1 + " done\n" + ts2 + " no newline"},
+			},
+			want: []LogEntry{
+				{Time: t1, Stream: StreamStdout, Message: "done"},
+				{Time: t2, Stream: StreamStdout, Message: "no newline"},
+			},
+		},
+		{
+			name: "crlf",
+			frames: []frame{
+				{stdcopy.Stdout, ts1 + " windows line\r\n"},
+				{stdcopy.Stderr, ts2 + " \r\n"},
+			},
+			want: []LogEntry{
+				{Time: t1, Stream: StreamStdout, Message: "windows line"},
+				{Time: t2, Stream: StreamStderr, Message: ""},
+			},
+		},
+		{
+			name: "no timestamp",
+			frames: []frame{
+				{stdcopy.Stdout, "plain line\n"},
+				{stdcopy.Stdout, "2024-13-45 not a timestamp\n"},
+			},
+			want: []LogEntry{
+				{Stream: StreamStdout, Message: "plain line"},
+				{Stream: StreamStdout, Message: "2024-13-45 not a timestamp"},
+			},
+		},
+		{
+			name: "tty raw stream",
+			tty:  true,
+			raw:  ts1 + " prompt> ls\r\n" + ts2 + " bin etc\r\n" + ts2 + " partial",
+			want: []LogEntry{
+				{Time: t1, Stream: StreamStdout, Message: "prompt> ls"},
+				{Time: t2, Stream: StreamStdout, Message: "bin etc"},
+				{Time: t2, Stream: StreamStdout, Message: "partial"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.raw)
+			if !tt.tty {
+				data = multiplex(t, tt.frames)
+			}
+
+			// Reading a byte at a time splits frame headers and lines at
+			// every possible point
+			for _, bytewise := range []bool{false, true} {
+				var r io.Reader = bytes.NewReader(data)
+				if bytewise {
+					r = iotest.OneByteReader(r)
+				}
+				var got []LogEntry
+				if err := DecodeLogs(r, tt.tty, func(e LogEntry) { got = append(got, e) }); err != nil {
+					t.Fatalf("DecodeLogs(bytewise=%v) error = %v", bytewise, err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("DecodeLogs(bytewise=%v) = %+v, want %+v", bytewise, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeLogsCorruptFrame(t *testing.T) {
+	// A TTY stream read as multiplexed has no valid frame headers
+	err := DecodeLogs(bytes.NewReader([]byte("2024-05-01T12:00:00Z hello\n")), false, func(LogEntry) {})
+	if err == nil {
+		t.Error("DecodeLogs() error = nil, want an error for a raw stream")
+	}
+}
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want LogEntry
+	}{
+		{
+			line: "2024-05-01T12:00:00.5Z message with  spaces",
+			want: LogEntry{Time: time.Date(2024, 5, 1, 12, 0, 0, 500000000, time.UTC), Message: "message with  spaces"},
+		},
+		{
+			line: "2024-05-01T14:00:00+02:00 offset",
+			want: LogEntry{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Message: "offset"},
+		},
+		{line: "no timestamp here", want: LogEntry{Message: "no timestamp here"}},
+		{line: "single", want: LogEntry{Message: "single"}},
+		{line: "", want: LogEntry{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got := ParseLogLine(tt.line)
+			if !got.Time.Equal(tt.want.Time) || got.Message != tt.want.Message || got.Stream != "" {
+				t.Errorf("ParseLogLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ContainerStats is a sample of a container's resource usage
+type ContainerStats struct {
+	// CPUPercent is relative to one CPU, so a container saturating two
+	// CPUs reports 200
+	CPUPercent float64 `json:"cpuPercent"`
+	// PerCPUPercent is only available on cgroup v1 hosts
+	PerCPUPercent []float64     `json:"perCpuPercent,omitempty"`
+	OnlineCPUs    uint32        `json:"onlineCpus"`
+	Throttling    CPUThrottling `json:"throttling"`
+	// MemoryUsage excludes the page cache reported in MemoryCache
+	MemoryUsage   uint64  `json:"memoryUsage"`
+	MemoryCache   uint64  `json:"memoryCache"`
+	MemoryLimit   uint64  `json:"memoryLimit"`
+	MemoryPercent float64 `json:"memoryPercent"`
+	// NetworkRx and NetworkTx are totals over all interfaces
+	NetworkRx  uint64                    `json:"networkRx"`
+	NetworkTx  uint64                    `json:"networkTx"`
+	Networks   map[string]InterfaceStats `json:"networks,omitempty"`
+	BlockRead  uint64                    `json:"blockRead"`
+	BlockWrite uint64                    `json:"blockWrite"`
+	PIDs       uint64                    `json:"pids"`
+	PIDsLimit  uint64                    `json:"pidsLimit,omitempty"`
+}
+
+// CPUThrottling reports how often the container hit its CPU quota
+type CPUThrottling struct {
+	Periods          uint64 `json:"periods"`
+	ThrottledPeriods uint64 `json:"throttledPeriods"`
+	// ThrottledTime is in nanoseconds
+	ThrottledTime uint64 `json:"throttledTime"`
+}
+
+// InterfaceStats are the cumulative counters of one network interface
+type InterfaceStats struct {
+	RxBytes   uint64 `json:"rxBytes"`
+	TxBytes   uint64 `json:"txBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	TxPackets uint64 `json:"txPackets"`
+	RxErrors  uint64 `json:"rxErrors"`
+	TxErrors  uint64 `json:"txErrors"`
+	RxDropped uint64 `json:"rxDropped"`
+	TxDropped uint64 `json:"txDropped"`
+}
+
+func (c *Client) GetContainerStats(ctx context.Context, id string) (*ContainerStats, error) {
+	stats, err := c.cli.ContainerStats(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	defer stats.Body.Close()
+
+	var v types.StatsJSON
+	if err := json.NewDecoder(stats.Body).Decode(&v); err != nil {
+		return nil, err
+	}
+	return convertStats(&v), nil
+}
+
+// StreamContainerStats calls emit with each sample the daemon sends, about
+// once a second, until the container stops or ctx is canceled
+func (c *Client) StreamContainerStats(ctx context.Context, id string, emit func(*ContainerStats)) error {
+	stats, err := c.cli.ContainerStats(ctx, id, true)
+	if err != nil {
+		return err
+	}
+	defer stats.Body.Close()
+
+	decoder := json.NewDecoder(stats.Body)
+	for {
+		var v types.StatsJSON
+		if err := decoder.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		emit(convertStats(&v))
+	}
+}
+
+// convertStats computes a sample the way the docker CLI's stats command
+// does, on cgroup v1 and v2 alike
+func convertStats(v *types.StatsJSON) *ContainerStats {
+	stats := &ContainerStats{
+		OnlineCPUs: v.CPUStats.OnlineCPUs,
+		Throttling: CPUThrottling{
+			Periods:          v.CPUStats.ThrottlingData.Periods,
+			ThrottledPeriods: v.CPUStats.ThrottlingData.ThrottledPeriods,
+			ThrottledTime:    v.CPUStats.ThrottlingData.ThrottledTime,
+		},
+		MemoryLimit: v.MemoryStats.Limit,
+		PIDs:        v.PidsStats.Current,
+		PIDsLimit:   v.PidsStats.Limit,
+	}
+
+	// CPU: per-CPU usage is only reported on cgroup v1, so the number of
+	// CPUs comes from online_cpus when the daemon provides it
+	percpu := v.CPUStats.CPUUsage.PercpuUsage
+	if stats.OnlineCPUs == 0 {
+		stats.OnlineCPUs = uint32(len(percpu))
+	}
+	cpuDelta := float64(v.CPUStats.CPUUsage.TotalUsage) - float64(v.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(v.CPUStats.SystemUsage) - float64(v.PreCPUStats.SystemUsage)
+	if systemDelta > 0 && cpuDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * float64(stats.OnlineCPUs) * 100.0
+	}
+	prePercpu := v.PreCPUStats.CPUUsage.PercpuUsage
+	if systemDelta > 0 && len(percpu) > 0 && len(prePercpu) == len(percpu) {
+		stats.PerCPUPercent = make([]float64, len(percpu))
+		for i := range percpu {
+			if delta := float64(percpu[i]) - float64(prePercpu[i]); delta > 0 {
+				stats.PerCPUPercent[i] = delta / systemDelta * float64(stats.OnlineCPUs) * 100.0
+			}
+		}
+	}
+
+	// Memory: the kernel counts reclaimable page cache as used, so subtract
+	// inactive file pages like docker stats does
+	stats.MemoryUsage = v.MemoryStats.Usage
+	if cache, ok := v.MemoryStats.Stats["total_inactive_file"]; ok && cache < v.MemoryStats.Usage {
+		stats.MemoryCache = cache // cgroup v1
+	} else if cache := v.MemoryStats.Stats["inactive_file"]; cache < v.MemoryStats.Usage {
+		stats.MemoryCache = cache // cgroup v2
+	}
+	stats.MemoryUsage -= stats.MemoryCache
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100.0
+	}
+
+	// Network, per interface and in total
+	if len(v.Networks) > 0 {
+		stats.Networks = make(map[string]InterfaceStats, len(v.Networks))
+	}
+	for name, net := range v.Networks {
+		stats.Networks[name] = InterfaceStats{
+			RxBytes:   net.RxBytes,
+			TxBytes:   net.TxBytes,
+			RxPackets: net.RxPackets,
+			TxPackets: net.TxPackets,
+			RxErrors:  net.RxErrors,
+			TxErrors:  net.TxErrors,
+			RxDropped: net.RxDropped,
+			TxDropped: net.TxDropped,
+		}
+		stats.NetworkRx += net.RxBytes
+		stats.NetworkTx += net.TxBytes
+	}
+
+	// Block I/O: entries are per device and operation; cgroup v1 spells
+	// operations "Read"/"Write" and v2 "read"/"write"
+	for _, entry := range v.BlkioStats.IoServiceBytesRecursive {
+		if entry.Op == "" {
+			continue
+		}
+		switch entry.Op[0] {
+		case 'r', 'R':
+			stats.BlockRead += entry.Value
+		case 'w', 'W':
+			stats.BlockWrite += entry.Value
+		}
+	}
+
+	return stats
+}
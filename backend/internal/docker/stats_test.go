@@ -0,0 +1,120 @@
+package docker
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestConvertStats(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want ContainerStats
+	}{
+		{
+			name: "cgroup v1",
+			json: `{
+				"cpu_stats": {"cpu_usage": {"total_usage": 400, "percpu_usage": [300, 100]}, "system_cpu_usage": 2000,
+					"throttling_data": {"periods": 10, "throttled_periods": 2, "throttled_time": 5}},
+				"precpu_stats": {"cpu_usage": {"total_usage": 200, "percpu_usage": [200, 0]}, "system_cpu_usage": 1000},
+				"memory_stats": {"usage": 1000, "limit": 4000, "stats": {"total_inactive_file": 200, "inactive_file": 999}},
+				"networks": {"eth0": {"rx_bytes": 10, "tx_bytes": 20}, "eth1": {"rx_bytes": 1, "tx_bytes": 2}},
+				"blkio_stats": {"io_service_bytes_recursive": [
+					{"op": "Read", "value": 7}, {"op": "Write", "value": 3}, {"op": "Total", "value": 10}, {"op": "Read", "value": 1}]},
+				"pids_stats": {"current": 4, "limit": 100}
+			}`,
+			want: ContainerStats{
+				CPUPercent:    40,
+				PerCPUPercent: []float64{20, 20},
+				OnlineCPUs:    2,
+				Throttling:    CPUThrottling{Periods: 10, ThrottledPeriods: 2, ThrottledTime: 5},
+				MemoryUsage:   800,
+				MemoryCache:   200,
+				MemoryLimit:   4000,
+				MemoryPercent: 20,
+				Networks: map[string]InterfaceStats{
+					"eth0": {RxBytes: 10, TxBytes: 20},
+					"eth1": {RxBytes: 1, TxBytes: 2},
+				},
+				NetworkRx:  11,
+				NetworkTx:  22,
+				BlockRead:  8,
+				BlockWrite: 3,
+				PIDs:       4,
+				PIDsLimit:  100,
+			},
+		},
+		{
+			name: "cgroup v2",
+			json: `{
+				"cpu_stats": {"cpu_usage": {"total_usage": 3000}, "system_cpu_usage": 20000, "online_cpus": 4},
+				"precpu_stats": {"cpu_usage": {"total_usage": 1000}, "system_cpu_usage": 10000},
+				"memory_stats": {"usage": 500, "limit": 1000, "stats": {"inactive_file": 100}},
+				"blkio_stats": {"io_service_bytes_recursive": [{"op": "read", "value": 5}, {"op": "write", "value": 6}]}
+			}`,
+			want: ContainerStats{
+				CPUPercent:    80,
+				OnlineCPUs:    4,
+				MemoryUsage:   400,
+				MemoryCache:   100,
+				MemoryLimit:   1000,
+				MemoryPercent: 40,
+				BlockRead:     5,
+				BlockWrite:    6,
+			},
+		},
+		{
+			name: "first sample has no previous usage",
+			json: `{
+				"cpu_stats": {"cpu_usage": {"total_usage": 3000}, "system_cpu_usage": 20000, "online_cpus": 2},
+				"memory_stats": {"usage": 500}
+			}`,
+			want: ContainerStats{
+				CPUPercent:  30,
+				OnlineCPUs:  2,
+				MemoryUsage: 500,
+			},
+		},
+		{
+			name: "cache larger than usage is ignored",
+			json: `{"memory_stats": {"usage": 100, "limit": 1000, "stats": {"inactive_file": 500}}}`,
+			want: ContainerStats{
+				MemoryUsage:   100,
+				MemoryLimit:   1000,
+				MemoryPercent: 10,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v types.StatsJSON
+			if err := json.Unmarshal([]byte(tt.json), &v); err != nil {
+				t.Fatal(err)
+			}
+			got := convertStats(&v)
+
+			// Compare percentages with a tolerance for float rounding
+			if math.Abs(got.CPUPercent-tt.want.CPUPercent) > 1e-9 {
+				t.Errorf("CPUPercent = %v, want %v", got.CPUPercent, tt.want.CPUPercent)
+			}
+			if len(got.PerCPUPercent) != len(tt.want.PerCPUPercent) {
+				t.Fatalf("PerCPUPercent = %v, want %v", got.PerCPUPercent, tt.want.PerCPUPercent)
+			}
+			for i := range got.PerCPUPercent {
+				if math.Abs(got.PerCPUPercent[i]-tt.want.PerCPUPercent[i]) > 1e-9 {
+					t.Errorf("PerCPUPercent = %v, want %v", got.PerCPUPercent, tt.want.PerCPUPercent)
+				}
+			}
+			got.CPUPercent, tt.want.CPUPercent = 0, 0
+			got.PerCPUPercent, tt.want.PerCPUPercent = nil, nil
+
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("convertStats() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package exporter
+
+import (
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"aperture-science-network/internal/stack"
+	"aperture-science-network/internal/stats"
+	"aperture-science-network/internal/version"
+)
+
+// ContentType is the media type of the exposition format written by Write
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// stackStatuses are the statuses reported for stacks, always exported so
+// that absent statuses read as zero rather than missing
+var stackStatuses = []string{"running", "partial", "stopped"}
+
+// HubStats reports on the WebSocket hub
+type HubStats interface {
+	ClientCount() int
+	DroppedClients() uint64
+}
+
+// Exporter gathers host, container, stack and server metrics for
+// Prometheus. Host and container values are read when scraped; request
+// latencies are recorded as requests complete.
+type Exporter struct {
+	statsProvider  stats.Provider
+	containerStats *stats.ContainerWatcher
+	stackProvider  stack.Provider
+	hub            HubStats
+	http           httpMetrics
+}
+
+// New creates an exporter reading from the given sources
+func New(statsProvider stats.Provider, containerStats *stats.ContainerWatcher, stackProvider stack.Provider, hub HubStats) *Exporter {
+	return &Exporter{
+		statsProvider:  statsProvider,
+		containerStats: containerStats,
+		stackProvider:  stackProvider,
+		hub:            hub,
+	}
+}
+
+// ObserveRequest records the latency of an HTTP request. route is the
+// matched route pattern, so that IDs in paths do not multiply series.
+func (e *Exporter) ObserveRequest(method, route string, status int, duration time.Duration) {
+	e.http.observe(method, route, status, duration)
+}
+
+// Write writes every metric in the Prometheus text format
+func (e *Exporter) Write(w io.Writer) error {
+	out := newExpositionWriter(w)
+
+	out.family("celeste_build_info", "Version of the running server", typeGauge)
+	out.sample("celeste_build_info", Labels{"version": version.Version}, 1)
+
+	e.writeHost(out)
+	e.writeContainers(out)
+	e.writeStacks(out)
+	e.writeServer(out)
+
+	return out.flush()
+}
+
+func (e *Exporter) writeHost(out *expositionWriter) {
+	sys, err := e.statsProvider.GetSystemStats()
+	if err != nil {
+		log.Printf("Error getting system stats for metrics: %v", err)
+		return
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"celeste_host_cpu_usage_percent", "Host CPU usage over all cores", sys.CPUUsage},
+		{"celeste_host_cpu_cores", "Number of physical CPU cores", float64(sys.CPUCores)},
+		{"celeste_host_memory_used_bytes", "Host memory in use", float64(sys.MemoryUsed)},
+		{"celeste_host_memory_total_bytes", "Total host memory", float64(sys.MemoryTotal)},
+		{"celeste_host_disk_used_bytes", "Used space on the root filesystem", float64(sys.DiskUsed)},
+		{"celeste_host_disk_total_bytes", "Size of the root filesystem", float64(sys.DiskTotal)},
+		{"celeste_host_uptime_seconds", "Time since the host booted", float64(sys.Uptime)},
+	}
+	for _, g := range gauges {
+		out.family(g.name, g.help, typeGauge)
+		out.sample(g.name, nil, g.value)
+	}
+}
+
+func (e *Exporter) writeContainers(out *expositionWriter) {
+	samples := e.containerStats.Snapshot()
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].Container.Name < samples[j].Container.Name
+	})
+
+	labels := make([]Labels, len(samples))
+	for i, sample := range samples {
+		ctr := sample.Container
+		labels[i] = Labels{
+			"id":      ctr.ID,
+			"name":    ctr.Name,
+			"project": ctr.Labels["com.docker.compose.project"],
+			"service": ctr.Labels["com.docker.compose.service"],
+		}
+	}
+
+	metrics := []struct {
+		name       string
+		help       string
+		metricType string
+		value      func(s stats.ContainerSample) float64
+	}{
+		{"celeste_container_cpu_usage_percent", "Container CPU usage relative to one CPU", typeGauge,
+			func(s stats.ContainerSample) float64 { return s.Stats.CPUPercent }},
+		{"celeste_container_online_cpus", "CPUs available to the container", typeGauge,
+			func(s stats.ContainerSample) float64 { return float64(s.Stats.OnlineCPUs) }},
+		{"celeste_container_cpu_throttled_periods_total", "Scheduling periods in which the container was throttled", typeCounter,
+			func(s stats.ContainerSample) float64 { return float64(s.Stats.Throttling.ThrottledPeriods) }},
+		{"celeste_container_cpu_throttled_seconds_total", "Time the container was throttled", typeCounter,
+			func(s stats.ContainerSample) float64 { return float64(s.Stats.Throttling.ThrottledTime) / 1e9 }},
+		{"celeste_container_memory_usage_bytes", "Container memory in use, excluding page cache", typeGauge,
+			func(s stats.ContainerSample) float64 { return float64(s.Stats.MemoryUsage) }},
+		{"celeste_container_memory_cache_bytes", "Container page cache", typeGauge,
+			func(s stats.ContainerSample) float64 { return float64(s.Stats.MemoryCache) }},
+		{"celeste_container_memory_limit_bytes", "Container memory limit", typeGauge,
+			func(s stats.ContainerSample) float64 { return float64(s.Stats.MemoryLimit) }},
+		{"celeste_container_block_read_bytes_total", "Bytes read from block devices", typeCounter,
+			func(s stats.ContainerSample) float64 { return float64(s.Stats.BlockRead) }},
+		{"celeste_container_block_write_bytes_total", "Bytes written to block devices", typeCounter,
+			func(s stats.ContainerSample) float64 { return float64(s.Stats.BlockWrite) }},
+		{"celeste_container_pids", "Processes and threads in the container", typeGauge,
+			func(s stats.ContainerSample) float64 { return float64(s.Stats.PIDs) }},
+	}
+	for _, m := range metrics {
+		out.family(m.name, m.help, m.metricType)
+		for i, sample := range samples {
+			out.sample(m.name, labels[i], m.value(sample))
+		}
+	}
+
+	// Network counters are per interface
+	network := []struct {
+		name  string
+		help  string
+		value func(s stats.ContainerSample, iface string) float64
+	}{
+		{"celeste_container_network_receive_bytes_total", "Bytes received by the container", func(s stats.ContainerSample, iface string) float64 {
+			return float64(s.Stats.Networks[iface].RxBytes)
+		}},
+		{"celeste_container_network_transmit_bytes_total", "Bytes sent by the container", func(s stats.ContainerSample, iface string) float64 {
+			return float64(s.Stats.Networks[iface].TxBytes)
+		}},
+		{"celeste_container_network_receive_errors_total", "Receive errors of the container", func(s stats.ContainerSample, iface string) float64 {
+			return float64(s.Stats.Networks[iface].RxErrors)
+		}},
+		{"celeste_container_network_transmit_errors_total", "Transmit errors of the container", func(s stats.ContainerSample, iface string) float64 {
+			return float64(s.Stats.Networks[iface].TxErrors)
+		}},
+	}
+	for _, m := range network {
+		out.family(m.name, m.help, typeCounter)
+		for i, sample := range samples {
+			ifaces := make([]string, 0, len(sample.Stats.Networks))
+			for iface := range sample.Stats.Networks {
+				ifaces = append(ifaces, iface)
+			}
+			sort.Strings(ifaces)
+			for _, iface := range ifaces {
+				out.sample(m.name, labels[i].with("interface", iface), m.value(sample, iface))
+			}
+		}
+	}
+}
+
+func (e *Exporter) writeStacks(out *expositionWriter) {
+	stacks, err := e.stackProvider.ListStacks()
+	if err != nil {
+		log.Printf("Error listing stacks for metrics: %v", err)
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, s := range stacks {
+		counts[s.Status]++
+	}
+
+	out.family("celeste_stacks", "Number of stacks by status", typeGauge)
+	for _, status := range stackStatuses {
+		out.sample("celeste_stacks", Labels{"status": status}, float64(counts[status]))
+	}
+}
+
+func (e *Exporter) writeServer(out *expositionWriter) {
+	e.http.write(out)
+
+	out.family("celeste_websocket_clients", "Connected WebSocket clients", typeGauge)
+	out.sample("celeste_websocket_clients", nil, float64(e.hub.ClientCount()))
+
+	out.family("celeste_websocket_dropped_clients_total", "WebSocket clients disconnected because they could not keep up with broadcasts", typeCounter)
+	out.sample("celeste_websocket_dropped_clients_total", nil, float64(e.hub.DroppedClients()))
+}
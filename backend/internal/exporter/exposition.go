@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metric types of the Prometheus text exposition format
+const (
+	typeGauge     = "gauge"
+	typeCounter   = "counter"
+	typeHistogram = "histogram"
+)
+
+// Labels are the label names and values of a sample
+type Labels map[string]string
+
+// expositionWriter writes metric families in the Prometheus text format,
+// version 0.0.4
+type expositionWriter struct {
+	w *bufio.Writer
+}
+
+func newExpositionWriter(w io.Writer) *expositionWriter {
+	return &expositionWriter{w: bufio.NewWriter(w)}
+}
+
+// family starts a metric family; its samples must follow
+func (e *expositionWriter) family(name, help, metricType string) {
+	e.w.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	e.w.WriteString("# TYPE " + name + " " + metricType + "\n")
+}
+
+func (e *expositionWriter) sample(name string, labels Labels, value float64) {
+	e.w.WriteString(name)
+	if len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for key := range labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		e.w.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				e.w.WriteByte(',')
+			}
+			e.w.WriteString(key + `="` + escapeLabel(labels[key]) + `"`)
+		}
+		e.w.WriteByte('}')
+	}
+	e.w.WriteByte(' ')
+	e.w.WriteString(formatValue(value))
+	e.w.WriteByte('\n')
+}
+
+func (e *expositionWriter) flush() error {
+	return e.w.Flush()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// with returns a copy of labels with one more label set
+func (l Labels) with(name, value string) Labels {
+	labels := make(Labels, len(l)+1)
+	for k, v := range l {
+		labels[k] = v
+	}
+	labels[name] = value
+	return labels
+}
@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestExpositionWriter(t *testing.T) {
+	var buf strings.Builder
+	out := newExpositionWriter(&buf)
+
+	out.family("celeste_test_info", "Help with a \\ backslash\nand a newline", typeGauge)
+	out.sample("celeste_test_info", nil, 1)
+	out.sample("celeste_test_info", Labels{"version": "1.2.3"}, 0.5)
+
+	out.family("celeste_test_total", `Quotes "stay" as they are in help`, typeCounter)
+	out.sample("celeste_test_total", Labels{"path": `C:\data`, "name": `say "hi"`, "note": "two\nlines"}, 1234567890123)
+	out.sample("celeste_test_total", Labels{"value": "inf"}, math.Inf(1))
+	out.sample("celeste_test_total", Labels{"value": "-inf"}, math.Inf(-1))
+	out.sample("celeste_test_total", Labels{"value": "nan"}, math.NaN())
+	out.sample("celeste_test_total", Labels{"value": "small"}, 0.000125)
+
+	if err := out.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP celeste_test_info Help with a \\ backslash\nand a newline
+# TYPE celeste_test_info gauge
+celeste_test_info 1
+celeste_test_info{version="1.2.3"} 0.5
+# HELP celeste_test_total Quotes "stay" as they are in help
+# TYPE celeste_test_total counter
+celeste_test_total{name="say \"hi\"",note="two\nlines",path="C:\\data"} 1.234567890123e+12
+celeste_test_total{value="inf"} +Inf
+celeste_test_total{value="-inf"} -Inf
+celeste_test_total{value="nan"} NaN
+celeste_test_total{value="small"} 0.000125
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package exporter
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram buckets
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	method string
+	route  string
+	status string
+}
+
+type histogram struct {
+	// counts holds the observations per bucket, not yet cumulative
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// httpMetrics records request latencies per method, route and status
+type httpMetrics struct {
+	mu       sync.Mutex
+	requests map[requestKey]*histogram
+}
+
+func (m *httpMetrics) observe(method, route string, status int, duration time.Duration) {
+	key := requestKey{method: method, route: route, status: strconv.Itoa(status)}
+	seconds := duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.requests == nil {
+		m.requests = make(map[requestKey]*histogram)
+	}
+	h, ok := m.requests[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.requests[key] = h
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (m *httpMetrics) write(e *expositionWriter) {
+	const name = "celeste_http_request_duration_seconds"
+	e.family(name, "Latency of HTTP requests, excluding WebSocket and event streams", typeHistogram)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+
+	for _, key := range keys {
+		h := m.requests[key]
+		labels := Labels{"method": key.method, "route": key.route, "status": key.status}
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += h.counts[i]
+			e.sample(name+"_bucket", labels.with("le", formatValue(bound)), float64(cumulative))
+		}
+		e.sample(name+"_bucket", labels.with("le", "+Inf"), float64(h.count))
+		e.sample(name+"_sum", labels, h.sum)
+		e.sample(name+"_count", labels, float64(h.count))
+	}
+}
@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPMetricsHistogram(t *testing.T) {
+	var m httpMetrics
+	m.observe("GET", "/api/stacks", 200, 3*time.Millisecond)
+	m.observe("GET", "/api/stacks", 200, 5*time.Millisecond)
+	m.observe("GET", "/api/stacks", 200, 200*time.Millisecond)
+	m.observe("GET", "/api/stacks", 200, 30*time.Second)
+	m.observe("POST", "/api/auth/login", 401, 75*time.Millisecond)
+
+	var buf strings.Builder
+	out := newExpositionWriter(&buf)
+	m.write(out)
+	if err := out.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Buckets are cumulative; observations above the last bound only count
+	// towards +Inf
+	want := `# HELP celeste_http_request_duration_seconds Latency of HTTP requests, excluding WebSocket and event streams
+# TYPE celeste_http_request_duration_seconds histogram
+celeste_http_request_duration_seconds_bucket{le="0.005",method="POST",route="/api/auth/login",status="401"} 0
+celeste_http_request_duration_seconds_bucket{le="0.01",method="POST",route="/api/auth/login",status="401"} 0
+celeste_http_request_duration_seconds_bucket{le="0.025",method="POST",route="/api/auth/login",status="401"} 0
+celeste_http_request_duration_seconds_bucket{le="0.05",method="POST",route="/api/auth/login",status="401"} 0
+celeste_http_request_duration_seconds_bucket{le="0.1",method="POST",route="/api/auth/login",status="401"} 1
+celeste_http_request_duration_seconds_bucket{le="0.25",method="POST",route="/api/auth/login",status="401"} 1
+celeste_http_request_duration_seconds_bucket{le="0.5",method="POST",route="/api/auth/login",status="401"} 1
+celeste_http_request_duration_seconds_bucket{le="1",method="POST",route="/api/auth/login",status="401"} 1
+celeste_http_request_duration_seconds_bucket{le="2.5",method="POST",route="/api/auth/login",status="401"} 1
+celeste_http_request_duration_seconds_bucket{le="5",method="POST",route="/api/auth/login",status="401"} 1
+celeste_http_request_duration_seconds_bucket{le="10",method="POST",route="/api/auth/login",status="401"} 1
+celeste_http_request_duration_seconds_bucket{le="+Inf",method="POST",route="/api/auth/login",status="401"} 1
+celeste_http_request_duration_seconds_sum{method="POST",route="/api/auth/login",status="401"} 0.075
+celeste_http_request_duration_seconds_count{method="POST",route="/api/auth/login",status="401"} 1
+celeste_http_request_duration_seconds_bucket{le="0.005",method="GET",route="/api/stacks",status="200"} 2
+celeste_http_request_duration_seconds_bucket{le="0.01",method="GET",route="/api/stacks",status="200"} 2
+celeste_http_request_duration_seconds_bucket{le="0.025",method="GET",route="/api/stacks",status="200"} 2
+celeste_http_request_duration_seconds_bucket{le="0.05",method="GET",route="/api/stacks",status="200"} 2
+celeste_http_request_duration_seconds_bucket{le="0.1",method="GET",route="/api/stacks",status="200"} 2
+celeste_http_request_duration_seconds_bucket{le="0.25",method="GET",route="/api/stacks",status="200"} 3
+celeste_http_request_duration_seconds_bucket{le="0.5",method="GET",route="/api/stacks",status="200"} 3
+celeste_http_request_duration_seconds_bucket{le="1",method="GET",route="/api/stacks",status="200"} 3
+celeste_http_request_duration_seconds_bucket{le="2.5",method="GET",route="/api/stacks",status="200"} 3
+celeste_http_request_duration_seconds_bucket{le="5",method="GET",route="/api/stacks",status="200"} 3
+celeste_http_request_duration_seconds_bucket{le="10",method="GET",route="/api/stacks",status="200"} 3
+celeste_http_request_duration_seconds_bucket{le="+Inf",method="GET",route="/api/stacks",status="200"} 4
+celeste_http_request_duration_seconds_sum{method="GET",route="/api/stacks",status="200"} 30.208
+celeste_http_request_duration_seconds_count{method="GET",route="/api/stacks",status="200"} 4
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
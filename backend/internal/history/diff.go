@@ -0,0 +1,223 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change
+const contextLines = 3
+
+type editKind byte
+
+const (
+	editEqual  editKind = ' '
+	editDelete editKind = '-'
+	editInsert editKind = '+'
+)
+
+// edit is one line of a diff script. a and b are the 0-based positions in
+// the old and new text at which the edit applies.
+type edit struct {
+	kind editKind
+	a, b int
+}
+
+// Diff returns a unified diff turning from into to, labelled with the given
+// file names. It returns an empty string if the texts are identical.
+func Diff(from string, to string, fromLabel string, toLabel string) string {
+	a, b := splitLines(from), splitLines(to)
+	edits := diffLines(a, b)
+
+	var out strings.Builder
+	for _, hunk := range hunks(edits) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromLabel, toLabel)
+		}
+
+		aStart, bStart := hunk[0].a, hunk[0].b
+		aLen, bLen := 0, 0
+		for _, e := range hunk {
+			if e.kind != editInsert {
+				aLen++
+			}
+			if e.kind != editDelete {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+
+		for _, e := range hunk {
+			line := ""
+			if e.kind == editInsert {
+				line = b[e.b]
+			} else {
+				line = a[e.a]
+			}
+			out.WriteByte(byte(e.kind))
+			out.WriteString(line)
+			out.WriteByte('\n')
+		}
+	}
+	return out.String()
+}
+
+func hunkRange(start int, length int) string {
+	// Empty ranges are reported at the line before the change
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// hunks groups an edit script into runs of changes with surrounding context,
+// merging changes separated by fewer than twice the context
+func hunks(edits []edit) [][]edit {
+	result := make([][]edit, 0)
+	start, end := -1, -1
+	for i, e := range edits {
+		if e.kind == editEqual {
+			continue
+		}
+		lo, hi := max(i-contextLines, 0), min(i+contextLines+1, len(edits))
+		if start >= 0 && lo <= end {
+			end = hi
+			continue
+		}
+		if start >= 0 {
+			result = append(result, edits[start:end])
+		}
+		start, end = lo, hi
+	}
+	if start >= 0 {
+		result = append(result, edits[start:end])
+	}
+	return result
+}
+
+// diffLines computes a shortest edit script between a and b using the
+// linear space variant of Myers' algorithm, which splits the problem at the
+// middle snake of an optimal path instead of keeping every round's state
+func diffLines(a []string, b []string) []edit {
+	size := 2*(len(a)+len(b)) + 3
+	d := &differ{
+		a:      a,
+		b:      b,
+		fwd:    make([]int, size),
+		rev:    make([]int, size),
+		offset: len(a) + len(b) + 1,
+		edits:  make([]edit, 0, len(a)+len(b)),
+	}
+	d.compare(0, len(a), 0, len(b))
+	return d.edits
+}
+
+type differ struct {
+	a, b     []string
+	fwd, rev []int
+	offset   int
+	edits    []edit
+}
+
+// compare appends the edits turning a[aLo:aHi] into b[bLo:bHi]
+func (d *differ) compare(aLo int, aHi int, bLo int, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.edits = append(d.edits, edit{kind: editEqual, a: aLo, b: bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+		suffix++
+	}
+
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			d.edits = append(d.edits, edit{kind: editInsert, a: aLo, b: y})
+		}
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			d.edits = append(d.edits, edit{kind: editDelete, a: x, b: bLo})
+		}
+	default:
+		// The first half ends with the snake's leading edit, the second half
+		// starts after the snake
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for i := 0; i < u-x; i++ {
+			d.edits = append(d.edits, edit{kind: editEqual, a: x + i, b: y + i})
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.edits = append(d.edits, edit{kind: editEqual, a: aHi + i, b: bHi + i})
+	}
+}
+
+// middleSnake searches forwards from the start and backwards from the end
+// of a[aLo:aHi] and b[bLo:bHi] at once, returning the start (x, y) and end
+// (u, v) of the diagonal run where the two searches meet
+func (d *differ) middleSnake(aLo int, aHi int, bLo int, bHi int) (int, int, int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	fwd, rev, offset := d.fwd, d.rev, d.offset
+	fwd[offset+1], rev[offset+1] = 0, 0
+
+	// fwd holds the furthest x reached on each diagonal k = x - y; rev holds
+	// the same counted from the end, on diagonals of the reversed texts
+	// The searches always meet by depth (n+m+1)/2
+	for depth := 0; ; depth++ {
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && fwd[offset+k-1] < fwd[offset+k+1]) {
+				x = fwd[offset+k+1]
+			} else {
+				x = fwd[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			fwd[offset+k] = x
+			if odd && delta-k >= -(depth-1) && delta-k <= depth-1 && x+rev[offset+delta-k] >= n {
+				return aLo + startX, bLo + startY, aLo + x, bLo + y
+			}
+		}
+
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && rev[offset+k-1] < rev[offset+k+1]) {
+				x = rev[offset+k+1]
+			} else {
+				x = rev[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			rev[offset+k] = x
+			if !odd && delta-k >= -depth && delta-k <= depth && x+fwd[offset+delta-k] >= n {
+				return aHi - x, bHi - y, aHi - startX, bHi - startY
+			}
+		}
+	}
+}
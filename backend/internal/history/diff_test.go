@@ -0,0 +1,153 @@
+package history
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// applyEdits checks that edits is a valid script from a to b and returns
+// the number of unchanged lines
+func applyEdits(t *testing.T, a []string, b []string, edits []edit) int {
+	t.Helper()
+	x, y, equal := 0, 0, 0
+	for _, e := range edits {
+		if e.a != x || e.b != y {
+			t.Fatalf("edit %c at (%d,%d), expected (%d,%d)", e.kind, e.a, e.b, x, y)
+		}
+		switch e.kind {
+		case editEqual:
+			if a[x] != b[y] {
+				t.Fatalf("equal edit on %q and %q", a[x], b[y])
+			}
+			x++
+			y++
+			equal++
+		case editDelete:
+			x++
+		case editInsert:
+			y++
+		}
+	}
+	if x != len(a) || y != len(b) {
+		t.Fatalf("script ends at (%d,%d), want (%d,%d)", x, y, len(a), len(b))
+	}
+	return equal
+}
+
+// lcsLength is the reference length of the longest common subsequence
+func lcsLength(a []string, b []string) int {
+	row := make([]int, len(b)+1)
+	for i := len(a) - 1; i >= 0; i-- {
+		diag := 0
+		for j := len(b) - 1; j >= 0; j-- {
+			next := row[j]
+			if a[i] == b[j] {
+				row[j] = diag + 1
+			} else {
+				row[j] = max(row[j], row[j+1])
+			}
+			diag = next
+		}
+	}
+	return row[0]
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"both empty", "", ""},
+		{"identical", "a b c", "a b c"},
+		{"all inserted", "", "a b c"},
+		{"all deleted", "a b c", ""},
+		{"replace middle", "a b c", "a x c"},
+		{"insert front", "b c", "a b c"},
+		{"delete end", "a b c", "a b"},
+		{"reordered", "a b c d", "d c b a"},
+		{"repeated lines", "a a b a a", "a b a b a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			equal := applyEdits(t, a, b, diffLines(a, b))
+			if want := lcsLength(a, b); equal != want {
+				t.Errorf("diff keeps %d lines, want %d", equal, want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		result := make([]string, r.Intn(20))
+		for i := range result {
+			result[i] = string(rune('a' + r.Intn(4)))
+		}
+		return result
+	}
+	for i := 0; i < 2000; i++ {
+		a, b := lines(), lines()
+		if equal, want := applyEdits(t, a, b, diffLines(a, b)), lcsLength(a, b); equal != want {
+			t.Fatalf("diff of %v and %v keeps %d lines, want %d", a, b, equal, want)
+		}
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	// Completely different inputs are the worst case for the edit distance
+	a := make([]string, 5000)
+	b := make([]string, 5000)
+	for i := range a {
+		a[i] = fmt.Sprintf("a%d", i)
+		b[i] = fmt.Sprintf("b%d", i)
+	}
+	if equal := applyEdits(t, a, b, diffLines(a, b)); equal != 0 {
+		t.Errorf("diff keeps %d lines, want 0", equal)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := "services:\n  web:\n    image: nginx:1.25\n    ports:\n      - 80:80\n"
+	to := "services:\n  web:\n    image: nginx:1.27\n    ports:\n      - 80:80\n"
+	want := `--- revision 1
++++ current
+@@ -1,5 +1,5 @@
+ services:
+   web:
+-    image: nginx:1.25
++    image: nginx:1.27
+     ports:
+       - 80:80
+`
+	if got := Diff(from, to, "revision 1", "current"); got != want {
+		t.Errorf("Diff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := Diff(from, from, "a", "b"); got != "" {
+		t.Errorf("Diff() of identical texts = %q, want empty", got)
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	from := strings.Join(lines, "\n") + "\n"
+	lines[1] = "changed 2"
+	lines[17] = "changed 18"
+	to := strings.Join(lines, "\n") + "\n"
+
+	got := Diff(from, to, "a", "b")
+	for _, header := range []string{"@@ -1,5 +1,5 @@", "@@ -15,6 +15,6 @@"} {
+		if !strings.Contains(got, header) {
+			t.Errorf("Diff() missing hunk %q:\n%s", header, got)
+		}
+	}
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Errorf("Diff() has %d hunks, want 2", n)
+	}
+}
@@ -0,0 +1,233 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"aperture-science-network/internal/fsutil"
+)
+
+const (
+	indexFile = "index.json"
+
+	// DefaultMaxRevisions is the number of revisions kept per stack
+	DefaultMaxRevisions = 100
+)
+
+// FileStore implements Store with one directory per stack holding an index
+// of revisions and one file per revision's content
+type FileStore struct {
+	dir          string
+	maxRevisions int
+	mu           sync.Mutex
+}
+
+// NewFileStore creates a revision store rooted at dir
+func NewFileStore(dir string, maxRevisions int) (*FileStore, error) {
+	if maxRevisions <= 0 {
+		maxRevisions = DefaultMaxRevisions
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, maxRevisions: maxRevisions}, nil
+}
+
+// Ensure FileStore implements Store
+var _ Store = (*FileStore)(nil)
+
+func (s *FileStore) Record(stack string, content string, author string, message string) (*Revision, error) {
+	dir, err := s.stackDir(stack)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisions, err := s.loadLocked(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashContent(content)
+	if len(revisions) > 0 && revisions[len(revisions)-1].Hash == hash {
+		latest := revisions[len(revisions)-1]
+		return &latest, nil
+	}
+
+	id := 1
+	if len(revisions) > 0 {
+		id = revisions[len(revisions)-1].ID + 1
+	}
+	rev := Revision{
+		ID:      id,
+		Stack:   stack,
+		Author:  author,
+		Message: message,
+		Time:    time.Now().UTC(),
+		Size:    len(content),
+		Hash:    hash,
+	}
+
+	if err := fsutil.WriteFileAtomic(revisionPath(dir, id), []byte(content), 0600); err != nil {
+		return nil, err
+	}
+
+	revisions = append(revisions, rev)
+	var expired []Revision
+	if len(revisions) > s.maxRevisions {
+		expired = revisions[:len(revisions)-s.maxRevisions]
+		revisions = revisions[len(revisions)-s.maxRevisions:]
+	}
+	if err := s.saveLocked(dir, revisions); err != nil {
+		os.Remove(revisionPath(dir, id))
+		return nil, err
+	}
+	for _, old := range expired {
+		os.Remove(revisionPath(dir, old.ID))
+	}
+
+	return &rev, nil
+}
+
+func (s *FileStore) List(stack string) ([]Revision, error) {
+	dir, err := s.stackDir(stack)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisions, err := s.loadLocked(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Revision, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		result = append(result, revisions[i])
+	}
+	return result, nil
+}
+
+func (s *FileStore) Get(stack string, id int) (*Revision, string, error) {
+	dir, err := s.stackDir(stack)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisions, err := s.loadLocked(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, rev := range revisions {
+		if rev.ID == id {
+			content, err := os.ReadFile(revisionPath(dir, id))
+			if err != nil {
+				return nil, "", err
+			}
+			return &rev, string(content), nil
+		}
+	}
+	return nil, "", ErrRevisionNotFound
+}
+
+func (s *FileStore) Rename(stack string, newName string) error {
+	dir, err := s.stackDir(stack)
+	if err != nil {
+		return err
+	}
+	newDir, err := s.stackDir(newName)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisions, err := s.loadLocked(dir)
+	if err != nil {
+		return err
+	}
+
+	// History left behind by an earlier stack of the same name is replaced
+	if err := os.RemoveAll(newDir); err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		return os.RemoveAll(dir)
+	}
+	if err := os.Rename(dir, newDir); err != nil {
+		return err
+	}
+	for i := range revisions {
+		revisions[i].Stack = newName
+	}
+	return s.saveLocked(newDir, revisions)
+}
+
+func (s *FileStore) Delete(stack string) error {
+	dir, err := s.stackDir(stack)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return os.RemoveAll(dir)
+}
+
+func (s *FileStore) stackDir(stack string) (string, error) {
+	if stack == "" || stack == "." || stack == ".." || strings.ContainsAny(stack, `/\`) {
+		return "", fmt.Errorf("invalid stack name: %q", stack)
+	}
+	return filepath.Join(s.dir, stack), nil
+}
+
+// loadLocked reads a stack's revision index, oldest first. Callers must hold mu.
+func (s *FileStore) loadLocked(dir string) ([]Revision, error) {
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Revision{}, nil
+		}
+		return nil, err
+	}
+
+	var revisions []Revision
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// saveLocked atomically rewrites a stack's revision index. Callers must hold mu.
+func (s *FileStore) saveLocked(dir string, revisions []Revision) error {
+	data, err := json.MarshalIndent(revisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(filepath.Join(dir, indexFile), data, 0600)
+}
+
+func revisionPath(dir string, id int) string {
+	return filepath.Join(dir, fmt.Sprintf("%06d.yml", id))
+}
+
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
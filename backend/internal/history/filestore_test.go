@@ -0,0 +1,90 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreRecord(t *testing.T) {
+	store, err := NewFileStore(t.TempDir(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := store.Record("web", "a", "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, err := store.Record("web", "a", "bob", "again")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.ID != first.ID {
+		t.Errorf("identical content recorded as revision %d, want %d", same.ID, first.ID)
+	}
+
+	store.Record("web", "b", "alice", "")
+	store.Record("web", "c", "alice", "")
+	revisions, err := store.List("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].ID != 3 || revisions[1].ID != 2 {
+		t.Fatalf("List() = %+v, want revisions 3 and 2", revisions)
+	}
+	if _, _, err := store.Get("web", 1); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("Get() of an expired revision = %v, want %v", err, ErrRevisionNotFound)
+	}
+	if _, content, err := store.Get("web", 3); err != nil || content != "c" {
+		t.Errorf("Get() = %q, %v, want \"c\"", content, err)
+	}
+}
+
+func TestFileStoreRenameAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A stack without history leaves nothing behind
+	if err := store.Rename("empty", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "renamed")); !os.IsNotExist(err) {
+		t.Errorf("Rename() of a stack without history created its directory")
+	}
+
+	store.Record("web", "a", "alice", "")
+	if err := store.Rename("web", "site"); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, _ := store.List("web"); len(revisions) != 0 {
+		t.Errorf("old name still has %d revisions", len(revisions))
+	}
+	rev, content, err := store.Get("site", 1)
+	if err != nil || content != "a" || rev.Stack != "site" {
+		t.Fatalf("Get() after rename = %+v, %q, %v", rev, content, err)
+	}
+
+	if err := store.Delete("site"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "site")); !os.IsNotExist(err) {
+		t.Errorf("Delete() left the history directory behind")
+	}
+}
+
+func TestFileStoreInvalidStack(t *testing.T) {
+	store, err := NewFileStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`} {
+		if _, err := store.Record(name, "a", "alice", ""); err == nil {
+			t.Errorf("Record(%q) succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package history
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Revision describes one saved version of a stack's compose file
+type Revision struct {
+	ID      int       `json:"id"`
+	Stack   string    `json:"stack"`
+	Author  string    `json:"author"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+	Size    int       `json:"size"`
+	Hash    string    `json:"hash"`
+}
+
+// Store defines the interface for compose file revision storage
+type Store interface {
+	// Record saves content as the newest revision of a stack. If content is
+	// identical to the newest revision, that revision is returned instead.
+	Record(stack string, content string, author string, message string) (*Revision, error)
+
+	// List returns a stack's revisions, newest first
+	List(stack string) ([]Revision, error)
+
+	// Get returns a revision and its content
+	Get(stack string, id int) (*Revision, string, error)
+
+	// Rename moves a stack's history to a new stack name
+	Rename(stack string, newName string) error
+
+	// Delete removes a stack's history
+	Delete(stack string) error
+}
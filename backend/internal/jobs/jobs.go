@@ -0,0 +1,410 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job has already finished")
+	errCanceled    = errors.New("canceled")
+)
+
+// Status is the lifecycle state of a job
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+const (
+	// DefaultWorkers is the default number of jobs that may run at once
+	DefaultWorkers = 4
+	// maxLines is the number of output lines retained per job for replay
+	maxLines = 5000
+	// maxHistory is the number of finished jobs retained
+	maxHistory = 200
+)
+
+// Line is a single line of job output. Seq increases monotonically per job
+// so clients can merge replayed and live output.
+type Line struct {
+	Seq    int       `json:"seq"`
+	Stream string    `json:"stream"`
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time"`
+}
+
+// Job is a snapshot of a background operation
+type Job struct {
+	ID         string            `json:"id"`
+	Stack      string            `json:"stack"`
+	Action     string            `json:"action"`
+	User       string            `json:"user"`
+	Params     map[string]string `json:"params,omitempty"`
+	Status     Status            `json:"status"`
+	Error      string            `json:"error,omitempty"`
+	ExitCode   *int              `json:"exitCode,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	StartedAt  *time.Time        `json:"startedAt,omitempty"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+}
+
+// Finished reports whether the job has reached a terminal state
+func (j *Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCanceled
+}
+
+// EventKind distinguishes job notifications
+type EventKind string
+
+const (
+	EventStatus EventKind = "status"
+	EventOutput EventKind = "output"
+)
+
+// Event notifies subscribers of a status change or a new output line
+type Event struct {
+	Kind EventKind
+	Job  Job
+	Line *Line
+}
+
+// RunFunc performs the work of a job, reporting output line by line
+type RunFunc func(ctx context.Context, output func(stream string, line string)) error
+
+// Spec describes a job to enqueue
+type Spec struct {
+	// Stack is the stack the job operates on. Jobs for the same stack run
+	// one at a time, in the order they were enqueued.
+	Stack  string
+	Action string
+	User   string
+	// Params holds details of the operation worth recording, such as the
+	// target name of a rename
+	Params  map[string]string
+	Timeout time.Duration
+	Run     RunFunc
+}
+
+type job struct {
+	mu       sync.Mutex
+	info     Job
+	spec     Spec
+	lines    []Line
+	nextSeq  int
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+// Filter selects jobs in List. Zero values match everything.
+type Filter struct {
+	Stack  string
+	Status Status
+}
+
+// Manager queues jobs, running at most one per stack and at most a fixed
+// number overall, and retains their output
+type Manager struct {
+	mu          sync.RWMutex
+	workers     int
+	running     int
+	jobs        map[string]*job
+	pending     []*job
+	busy        map[string]bool
+	finished    []string
+	subscribers []func(Event)
+}
+
+// NewManager creates a job manager running at most workers jobs at once
+func NewManager(workers int) *Manager {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Manager{
+		workers: workers,
+		jobs:    make(map[string]*job),
+		busy:    make(map[string]bool),
+	}
+}
+
+// Subscribe registers a callback for job events
+func (m *Manager) Subscribe(fn func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Enqueue adds a job to the queue and returns it immediately
+func (m *Manager) Enqueue(spec Spec) Job {
+	j := &job{
+		info: Job{
+			ID:        newID(),
+			Stack:     spec.Stack,
+			Action:    spec.Action,
+			User:      spec.User,
+			Params:    spec.Params,
+			Status:    StatusQueued,
+			CreatedAt: time.Now().UTC(),
+		},
+		spec:    spec,
+		nextSeq: 1,
+	}
+
+	m.mu.Lock()
+	m.jobs[j.info.ID] = j
+	m.pending = append(m.pending, j)
+	m.mu.Unlock()
+
+	m.publish(Event{Kind: EventStatus, Job: j.snapshot()})
+
+	m.dispatch()
+	return j.snapshot()
+}
+
+// Get returns a job by ID
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.RLock()
+	j, ok := m.jobs[id]
+	m.mu.RUnlock()
+	if !ok {
+		return Job{}, false
+	}
+	return j.snapshot(), true
+}
+
+// List returns retained jobs matching filter, newest first
+func (m *Manager) List(filter Filter) []Job {
+	m.mu.RLock()
+	all := make([]*job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		all = append(all, j)
+	}
+	m.mu.RUnlock()
+
+	result := make([]Job, 0, len(all))
+	for _, j := range all {
+		info := j.snapshot()
+		if filter.Stack != "" && info.Stack != filter.Stack {
+			continue
+		}
+		if filter.Status != "" && info.Status != filter.Status {
+			continue
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, k int) bool { return result[i].CreatedAt.After(result[k].CreatedAt) })
+	return result
+}
+
+// Output returns the retained output lines of a job with Seq > after
+func (m *Manager) Output(id string, after int) ([]Line, bool) {
+	m.mu.RLock()
+	j, ok := m.jobs[id]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	lines := make([]Line, 0)
+	for _, l := range j.lines {
+		if l.Seq > after {
+			lines = append(lines, l)
+		}
+	}
+	return lines, true
+}
+
+// Cancel removes a queued job or stops a running one. Running compose
+// processes are killed through their context.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, ErrJobNotFound
+	}
+
+	j.mu.Lock()
+	switch j.info.Status {
+	case StatusQueued:
+		now := time.Now().UTC()
+		j.info.Status = StatusCanceled
+		j.info.Error = errCanceled.Error()
+		j.info.FinishedAt = &now
+		j.mu.Unlock()
+
+		for i, p := range m.pending {
+			if p == j {
+				m.pending = append(m.pending[:i], m.pending[i+1:]...)
+				break
+			}
+		}
+		m.retireLocked(id)
+		m.mu.Unlock()
+
+		snapshot := j.snapshot()
+		m.publish(Event{Kind: EventStatus, Job: snapshot})
+		return snapshot, nil
+
+	case StatusRunning:
+		j.canceled = true
+		cancel := j.cancel
+		j.mu.Unlock()
+		m.mu.Unlock()
+
+		cancel()
+		return j.snapshot(), nil
+
+	default:
+		j.mu.Unlock()
+		m.mu.Unlock()
+		return j.snapshot(), ErrJobFinished
+	}
+}
+
+// dispatch starts pending jobs, oldest first, while workers are free and
+// their stack has no running job
+func (m *Manager) dispatch() {
+	type start struct {
+		job    *job
+		ctx    context.Context
+		cancel context.CancelFunc
+	}
+
+	m.mu.Lock()
+	started := make([]start, 0)
+	remaining := m.pending[:0]
+	for _, j := range m.pending {
+		if m.running < m.workers && !m.busy[j.info.Stack] {
+			m.running++
+			m.busy[j.info.Stack] = true
+
+			// Mark the job running while it is dequeued, so Cancel never
+			// sees it queued once it has left the queue
+			ctx, cancel := context.WithTimeout(context.Background(), j.spec.Timeout)
+			j.mu.Lock()
+			now := time.Now().UTC()
+			j.info.Status = StatusRunning
+			j.info.StartedAt = &now
+			j.cancel = cancel
+			j.mu.Unlock()
+
+			started = append(started, start{job: j, ctx: ctx, cancel: cancel})
+			continue
+		}
+		remaining = append(remaining, j)
+	}
+	m.pending = remaining
+	m.mu.Unlock()
+
+	for _, st := range started {
+		m.publish(Event{Kind: EventStatus, Job: st.job.snapshot()})
+		go m.execute(st.ctx, st.cancel, st.job)
+	}
+}
+
+func (m *Manager) execute(ctx context.Context, cancel context.CancelFunc, j *job) {
+	defer cancel()
+
+	err := j.spec.Run(ctx, func(stream string, text string) {
+		line := j.append(stream, text)
+		m.publish(Event{Kind: EventOutput, Job: j.snapshot(), Line: &line})
+	})
+
+	j.mu.Lock()
+	finished := time.Now().UTC()
+	j.info.FinishedAt = &finished
+	exitCode := 0
+	switch {
+	case err == nil:
+		j.info.Status = StatusSucceeded
+	case j.canceled:
+		j.info.Status = StatusCanceled
+		j.info.Error = errCanceled.Error()
+		exitCode = -1
+	default:
+		j.info.Status = StatusFailed
+		j.info.Error = err.Error()
+		exitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+	}
+	j.info.ExitCode = &exitCode
+	j.mu.Unlock()
+
+	m.mu.Lock()
+	m.running--
+	delete(m.busy, j.info.Stack)
+	m.retireLocked(j.info.ID)
+	m.mu.Unlock()
+
+	m.publish(Event{Kind: EventStatus, Job: j.snapshot()})
+	m.dispatch()
+}
+
+// retireLocked records a finished job and drops the oldest beyond
+// maxHistory. Callers must hold mu.
+func (m *Manager) retireLocked(id string) {
+	m.finished = append(m.finished, id)
+	for len(m.finished) > maxHistory {
+		delete(m.jobs, m.finished[0])
+		m.finished = m.finished[1:]
+	}
+}
+
+func (m *Manager) publish(e Event) {
+	m.mu.RLock()
+	subscribers := m.subscribers
+	m.mu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(e)
+	}
+}
+
+func (j *job) append(stream string, text string) Line {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	line := Line{
+		Seq:    j.nextSeq,
+		Stream: stream,
+		Text:   text,
+		Time:   time.Now().UTC(),
+	}
+	j.nextSeq++
+	j.lines = append(j.lines, line)
+	if len(j.lines) > maxLines {
+		j.lines = j.lines[len(j.lines)-maxLines:]
+	}
+	return line
+}
+
+func (j *job) snapshot() Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.info
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,283 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingRun returns a RunFunc that reports on started when it begins and
+// returns once release is closed or its context ends
+func blockingRun(started chan<- string, release <-chan struct{}, name string) RunFunc {
+	return func(ctx context.Context, output func(string, string)) error {
+		started <- name
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// waitStatus polls a job until it reaches status
+func waitStatus(t *testing.T, m *Manager, id string, status Status) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		j, ok := m.Get(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if j.Status == status {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s status = %s, want %s", id, j.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func expectStarted(t *testing.T, started <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-started:
+		if got != want {
+			t.Fatalf("started %s, want %s", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not start", want)
+	}
+}
+
+func expectNotStarted(t *testing.T, started <-chan string) {
+	t.Helper()
+	select {
+	case got := <-started:
+		t.Fatalf("%s started early", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestManagerSerializesStack(t *testing.T) {
+	m := NewManager(4)
+	started := make(chan string, 4)
+	releaseFirst := make(chan struct{})
+	releaseSecond := make(chan struct{})
+
+	first := m.Enqueue(Spec{Stack: "web", Action: "up", Timeout: time.Minute, Run: blockingRun(started, releaseFirst, "first")})
+	second := m.Enqueue(Spec{Stack: "web", Action: "down", Timeout: time.Minute, Run: blockingRun(started, releaseSecond, "second")})
+
+	expectStarted(t, started, "first")
+	expectNotStarted(t, started)
+	if j, _ := m.Get(second.ID); j.Status != StatusQueued {
+		t.Errorf("second job status = %s, want %s", j.Status, StatusQueued)
+	}
+
+	close(releaseFirst)
+	waitStatus(t, m, first.ID, StatusSucceeded)
+	expectStarted(t, started, "second")
+	close(releaseSecond)
+	waitStatus(t, m, second.ID, StatusSucceeded)
+}
+
+func TestManagerWorkerLimit(t *testing.T) {
+	m := NewManager(2)
+	started := make(chan string, 3)
+	release := make(chan struct{})
+
+	var jobs []Job
+	for _, stack := range []string{"a", "b", "c"} {
+		jobs = append(jobs, m.Enqueue(Spec{Stack: stack, Action: "up", Timeout: time.Minute, Run: blockingRun(started, release, stack)}))
+	}
+
+	// Different stacks run side by side up to the worker limit
+	running := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case name := <-started:
+			running[name] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d jobs started, want 2", i)
+		}
+	}
+	if !running["a"] || !running["b"] {
+		t.Fatalf("running = %v, want a and b", running)
+	}
+	expectNotStarted(t, started)
+
+	close(release)
+	expectStarted(t, started, "c")
+	for _, j := range jobs {
+		waitStatus(t, m, j.ID, StatusSucceeded)
+	}
+}
+
+func TestManagerCancelQueued(t *testing.T) {
+	m := NewManager(1)
+	started := make(chan string, 2)
+	release := make(chan struct{})
+
+	running := m.Enqueue(Spec{Stack: "web", Timeout: time.Minute, Run: blockingRun(started, release, "running")})
+	queued := m.Enqueue(Spec{Stack: "web", Timeout: time.Minute, Run: blockingRun(started, release, "queued")})
+	expectStarted(t, started, "running")
+
+	j, err := m.Cancel(queued.ID)
+	if err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if j.Status != StatusCanceled || j.StartedAt != nil {
+		t.Errorf("Cancel() = %+v, want canceled without starting", j)
+	}
+
+	close(release)
+	waitStatus(t, m, running.ID, StatusSucceeded)
+	expectNotStarted(t, started)
+
+	if _, err := m.Cancel(queued.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel() of a finished job error = %v, want %v", err, ErrJobFinished)
+	}
+	if _, err := m.Cancel("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Cancel() of an unknown job error = %v, want %v", err, ErrJobNotFound)
+	}
+}
+
+func TestManagerCancelRunningCommand(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	m := NewManager(1)
+	started := make(chan struct{})
+	j := m.Enqueue(Spec{Stack: "web", Action: "pull", Timeout: time.Minute, Run: func(ctx context.Context, output func(string, string)) error {
+		cmd := exec.CommandContext(ctx, "sleep", "30")
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		close(started)
+		return cmd.Wait()
+	}})
+	<-started
+
+	begin := time.Now()
+	if _, err := m.Cancel(j.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	got := waitStatus(t, m, j.ID, StatusCanceled)
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Errorf("command took %s to stop", elapsed)
+	}
+	if got.Error != errCanceled.Error() || got.ExitCode == nil || *got.ExitCode != -1 {
+		t.Errorf("job = %+v, want canceled with exit code -1", got)
+	}
+}
+
+func TestManagerExitCode(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	tests := []struct {
+		name       string
+		run        RunFunc
+		wantStatus Status
+		wantCode   int
+	}{
+		{
+			name:       "success",
+			run:        func(ctx context.Context, output func(string, string)) error { return nil },
+			wantStatus: StatusSucceeded,
+			wantCode:   0,
+		},
+		{
+			name: "command exit code",
+			run: func(ctx context.Context, output func(string, string)) error {
+				return exec.CommandContext(ctx, "sh", "-c", "exit 3").Run()
+			},
+			wantStatus: StatusFailed,
+			wantCode:   3,
+		},
+		{
+			name: "other error",
+			run: func(ctx context.Context, output func(string, string)) error {
+				return errors.New("compose file not found")
+			},
+			wantStatus: StatusFailed,
+			wantCode:   -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(1)
+			j := m.Enqueue(Spec{Stack: "web", Timeout: time.Minute, Run: tt.run})
+			got := waitStatus(t, m, j.ID, tt.wantStatus)
+			if got.ExitCode == nil || *got.ExitCode != tt.wantCode {
+				t.Errorf("ExitCode = %v, want %d", got.ExitCode, tt.wantCode)
+			}
+			if got.FinishedAt == nil {
+				t.Errorf("FinishedAt not set")
+			}
+
+			// The exit code stays in the history listing
+			listed := m.List(Filter{Stack: "web"})
+			if len(listed) != 1 || listed[0].ExitCode == nil || *listed[0].ExitCode != tt.wantCode {
+				t.Errorf("List() = %+v, want the job with exit code %d", listed, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestManagerOutput(t *testing.T) {
+	m := NewManager(1)
+	var mu sync.Mutex
+	var events []Line
+	m.Subscribe(func(e Event) {
+		if e.Kind == EventOutput {
+			mu.Lock()
+			events = append(events, *e.Line)
+			mu.Unlock()
+		}
+	})
+
+	j := m.Enqueue(Spec{Stack: "web", Timeout: time.Minute, Run: func(ctx context.Context, output func(string, string)) error {
+		output("stdout", "Pulling web")
+		output("stderr", "Pulled")
+		return nil
+	}})
+	waitStatus(t, m, j.ID, StatusSucceeded)
+
+	lines, ok := m.Output(j.ID, 1)
+	if !ok || len(lines) != 1 || lines[0].Seq != 2 || lines[0].Stream != "stderr" || lines[0].Text != "Pulled" {
+		t.Errorf("Output(after 1) = %+v, want the second line", lines)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 || events[0].Seq != 1 {
+		t.Errorf("output events = %+v, want two lines in order", events)
+	}
+}
+
+func TestManagerPrunesHistory(t *testing.T) {
+	m := NewManager(DefaultWorkers)
+	done := func(ctx context.Context, output func(string, string)) error { return nil }
+
+	first := m.Enqueue(Spec{Stack: "web", Timeout: time.Minute, Run: done})
+	waitStatus(t, m, first.ID, StatusSucceeded)
+
+	var last Job
+	for i := 0; i < maxHistory; i++ {
+		last = m.Enqueue(Spec{Stack: "web", Timeout: time.Minute, Run: done})
+	}
+	waitStatus(t, m, last.ID, StatusSucceeded)
+
+	if _, ok := m.Get(first.ID); ok {
+		t.Errorf("oldest job still retained beyond maxHistory")
+	}
+	if got := len(m.List(Filter{})); got != maxHistory {
+		t.Errorf("List() returned %d jobs, want %d", got, maxHistory)
+	}
+}
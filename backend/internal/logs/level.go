@@ -0,0 +1,124 @@
+package logs
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Log levels reported by DetectLevel
+const (
+	LevelTrace = "trace"
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+// levelKeys are the field names holding the level in JSON and logfmt logs
+var levelKeys = []string{"level", "lvl", "severity", "log.level", "loglevel"}
+
+// DetectLevel guesses the level of a log message written as JSON (e.g.
+// {"level":"error"}, or numeric pino/bunyan levels), logfmt (level=error) or
+// plain text starting with a level such as "[WARN]" or "ERROR:". It returns
+// an empty string if no level is found.
+func DetectLevel(message string) string {
+	message = strings.TrimSpace(message)
+	if strings.HasPrefix(message, "{") {
+		if level, ok := jsonLevel(message); ok {
+			return level
+		}
+	}
+	if level := logfmtLevel(message); level != "" {
+		return level
+	}
+	return textLevel(message)
+}
+
+func jsonLevel(message string) (string, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(message), &fields); err != nil {
+		return "", false
+	}
+	for _, key := range levelKeys {
+		switch v := fields[key].(type) {
+		case string:
+			return normalizeLevel(v), true
+		case float64:
+			// pino and bunyan use 10 (trace) to 60 (fatal)
+			return numericLevel(v), true
+		}
+	}
+	return "", true
+}
+
+func logfmtLevel(message string) string {
+	for _, field := range strings.Fields(message) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		for _, levelKey := range levelKeys {
+			if strings.EqualFold(key, levelKey) {
+				return normalizeLevel(strings.Trim(value, `"'`))
+			}
+		}
+	}
+	return ""
+}
+
+// textLevel looks at the first words of a plain text message, after any
+// leading timestamp, for a level name. To avoid matching prose such as
+// "connection error", the name must be upper case, bracketed or followed by
+// a colon.
+func textLevel(message string) string {
+	for i, word := range strings.Fields(message) {
+		if i >= 4 {
+			break
+		}
+		name := strings.Trim(word, "[]()<>:|")
+		marked := strings.ContainsAny(word[:1], "[(<") || strings.HasSuffix(word, ":") || name == strings.ToUpper(name)
+		if !marked {
+			continue
+		}
+		if level := normalizeLevel(name); level != "" {
+			return level
+		}
+	}
+	return ""
+}
+
+func normalizeLevel(value string) string {
+	switch strings.ToLower(value) {
+	case "trace", "trc":
+		return LevelTrace
+	case "debug", "dbg":
+		return LevelDebug
+	case "info", "inf", "information", "notice":
+		return LevelInfo
+	case "warn", "warning", "wrn":
+		return LevelWarn
+	case "error", "err", "eror":
+		return LevelError
+	case "fatal", "ftl", "critical", "crit", "panic", "emerg", "alert":
+		return LevelFatal
+	}
+	return ""
+}
+
+func numericLevel(value float64) string {
+	switch {
+	case value >= 60:
+		return LevelFatal
+	case value >= 50:
+		return LevelError
+	case value >= 40:
+		return LevelWarn
+	case value >= 30:
+		return LevelInfo
+	case value >= 20:
+		return LevelDebug
+	default:
+		return LevelTrace
+	}
+}
@@ -0,0 +1,52 @@
+package logs
+
+import "testing"
+
+func TestDetectLevel(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		// JSON
+		{`{"level":"error","msg":"boom"}`, LevelError},
+		{`{"severity":"WARNING","message":"slow"}`, LevelWarn},
+		{`{"log.level":"debug"}`, LevelDebug},
+		{`{"level":"verbose"}`, ""},
+		{`{"msg":"no level","error":"[ERROR] nested"}`, ""},
+		// pino and bunyan numeric levels
+		{`{"level":10,"msg":"x"}`, LevelTrace},
+		{`{"level":20}`, LevelDebug},
+		{`{"level":30}`, LevelInfo},
+		{`{"level":40}`, LevelWarn},
+		{`{"level":50}`, LevelError},
+		{`{"level":60}`, LevelFatal},
+		// Not JSON after all
+		{`{broken json ERROR: parse`, LevelError},
+		// logfmt
+		{`time=2024-05-01T12:00:00Z level=warn msg="disk almost full"`, LevelWarn},
+		{`ts=1 LVL="ERR" caller=main.go:12`, LevelError},
+		{`t=now severity=critical`, LevelFatal},
+		{`msg="level=error is only in the message"`, ""},
+		// Plain text
+		{"[WARN] Slow response", LevelWarn},
+		{"ERROR: cannot connect", LevelError},
+		{"2024-05-01 12:00:00 INFO Listening on :8080", LevelInfo},
+		{"12:00:00.123 <debug> cache miss", LevelDebug},
+		{"[notice] signal received", LevelInfo},
+		{"panic: runtime error: index out of range", LevelFatal},
+		{"W0501 12:00:00 something", ""},
+		// Prose mentioning a level
+		{"connection error while reading body", ""},
+		{"Retrying after error", ""},
+		{"the request was a fatal mistake", ""},
+		{"one two three four ERROR: too late", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if got := DetectLevel(tt.message); got != tt.want {
+				t.Errorf("DetectLevel(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"aperture-science-network/internal/docker"
+)
+
+const (
+	// MaxScanLines is the number of most recent lines searched per container
+	MaxScanLines = 50000
+
+	// MaxContext bounds the number of lines shown on each side of a match
+	MaxContext = 20
+
+	// DefaultLimit and MaxLimit bound the number of matches per page
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+var (
+	ErrInvalidPattern = errors.New("invalid pattern")
+	ErrInvalidLevel   = errors.New("invalid level")
+)
+
+// Query selects the lines returned by Search. An empty Text matches every
+// line; Levels, if set, limits matches to lines with one of those levels.
+type Query struct {
+	Text          string
+	Regex         bool
+	CaseSensitive bool
+	Levels        []string
+	Since         time.Time
+	Until         time.Time
+	Context       int
+	Offset        int
+	Limit         int
+}
+
+// Match is a matching line with the lines around it from the same container
+type Match struct {
+	docker.LogEntry
+	Level  string            `json:"level,omitempty"`
+	Before []docker.LogEntry `json:"before,omitempty"`
+	After  []docker.LogEntry `json:"after,omitempty"`
+}
+
+// Result is one page of matches, newest first
+type Result struct {
+	Matches []Match `json:"matches"`
+	Total   int     `json:"total"`
+	Offset  int     `json:"offset"`
+	Limit   int     `json:"limit"`
+	// Truncated is set if a container had more than MaxScanLines lines in
+	// the time range, so its oldest lines were not searched
+	Truncated bool `json:"truncated"`
+}
+
+// Search reads the logs of sources in the query's time range and returns
+// the requested page of matching lines
+func Search(ctx context.Context, client docker.DockerClient, sources []Source, q Query) (*Result, error) {
+	match, err := q.matcher()
+	if err != nil {
+		return nil, err
+	}
+	levels := make(map[string]bool)
+	for _, level := range q.Levels {
+		if normalizeLevel(level) != level {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLevel, level)
+		}
+		levels[level] = true
+	}
+
+	q.Context = min(max(q.Context, 0), MaxContext)
+	q.Offset = max(q.Offset, 0)
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	q.Limit = min(q.Limit, MaxLimit)
+
+	type hit struct {
+		source int
+		index  int
+		level  string
+	}
+
+	result := &Result{Matches: make([]Match, 0), Offset: q.Offset, Limit: q.Limit}
+	entries := make([][]docker.LogEntry, len(sources))
+	hits := make([]hit, 0)
+	for i, source := range sources {
+		logs, err := client.GetContainerLogs(ctx, source.ID, docker.LogOptions{
+			Since: q.Since,
+			Until: q.Until,
+			Tail:  strconv.Itoa(MaxScanLines),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.ID, err)
+		}
+		if len(logs.Entries) >= MaxScanLines {
+			result.Truncated = true
+		}
+
+		for j := range logs.Entries {
+			entry := &logs.Entries[j]
+			entry.Container = source.ID
+			entry.Service = source.Service
+			if !match(entry.Message) {
+				continue
+			}
+			level := DetectLevel(entry.Message)
+			if len(levels) > 0 && !levels[level] {
+				continue
+			}
+			hits = append(hits, hit{source: i, index: j, level: level})
+		}
+		entries[i] = logs.Entries
+	}
+
+	sort.SliceStable(hits, func(a, b int) bool {
+		return entries[hits[a].source][hits[a].index].Time.After(entries[hits[b].source][hits[b].index].Time)
+	})
+
+	result.Total = len(hits)
+	if q.Offset >= len(hits) {
+		return result, nil
+	}
+	for _, h := range hits[q.Offset:min(q.Offset+q.Limit, len(hits))] {
+		lines := entries[h.source]
+		result.Matches = append(result.Matches, Match{
+			LogEntry: lines[h.index],
+			Level:    h.level,
+			Before:   lines[max(h.index-q.Context, 0):h.index],
+			After:    lines[h.index+1 : min(h.index+1+q.Context, len(lines))],
+		})
+	}
+	return result, nil
+}
+
+func (q Query) matcher() (func(string) bool, error) {
+	if q.Regex {
+		pattern := q.Text
+		if !q.CaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPattern, err)
+		}
+		return re.MatchString, nil
+	}
+
+	if q.CaseSensitive {
+		return func(message string) bool {
+			return strings.Contains(message, q.Text)
+		}, nil
+	}
+	text := strings.ToLower(q.Text)
+	return func(message string) bool {
+		return strings.Contains(strings.ToLower(message), text)
+	}, nil
+}
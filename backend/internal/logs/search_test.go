@@ -0,0 +1,207 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/mock"
+)
+
+// The mock client returns the same five lines for every container:
+//
+//	10:00:00 [INFO] Container started
+//	10:00:01 [INFO] Listening on port 8080
+//	10:00:02 [DEBUG] Health check passed
+//	10:00:05 [INFO] Connection established
+//	10:00:10 [DEBUG] Processing request
+var searchSources = []Source{{ID: "a1b2c3d4e5f6", Service: "frontend"}, {ID: "b2c3d4e5f6a7", Service: "backend"}}
+
+func mockTime(sec int) time.Time {
+	return time.Date(2024, 1, 7, 10, 0, sec, 0, time.UTC)
+}
+
+func messages(entries []docker.LogEntry) []string {
+	result := make([]string, len(entries))
+	for i, e := range entries {
+		result[i] = e.Message
+	}
+	return result
+}
+
+func TestSearch(t *testing.T) {
+	type match struct {
+		service string
+		message string
+	}
+	tests := []struct {
+		name      string
+		query     Query
+		wantTotal int
+		want      []match
+	}{
+		{
+			name:      "text, newest first",
+			query:     Query{Text: "HEALTH"},
+			wantTotal: 2,
+			want:      []match{{"frontend", "[DEBUG] Health check passed"}, {"backend", "[DEBUG] Health check passed"}},
+		},
+		{
+			name:      "case sensitive",
+			query:     Query{Text: "HEALTH", CaseSensitive: true},
+			wantTotal: 0,
+			want:      []match{},
+		},
+		{
+			name:      "regex",
+			query:     Query{Text: `port \d+$`, Regex: true},
+			wantTotal: 2,
+			want:      []match{{"frontend", "[INFO] Listening on port 8080"}, {"backend", "[INFO] Listening on port 8080"}},
+		},
+		{
+			name:      "levels",
+			query:     Query{Levels: []string{LevelDebug}, Limit: 2},
+			wantTotal: 4,
+			want:      []match{{"frontend", "[DEBUG] Processing request"}, {"backend", "[DEBUG] Processing request"}},
+		},
+		{
+			name:      "second page",
+			query:     Query{Levels: []string{LevelInfo}, Offset: 2, Limit: 3},
+			wantTotal: 6,
+			want: []match{
+				{"frontend", "[INFO] Listening on port 8080"},
+				{"backend", "[INFO] Listening on port 8080"},
+				{"frontend", "[INFO] Container started"},
+			},
+		},
+		{
+			name:      "offset past the end",
+			query:     Query{Levels: []string{LevelInfo}, Offset: 10},
+			wantTotal: 6,
+			want:      []match{},
+		},
+		{
+			name:      "time range",
+			query:     Query{Since: mockTime(1), Until: mockTime(5), Levels: []string{LevelInfo}},
+			wantTotal: 4,
+			want: []match{
+				{"frontend", "[INFO] Connection established"},
+				{"backend", "[INFO] Connection established"},
+				{"frontend", "[INFO] Listening on port 8080"},
+				{"backend", "[INFO] Listening on port 8080"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Search(context.Background(), mock.NewDockerClient(), searchSources, tt.query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if result.Total != tt.wantTotal {
+				t.Errorf("Total = %d, want %d", result.Total, tt.wantTotal)
+			}
+			got := make([]match, len(result.Matches))
+			for i, m := range result.Matches {
+				got[i] = match{m.Service, m.Message}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Matches = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Matches[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSearchContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		context    int
+		wantBefore []string
+		wantAfter  []string
+	}{
+		{
+			name:       "middle",
+			text:       "Health check",
+			context:    1,
+			wantBefore: []string{"[INFO] Listening on port 8080"},
+			wantAfter:  []string{"[INFO] Connection established"},
+		},
+		{
+			name:       "first line",
+			text:       "Container started",
+			context:    2,
+			wantBefore: []string{},
+			wantAfter:  []string{"[INFO] Listening on port 8080", "[DEBUG] Health check passed"},
+		},
+		{
+			name:       "last line",
+			text:       "Processing",
+			context:    MaxContext + 10,
+			wantBefore: []string{"[INFO] Container started", "[INFO] Listening on port 8080", "[DEBUG] Health check passed", "[INFO] Connection established"},
+			wantAfter:  []string{},
+		},
+		{
+			name:       "none",
+			text:       "Health check",
+			context:    -1,
+			wantBefore: []string{},
+			wantAfter:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Search(context.Background(), mock.NewDockerClient(), searchSources[:1], Query{Text: tt.text, Context: tt.context})
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(result.Matches) != 1 {
+				t.Fatalf("Matches = %+v, want one", result.Matches)
+			}
+			m := result.Matches[0]
+			if got := messages(m.Before); !slices.Equal(got, tt.wantBefore) {
+				t.Errorf("Before = %q, want %q", got, tt.wantBefore)
+			}
+			if got := messages(m.After); !slices.Equal(got, tt.wantAfter) {
+				t.Errorf("After = %q, want %q", got, tt.wantAfter)
+			}
+			for _, e := range slices.Concat(m.Before, m.After) {
+				if e.Container != "a1b2c3d4e5f6" || e.Service != "frontend" {
+					t.Errorf("context line %+v is missing its source", e)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchPageLimits(t *testing.T) {
+	result, err := Search(context.Background(), mock.NewDockerClient(), searchSources, Query{Limit: MaxLimit + 1, Offset: -5})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if result.Limit != MaxLimit || result.Offset != 0 || result.Total != 10 || len(result.Matches) != 10 {
+		t.Errorf("Search() = limit %d, offset %d, total %d, %d matches; want %d, 0, 10, 10",
+			result.Limit, result.Offset, result.Total, len(result.Matches), MaxLimit)
+	}
+	if result.Truncated {
+		t.Errorf("Truncated = true, want false")
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	client := mock.NewDockerClient()
+	if _, err := Search(context.Background(), client, searchSources, Query{Text: "(", Regex: true}); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Search() with a bad regex error = %v, want %v", err, ErrInvalidPattern)
+	}
+	if _, err := Search(context.Background(), client, searchSources, Query{Levels: []string{"WARNING"}}); !errors.Is(err, ErrInvalidLevel) {
+		t.Errorf("Search() with a bad level error = %v, want %v", err, ErrInvalidLevel)
+	}
+}
@@ -0,0 +1,194 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"aperture-science-network/internal/docker"
+)
+
+const (
+	// MaxPending is the number of entries buffered for a slow reader before
+	// the oldest are dropped
+	MaxPending = 2000
+
+	// reorderWindow is how long entries from several containers are held so
+	// they can be put in timestamp order
+	reorderWindow = 250 * time.Millisecond
+	flushInterval = 100 * time.Millisecond
+)
+
+// Source is a container whose logs are streamed
+type Source struct {
+	ID      string
+	Service string
+}
+
+// Batch is the part of a stream buffered since it was last read. Dropped
+// counts the entries discarded before it because the reader fell behind.
+type Batch struct {
+	Entries []docker.LogEntry
+	Dropped int
+}
+
+// Stream is the merged log output of one or more containers
+type Stream struct {
+	window time.Duration
+	ready  chan struct{}
+
+	mu      sync.Mutex
+	pending []docker.LogEntry
+	dropped int
+	done    bool
+	errs    []error
+}
+
+type held struct {
+	entry   docker.LogEntry
+	arrived time.Time
+}
+
+// Follow starts streaming the logs of sources, tagging each entry with its
+// container and service. Entries from several containers are interleaved by
+// timestamp. The containers' streams are never blocked by the reader: if it
+// falls more than MaxPending entries behind, the oldest are dropped.
+func Follow(ctx context.Context, client docker.DockerClient, sources []Source, opts docker.LogOptions) *Stream {
+	s := &Stream{ready: make(chan struct{}, 1)}
+	if len(sources) > 1 {
+		s.window = reorderWindow
+	}
+
+	incoming := make(chan held, 256)
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := client.StreamContainerLogs(ctx, source.ID, opts, func(entry docker.LogEntry) {
+				entry.Container = source.ID
+				entry.Service = source.Service
+				select {
+				case incoming <- held{entry: entry, arrived: time.Now()}:
+				case <-ctx.Done():
+				}
+			})
+			if err != nil {
+				s.fail(fmt.Errorf("%s: %w", source.ID, err))
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(incoming)
+	}()
+
+	go s.merge(incoming)
+	return s
+}
+
+// Ready is signaled whenever entries are available or the stream ends
+func (s *Stream) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Take returns the entries buffered since the last call and whether more
+// may follow
+func (s *Stream) Take() (Batch, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := Batch{Entries: s.pending, Dropped: s.dropped}
+	s.pending = nil
+	s.dropped = 0
+	return batch, !s.done
+}
+
+// Err returns the errors of the containers whose streams failed
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.errs...)
+}
+
+func (s *Stream) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+func (s *Stream) merge(incoming <-chan held) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	buffer := make([]held, 0)
+	for {
+		select {
+		case h, ok := <-incoming:
+			if !ok {
+				sortHeld(buffer)
+				s.push(buffer, true)
+				return
+			}
+			buffer = append(buffer, h)
+			if s.window == 0 {
+				s.push(buffer, false)
+				buffer = buffer[:0]
+			}
+		case now := <-ticker.C:
+			buffer = s.release(buffer, now)
+		}
+	}
+}
+
+// release passes on the entries held for the reorder window, together with
+// any entries with an earlier timestamp, and returns those still held
+func (s *Stream) release(buffer []held, now time.Time) []held {
+	var cutoff time.Time
+	found := false
+	for _, h := range buffer {
+		if now.Sub(h.arrived) >= s.window && (!found || h.entry.Time.After(cutoff)) {
+			cutoff = h.entry.Time
+			found = true
+		}
+	}
+	if !found {
+		return buffer
+	}
+
+	sortHeld(buffer)
+	n := sort.Search(len(buffer), func(i int) bool {
+		return buffer[i].entry.Time.After(cutoff)
+	})
+	s.push(buffer[:n], false)
+	return append(make([]held, 0, len(buffer)-n), buffer[n:]...)
+}
+
+func (s *Stream) push(entries []held, done bool) {
+	s.mu.Lock()
+	for _, h := range entries {
+		s.pending = append(s.pending, h.entry)
+	}
+	if over := len(s.pending) - MaxPending; over > 0 {
+		s.pending = append([]docker.LogEntry(nil), s.pending[over:]...)
+		s.dropped += over
+	}
+	if done {
+		s.done = true
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+func sortHeld(buffer []held) {
+	sort.SliceStable(buffer, func(i, j int) bool {
+		return buffer[i].entry.Time.Before(buffer[j].entry.Time)
+	})
+}
@@ -0,0 +1,234 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"aperture-science-network/internal/docker"
+)
+
+// fakeLogs serves each container's log stream from a function
+type fakeLogs struct {
+	docker.DockerClient
+	streams map[string]func(ctx context.Context, emit func(docker.LogEntry)) error
+}
+
+func (f *fakeLogs) StreamContainerLogs(ctx context.Context, id string, opts docker.LogOptions, emit func(docker.LogEntry)) error {
+	return f.streams[id](ctx, emit)
+}
+
+// lines emits one entry per timestamp and ends
+func lines(times ...time.Time) func(context.Context, func(docker.LogEntry)) error {
+	return func(ctx context.Context, emit func(docker.LogEntry)) error {
+		for _, t := range times {
+			emit(docker.LogEntry{Time: t, Message: t.Format("15:04:05")})
+		}
+		return nil
+	}
+}
+
+func at(sec int) time.Time {
+	return time.Date(2024, 5, 1, 12, 0, sec, 0, time.UTC)
+}
+
+// readUntil takes batches from s until n entries arrived or the stream
+// ended, returning the entries, the dropped count and whether more may
+// follow
+func readUntil(t *testing.T, s *Stream, n int) ([]docker.LogEntry, int, bool) {
+	t.Helper()
+	var entries []docker.LogEntry
+	dropped := 0
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case <-s.Ready():
+		case <-deadline:
+			t.Fatalf("stream produced %d entries, want %d", len(entries), n)
+		}
+		batch, more := s.Take()
+		entries = append(entries, batch.Entries...)
+		dropped += batch.Dropped
+		if !more || len(entries) >= n {
+			return entries, dropped, more
+		}
+	}
+}
+
+func summary(entries []docker.LogEntry) string {
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = e.Container + "@" + e.Message
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestFollowSingleSource(t *testing.T) {
+	client := &fakeLogs{streams: map[string]func(context.Context, func(docker.LogEntry)) error{
+		// A single container is passed through in its own order
+		"a": lines(at(2), at(1), at(3)),
+	}}
+	s := Follow(context.Background(), client, []Source{{ID: "a", Service: "web"}}, docker.LogOptions{})
+
+	entries, dropped, more := readUntil(t, s, 3)
+	if got, want := summary(entries), "a@12:00:02 a@12:00:01 a@12:00:03"; got != want {
+		t.Errorf("entries = %s, want %s", got, want)
+	}
+	if entries[0].Service != "web" {
+		t.Errorf("Service = %q, want web", entries[0].Service)
+	}
+	if dropped != 0 {
+		t.Errorf("Dropped = %d, want 0", dropped)
+	}
+	if more {
+		if _, _, more = readUntil(t, s, 1); more {
+			t.Errorf("stream still open after its source ended")
+		}
+	}
+}
+
+func TestFollowInterleavesSources(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// b's earlier line arrives after a's later ones, within the reorder
+	// window
+	bReady := make(chan struct{})
+	client := &fakeLogs{streams: map[string]func(context.Context, func(docker.LogEntry)) error{
+		"a": func(ctx context.Context, emit func(docker.LogEntry)) error {
+			emit(docker.LogEntry{Time: at(3), Message: "12:00:03"})
+			emit(docker.LogEntry{Time: at(5), Message: "12:00:05"})
+			close(bReady)
+			<-ctx.Done()
+			return nil
+		},
+		"b": func(ctx context.Context, emit func(docker.LogEntry)) error {
+			<-bReady
+			emit(docker.LogEntry{Time: at(1), Message: "12:00:01"})
+			emit(docker.LogEntry{Time: at(4), Message: "12:00:04"})
+			<-ctx.Done()
+			return nil
+		},
+	}}
+	s := Follow(ctx, client, []Source{{ID: "a"}, {ID: "b"}}, docker.LogOptions{})
+
+	entries, _, more := readUntil(t, s, 4)
+	if got, want := summary(entries), "b@12:00:01 a@12:00:03 b@12:00:04 a@12:00:05"; got != want {
+		t.Errorf("entries = %s, want %s", got, want)
+	}
+	if !more {
+		t.Errorf("stream ended while its sources follow")
+	}
+
+	cancel()
+	if _, _, more := readUntil(t, s, 1); more {
+		t.Errorf("stream still open after cancel")
+	}
+}
+
+func TestStreamRelease(t *testing.T) {
+	s := &Stream{window: reorderWindow, ready: make(chan struct{}, 1)}
+	now := time.Now()
+	old := now.Add(-reorderWindow)
+	fresh := now.Add(-reorderWindow / 2)
+	entry := func(container string, sec int, arrived time.Time) held {
+		return held{entry: docker.LogEntry{Container: container, Message: fmt.Sprint(sec), Time: at(sec)}, arrived: arrived}
+	}
+
+	// a@5 has been held for the window, so it goes out with everything
+	// older than it, even entries that arrived just now
+	buffer := []held{entry("a", 5, old), entry("b", 6, fresh), entry("b", 2, fresh), entry("a", 7, fresh)}
+	remaining := s.release(buffer, now)
+
+	batch, _ := s.Take()
+	if got, want := summary(batch.Entries), "b@2 a@5"; got != want {
+		t.Errorf("released = %s, want %s", got, want)
+	}
+	var kept []docker.LogEntry
+	for _, h := range remaining {
+		kept = append(kept, h.entry)
+	}
+	if got, want := summary(kept), "b@6 a@7"; got != want {
+		t.Errorf("held = %s, want %s", got, want)
+	}
+
+	// Nothing is released before the window has passed
+	if again := s.release(remaining, now); len(again) != 2 {
+		t.Errorf("release() kept %d entries, want 2", len(again))
+	}
+	if batch, _ := s.Take(); len(batch.Entries) != 0 {
+		t.Errorf("released %s before the window passed", summary(batch.Entries))
+	}
+}
+
+func TestFollowDropsForSlowReader(t *testing.T) {
+	const extra = 25
+	times := make([]time.Time, MaxPending+extra)
+	for i := range times {
+		times[i] = at(0).Add(time.Duration(i) * time.Millisecond)
+	}
+	client := &fakeLogs{streams: map[string]func(context.Context, func(docker.LogEntry)) error{
+		"a": lines(times...),
+	}}
+	s := Follow(context.Background(), client, []Source{{ID: "a"}}, docker.LogOptions{})
+
+	// Do not read until the source has finished
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		done := s.done
+		s.mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	batch, more := s.Take()
+	if more {
+		t.Errorf("Take() reports more after the stream ended")
+	}
+	if batch.Dropped != extra {
+		t.Errorf("Dropped = %d, want %d", batch.Dropped, extra)
+	}
+	if len(batch.Entries) != MaxPending {
+		t.Fatalf("got %d entries, want %d", len(batch.Entries), MaxPending)
+	}
+	if !batch.Entries[0].Time.Equal(times[extra]) || !batch.Entries[MaxPending-1].Time.Equal(times[len(times)-1]) {
+		t.Errorf("kept %v to %v, want the newest entries", batch.Entries[0].Time, batch.Entries[MaxPending-1].Time)
+	}
+
+	// The count is reported once
+	if batch, _ := s.Take(); batch.Dropped != 0 || len(batch.Entries) != 0 {
+		t.Errorf("second Take() = %d entries, %d dropped, want nothing", len(batch.Entries), batch.Dropped)
+	}
+}
+
+func TestFollowSourceError(t *testing.T) {
+	client := &fakeLogs{streams: map[string]func(context.Context, func(docker.LogEntry)) error{
+		"a": lines(at(1)),
+		"b": func(ctx context.Context, emit func(docker.LogEntry)) error {
+			return errors.New("no such container")
+		},
+	}}
+	s := Follow(context.Background(), client, []Source{{ID: "a"}, {ID: "b"}}, docker.LogOptions{})
+
+	entries, _, more := readUntil(t, s, 2)
+	for more {
+		var rest []docker.LogEntry
+		rest, _, more = readUntil(t, s, 1)
+		entries = append(entries, rest...)
+	}
+	if got, want := summary(entries), "a@12:00:01"; got != want {
+		t.Errorf("entries = %s, want %s", got, want)
+	}
+	if err := s.Err(); err == nil || !strings.Contains(err.Error(), "b: no such container") {
+		t.Errorf("Err() = %v, want the failed container's error", err)
+	}
+}
@@ -0,0 +1,43 @@
+package logs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "", want: time.Time{}},
+		{value: "15m", want: now.Add(-15 * time.Minute)},
+		{value: "1h30m", want: now.Add(-90 * time.Minute)},
+		{value: "2024-04-30T08:00:00Z", want: time.Date(2024, 4, 30, 8, 0, 0, 0, time.UTC)},
+		{value: "2024-04-30T10:00:00.5+02:00", want: time.Date(2024, 4, 30, 8, 0, 0, 500000000, time.UTC)},
+		{value: "1714564800", want: time.Unix(1714564800, 0)},
+		{value: "1714564800.25", want: time.Unix(1714564800, 250000000)},
+		{value: " 1714564800 ", want: time.Unix(1714564800, 0)},
+		{value: "yesterday", wantErr: true},
+		{value: "2024-04-30", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseTime(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTime(%q) error = %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"log"
+	"time"
+
+	"aperture-science-network/internal/stats"
+)
+
+// DefaultInterval is how often the collector samples stats
+const DefaultInterval = 10 * time.Second
+
+// Collector samples host and container stats into a Store, whether or not
+// anyone is watching them live
+type Collector struct {
+	store          *Store
+	containerStats *stats.ContainerWatcher
+	statsProvider  stats.Provider
+	interval       time.Duration
+}
+
+// NewCollector creates a collector recording into store every interval
+func NewCollector(store *Store, containerStats *stats.ContainerWatcher, statsProvider stats.Provider, interval time.Duration) *Collector {
+	return &Collector{
+		store:          store,
+		containerStats: containerStats,
+		statsProvider:  statsProvider,
+		interval:       interval,
+	}
+}
+
+// Run samples stats until the process exits
+func (c *Collector) Run() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		c.collect(now)
+		c.store.Prune(now)
+	}
+}
+
+func (c *Collector) collect(now time.Time) {
+	if sysStats, err := c.statsProvider.GetSystemStats(); err != nil {
+		log.Printf("Error getting system stats: %v", err)
+	} else {
+		c.store.RecordSystem(now, sysStats)
+	}
+
+	for _, sample := range c.containerStats.Snapshot() {
+		c.store.RecordContainer(now, sample.Container, sample.Stats)
+	}
+}
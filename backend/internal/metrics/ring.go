@@ -0,0 +1,41 @@
+package metrics
+
+// ring is a circular buffer of points with a fixed number of values each.
+// It grows up to its capacity, then overwrites the oldest points.
+type ring struct {
+	capacity int
+	width    int
+	times    []int64
+	values   []float64
+	// next is the slot the next point is written to once the ring is full
+	next int
+}
+
+func newRing(capacity, width int) *ring {
+	return &ring{capacity: capacity, width: width}
+}
+
+func (r *ring) push(t int64, values []float64) {
+	if len(r.times) < r.capacity {
+		r.times = append(r.times, t)
+		r.values = append(r.values, values...)
+		return
+	}
+	r.times[r.next] = t
+	copy(r.values[r.next*r.width:], values)
+	r.next = (r.next + 1) % r.capacity
+}
+
+// each calls fn, oldest first, for the points with from <= t <= to. The
+// values slice is only valid during the call.
+func (r *ring) each(from, to int64, fn func(t int64, values []float64)) {
+	n := len(r.times)
+	for i := 0; i < n; i++ {
+		slot := (r.next + i) % n
+		t := r.times[slot]
+		if t < from || t > to {
+			continue
+		}
+		fn(t, r.values[slot*r.width:(slot+1)*r.width])
+	}
+}
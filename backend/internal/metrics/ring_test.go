@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		pushed   int
+		from, to int64
+		want     []int64
+	}{
+		{"empty", 3, 0, 0, 100, []int64{}},
+		{"partially filled", 3, 2, 0, 100, []int64{1, 2}},
+		{"full", 3, 3, 0, 100, []int64{1, 2, 3}},
+		{"wrapped keeps newest", 3, 5, 0, 100, []int64{3, 4, 5}},
+		{"wrapped twice", 3, 7, 0, 100, []int64{5, 6, 7}},
+		{"range filter", 5, 5, 2, 4, []int64{2, 3, 4}},
+		{"range filter after wrap", 3, 5, 4, 4, []int64{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRing(tt.capacity, 2)
+			for i := 1; i <= tt.pushed; i++ {
+				r.push(int64(i), []float64{float64(i), float64(-i)})
+			}
+
+			got := make([]int64, 0)
+			r.each(tt.from, tt.to, func(ts int64, values []float64) {
+				if values[0] != float64(ts) || values[1] != float64(-ts) {
+					t.Errorf("point %d has values %v", ts, values)
+				}
+				got = append(got, ts)
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("each() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
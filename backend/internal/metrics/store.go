@@ -0,0 +1,358 @@
+package metrics
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/stats"
+)
+
+// Series targets
+const (
+	TargetSystem    = "system"
+	TargetContainer = "container"
+)
+
+// Resolutions a query can be answered at
+const (
+	ResolutionRaw = "raw"
+	Resolution1m  = "1m"
+	Resolution10m = "10m"
+)
+
+var ErrInvalidResolution = errors.New("resolution must be raw, 1m or 10m")
+
+// tier is one level of the store. Raw samples are kept for an hour; older
+// data survives only as rollups, averaged per minute for a day and per ten
+// minutes for a month.
+type tier struct {
+	resolution string
+	step       time.Duration
+	retention  time.Duration
+}
+
+var tiers = []tier{
+	{resolution: ResolutionRaw, retention: time.Hour},
+	{resolution: Resolution1m, step: time.Minute, retention: 24 * time.Hour},
+	{resolution: Resolution10m, step: 10 * time.Minute, retention: 30 * 24 * time.Hour},
+}
+
+// Field is a metric recorded for a target. Rollups average gauges and keep
+// the latest value of counters, which only grow.
+type Field struct {
+	Name    string
+	Counter bool
+}
+
+var fields = map[string][]Field{
+	TargetSystem: {
+		{Name: "cpuUsage"},
+		{Name: "memoryUsed"},
+		{Name: "memoryPercent"},
+		{Name: "diskUsed"},
+		{Name: "diskPercent"},
+	},
+	TargetContainer: {
+		{Name: "cpuPercent"},
+		{Name: "memoryUsage"},
+		{Name: "memoryPercent"},
+		{Name: "networkRx", Counter: true},
+		{Name: "networkTx", Counter: true},
+		{Name: "blockRead", Counter: true},
+		{Name: "blockWrite", Counter: true},
+		{Name: "pids"},
+	},
+}
+
+// Series identifies the source of a set of points. Container series are
+// keyed by container ID, so a recreated container starts a new series with
+// the same name.
+type Series struct {
+	Target string `json:"target"`
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Stack  string `json:"stack,omitempty"`
+}
+
+// Point is the value of each of a series' fields at a time. Rollup points
+// are stamped with the start of their interval.
+type Point struct {
+	Time   time.Time          `json:"time"`
+	Values map[string]float64 `json:"values"`
+}
+
+// SeriesData is a series with its points in a query's range, oldest first
+type SeriesData struct {
+	Series
+	Points []Point `json:"points"`
+}
+
+// Query selects the series and range returned by Store.Query
+type Query struct {
+	Since time.Time
+	Until time.Time
+	// Resolution is raw, 1m or 10m. If empty, the finest resolution still
+	// retained at Since is used.
+	Resolution string
+	// Match selects the series to return
+	Match func(Series) bool
+}
+
+// Result is the answer to a query
+type Result struct {
+	Resolution string       `json:"resolution"`
+	Since      time.Time    `json:"since"`
+	Until      time.Time    `json:"until"`
+	Series     []SeriesData `json:"series"`
+}
+
+// Store keeps recent metrics of the host and each container in memory,
+// downsampling them as they age
+type Store struct {
+	mu       sync.RWMutex
+	interval time.Duration
+	series   map[string]*series
+}
+
+type series struct {
+	info   Series
+	fields []Field
+	last   time.Time
+	tiers  []*tierData
+}
+
+// tierData holds a series' points at one tier, plus the rollup being
+// accumulated for the current interval
+type tierData struct {
+	step   time.Duration
+	ring   *ring
+	bucket int64
+	sum    []float64
+	latest []float64
+	count  int
+}
+
+// NewStore creates a store for samples recorded every interval
+func NewStore(interval time.Duration) *Store {
+	return &Store{
+		interval: interval,
+		series:   make(map[string]*series),
+	}
+}
+
+// RecordSystem adds a sample of host statistics
+func (s *Store) RecordSystem(t time.Time, st *stats.SystemStats) {
+	s.record(Series{Target: TargetSystem, ID: st.Hostname, Name: st.Hostname}, t, []float64{
+		st.CPUUsage,
+		float64(st.MemoryUsed),
+		st.MemoryPercent,
+		float64(st.DiskUsed),
+		st.DiskPercent,
+	})
+}
+
+// RecordContainer adds a sample of a container's statistics
+func (s *Store) RecordContainer(t time.Time, ctr docker.ContainerInfo, st *docker.ContainerStats) {
+	info := Series{
+		Target: TargetContainer,
+		ID:     ctr.ID,
+		Name:   ctr.Name,
+		Stack:  ctr.Labels["com.docker.compose.project"],
+	}
+	s.record(info, t, []float64{
+		st.CPUPercent,
+		float64(st.MemoryUsage),
+		st.MemoryPercent,
+		float64(st.NetworkRx),
+		float64(st.NetworkTx),
+		float64(st.BlockRead),
+		float64(st.BlockWrite),
+		float64(st.PIDs),
+	})
+}
+
+func (s *Store) record(info Series, t time.Time, values []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The host has a single series whatever its hostname
+	key := info.Target + ":" + info.ID
+	if info.Target == TargetSystem {
+		key = TargetSystem
+	}
+
+	ser, ok := s.series[key]
+	if !ok {
+		ser = s.newSeries(info)
+		s.series[key] = ser
+	}
+	ser.info = info
+	ser.last = t
+	for _, td := range ser.tiers {
+		td.add(t, values, ser.fields)
+	}
+}
+
+func (s *Store) newSeries(info Series) *series {
+	ser := &series{info: info, fields: fields[info.Target]}
+	for _, tier := range tiers {
+		step := tier.step
+		if step == 0 {
+			step = s.interval
+		}
+		ser.tiers = append(ser.tiers, &tierData{
+			step: tier.step,
+			ring: newRing(int(tier.retention/step)+1, len(ser.fields)),
+		})
+	}
+	return ser
+}
+
+func (td *tierData) add(t time.Time, values []float64, fields []Field) {
+	if td.step == 0 {
+		td.ring.push(t.UnixNano(), values)
+		return
+	}
+
+	bucket := t.Truncate(td.step).UnixNano()
+	if td.count > 0 && bucket > td.bucket {
+		td.ring.push(td.bucket, td.rollup(fields))
+		td.count = 0
+	}
+	if td.count == 0 {
+		td.bucket = bucket
+		td.sum = make([]float64, len(values))
+		td.latest = make([]float64, len(values))
+	}
+	for i, v := range values {
+		td.sum[i] += v
+	}
+	copy(td.latest, values)
+	td.count++
+}
+
+// rollup summarizes the samples of the current interval
+func (td *tierData) rollup(fields []Field) []float64 {
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		if field.Counter {
+			values[i] = td.latest[i]
+		} else {
+			values[i] = td.sum[i] / float64(td.count)
+		}
+	}
+	return values
+}
+
+// Query returns the points of the matching series in the query's range.
+// Series without points in the range are left out.
+func (s *Store) Query(q Query) (*Result, error) {
+	now := time.Now()
+	if q.Until.IsZero() || q.Until.After(now) {
+		q.Until = now
+	}
+
+	index, err := pickTier(q.Resolution, q.Since, now)
+	if err != nil {
+		return nil, err
+	}
+	tier := tiers[index]
+
+	// Points past the tier's retention may linger in its ring
+	from := q.Since
+	if oldest := now.Add(-tier.retention); from.Before(oldest) {
+		from = oldest
+	}
+
+	result := &Result{
+		Resolution: tier.resolution,
+		Since:      q.Since,
+		Until:      q.Until,
+		Series:     make([]SeriesData, 0),
+	}
+
+	s.mu.RLock()
+	for _, ser := range s.series {
+		if q.Match != nil && !q.Match(ser.info) {
+			continue
+		}
+		points := ser.tiers[index].points(ser.fields, from.UnixNano(), q.Until.UnixNano())
+		if len(points) > 0 {
+			result.Series = append(result.Series, SeriesData{Series: ser.info, Points: points})
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result.Series, func(i, j int) bool {
+		a, b := result.Series[i].Series, result.Series[j].Series
+		if a.Target != b.Target {
+			return a.Target == TargetSystem
+		}
+		if a.Stack != b.Stack {
+			return a.Stack < b.Stack
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	return result, nil
+}
+
+// pickTier returns the index of the tier with the given resolution or, if
+// resolution is empty, of the finest tier still retaining since. A query for
+// "the last hour" starts a moment before the raw tier's retention by the
+// time it runs, so tiers are picked with a minute of grace.
+func pickTier(resolution string, since time.Time, now time.Time) (int, error) {
+	if resolution != "" {
+		for i, tier := range tiers {
+			if tier.resolution == resolution {
+				return i, nil
+			}
+		}
+		return 0, ErrInvalidResolution
+	}
+
+	for i, tier := range tiers {
+		if !since.Before(now.Add(-tier.retention - time.Minute)) {
+			return i, nil
+		}
+	}
+	return len(tiers) - 1, nil
+}
+
+// points returns the tier's points in [from, to], including the rollup of
+// the interval still in progress
+func (td *tierData) points(fields []Field, from, to int64) []Point {
+	points := make([]Point, 0)
+	add := func(t int64, values []float64) {
+		point := Point{Time: time.Unix(0, t).UTC(), Values: make(map[string]float64, len(fields))}
+		for i, field := range fields {
+			point.Values[field.Name] = values[i]
+		}
+		points = append(points, point)
+	}
+
+	td.ring.each(from, to, add)
+	if td.count > 0 && td.bucket >= from && td.bucket <= to {
+		add(td.bucket, td.rollup(fields))
+	}
+	return points
+}
+
+// Prune drops series that have not been recorded for longer than the
+// longest retention, such as those of removed containers
+func (s *Store) Prune(now time.Time) {
+	cutoff := now.Add(-tiers[len(tiers)-1].retention)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, ser := range s.series {
+		if ser.last.Before(cutoff) {
+			delete(s.series, key)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"aperture-science-network/internal/docker"
+)
+
+func TestTierRollup(t *testing.T) {
+	fields := []Field{{Name: "cpu"}, {Name: "rx", Counter: true}}
+	td := &tierData{step: time.Minute, ring: newRing(10, len(fields))}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Two samples in the first minute, one in the second
+	td.add(start.Add(10*time.Second), []float64{10, 100}, fields)
+	td.add(start.Add(40*time.Second), []float64{30, 150}, fields)
+	td.add(start.Add(70*time.Second), []float64{50, 200}, fields)
+
+	points := td.points(fields, 0, start.Add(time.Hour).UnixNano())
+	if len(points) != 2 {
+		t.Fatalf("points() returned %d points, want 2", len(points))
+	}
+
+	tests := []struct {
+		point int
+		time  time.Time
+		cpu   float64
+		rx    float64
+	}{
+		{0, start, 20, 150},
+		{1, start.Add(time.Minute), 50, 200},
+	}
+	for _, tt := range tests {
+		p := points[tt.point]
+		if !p.Time.Equal(tt.time) || p.Values["cpu"] != tt.cpu || p.Values["rx"] != tt.rx {
+			t.Errorf("point %d = %v %v, want %v cpu=%v rx=%v", tt.point, p.Time, p.Values, tt.time, tt.cpu, tt.rx)
+		}
+	}
+}
+
+func TestPickTier(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		resolution string
+		since      time.Time
+		want       string
+		wantErr    error
+	}{
+		{"last hour is raw", "", now.Add(-time.Hour), ResolutionRaw, nil},
+		{"last day is per minute", "", now.Add(-24 * time.Hour), Resolution1m, nil},
+		{"last week is per ten minutes", "", now.Add(-7 * 24 * time.Hour), Resolution10m, nil},
+		{"beyond retention", "", now.Add(-365 * 24 * time.Hour), Resolution10m, nil},
+		{"explicit resolution", Resolution10m, now.Add(-time.Minute), Resolution10m, nil},
+		{"unknown resolution", "5m", now, "", ErrInvalidResolution},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := pickTier(tt.resolution, tt.since, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("pickTier() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && tiers[index].resolution != tt.want {
+				t.Errorf("pickTier() = %s, want %s", tiers[index].resolution, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreQuery(t *testing.T) {
+	store := NewStore(10 * time.Second)
+	now := time.Now()
+	web := docker.ContainerInfo{ID: "a1", Name: "web", Labels: map[string]string{"com.docker.compose.project": "site"}}
+	db := docker.ContainerInfo{ID: "b2", Name: "db", Labels: map[string]string{"com.docker.compose.project": "data"}}
+	for i := 3; i > 0; i-- {
+		at := now.Add(-time.Duration(i) * 10 * time.Second)
+		store.RecordContainer(at, web, &docker.ContainerStats{CPUPercent: float64(i)})
+		store.RecordContainer(at, db, &docker.ContainerStats{CPUPercent: 1})
+	}
+
+	result, err := store.Query(Query{
+		Since: now.Add(-time.Hour),
+		Match: func(s Series) bool { return s.Stack == "site" },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Resolution != ResolutionRaw {
+		t.Errorf("Resolution = %s, want %s", result.Resolution, ResolutionRaw)
+	}
+	if len(result.Series) != 1 || result.Series[0].Name != "web" {
+		t.Fatalf("Series = %+v, want only web", result.Series)
+	}
+	points := result.Series[0].Points
+	if len(points) != 3 || points[0].Values["cpuPercent"] != 3 || points[2].Values["cpuPercent"] != 1 {
+		t.Errorf("Points = %+v, want 3 points oldest first", points)
+	}
+
+	store.Prune(now.Add(31 * 24 * time.Hour))
+	if result, _ := store.Query(Query{Since: now.Add(-time.Hour)}); len(result.Series) != 0 {
+		t.Errorf("Prune() kept %d series", len(result.Series))
+	}
+}
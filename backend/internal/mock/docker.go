@@ -2,7 +2,11 @@ package mock
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"math/rand"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,12 +17,19 @@ import (
 type DockerClient struct {
 	mu        sync.Mutex
 	startTime time.Time
+	execs     map[string]*mockExec
+	execCount int
+
+	eventsMu    sync.Mutex
+	subscribers map[chan docker.Event]struct{}
 }
 
 // NewDockerClient creates a new mock Docker client
 func NewDockerClient() *DockerClient {
 	return &DockerClient{
-		startTime: time.Now(),
+		startTime:   time.Now(),
+		execs:       make(map[string]*mockExec),
+		subscribers: make(map[chan docker.Event]struct{}),
 	}
 }
 
@@ -31,6 +42,7 @@ func (c *DockerClient) ListContainers(ctx context.Context, all bool) ([]docker.C
 			ID:      "a1b2c3d4e5f6",
 			Name:    "celeste-frontend",
 			Image:   "celeste:latest",
+			ImageID: "abc123def456",
 			Status:  "Up 2 hours",
 			State:   "running",
 			Created: time.Now().Add(-2 * time.Hour).Unix(),
@@ -47,6 +59,7 @@ func (c *DockerClient) ListContainers(ctx context.Context, all bool) ([]docker.C
 			ID:      "b2c3d4e5f6a7",
 			Name:    "celeste-backend",
 			Image:   "celeste:latest",
+			ImageID: "abc123def456",
 			Status:  "Up 2 hours",
 			State:   "running",
 			Created: time.Now().Add(-2 * time.Hour).Unix(),
@@ -63,6 +76,7 @@ func (c *DockerClient) ListContainers(ctx context.Context, all bool) ([]docker.C
 			ID:      "c3d4e5f6a7b8",
 			Name:    "prometheus",
 			Image:   "prom/prometheus:latest",
+			ImageID: "def456abc789",
 			Status:  "Up 5 hours",
 			State:   "running",
 			Created: time.Now().Add(-5 * time.Hour).Unix(),
@@ -79,6 +93,7 @@ func (c *DockerClient) ListContainers(ctx context.Context, all bool) ([]docker.C
 			ID:      "d4e5f6a7b8c9",
 			Name:    "grafana",
 			Image:   "grafana/grafana:latest",
+			ImageID: "0a1b2c3d4e5f",
 			Status:  "Exited (0) 1 hour ago",
 			State:   "exited",
 			Created: time.Now().Add(-6 * time.Hour).Unix(),
@@ -93,6 +108,7 @@ func (c *DockerClient) ListContainers(ctx context.Context, all bool) ([]docker.C
 			ID:      "e5f6a7b8c9d0",
 			Name:    "redis",
 			Image:   "redis:alpine",
+			ImageID: "1b2c3d4e5f6a",
 			Status:  "Exited (0) 30 minutes ago",
 			State:   "exited",
 			Created: time.Now().Add(-3 * time.Hour).Unix(),
@@ -130,24 +146,75 @@ func (c *DockerClient) GetContainer(ctx context.Context, id string) (*docker.Con
 }
 
 func (c *DockerClient) StartContainer(ctx context.Context, id string) error {
+	c.containerEvent(ctx, id, "start")
 	return nil
 }
 
 func (c *DockerClient) StopContainer(ctx context.Context, id string) error {
+	c.containerEvent(ctx, id, "kill", "die", "stop")
 	return nil
 }
 
 func (c *DockerClient) RestartContainer(ctx context.Context, id string) error {
+	c.containerEvent(ctx, id, "kill", "die", "stop", "start", "restart")
 	return nil
 }
 
-func (c *DockerClient) GetContainerLogs(ctx context.Context, id string, tail string) (string, error) {
-	return `2024-01-07T10:00:00.000Z [INFO] Container started
+// mockLogs is the log history of every mock container
+const mockLogs = `2024-01-07T10:00:00.000Z [INFO] Container started
 2024-01-07T10:00:01.000Z [INFO] Listening on port 8080
 2024-01-07T10:00:02.000Z [DEBUG] Health check passed
 2024-01-07T10:00:05.000Z [INFO] Connection established
 2024-01-07T10:00:10.000Z [DEBUG] Processing request
-`, nil
+`
+
+func (c *DockerClient) GetContainerLogs(ctx context.Context, id string, opts docker.LogOptions) (*docker.ContainerLogs, error) {
+	opts.Follow = false
+	logs := &docker.ContainerLogs{Entries: make([]docker.LogEntry, 0)}
+	err := c.StreamContainerLogs(ctx, id, opts, func(entry docker.LogEntry) {
+		logs.Entries = append(logs.Entries, entry)
+	})
+	return logs, err
+}
+
+// StreamContainerLogs replays the canned logs and, when following, adds a
+// line every couple of seconds
+func (c *DockerClient) StreamContainerLogs(ctx context.Context, id string, opts docker.LogOptions, emit func(docker.LogEntry)) error {
+	lines := strings.Split(strings.TrimSuffix(mockLogs, "\n"), "\n")
+	if n, err := strconv.Atoi(opts.Tail); err == nil && n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	for _, line := range lines {
+		entry := docker.ParseLogLine(line)
+		entry.Stream = docker.StreamStdout
+		if (!opts.Since.IsZero() && entry.Time.Before(opts.Since)) || (!opts.Until.IsZero() && entry.Time.After(opts.Until)) {
+			continue
+		}
+		emit(entry)
+	}
+	if !opts.Follow {
+		return nil
+	}
+
+	messages := []string{"[INFO] Handled request", "[DEBUG] Health check passed", "[WARN] Slow response", "[ERROR] Upstream timed out"}
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if !opts.Until.IsZero() && now.After(opts.Until) {
+				return nil
+			}
+			stream := docker.StreamStdout
+			message := messages[rand.Intn(len(messages))]
+			if strings.HasPrefix(message, "[ERROR]") {
+				stream = docker.StreamStderr
+			}
+			emit(docker.LogEntry{Time: now.UTC(), Stream: stream, Message: message})
+		}
+	}
 }
 
 func (c *DockerClient) GetContainerStats(ctx context.Context, id string) (*docker.ContainerStats, error) {
@@ -182,18 +249,246 @@ func (c *DockerClient) GetContainerStats(ctx context.Context, id string) (*docke
 	memory := base.memory + memVariation
 	memPercent := float64(memory) / float64(base.limit) * 100
 
+	// Counters grow with uptime like the real ones
+	elapsed := uint64(time.Since(c.startTime).Seconds()) + 1
+	rx := elapsed * uint64(2000+rand.Intn(200))
+	tx := elapsed * uint64(1000+rand.Intn(100))
+
 	return &docker.ContainerStats{
 		CPUPercent:    cpu,
+		PerCPUPercent: []float64{cpu * 0.6, cpu * 0.4},
+		OnlineCPUs:    2,
+		Throttling: docker.CPUThrottling{
+			Periods:          elapsed * 10,
+			ThrottledPeriods: elapsed / 20,
+			ThrottledTime:    elapsed / 20 * uint64(5*time.Millisecond),
+		},
 		MemoryUsage:   memory,
+		MemoryCache:   base.memory / 8,
 		MemoryLimit:   base.limit,
 		MemoryPercent: memPercent,
-		NetworkRx:     uint64(rand.Intn(1000000)),
-		NetworkTx:     uint64(rand.Intn(500000)),
-		BlockRead:     uint64(rand.Intn(10000000)),
-		BlockWrite:    uint64(rand.Intn(5000000)),
+		NetworkRx:     rx,
+		NetworkTx:     tx,
+		Networks: map[string]docker.InterfaceStats{
+			"eth0": {RxBytes: rx, TxBytes: tx, RxPackets: rx / 800, TxPackets: tx / 800},
+		},
+		BlockRead:  elapsed * 4096,
+		BlockWrite: elapsed * 2048,
+		PIDs:       uint64(8 + rand.Intn(4)),
 	}, nil
 }
 
+// StreamContainerStats emits a fresh sample every second
+func (c *DockerClient) StreamContainerStats(ctx context.Context, id string, emit func(*docker.ContainerStats)) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		stats, err := c.GetContainerStats(ctx, id)
+		if err != nil {
+			return err
+		}
+		emit(stats)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// mockExec is an exec created by CreateExec
+type mockExec struct {
+	container string
+	user      string
+	exitCode  int
+}
+
+func (c *DockerClient) CreateExec(ctx context.Context, containerID string, opts docker.ExecOptions) (string, error) {
+	ctr, err := c.GetContainer(ctx, containerID)
+	if err != nil {
+		return "", err
+	}
+	if ctr.State != "running" {
+		return "", fmt.Errorf("container %s is not running", ctr.ID)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.execCount++
+	id := fmt.Sprintf("exec%08d", c.execCount)
+	c.execs[id] = &mockExec{container: ctr.ID, user: opts.User}
+	return id, nil
+}
+
+// AttachExec starts a fake shell that echoes its input and understands a
+// few commands, such as echo, hostname and exit
+func (c *DockerClient) AttachExec(ctx context.Context, execID string, size docker.TerminalSize) (io.ReadWriteCloser, error) {
+	c.mu.Lock()
+	exec, ok := c.execs[execID]
+	c.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no such exec: %s", execID)
+	}
+
+	reader, writer := io.Pipe()
+	shell := &mockShell{
+		client: c,
+		exec:   exec,
+		input:  make(chan []byte),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+		reader: reader,
+		writer: writer,
+	}
+	go shell.run()
+	return shell, nil
+}
+
+func (c *DockerClient) ResizeExec(ctx context.Context, execID string, size docker.TerminalSize) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.execs[execID]; !ok {
+		return fmt.Errorf("no such exec: %s", execID)
+	}
+	return nil
+}
+
+func (c *DockerClient) ExecExitCode(ctx context.Context, execID string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	exec, ok := c.execs[execID]
+	if !ok {
+		return 0, fmt.Errorf("no such exec: %s", execID)
+	}
+	return exec.exitCode, nil
+}
+
+const mockPrompt = "/ # "
+
+// mockShell is the terminal of a mock exec. Input is handled one write at a
+// time by run, which writes the echo and command output to the pipe.
+type mockShell struct {
+	client *DockerClient
+	exec   *mockExec
+	input  chan []byte
+	done   chan struct{}
+	exited chan struct{}
+	once   sync.Once
+	reader *io.PipeReader
+	writer *io.PipeWriter
+}
+
+func (s *mockShell) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+func (s *mockShell) Write(p []byte) (int, error) {
+	data := append([]byte(nil), p...)
+	select {
+	case s.input <- data:
+		return len(p), nil
+	case <-s.exited:
+		return 0, io.ErrClosedPipe
+	case <-s.done:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+func (s *mockShell) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+		s.reader.Close()
+	})
+	return nil
+}
+
+func (s *mockShell) run() {
+	defer close(s.exited)
+	defer s.writer.Close()
+
+	write := func(text string) {
+		s.writer.Write([]byte(text))
+	}
+
+	var line []byte
+	write(mockPrompt)
+	for {
+		select {
+		case <-s.done:
+			return
+		case data := <-s.input:
+			for _, b := range data {
+				switch b {
+				case '\r', '\n':
+					write("\r\n")
+					if output, code, exit := s.execute(strings.TrimSpace(string(line))); exit {
+						s.setExitCode(code)
+						return
+					} else if output != "" {
+						write(output + "\r\n")
+					}
+					line = line[:0]
+					write(mockPrompt)
+				case 0x7f, '\b':
+					if len(line) > 0 {
+						line = line[:len(line)-1]
+						write("\b \b")
+					}
+				case 0x03: // Ctrl-C
+					line = line[:0]
+					write("^C\r\n" + mockPrompt)
+				case 0x04: // Ctrl-D
+					if len(line) == 0 {
+						return
+					}
+				default:
+					line = append(line, b)
+					write(string(b))
+				}
+			}
+		}
+	}
+}
+
+// execute runs one command line, returning its output and, for exit, the
+// exit code
+func (s *mockShell) execute(line string) (string, int, bool) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return "", 0, false
+	}
+
+	switch args[0] {
+	case "exit":
+		code := 0
+		if len(args) > 1 {
+			code, _ = strconv.Atoi(args[1])
+		}
+		return "", code, true
+	case "echo":
+		return strings.Join(args[1:], " "), 0, false
+	case "hostname":
+		return s.exec.container, 0, false
+	case "whoami":
+		if s.exec.user != "" {
+			return s.exec.user, 0, false
+		}
+		return "root", 0, false
+	case "pwd":
+		return "/", 0, false
+	case "ls":
+		return "bin   dev   etc   home  lib   proc  root  run   sys   tmp   usr   var", 0, false
+	}
+	return "sh: " + args[0] + ": not found", 0, false
+}
+
+func (s *mockShell) setExitCode(code int) {
+	s.client.mu.Lock()
+	s.exec.exitCode = code
+	s.client.mu.Unlock()
+}
+
 func (c *DockerClient) ListVolumes(ctx context.Context) ([]docker.VolumeInfo, error) {
 	return []docker.VolumeInfo{
 		{
@@ -201,7 +496,7 @@ func (c *DockerClient) ListVolumes(ctx context.Context) ([]docker.VolumeInfo, er
 			Driver:     "local",
 			Mountpoint: "/var/lib/docker/volumes/celeste_data/_data",
 			CreatedAt:  time.Now().Add(-24 * time.Hour).Format(time.RFC3339),
-			Labels:     map[string]string{"project": "celeste"},
+			Labels:     map[string]string{"com.docker.compose.project": "celeste", "com.docker.compose.volume": "data"},
 			UsedBy:     []string{"celeste-backend"},
 		},
 		{
@@ -209,7 +504,7 @@ func (c *DockerClient) ListVolumes(ctx context.Context) ([]docker.VolumeInfo, er
 			Driver:     "local",
 			Mountpoint: "/var/lib/docker/volumes/prometheus_data/_data",
 			CreatedAt:  time.Now().Add(-48 * time.Hour).Format(time.RFC3339),
-			Labels:     map[string]string{"project": "monitoring"},
+			Labels:     map[string]string{"com.docker.compose.project": "monitoring", "com.docker.compose.volume": "prometheus_data"},
 			UsedBy:     []string{"prometheus"},
 		},
 		{
@@ -217,13 +512,14 @@ func (c *DockerClient) ListVolumes(ctx context.Context) ([]docker.VolumeInfo, er
 			Driver:     "local",
 			Mountpoint: "/var/lib/docker/volumes/grafana_data/_data",
 			CreatedAt:  time.Now().Add(-48 * time.Hour).Format(time.RFC3339),
-			Labels:     map[string]string{"project": "monitoring"},
+			Labels:     map[string]string{"com.docker.compose.project": "monitoring", "com.docker.compose.volume": "grafana_data"},
 			UsedBy:     []string{"grafana"},
 		},
 	}, nil
 }
 
 func (c *DockerClient) CreateVolume(ctx context.Context, name string, driver string, labels map[string]string) (*docker.VolumeInfo, error) {
+	c.publish(docker.Event{Type: docker.EventVolume, Action: "create", ID: name, Name: name})
 	return &docker.VolumeInfo{
 		Name:       name,
 		Driver:     driver,
@@ -234,6 +530,7 @@ func (c *DockerClient) CreateVolume(ctx context.Context, name string, driver str
 }
 
 func (c *DockerClient) DeleteVolume(ctx context.Context, name string, force bool) error {
+	c.publish(docker.Event{Type: docker.EventVolume, Action: "destroy", ID: name, Name: name})
 	return nil
 }
 
@@ -267,6 +564,7 @@ func (c *DockerClient) ListNetworks(ctx context.Context) ([]docker.NetworkInfo,
 }
 
 func (c *DockerClient) CreateNetwork(ctx context.Context, name string, driver string) (*docker.NetworkInfo, error) {
+	c.publish(docker.Event{Type: docker.EventNetwork, Action: "create", ID: "netnew12345", Name: name})
 	return &docker.NetworkInfo{
 		ID:     "netnew12345",
 		Name:   name,
@@ -276,36 +574,59 @@ func (c *DockerClient) CreateNetwork(ctx context.Context, name string, driver st
 }
 
 func (c *DockerClient) DeleteNetwork(ctx context.Context, id string) error {
+	c.publish(docker.Event{Type: docker.EventNetwork, Action: "destroy", ID: id})
 	return nil
 }
 
-func (c *DockerClient) ListImages(ctx context.Context) ([]docker.ImageInfo, error) {
-	return []docker.ImageInfo{
-		{
-			ID:      "sha256:abc123",
-			Tags:    []string{"celeste:latest", "celeste:2.1.1"},
-			Size:    150 * 1024 * 1024,
-			Created: time.Now().Add(-24 * time.Hour).Unix(),
-		},
-		{
-			ID:      "sha256:def456",
-			Tags:    []string{"prom/prometheus:latest"},
-			Size:    250 * 1024 * 1024,
-			Created: time.Now().Add(-72 * time.Hour).Unix(),
-		},
-		{
-			ID:      "sha256:ghi789",
-			Tags:    []string{"grafana/grafana:latest"},
-			Size:    400 * 1024 * 1024,
-			Created: time.Now().Add(-72 * time.Hour).Unix(),
-		},
-		{
-			ID:      "sha256:jkl012",
-			Tags:    []string{"redis:alpine"},
-			Size:    30 * 1024 * 1024,
-			Created: time.Now().Add(-168 * time.Hour).Unix(),
-		},
-	}, nil
+// Events delivers the events produced by the mock's own operations. Past
+// events are not replayed.
+func (c *DockerClient) Events(ctx context.Context, since time.Time) (<-chan docker.Event, <-chan error) {
+	events := make(chan docker.Event, 16)
+	errs := make(chan error, 1)
+
+	c.eventsMu.Lock()
+	c.subscribers[events] = struct{}{}
+	c.eventsMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.eventsMu.Lock()
+		delete(c.subscribers, events)
+		c.eventsMu.Unlock()
+		errs <- ctx.Err()
+		close(errs)
+	}()
+
+	return events, errs
+}
+
+func (c *DockerClient) publish(event docker.Event) {
+	event.Time = time.Now().UTC()
+
+	c.eventsMu.Lock()
+	defer c.eventsMu.Unlock()
+	for subscriber := range c.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+func (c *DockerClient) containerEvent(ctx context.Context, id string, actions ...string) {
+	ctr, err := c.GetContainer(ctx, id)
+	if err != nil {
+		return
+	}
+	for _, action := range actions {
+		c.publish(docker.Event{
+			Type:   docker.EventContainer,
+			Action: action,
+			ID:     ctr.ID,
+			Name:   ctr.Name,
+			Stack:  ctr.Labels["com.docker.compose.project"],
+		})
+	}
 }
 
 func (c *DockerClient) Close() error {
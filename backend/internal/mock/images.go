@@ -0,0 +1,351 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"aperture-science-network/internal/docker"
+)
+
+type mockImage struct {
+	info         docker.ImageInfo
+	architecture string
+	env          []string
+	entrypoint   []string
+	cmd          []string
+	workingDir   string
+	exposedPorts []string
+	labels       map[string]string
+}
+
+func mockImages() []mockImage {
+	return []mockImage{
+		{
+			info: docker.ImageInfo{
+				ID:         "abc123def456",
+				Tags:       []string{"celeste:latest", "celeste:2.1.1"},
+				Digests:    []string{"celeste@sha256:3f1e4c0a9b2d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f"},
+				Size:       150 * 1024 * 1024,
+				SharedSize: 8 * 1024 * 1024,
+				Created:    time.Now().Add(-24 * time.Hour).Unix(),
+			},
+			architecture: "amd64",
+			env:          []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "GIN_MODE=release"},
+			entrypoint:   []string{"/app/celeste"},
+			workingDir:   "/app",
+			exposedPorts: []string{"8080/tcp"},
+			labels:       map[string]string{"org.opencontainers.image.title": "celeste"},
+		},
+		{
+			info: docker.ImageInfo{
+				ID:         "def456abc789",
+				Tags:       []string{"prom/prometheus:latest"},
+				Digests:    []string{"prom/prometheus@sha256:5a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b"},
+				Size:       250 * 1024 * 1024,
+				SharedSize: 0,
+				Created:    time.Now().Add(-72 * time.Hour).Unix(),
+			},
+			architecture: "amd64",
+			env:          []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"},
+			entrypoint:   []string{"/bin/prometheus"},
+			cmd:          []string{"--config.file=/etc/prometheus/prometheus.yml", "--storage.tsdb.path=/prometheus"},
+			workingDir:   "/prometheus",
+			exposedPorts: []string{"9090/tcp"},
+			labels:       map[string]string{"maintainer": "The Prometheus Authors <prometheus-developers@googlegroups.com>"},
+		},
+		{
+			info: docker.ImageInfo{
+				ID:         "0a1b2c3d4e5f",
+				Tags:       []string{"grafana/grafana:latest"},
+				Digests:    []string{"grafana/grafana@sha256:7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2b1a0f9e8d7c6b"},
+				Size:       400 * 1024 * 1024,
+				SharedSize: 8 * 1024 * 1024,
+				Created:    time.Now().Add(-72 * time.Hour).Unix(),
+			},
+			architecture: "amd64",
+			env:          []string{"PATH=/usr/share/grafana/bin:/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "GF_PATHS_DATA=/var/lib/grafana"},
+			entrypoint:   []string{"/run.sh"},
+			workingDir:   "/usr/share/grafana",
+			exposedPorts: []string{"3000/tcp"},
+			labels:       map[string]string{},
+		},
+		{
+			info: docker.ImageInfo{
+				ID:         "1b2c3d4e5f6a",
+				Tags:       []string{"redis:alpine"},
+				Digests:    []string{"redis@sha256:9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2b1a0f9e8d"},
+				Size:       30 * 1024 * 1024,
+				SharedSize: 8 * 1024 * 1024,
+				Created:    time.Now().Add(-168 * time.Hour).Unix(),
+			},
+			architecture: "amd64",
+			env:          []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "REDIS_VERSION=7.4.1"},
+			entrypoint:   []string{"docker-entrypoint.sh"},
+			cmd:          []string{"redis-server"},
+			workingDir:   "/data",
+			exposedPorts: []string{"6379/tcp"},
+			labels:       map[string]string{},
+		},
+		{
+			info: docker.ImageInfo{
+				ID:         "2c3d4e5f6a7b",
+				Tags:       []string{"nginx:1.25"},
+				Digests:    []string{"nginx@sha256:1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f0e"},
+				Size:       190 * 1024 * 1024,
+				SharedSize: 0,
+				Created:    time.Now().Add(-720 * time.Hour).Unix(),
+			},
+			architecture: "amd64",
+			env:          []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "NGINX_VERSION=1.25.5"},
+			entrypoint:   []string{"/docker-entrypoint.sh"},
+			cmd:          []string{"nginx", "-g", "daemon off;"},
+			exposedPorts: []string{"80/tcp"},
+			labels:       map[string]string{"maintainer": "NGINX Docker Maintainers <docker-maint@nginx.com>"},
+		},
+		{
+			// The previous celeste build, left dangling by the last deploy
+			info: docker.ImageInfo{
+				ID:         "3d4e5f6a7b8c",
+				Tags:       []string{},
+				Digests:    []string{},
+				Size:       148 * 1024 * 1024,
+				SharedSize: 8 * 1024 * 1024,
+				Created:    time.Now().Add(-96 * time.Hour).Unix(),
+			},
+			architecture: "amd64",
+			env:          []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "GIN_MODE=release"},
+			entrypoint:   []string{"/app/celeste"},
+			workingDir:   "/app",
+			exposedPorts: []string{"8080/tcp"},
+			labels:       map[string]string{"org.opencontainers.image.title": "celeste"},
+		},
+	}
+}
+
+func (c *DockerClient) ListImages(ctx context.Context) ([]docker.ImageInfo, error) {
+	images := mockImages()
+	result := make([]docker.ImageInfo, len(images))
+	for i, img := range images {
+		result[i] = c.withUsers(ctx, img.info)
+	}
+	return result, nil
+}
+
+func (c *DockerClient) InspectImage(ctx context.Context, id string) (*docker.ImageDetails, error) {
+	img, err := findMockImage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &docker.ImageDetails{
+		ImageInfo:    c.withUsers(ctx, img.info),
+		Architecture: img.architecture,
+		OS:           "linux",
+		Env:          img.env,
+		Entrypoint:   img.entrypoint,
+		Cmd:          nonNil(img.cmd),
+		WorkingDir:   img.workingDir,
+		ExposedPorts: img.exposedPorts,
+		Volumes:      []string{},
+		Labels:       img.labels,
+	}
+	details.SharedSize = -1
+	for _, layer := range mockHistory(img) {
+		if layer.Size > 0 {
+			details.Layers = append(details.Layers, fmt.Sprintf("sha256:%064x", layer.Created+layer.Size))
+		}
+	}
+	return details, nil
+}
+
+func (c *DockerClient) ImageHistory(ctx context.Context, id string) ([]docker.ImageLayer, error) {
+	img, err := findMockImage(id)
+	if err != nil {
+		return nil, err
+	}
+	return mockHistory(img), nil
+}
+
+func (c *DockerClient) PullImage(ctx context.Context, ref string, auth *docker.RegistryAuth, progress func(docker.PullProgress)) error {
+	if ref == "" || strings.ContainsAny(ref, " \t") || ref != strings.ToLower(ref) {
+		return fmt.Errorf("%w: %s", docker.ErrInvalidReference, ref)
+	}
+	if !strings.Contains(ref, ":") && !strings.Contains(ref, "@") {
+		ref += ":latest"
+	}
+	tag := ref[strings.LastIndexAny(ref, ":@")+1:]
+
+	emit := func(p docker.PullProgress) {
+		if progress != nil {
+			progress(p)
+		}
+	}
+	emit(docker.PullProgress{Status: "Pulling from " + strings.Split(ref, ":")[0], ID: tag})
+
+	layers := []string{"a2318d6c47ec", "0b4d5a6f3c21", "7f3e2d1c0b9a"}
+	sizes := []int64{3 * 1024 * 1024, 24 * 1024 * 1024, 512 * 1024}
+	for i, layer := range layers {
+		emit(docker.PullProgress{ID: layer, Status: "Pulling fs layer"})
+		for step := int64(1); step <= 4; step++ {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(150 * time.Millisecond):
+			}
+			emit(docker.PullProgress{ID: layer, Status: "Downloading", Current: sizes[i] * step / 4, Total: sizes[i]})
+		}
+		emit(docker.PullProgress{ID: layer, Status: "Download complete"})
+		emit(docker.PullProgress{ID: layer, Status: "Pull complete"})
+	}
+	emit(docker.PullProgress{Status: "Digest: sha256:4b6a3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b"})
+	emit(docker.PullProgress{Status: "Status: Downloaded newer image for " + ref})
+
+	c.publish(docker.Event{Type: docker.EventImage, Action: "pull", ID: ref, Name: ref})
+	return nil
+}
+
+func (c *DockerClient) RemoveImage(ctx context.Context, ref string, force bool) ([]docker.ImageRemoval, error) {
+	img, err := findMockImage(ref)
+	if err != nil {
+		return nil, err
+	}
+	info := c.withUsers(ctx, img.info)
+
+	// Removing one of several tags only untags it
+	for _, tag := range info.Tags {
+		if tag == ref && len(info.Tags) > 1 {
+			c.publish(docker.Event{Type: docker.EventImage, Action: "untag", ID: info.ID, Name: tag})
+			return []docker.ImageRemoval{{Untagged: tag}}, nil
+		}
+	}
+
+	for _, ctr := range info.Containers {
+		if ctr.State == "running" || !force {
+			return nil, fmt.Errorf("%w: image is being used by container %s", docker.ErrImageConflict, ctr.ID)
+		}
+	}
+	if len(info.Tags) > 1 && !force {
+		return nil, fmt.Errorf("%w: image is referenced in multiple repositories", docker.ErrImageConflict)
+	}
+
+	removed := make([]docker.ImageRemoval, 0, len(info.Tags)+1)
+	for _, tag := range info.Tags {
+		removed = append(removed, docker.ImageRemoval{Untagged: tag})
+		c.publish(docker.Event{Type: docker.EventImage, Action: "untag", ID: info.ID, Name: tag})
+	}
+	removed = append(removed, docker.ImageRemoval{Deleted: info.ID})
+	c.publish(docker.Event{Type: docker.EventImage, Action: "delete", ID: info.ID})
+	return removed, nil
+}
+
+func (c *DockerClient) TagImage(ctx context.Context, source string, target string) error {
+	img, err := findMockImage(source)
+	if err != nil {
+		return err
+	}
+	c.publish(docker.Event{Type: docker.EventImage, Action: "tag", ID: img.info.ID, Name: target})
+	return nil
+}
+
+func (c *DockerClient) PruneImages(ctx context.Context, opts docker.ImagePruneOptions) (*docker.ImagePruneReport, error) {
+	images, _ := c.ListImages(ctx)
+	report := &docker.ImagePruneReport{
+		Images: docker.PruneCandidates(images, opts.All),
+		DryRun: opts.DryRun,
+	}
+	for _, img := range report.Images {
+		report.SpaceReclaimed += uint64(img.Size - img.SharedSize)
+		if !opts.DryRun {
+			c.publish(docker.Event{Type: docker.EventImage, Action: "delete", ID: img.ID})
+		}
+	}
+	return report, nil
+}
+
+// withUsers fills in the containers and stacks using an image, matching
+// the mock containers by image name
+func (c *DockerClient) withUsers(ctx context.Context, info docker.ImageInfo) docker.ImageInfo {
+	info.Containers = []docker.ImageContainer{}
+	info.Stacks = []string{}
+	info.Dangling = len(info.Tags) == 0
+
+	containers, _ := c.ListContainers(ctx, true)
+	stacks := make(map[string]bool)
+	for _, ctr := range containers {
+		for _, tag := range info.Tags {
+			if ctr.Image != tag {
+				continue
+			}
+			stack := ctr.Labels["com.docker.compose.project"]
+			info.Containers = append(info.Containers, docker.ImageContainer{
+				ID:    ctr.ID,
+				Name:  ctr.Name,
+				State: ctr.State,
+				Stack: stack,
+			})
+			if stack != "" && !stacks[stack] {
+				stacks[stack] = true
+				info.Stacks = append(info.Stacks, stack)
+			}
+		}
+	}
+	sort.Strings(info.Stacks)
+	return info
+}
+
+func findMockImage(ref string) (*mockImage, error) {
+	ref = strings.TrimPrefix(ref, "sha256:")
+	if ref != "" && !strings.ContainsAny(ref, ":@/") && !strings.Contains(ref, ".") {
+		// Bare names refer to the latest tag, unless they are an ID prefix
+		for _, img := range mockImages() {
+			if strings.HasPrefix(img.info.ID, ref) {
+				return &img, nil
+			}
+		}
+		ref += ":latest"
+	}
+	for _, img := range mockImages() {
+		for _, tag := range img.info.Tags {
+			if tag == ref {
+				return &img, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", docker.ErrImageNotFound, ref)
+}
+
+// mockHistory makes up a plausible build history for an image, newest first
+func mockHistory(img *mockImage) []docker.ImageLayer {
+	base := time.Unix(img.info.Created, 0)
+	baseSize := int64(8 * 1024 * 1024)
+	appSize := img.info.Size - baseSize - 2*1024*1024
+
+	layers := []docker.ImageLayer{
+		{ID: img.info.ID, Created: base.Unix(), CreatedBy: `/bin/sh -c #(nop)  ENTRYPOINT ["` + strings.Join(img.entrypoint, `" "`) + `"]`, Tags: img.info.Tags},
+		{Created: base.Add(-time.Minute).Unix(), CreatedBy: "COPY . " + orDefault(img.workingDir, "/") + " # buildkit", Size: appSize, Comment: "buildkit.dockerfile.v0"},
+		{Created: base.Add(-2 * time.Minute).Unix(), CreatedBy: "RUN /bin/sh -c apk add --no-cache ca-certificates tzdata # buildkit", Size: 2 * 1024 * 1024, Comment: "buildkit.dockerfile.v0"},
+		{Created: base.Add(-30 * 24 * time.Hour).Unix(), CreatedBy: `/bin/sh -c #(nop)  CMD ["/bin/sh"]`},
+		{Created: base.Add(-30*24*time.Hour - time.Second).Unix(), CreatedBy: "/bin/sh -c #(nop) ADD file:6f1d2e3c4b5a in / ", Size: baseSize},
+	}
+	if len(layers[0].Tags) == 0 {
+		layers[0].Tags = nil
+	}
+	return layers
+}
+
+func orDefault(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
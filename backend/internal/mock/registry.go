@@ -0,0 +1,46 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+
+	"aperture-science-network/internal/registry"
+)
+
+// Registry is a mock implementation of registry.Resolver. The celeste image
+// has a newer build available; the others are up to date.
+type Registry struct{}
+
+// NewRegistry creates a new mock registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Ensure Registry implements registry.Resolver
+var _ registry.Resolver = (*Registry)(nil)
+
+func (r *Registry) Digest(ctx context.Context, ref string) (string, error) {
+	parsed, err := registry.ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Digest != "" {
+		return parsed.Digest, nil
+	}
+
+	if parsed.Name == "docker.io/library/celeste" && parsed.Tag == "latest" {
+		return "sha256:8d2c4f6e1a3b5c7d9e0f2a4b6c8d0e1f3a5b7c9d1e2f4a6b8c0d2e3f5a7b9c1d", nil
+	}
+	for _, img := range mockImages() {
+		for _, tag := range img.info.Tags {
+			if t, err := registry.ParseReference(tag); err == nil && t.Name == parsed.Name && t.Tag == parsed.Tag {
+				for _, d := range img.info.Digests {
+					if digested, err := registry.ParseReference(d); err == nil {
+						return digested.Digest, nil
+					}
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %s:%s", registry.ErrManifestNotFound, parsed.Name, parsed.Tag)
+}
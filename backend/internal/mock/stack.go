@@ -2,13 +2,20 @@ package mock
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"aperture-science-network/internal/stack"
 )
 
 // StackProvider is a mock implementation of stack.Provider
 type StackProvider struct {
-	stacks map[string]*stack.StackInfo
+	mu      sync.RWMutex
+	stacks  map[string]*stack.StackInfo
+	compose map[string]string
+	files   map[string]map[string]string
 }
 
 // NewStackProvider creates a new mock stack provider
@@ -21,6 +28,7 @@ func NewStackProvider() *StackProvider {
 				Status:          "partial",
 				Services:        3,
 				RunningServices: 2,
+				ComposeFiles:    []string{"docker-compose.yml"},
 			},
 			"monitoring": {
 				Name:            "monitoring",
@@ -28,6 +36,7 @@ func NewStackProvider() *StackProvider {
 				Status:          "partial",
 				Services:        2,
 				RunningServices: 1,
+				ComposeFiles:    []string{"docker-compose.yml"},
 			},
 			"database": {
 				Name:            "database",
@@ -35,8 +44,14 @@ func NewStackProvider() *StackProvider {
 				Status:          "stopped",
 				Services:        2,
 				RunningServices: 0,
+				ComposeFiles:    []string{"docker-compose.yml"},
 			},
 		},
+		compose: make(map[string]string),
+		files: map[string]map[string]string{
+			"celeste":    {".env": "REDIS_PASSWORD=changeme\n"},
+			"monitoring": {"prometheus/prometheus.yml": "global:\n  scrape_interval: 15s\n"},
+		},
 	}
 }
 
@@ -44,6 +59,9 @@ func NewStackProvider() *StackProvider {
 var _ stack.Provider = (*StackProvider)(nil)
 
 func (p *StackProvider) ListStacks() ([]stack.StackInfo, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	stacks := make([]stack.StackInfo, 0, len(p.stacks))
 	for _, s := range p.stacks {
 		stacks = append(stacks, *s)
@@ -52,16 +70,26 @@ func (p *StackProvider) ListStacks() ([]stack.StackInfo, error) {
 }
 
 func (p *StackProvider) GetStack(name string) (*stack.StackInfo, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	if s, ok := p.stacks[name]; ok {
-		return s, nil
+		info := *s
+		return &info, nil
 	}
-	return nil, fmt.Errorf("stack not found: %s", name)
+	return nil, stack.ErrStackNotFound
 }
 
 func (p *StackProvider) GetComposeFile(name string) (string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	if _, ok := p.stacks[name]; !ok {
 		return "", fmt.Errorf("stack not found: %s", name)
 	}
+	if content, ok := p.compose[name]; ok {
+		return content, nil
+	}
 
 	// Return mock compose content based on stack name
 	switch name {
@@ -148,19 +176,217 @@ services:
 }
 
 func (p *StackProvider) UpdateComposeFile(name string, content string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if _, ok := p.stacks[name]; !ok {
 		return fmt.Errorf("stack not found: %s", name)
 	}
-	// In mock mode, just pretend to save
+	// In mock mode, keep the content in memory only
+	p.compose[name] = content
 	return nil
 }
 
+func (p *StackProvider) CreateStack(name string, compose string, env string) (*stack.StackInfo, error) {
+	if !stack.ValidName(name) {
+		return nil, stack.ErrInvalidStackName
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.stacks[name]; ok {
+		return nil, stack.ErrStackExists
+	}
+	p.stacks[name] = &stack.StackInfo{
+		Name:         name,
+		Path:         "/stacks/" + name,
+		Status:       "stopped",
+		ComposeFiles: []string{"docker-compose.yml"},
+	}
+	p.compose[name] = compose
+
+	info := *p.stacks[name]
+	return &info, nil
+}
+
+func (p *StackProvider) RenameStack(name string, newName string) (*stack.StackInfo, error) {
+	if !stack.ValidName(newName) {
+		return nil, stack.ErrInvalidStackName
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.stacks[name]
+	if !ok {
+		return nil, stack.ErrStackNotFound
+	}
+	if _, ok := p.stacks[newName]; ok {
+		return nil, stack.ErrStackExists
+	}
+
+	s.Name = newName
+	s.Path = "/stacks/" + newName
+	p.stacks[newName] = s
+	delete(p.stacks, name)
+	if content, ok := p.compose[name]; ok {
+		p.compose[newName] = content
+		delete(p.compose, name)
+	}
+	if files, ok := p.files[name]; ok {
+		p.files[newName] = files
+		delete(p.files, name)
+	}
+
+	info := *s
+	return &info, nil
+}
+
+func (p *StackProvider) DeleteStack(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.stacks[name]; !ok {
+		return stack.ErrStackNotFound
+	}
+	delete(p.stacks, name)
+	delete(p.compose, name)
+	delete(p.files, name)
+	return nil
+}
+
+func (p *StackProvider) ListFiles(name string) ([]stack.FileEntry, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if _, ok := p.stacks[name]; !ok {
+		return nil, stack.ErrStackNotFound
+	}
+
+	entries := []stack.FileEntry{{Path: "docker-compose.yml", Mode: "-rw-r--r--", ModTime: time.Now()}}
+	paths := make([]string, 0, len(p.files[name]))
+	for filePath := range p.files[name] {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	for _, filePath := range paths {
+		entries = append(entries, stack.FileEntry{
+			Path:    strings.TrimSuffix(filePath, "/"),
+			Dir:     strings.HasSuffix(filePath, "/"),
+			Size:    int64(len(p.files[name][filePath])),
+			Mode:    "-rw-r--r--",
+			ModTime: time.Now(),
+		})
+	}
+	return entries, nil
+}
+
+func (p *StackProvider) ReadFile(name string, filePath string) (*stack.FileContent, error) {
+	rel, err := stack.CleanFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if rel == "docker-compose.yml" {
+		content, err := p.GetComposeFile(name)
+		if err != nil {
+			return nil, err
+		}
+		return &stack.FileContent{FileEntry: stack.FileEntry{Path: rel, Size: int64(len(content))}, Content: content}, nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	content, ok := p.files[name][rel]
+	if !ok {
+		return nil, stack.ErrFileNotFound
+	}
+	return &stack.FileContent{FileEntry: stack.FileEntry{Path: rel, Size: int64(len(content))}, Content: content}, nil
+}
+
+func (p *StackProvider) WriteFile(name string, filePath string, content string) (*stack.FileEntry, error) {
+	rel, err := stack.CleanFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if rel == "docker-compose.yml" {
+		return nil, stack.ErrProtectedFile
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.files[name][rel]; !ok {
+		return nil, stack.ErrFileNotFound
+	}
+	p.files[name][rel] = content
+	return &stack.FileEntry{Path: rel, Size: int64(len(content)), ModTime: time.Now()}, nil
+}
+
+func (p *StackProvider) CreateFile(name string, filePath string, content string, dir bool) (*stack.FileEntry, error) {
+	rel, err := stack.CleanFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.stacks[name]; !ok {
+		return nil, stack.ErrStackNotFound
+	}
+	if p.files[name] == nil {
+		p.files[name] = make(map[string]string)
+	}
+	if _, ok := p.files[name][rel]; ok || rel == "docker-compose.yml" {
+		return nil, stack.ErrFileExists
+	}
+	// In mock mode, directories are stored as keys with a trailing slash
+	key := rel
+	if dir {
+		key += "/"
+		content = ""
+	}
+	p.files[name][key] = content
+	return &stack.FileEntry{Path: rel, Dir: dir, Size: int64(len(content)), ModTime: time.Now()}, nil
+}
+
+func (p *StackProvider) DeleteFile(name string, filePath string) error {
+	rel, err := stack.CleanFilePath(filePath)
+	if err != nil {
+		return err
+	}
+	if rel == "docker-compose.yml" {
+		return stack.ErrProtectedFile
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.files[name][rel]; ok {
+		delete(p.files[name], rel)
+		return nil
+	}
+	if _, ok := p.files[name][rel+"/"]; ok {
+		delete(p.files[name], rel+"/")
+		return nil
+	}
+	return stack.ErrFileNotFound
+}
+
 func (p *StackProvider) StackExists(name string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	_, ok := p.stacks[name]
 	return ok
 }
 
 func (p *StackProvider) GetStackPath(name string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	if s, ok := p.stacks[name]; ok {
 		return s.Path
 	}
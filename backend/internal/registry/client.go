@@ -0,0 +1,321 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+)
+
+// requestTimeout bounds one registry lookup, token exchange included
+const requestTimeout = 30 * time.Second
+
+// maxManifestSize caps manifests downloaded to compute their digest
+const maxManifestSize = 4 * 1024 * 1024
+
+// manifestTypes are the manifest formats accepted, manifest lists first so
+// multi-platform images resolve to the same digest docker pull records
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed image reference
+type Reference struct {
+	// Name is the fully qualified repository, e.g. "docker.io/library/redis"
+	Name string
+	// Domain is the registry host, with its port if any
+	Domain string
+	// Path is the repository inside the registry, e.g. "library/redis"
+	Path   string
+	Tag    string
+	Digest string
+}
+
+// ParseReference normalizes a reference the way the Docker CLI does: Docker
+// Hub is the default registry, "library/" the default namespace and
+// "latest" the default tag
+func ParseReference(ref string) (Reference, error) {
+	named, err := reference.ParseNormalizedNamed(strings.TrimSpace(ref))
+	if err != nil {
+		return Reference{}, fmt.Errorf("%w: %v", ErrInvalidReference, err)
+	}
+	named = reference.TagNameOnly(named)
+
+	r := Reference{
+		Name:   named.Name(),
+		Domain: reference.Domain(named),
+		Path:   reference.Path(named),
+	}
+	if tagged, ok := named.(reference.Tagged); ok {
+		r.Tag = tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		r.Digest = digested.Digest().String()
+	}
+	return r, nil
+}
+
+// Options configures a Client
+type Options struct {
+	// Insecure lists registry hosts reached over plain HTTP. Loopback
+	// registries always are, as in the Docker daemon.
+	Insecure []string
+	// Credentials, if set, authenticate requests to registries that have
+	// one stored
+	Credentials CredentialStore
+}
+
+// Client queries registries over the Docker Registry HTTP API v2
+type Client struct {
+	httpClient  *http.Client
+	insecure    map[string]bool
+	credentials CredentialStore
+}
+
+// NewClient creates a registry client
+func NewClient(opts Options) *Client {
+	insecure := make(map[string]bool, len(opts.Insecure))
+	for _, host := range opts.Insecure {
+		if host = strings.TrimSpace(host); host != "" {
+			insecure[host] = true
+		}
+	}
+	return &Client{
+		httpClient:  &http.Client{Timeout: requestTimeout},
+		insecure:    insecure,
+		credentials: opts.Credentials,
+	}
+}
+
+func (c *Client) Digest(ctx context.Context, ref string) (string, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	if r.Digest != "" {
+		return r.Digest, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	manifestURL := c.baseURL(r.Domain) + "/v2/" + r.Path + "/manifests/" + r.Tag
+	authorization := ""
+	resp, err := c.manifestRequest(ctx, http.MethodHead, manifestURL, authorization)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		if authorization, err = c.authorize(ctx, resp.Header.Get("WWW-Authenticate"), r); err != nil {
+			return "", err
+		}
+		if resp, err = c.manifestRequest(ctx, http.MethodHead, manifestURL, authorization); err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+
+	if err := checkStatus(resp, r); err != nil {
+		return "", err
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+	// Some registries only send the digest header on GET
+	return c.digestFromBody(ctx, manifestURL, authorization, r)
+}
+
+// digestFromBody downloads the manifest and hashes it, for registries that
+// don't report its digest
+func (c *Client) digestFromBody(ctx context.Context, manifestURL string, authorization string, r Reference) (string, error) {
+	resp, err := c.manifestRequest(ctx, http.MethodGet, manifestURL, authorization)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, r); err != nil {
+		return "", err
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// Hashing a truncated manifest would give a wrong digest, so reading one
+	// byte past the limit tells an oversized manifest apart
+	h := sha256.New()
+	n, err := io.Copy(h, io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return "", err
+	}
+	if n > maxManifestSize {
+		return "", fmt.Errorf("manifest for %s:%s is larger than %d bytes", r.Name, r.Tag, maxManifestSize)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *Client) manifestRequest(ctx context.Context, method string, manifestURL string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return c.httpClient.Do(req)
+}
+
+// authorize answers a registry's authentication challenge with an
+// Authorization header value: the stored credential for a Basic challenge,
+// or a token for a Bearer one
+func (c *Client) authorize(ctx context.Context, challenge string, r Reference) (string, error) {
+	cred := c.credential(r.Domain)
+	scheme, params := parseChallenge(challenge)
+	switch {
+	case strings.EqualFold(scheme, "basic") && cred != nil:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(cred.Username+":"+cred.Password)), nil
+	case strings.EqualFold(scheme, "bearer") && params["realm"] != "":
+		token, err := c.token(ctx, params, cred, r)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnauthorized, r.Name)
+	}
+}
+
+// credential returns the stored credential for a registry, if any
+func (c *Client) credential(domain string) *Credential {
+	if c.credentials == nil {
+		return nil
+	}
+	cred, err := c.credentials.Lookup(domain)
+	if err != nil {
+		return nil
+	}
+	return cred
+}
+
+// token obtains a pull token from the authorization server named in a
+// Bearer challenge, presenting cred if set. Anonymous tokens are enough for
+// public repositories.
+func (c *Client) token(ctx context.Context, params map[string]string, cred *Credential, r Reference) (string, error) {
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %w", params["realm"], err)
+	}
+	query := tokenURL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + r.Path + ":pull"
+	}
+	query.Set("scope", scope)
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if cred != nil {
+		req.SetBasicAuth(cred.Username, cred.Password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("%w: %s", ErrUnauthorized, r.Name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request for %s returned %s", r.Name, resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response for %s: %w", r.Name, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// baseURL returns the registry's API root. Docker Hub's API is not served
+// from docker.io itself.
+func (c *Client) baseURL(domain string) string {
+	if domain == "docker.io" {
+		domain = "registry-1.docker.io"
+	}
+	if c.insecure[domain] || isLoopback(domain) {
+		return "http://" + domain
+	}
+	return "https://" + domain
+}
+
+func isLoopback(domain string) bool {
+	host := domain
+	if h, _, err := net.SplitHostPort(domain); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func checkStatus(resp *http.Response, r Reference) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s:%s", ErrManifestNotFound, r.Name, r.Tag)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: %s", ErrUnauthorized, r.Name)
+	default:
+		return fmt.Errorf("manifest request for %s:%s returned %s", r.Name, r.Tag, resp.Status)
+	}
+}
+
+// parseChallenge splits a WWW-Authenticate header such as
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.TrimSpace(key); key != "" {
+			params[strings.ToLower(key)] = value
+		}
+	}
+	return scheme, params
+}
@@ -0,0 +1,166 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    Reference
+		wantErr bool
+	}{
+		{
+			ref:  "redis",
+			want: Reference{Name: "docker.io/library/redis", Domain: "docker.io", Path: "library/redis", Tag: "latest"},
+		},
+		{
+			ref:  "grafana/grafana:10.4",
+			want: Reference{Name: "docker.io/grafana/grafana", Domain: "docker.io", Path: "grafana/grafana", Tag: "10.4"},
+		},
+		{
+			ref:  "ghcr.io/owner/app:v1",
+			want: Reference{Name: "ghcr.io/owner/app", Domain: "ghcr.io", Path: "owner/app", Tag: "v1"},
+		},
+		{
+			ref:  "localhost:5000/app",
+			want: Reference{Name: "localhost:5000/app", Domain: "localhost:5000", Path: "app", Tag: "latest"},
+		},
+		{
+			ref: "nginx@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			want: Reference{Name: "docker.io/library/nginx", Domain: "docker.io", Path: "library/nginx",
+				Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+		},
+		{ref: "Invalid/UPPER", wantErr: true},
+		{ref: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseReference(tt.ref)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidReference) {
+					t.Fatalf("ParseReference() error = %v, want %v", err, ErrInvalidReference)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseReference() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseReference() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		header     string
+		wantScheme string
+		wantParams map[string]string
+	}{
+		{
+			header:     `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/redis:pull"`,
+			wantScheme: "Bearer",
+			wantParams: map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:library/redis:pull"},
+		},
+		{
+			header:     `Basic realm="Registry Realm"`,
+			wantScheme: "Basic",
+			wantParams: map[string]string{"realm": "Registry Realm"},
+		},
+		{
+			header:     `Bearer Realm="https://ghcr.io/token", service=ghcr.io, scope="repository:a/b:pull,push"`,
+			wantScheme: "Bearer",
+			wantParams: map[string]string{"realm": "https://ghcr.io/token", "service": "ghcr.io", "scope": "repository:a/b:pull,push"},
+		},
+		{
+			header:     "Basic",
+			wantScheme: "Basic",
+			wantParams: map[string]string{},
+		},
+		{
+			header:     "",
+			wantScheme: "",
+			wantParams: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			scheme, params := parseChallenge(tt.header)
+			if scheme != tt.wantScheme {
+				t.Errorf("scheme = %q, want %q", scheme, tt.wantScheme)
+			}
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestClientDigest(t *testing.T) {
+	manifest := `{"schemaVersion":2}`
+	sum := sha256.Sum256([]byte(manifest))
+	bodyDigest := "sha256:" + hex.EncodeToString(sum[:])
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:app:pull" {
+				t.Errorf("token scope = %q", r.URL.Query().Get("scope"))
+			}
+			w.Write([]byte(`{"token":"secret"}`))
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/app/manifests/header":
+			w.Header().Set("Docker-Content-Digest", "sha256:fromheader")
+		case r.URL.Path == "/v2/app/manifests/body":
+			w.Write([]byte(manifest))
+		case r.URL.Path == "/v2/app/manifests/huge":
+			w.Write([]byte(strings.Repeat(" ", maxManifestSize+1)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr error
+	}{
+		{tag: "header", want: "sha256:fromheader"},
+		{tag: "body", want: bodyDigest},
+		{tag: "missing", wantErr: ErrManifestNotFound},
+		{tag: "huge"},
+	}
+	client := NewClient(Options{})
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := client.Digest(context.Background(), host+"/app:"+tt.tag)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Digest() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.want == "":
+				if err == nil {
+					t.Errorf("Digest() = %q, want an error", got)
+				}
+			case err != nil || got != tt.want:
+				t.Errorf("Digest() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
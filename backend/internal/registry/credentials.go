@@ -0,0 +1,238 @@
+package registry
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aperture-science-network/internal/fsutil"
+)
+
+// dockerHubAddress is the key Docker clients use for Docker Hub credentials
+const dockerHubAddress = "https://index.docker.io/v1/"
+
+type credentialsFile struct {
+	Credentials []Credential `json:"credentials"`
+}
+
+// FileCredentialStore implements CredentialStore as a single file encrypted
+// with AES-256-GCM
+type FileCredentialStore struct {
+	path        string
+	aead        cipher.AEAD
+	mu          sync.RWMutex
+	credentials map[string]*Credential
+}
+
+// NewFileCredentialStore loads (or initializes) the credentials at path,
+// encrypted with a 32-byte key
+func NewFileCredentialStore(path string, key []byte) (*FileCredentialStore, error) {
+	if len(key) < 32 {
+		return nil, errors.New("credential encryption key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileCredentialStore{
+		path:        path,
+		aead:        aead,
+		credentials: make(map[string]*Credential),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	nonceSize := aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, errors.New("credentials file is corrupt: " + path)
+	}
+	plain, err := aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt %s, its key may have been replaced", path)
+	}
+
+	var file credentialsFile
+	if err := json.Unmarshal(plain, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Credentials {
+		s.credentials[file.Credentials[i].Registry] = &file.Credentials[i]
+	}
+	return s, nil
+}
+
+// Ensure FileCredentialStore implements CredentialStore
+var _ CredentialStore = (*FileCredentialStore)(nil)
+
+func (s *FileCredentialStore) ListCredentials() ([]Credential, error) {
+	creds, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	for i := range creds {
+		creds[i].Password = ""
+	}
+	return creds, nil
+}
+
+func (s *FileCredentialStore) All() ([]Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	creds := make([]Credential, 0, len(s.credentials))
+	for _, c := range s.credentials {
+		creds = append(creds, *c)
+	}
+	sort.Slice(creds, func(i, j int) bool { return creds[i].Registry < creds[j].Registry })
+	return creds, nil
+}
+
+func (s *FileCredentialStore) Lookup(registry string) (*Credential, error) {
+	host, err := NormalizeRegistry(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.credentials[host]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	cred := *c
+	return &cred, nil
+}
+
+func (s *FileCredentialStore) SetCredential(cred Credential) (*Credential, error) {
+	host, err := NormalizeRegistry(cred.Registry)
+	if err != nil {
+		return nil, err
+	}
+	cred.Registry = host
+	cred.Username = strings.TrimSpace(cred.Username)
+	if cred.Username == "" {
+		return nil, fmt.Errorf("%w: username is required", ErrInvalidCredential)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.credentials[host]
+	if cred.Password == "" {
+		// Keep the stored password when only the username changes
+		if !ok {
+			return nil, fmt.Errorf("%w: password is required", ErrInvalidCredential)
+		}
+		cred.Password = existing.Password
+	}
+	cred.UpdatedAt = time.Now().UTC()
+	cred.CreatedAt = cred.UpdatedAt
+	if ok {
+		cred.CreatedAt = existing.CreatedAt
+	}
+
+	s.credentials[host] = &cred
+	if err := s.saveLocked(); err != nil {
+		if ok {
+			s.credentials[host] = existing
+		} else {
+			delete(s.credentials, host)
+		}
+		return nil, err
+	}
+
+	result := cred
+	result.Password = ""
+	return &result, nil
+}
+
+func (s *FileCredentialStore) DeleteCredential(registry string) error {
+	host, err := NormalizeRegistry(registry)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.credentials[host]
+	if !ok {
+		return ErrCredentialNotFound
+	}
+	delete(s.credentials, host)
+	if err := s.saveLocked(); err != nil {
+		s.credentials[host] = existing
+		return err
+	}
+	return nil
+}
+
+func (s *FileCredentialStore) saveLocked() error {
+	file := credentialsFile{Credentials: make([]Credential, 0, len(s.credentials))}
+	for _, c := range s.credentials {
+		file.Credentials = append(file.Credentials, *c)
+	}
+	sort.Slice(file.Credentials, func(i, j int) bool { return file.Credentials[i].Registry < file.Credentials[j].Registry })
+
+	plain, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(s.path, s.aead.Seal(nonce, nonce, plain, nil), 0600)
+}
+
+// NormalizeRegistry reduces a registry given as a host, a URL or an image
+// reference domain to the host (and port) credentials are stored under.
+// Docker Hub's aliases all become "docker.io".
+func NormalizeRegistry(registry string) (string, error) {
+	host := strings.ToLower(strings.TrimSpace(registry))
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+
+	if host == "" {
+		return "", fmt.Errorf("%w: registry is required", ErrInvalidCredential)
+	}
+	if u, err := url.Parse("//" + host); err != nil || u.Host != host || u.User != nil {
+		return "", fmt.Errorf("%w: invalid registry %q", ErrInvalidCredential, registry)
+	}
+
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		host = "docker.io"
+	}
+	return host, nil
+}
+
+// ServerAddress returns the key Docker clients look a registry's
+// credentials up by
+func ServerAddress(registry string) string {
+	if registry == "docker.io" {
+		return dockerHubAddress
+	}
+	return registry
+}
@@ -0,0 +1,101 @@
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeRegistry(t *testing.T) {
+	tests := []struct {
+		registry string
+		want     string
+		wantErr  bool
+	}{
+		{registry: "ghcr.io", want: "ghcr.io"},
+		{registry: " GHCR.io ", want: "ghcr.io"},
+		{registry: "https://ghcr.io/v2/", want: "ghcr.io"},
+		{registry: "http://localhost:5000", want: "localhost:5000"},
+		{registry: "registry.example.com:8443/team/app", want: "registry.example.com:8443"},
+		{registry: "docker.io", want: "docker.io"},
+		{registry: "index.docker.io", want: "docker.io"},
+		{registry: "https://index.docker.io/v1/", want: "docker.io"},
+		{registry: "registry-1.docker.io", want: "docker.io"},
+		{registry: "registry.hub.docker.com", want: "docker.io"},
+		{registry: "", wantErr: true},
+		{registry: "https://", wantErr: true},
+		{registry: "user:pass@ghcr.io", wantErr: true},
+		{registry: "bad host", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.registry, func(t *testing.T) {
+			got, err := NormalizeRegistry(tt.registry)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCredential) {
+					t.Fatalf("NormalizeRegistry() = %q, %v, want %v", got, err, ErrInvalidCredential)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeRegistry() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileCredentialStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	key := bytes.Repeat([]byte{1}, 32)
+
+	store, err := NewFileCredentialStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SetCredential(Credential{Registry: "ghcr.io", Username: "bot"}); !errors.Is(err, ErrInvalidCredential) {
+		t.Errorf("SetCredential() without a password = %v, want %v", err, ErrInvalidCredential)
+	}
+	saved, err := store.SetCredential(Credential{Registry: "https://GHCR.io", Username: "bot", Password: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Registry != "ghcr.io" || saved.Password != "" {
+		t.Errorf("SetCredential() = %+v, want normalized registry and no password", saved)
+	}
+	// Changing only the username keeps the password
+	if _, err := store.SetCredential(Credential{Registry: "ghcr.io", Username: "robot"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("s3cret")) || bytes.Contains(data, []byte("robot")) {
+		t.Error("credentials are stored in plain text")
+	}
+
+	reloaded, err := NewFileCredentialStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := reloaded.Lookup("ghcr.io/owner/app")
+	if err != nil || cred.Username != "robot" || cred.Password != "s3cret" {
+		t.Errorf("Lookup() = %+v, %v", cred, err)
+	}
+	if listed, _ := reloaded.ListCredentials(); len(listed) != 1 || listed[0].Password != "" {
+		t.Errorf("ListCredentials() = %+v, want one entry without password", listed)
+	}
+
+	if _, err := NewFileCredentialStore(path, bytes.Repeat([]byte{2}, 32)); err == nil {
+		t.Error("NewFileCredentialStore() with another key succeeded")
+	}
+
+	if err := reloaded.DeleteCredential("ghcr.io"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.Lookup("ghcr.io"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Lookup() after delete = %v, want %v", err, ErrCredentialNotFound)
+	}
+}
@@ -0,0 +1,58 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidReference   = errors.New("invalid image reference")
+	ErrManifestNotFound   = errors.New("manifest not found")
+	ErrUnauthorized       = errors.New("registry denied access")
+	ErrCredentialNotFound = errors.New("registry credential not found")
+	ErrInvalidCredential  = errors.New("invalid registry credential")
+)
+
+// Resolver looks up what a tag currently points to in its registry
+type Resolver interface {
+	// Digest returns the manifest digest a reference such as "redis:alpine"
+	// or "ghcr.io/owner/app:1.2" resolves to. For multi-platform images this
+	// is the digest of the manifest list, as recorded in local RepoDigests.
+	Digest(ctx context.Context, ref string) (string, error)
+}
+
+// Ensure Client implements Resolver
+var _ Resolver = (*Client)(nil)
+
+// Credential authenticates pulls from one registry
+type Credential struct {
+	// Registry is the registry host, with its port if any; "docker.io" for
+	// Docker Hub
+	Registry string `json:"registry"`
+	Username string `json:"username"`
+	// Password may also be an access token. It is never returned by the API.
+	Password  string    `json:"password,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CredentialStore persists registry credentials
+type CredentialStore interface {
+	// ListCredentials returns all credentials without their passwords,
+	// ordered by registry
+	ListCredentials() ([]Credential, error)
+
+	// All returns all credentials with their passwords
+	All() ([]Credential, error)
+
+	// Lookup returns the credential for a registry, with its password
+	Lookup(registry string) (*Credential, error)
+
+	// SetCredential creates or replaces a registry's credential. An empty
+	// password keeps the stored one. The result has no password.
+	SetCredential(cred Credential) (*Credential, error)
+
+	// DeleteCredential removes a registry's credential
+	DeleteCredential(registry string) error
+}
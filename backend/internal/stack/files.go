@@ -0,0 +1,314 @@
+package stack
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unicode/utf8"
+
+	"aperture-science-network/internal/compose"
+)
+
+// binarySniffLen is the number of leading bytes inspected for binary content
+const binarySniffLen = 8000
+
+// CleanFilePath normalizes a path relative to a stack directory, rejecting
+// absolute paths and paths that leave the directory
+func CleanFilePath(p string) (string, error) {
+	p = strings.ReplaceAll(p, `\`, "/")
+	if p == "" || strings.ContainsRune(p, 0) || strings.HasPrefix(p, "/") {
+		return "", ErrInvalidPath
+	}
+	p = path.Clean(p)
+	if p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return "", ErrInvalidPath
+	}
+	return p, nil
+}
+
+// IsBinary reports whether data looks like binary rather than text content
+func IsBinary(data []byte) bool {
+	if len(data) > binarySniffLen {
+		data = data[:binarySniffLen]
+		// Don't let a multi-byte rune cut at the boundary count as invalid
+		for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
+
+// openRoot opens a stack directory such that no operation through it can
+// reach outside the directory, whether through ".." or symbolic links
+func (p *FilesystemProvider) openRoot(name string) (*os.Root, error) {
+	if !p.StackExists(name) {
+		return nil, ErrStackNotFound
+	}
+	return os.OpenRoot(filepath.Join(p.stacksPath, name))
+}
+
+// isProtected reports whether rel is the stack's main compose file, which
+// is only written through UpdateComposeFile so that it is validated and
+// recorded in history
+func (p *FilesystemProvider) isProtected(name string, rel string) bool {
+	stackPath := filepath.Join(p.stacksPath, name)
+	main, err := compose.MainFile(stackPath)
+	if err != nil {
+		return false
+	}
+	mainRel, err := filepath.Rel(stackPath, main)
+	return err == nil && filepath.ToSlash(mainRel) == rel
+}
+
+func (p *FilesystemProvider) ListFiles(name string) ([]FileEntry, error) {
+	root, err := p.openRoot(name)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	entries := make([]FileEntry, 0)
+	err = fs.WalkDir(root.FS(), ".", func(rel string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip unreadable subtrees rather than failing the whole listing
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if rel == "." {
+			return nil
+		}
+		if len(entries) >= MaxListEntries {
+			return fs.SkipAll
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, fileEntry(rel, info))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (p *FilesystemProvider) ReadFile(name string, filePath string) (*FileContent, error) {
+	rel, err := CleanFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.openRoot(name)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	f, err := root.Open(filepath.FromSlash(rel))
+	if err != nil {
+		return nil, fileError(err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, ErrNotAFile
+	}
+	if info.Size() > MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	result := &FileContent{FileEntry: fileEntry(rel, info)}
+	if IsBinary(data) {
+		result.Binary = true
+	} else {
+		result.Content = string(data)
+	}
+	return result, nil
+}
+
+func (p *FilesystemProvider) WriteFile(name string, filePath string, content string) (*FileEntry, error) {
+	rel, err := checkWrite(filePath, content)
+	if err != nil {
+		return nil, err
+	}
+	if p.isProtected(name, rel) {
+		return nil, ErrProtectedFile
+	}
+	root, err := p.openRoot(name)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	native := filepath.FromSlash(rel)
+	info, err := root.Stat(native)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	if info.IsDir() {
+		return nil, ErrNotAFile
+	}
+
+	// Write to a temporary file next to the target and rename it into place
+	// so readers never see a partially written file
+	tmp := native + ".celeste-tmp"
+	if err := root.WriteFile(tmp, []byte(content), info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	if err := root.Rename(tmp, native); err != nil {
+		root.Remove(tmp)
+		return nil, err
+	}
+
+	info, err = root.Stat(native)
+	if err != nil {
+		return nil, err
+	}
+	entry := fileEntry(rel, info)
+	return &entry, nil
+}
+
+func (p *FilesystemProvider) CreateFile(name string, filePath string, content string, dir bool) (*FileEntry, error) {
+	rel, err := checkWrite(filePath, content)
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.openRoot(name)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	native := filepath.FromSlash(rel)
+	if _, err := root.Lstat(native); err == nil {
+		return nil, ErrFileExists
+	}
+	if parent := filepath.Dir(native); parent != "." {
+		if err := root.MkdirAll(parent, 0755); err != nil {
+			return nil, fileError(err)
+		}
+	}
+
+	if dir {
+		if err := root.Mkdir(native, 0755); err != nil {
+			return nil, fileError(err)
+		}
+	} else {
+		f, err := root.OpenFile(native, os.O_WRONLY|os.O_CREATE|os.O_EXCL, filePerm(rel))
+		if err != nil {
+			return nil, fileError(err)
+		}
+		_, err = f.WriteString(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			root.Remove(native)
+			return nil, err
+		}
+	}
+
+	info, err := root.Stat(native)
+	if err != nil {
+		return nil, err
+	}
+	entry := fileEntry(rel, info)
+	return &entry, nil
+}
+
+func (p *FilesystemProvider) DeleteFile(name string, filePath string) error {
+	rel, err := CleanFilePath(filePath)
+	if err != nil {
+		return err
+	}
+	if p.isProtected(name, rel) {
+		return ErrProtectedFile
+	}
+	root, err := p.openRoot(name)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	err = root.Remove(filepath.FromSlash(rel))
+	// rmdir reports a non-empty directory as either ENOTEMPTY or EEXIST
+	if errors.Is(err, syscall.EEXIST) {
+		return ErrDirectoryNotEmpty
+	}
+	return fileError(err)
+}
+
+// checkWrite validates the path and content of a write
+func checkWrite(filePath string, content string) (string, error) {
+	rel, err := CleanFilePath(filePath)
+	if err != nil {
+		return "", err
+	}
+	if len(content) > MaxFileSize {
+		return "", ErrFileTooLarge
+	}
+	if IsBinary([]byte(content)) {
+		return "", ErrBinaryContent
+	}
+	return rel, nil
+}
+
+// filePerm keeps environment files private since they commonly hold secrets
+func filePerm(rel string) os.FileMode {
+	base := path.Base(rel)
+	if base == ".env" || strings.HasSuffix(base, ".env") || strings.HasPrefix(base, ".env.") {
+		return 0600
+	}
+	return 0644
+}
+
+func fileEntry(rel string, info fs.FileInfo) FileEntry {
+	return FileEntry{
+		Path:    filepath.ToSlash(rel),
+		Dir:     info.IsDir(),
+		Symlink: info.Mode()&fs.ModeSymlink != 0,
+		Size:    info.Size(),
+		Mode:    info.Mode().String(),
+		ModTime: info.ModTime(),
+	}
+}
+
+// fileError maps filesystem errors to the provider's errors. Paths rejected
+// by os.Root for escaping the stack directory become ErrInvalidPath.
+func fileError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrFileNotFound
+	case errors.Is(err, fs.ErrExist):
+		return ErrFileExists
+	case errors.Is(err, syscall.ENOTEMPTY):
+		return ErrDirectoryNotEmpty
+	case strings.Contains(err.Error(), "path escapes from parent"):
+		return ErrInvalidPath
+	default:
+		return err
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"aperture-science-network/internal/compose"
 	"aperture-science-network/internal/docker"
 )
 
@@ -45,37 +47,8 @@ func (p *FilesystemProvider) ListStacks() ([]StackInfo, error) {
 	stacks := make([]StackInfo, 0)
 	for _, entry := range entries {
 		if entry.IsDir() {
-			composePath := filepath.Join(p.stacksPath, entry.Name(), "docker-compose.yml")
-			if _, err := os.Stat(composePath); err == nil {
-				stackName := entry.Name()
-				projectContainers := containersByProject[stackName]
-
-				// Count running services
-				runningCount := 0
-				for _, ctr := range projectContainers {
-					if ctr.State == "running" {
-						runningCount++
-					}
-				}
-
-				// Determine stack status
-				status := "stopped"
-				totalServices := len(projectContainers)
-				if totalServices > 0 {
-					if runningCount == totalServices {
-						status = "running"
-					} else if runningCount > 0 {
-						status = "partial"
-					}
-				}
-
-				stacks = append(stacks, StackInfo{
-					Name:            stackName,
-					Path:            filepath.Join(p.stacksPath, stackName),
-					Status:          status,
-					Services:        totalServices,
-					RunningServices: runningCount,
-				})
+			if _, err := compose.FindFiles(filepath.Join(p.stacksPath, entry.Name())); err == nil {
+				stacks = append(stacks, p.stackInfo(entry.Name(), containersByProject[entry.Name()]))
 			}
 		}
 	}
@@ -84,20 +57,74 @@ func (p *FilesystemProvider) ListStacks() ([]StackInfo, error) {
 }
 
 func (p *FilesystemProvider) GetStack(name string) (*StackInfo, error) {
-	stackPath := filepath.Join(p.stacksPath, name)
+	if !p.StackExists(name) {
+		return nil, ErrStackNotFound
+	}
 
-	if _, err := os.Stat(stackPath); os.IsNotExist(err) {
+	containers, err := p.dockerClient.ListContainers(context.Background(), true)
+	if err != nil {
 		return nil, err
 	}
 
-	return &StackInfo{
-		Name: name,
-		Path: stackPath,
-	}, nil
+	projectContainers := make([]docker.ContainerInfo, 0)
+	for _, ctr := range containers {
+		if ctr.Labels["com.docker.compose.project"] == name {
+			projectContainers = append(projectContainers, ctr)
+		}
+	}
+
+	info := p.stackInfo(name, projectContainers)
+	return &info, nil
+}
+
+// stackInfo derives a stack's status from the containers of its compose project
+func (p *FilesystemProvider) stackInfo(name string, projectContainers []docker.ContainerInfo) StackInfo {
+	// Count running services
+	runningCount := 0
+	for _, ctr := range projectContainers {
+		if ctr.State == "running" {
+			runningCount++
+		}
+	}
+
+	// Determine stack status
+	status := "stopped"
+	totalServices := len(projectContainers)
+	if totalServices > 0 {
+		if runningCount == totalServices {
+			status = "running"
+		} else if runningCount > 0 {
+			status = "partial"
+		}
+	}
+
+	stackPath := filepath.Join(p.stacksPath, name)
+	composeFiles := make([]string, 0)
+	if files, err := compose.FindFiles(stackPath); err == nil {
+		for _, file := range files {
+			// Report files relative to the stack when they live inside it
+			if rel, err := filepath.Rel(stackPath, file); err == nil && !strings.HasPrefix(rel, "..") {
+				file = rel
+			}
+			composeFiles = append(composeFiles, file)
+		}
+	}
+
+	return StackInfo{
+		Name:            name,
+		Path:            stackPath,
+		Status:          status,
+		Services:        totalServices,
+		RunningServices: runningCount,
+		ComposeFiles:    composeFiles,
+	}
 }
 
 func (p *FilesystemProvider) GetComposeFile(name string) (string, error) {
-	composePath := filepath.Join(p.stacksPath, name, "docker-compose.yml")
+	composePath, err := compose.MainFile(filepath.Join(p.stacksPath, name))
+	if err != nil {
+		return "", err
+	}
 	content, err := os.ReadFile(composePath)
 	if err != nil {
 		return "", err
@@ -106,14 +133,77 @@ func (p *FilesystemProvider) GetComposeFile(name string) (string, error) {
 }
 
 func (p *FilesystemProvider) UpdateComposeFile(name string, content string) error {
-	composePath := filepath.Join(p.stacksPath, name, "docker-compose.yml")
+	composePath, err := compose.MainFile(filepath.Join(p.stacksPath, name))
+	if err != nil {
+		return err
+	}
 	return os.WriteFile(composePath, []byte(content), 0644)
 }
 
-func (p *FilesystemProvider) StackExists(name string) bool {
+func (p *FilesystemProvider) CreateStack(name string, compose string, env string) (*StackInfo, error) {
+	if !ValidName(name) {
+		return nil, ErrInvalidStackName
+	}
+
 	stackPath := filepath.Join(p.stacksPath, name)
-	_, err := os.Stat(stackPath)
-	return err == nil
+	if err := os.Mkdir(stackPath, 0755); err != nil {
+		if os.IsExist(err) {
+			return nil, ErrStackExists
+		}
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(stackPath, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+		os.RemoveAll(stackPath)
+		return nil, err
+	}
+	if env != "" {
+		// .env files commonly hold secrets
+		if err := os.WriteFile(filepath.Join(stackPath, ".env"), []byte(env), 0600); err != nil {
+			os.RemoveAll(stackPath)
+			return nil, err
+		}
+	}
+
+	info := p.stackInfo(name, nil)
+	return &info, nil
+}
+
+func (p *FilesystemProvider) RenameStack(name string, newName string) (*StackInfo, error) {
+	if !ValidName(newName) {
+		return nil, ErrInvalidStackName
+	}
+	if !p.StackExists(name) {
+		return nil, ErrStackNotFound
+	}
+
+	newPath := filepath.Join(p.stacksPath, newName)
+	if _, err := os.Lstat(newPath); err == nil {
+		return nil, ErrStackExists
+	}
+	if err := os.Rename(filepath.Join(p.stacksPath, name), newPath); err != nil {
+		return nil, err
+	}
+
+	info := p.stackInfo(newName, nil)
+	return &info, nil
+}
+
+func (p *FilesystemProvider) DeleteStack(name string) error {
+	if !p.StackExists(name) {
+		return ErrStackNotFound
+	}
+	return os.RemoveAll(filepath.Join(p.stacksPath, name))
+}
+
+func (p *FilesystemProvider) StackExists(name string) bool {
+	// Existing directories may predate name validation, so only reject names
+	// that would escape the stacks directory
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(p.stacksPath, name))
+	return err == nil && info.IsDir()
 }
 
 func (p *FilesystemProvider) GetStackPath(name string) string {
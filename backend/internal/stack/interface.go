@@ -1,5 +1,41 @@
 package stack
 
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrStackNotFound     = errors.New("stack not found")
+	ErrStackExists       = errors.New("stack already exists")
+	ErrInvalidStackName  = errors.New("stack name must start with a lowercase letter or digit and contain only lowercase letters, digits, '-' or '_'")
+	ErrFileNotFound      = errors.New("file not found")
+	ErrFileExists        = errors.New("file already exists")
+	ErrFileTooLarge      = errors.New("file exceeds the maximum editable size")
+	ErrInvalidPath       = errors.New("path must be relative to the stack directory and stay inside it")
+	ErrNotAFile          = errors.New("path is a directory")
+	ErrBinaryContent     = errors.New("binary content cannot be edited")
+	ErrDirectoryNotEmpty = errors.New("directory is not empty")
+	ErrProtectedFile     = errors.New("the main compose file must be edited through the compose endpoint")
+)
+
+const (
+	// MaxFileSize is the largest stack file that can be read or written
+	MaxFileSize = 1024 * 1024
+	// MaxListEntries is the maximum number of entries returned by ListFiles
+	MaxListEntries = 5000
+)
+
+// namePattern matches valid compose project names, which stack names double as
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ValidName reports whether name can be used as a stack directory and
+// compose project name
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
 // StackInfo represents a compose stack with its status
 type StackInfo struct {
 	Name            string `json:"name"`
@@ -7,6 +43,33 @@ type StackInfo struct {
 	Status          string `json:"status"`
 	Services        int    `json:"services"`
 	RunningServices int    `json:"runningServices"`
+
+	// ComposeFiles lists the compose files passed to the CLI, main file
+	// first, relative to the stack directory where possible
+	ComposeFiles []string `json:"composeFiles"`
+
+	// UpdatesAvailable is set by the API when a newer image was found in
+	// the registry for any of the stack's services
+	UpdatesAvailable bool `json:"updatesAvailable"`
+}
+
+// FileEntry describes a file or directory inside a stack directory. Path is
+// slash-separated and relative to the stack directory.
+type FileEntry struct {
+	Path    string    `json:"path"`
+	Dir     bool      `json:"dir"`
+	Symlink bool      `json:"symlink,omitempty"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// FileContent is a file read from a stack directory. Content is left empty
+// for binary files.
+type FileContent struct {
+	FileEntry
+	Binary  bool   `json:"binary"`
+	Content string `json:"content,omitempty"`
 }
 
 // Provider defines the interface for stack operations
@@ -17,12 +80,40 @@ type Provider interface {
 	// GetStack returns a specific stack by name
 	GetStack(name string) (*StackInfo, error)
 
-	// GetComposeFile returns the content of a stack's docker-compose.yml
+	// GetComposeFile returns the content of a stack's main compose file
 	GetComposeFile(name string) (string, error)
 
-	// UpdateComposeFile updates the content of a stack's docker-compose.yml
+	// UpdateComposeFile updates the content of a stack's main compose file
 	UpdateComposeFile(name string, content string) error
 
+	// CreateStack creates a stack directory with a docker-compose.yml and,
+	// if env is non-empty, a .env file
+	CreateStack(name string, compose string, env string) (*StackInfo, error)
+
+	// RenameStack moves a stack directory to a new name. The compose project
+	// must be brought down first, since its name follows the directory.
+	RenameStack(name string, newName string) (*StackInfo, error)
+
+	// DeleteStack removes a stack directory and everything in it
+	DeleteStack(name string) error
+
+	// ListFiles returns the files and directories inside a stack directory
+	ListFiles(name string) ([]FileEntry, error)
+
+	// ReadFile returns a file inside a stack directory
+	ReadFile(name string, path string) (*FileContent, error)
+
+	// WriteFile replaces the content of an existing text file inside a stack
+	// directory
+	WriteFile(name string, path string, content string) (*FileEntry, error)
+
+	// CreateFile creates a new file, or a directory if dir is set, inside a
+	// stack directory along with any missing parent directories
+	CreateFile(name string, path string, content string, dir bool) (*FileEntry, error)
+
+	// DeleteFile removes a file or empty directory inside a stack directory
+	DeleteFile(name string, path string) error
+
 	// StackExists checks if a stack exists
 	StackExists(name string) bool
 
@@ -0,0 +1,225 @@
+package stats
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"aperture-science-network/internal/docker"
+)
+
+const (
+	// resyncInterval is how often the watcher compares its streams with the
+	// running containers, in case an event or a stream was lost
+	resyncInterval = 30 * time.Second
+
+	eventRetryMin = time.Second
+	eventRetryMax = 30 * time.Second
+)
+
+// ContainerSample is the latest stats of a running container
+type ContainerSample struct {
+	Container docker.ContainerInfo
+	Stats     *docker.ContainerStats
+	Time      time.Time
+}
+
+// ContainerWatcher keeps one stats stream open per running container and
+// caches the latest sample of each, so readers never wait on the daemon.
+// Streams are started and stopped as containers start and die.
+type ContainerWatcher struct {
+	dockerClient docker.DockerClient
+
+	mu         sync.RWMutex
+	containers map[string]*watchedContainer
+}
+
+type watchedContainer struct {
+	info    docker.ContainerInfo
+	stats   *docker.ContainerStats
+	updated time.Time
+	cancel  context.CancelFunc
+}
+
+// NewContainerWatcher creates a watcher; call Run to start it
+func NewContainerWatcher(dockerClient docker.DockerClient) *ContainerWatcher {
+	return &ContainerWatcher{
+		dockerClient: dockerClient,
+		containers:   make(map[string]*watchedContainer),
+	}
+}
+
+// Snapshot returns the latest sample of every running container that has
+// reported stats
+func (w *ContainerWatcher) Snapshot() []ContainerSample {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	samples := make([]ContainerSample, 0, len(w.containers))
+	for _, wc := range w.containers {
+		if wc.stats != nil {
+			samples = append(samples, ContainerSample{Container: wc.info, Stats: wc.stats, Time: wc.updated})
+		}
+	}
+	return samples
+}
+
+// Get returns the latest sample of a running container by ID or name
+func (w *ContainerWatcher) Get(id string) (ContainerSample, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for _, wc := range w.containers {
+		if (wc.info.ID == id || wc.info.Name == id) && wc.stats != nil {
+			return ContainerSample{Container: wc.info, Stats: wc.stats, Time: wc.updated}, true
+		}
+	}
+	return ContainerSample{}, false
+}
+
+// Run follows container lifecycle events until the process exits,
+// reconnecting with backoff when the event stream fails
+func (w *ContainerWatcher) Run() {
+	delay := eventRetryMin
+	for {
+		connected := time.Now()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		events, errs := w.dockerClient.Events(ctx, time.Time{})
+		err := w.follow(events, errs)
+		cancel()
+
+		// Only back off further if the stream failed straight away
+		if time.Since(connected) > eventRetryMax {
+			delay = eventRetryMin
+		}
+		log.Printf("Container stats event stream interrupted: %v (retrying in %s)", err, delay)
+		time.Sleep(delay)
+		delay = min(delay*2, eventRetryMax)
+	}
+}
+
+// follow applies container events until the stream fails. The event stream
+// is opened before the first resync so no start is missed in between.
+func (w *ContainerWatcher) follow(events <-chan docker.Event, errs <-chan error) error {
+	w.resync()
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			if event.Type != docker.EventContainer {
+				continue
+			}
+			switch event.Action {
+			case "start":
+				w.watch(event.ID)
+			case "die", "destroy":
+				w.unwatch(event.ID)
+			case "rename":
+				w.rename(event.ID, event.Name)
+			}
+
+		case <-ticker.C:
+			w.resync()
+
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// resync starts streams for running containers without one and stops
+// streams of containers that are no longer running
+func (w *ContainerWatcher) resync() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	containers, err := w.dockerClient.ListContainers(ctx, false)
+	if err != nil {
+		log.Printf("Error listing containers: %v", err)
+		return
+	}
+
+	running := make(map[string]bool)
+	for _, ctr := range containers {
+		running[ctr.ID] = true
+		w.start(ctr)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id, wc := range w.containers {
+		if !running[id] {
+			wc.cancel()
+			delete(w.containers, id)
+		}
+	}
+}
+
+func (w *ContainerWatcher) watch(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ctr, err := w.dockerClient.GetContainer(ctx, id)
+	if err != nil {
+		log.Printf("Error inspecting container %s: %v", id, err)
+		return
+	}
+	w.start(*ctr)
+}
+
+// start opens a stats stream for a container unless one is already open
+func (w *ContainerWatcher) start(ctr docker.ContainerInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.containers[ctr.ID]; ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wc := &watchedContainer{info: ctr, cancel: cancel}
+	w.containers[ctr.ID] = wc
+	go w.stream(ctx, wc)
+}
+
+func (w *ContainerWatcher) stream(ctx context.Context, wc *watchedContainer) {
+	id := wc.info.ID
+	err := w.dockerClient.StreamContainerStats(ctx, id, func(s *docker.ContainerStats) {
+		w.mu.Lock()
+		wc.stats = s
+		wc.updated = time.Now()
+		w.mu.Unlock()
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Error streaming stats for container %s: %v", id, err)
+	}
+
+	// Forget the container if the stream ended on its own; the next
+	// resync restarts it if the container is still running
+	w.mu.Lock()
+	if w.containers[id] == wc {
+		delete(w.containers, id)
+	}
+	w.mu.Unlock()
+	wc.cancel()
+}
+
+func (w *ContainerWatcher) unwatch(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if wc, ok := w.containers[id]; ok {
+		wc.cancel()
+		delete(w.containers, id)
+	}
+}
+
+func (w *ContainerWatcher) rename(id string, name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if wc, ok := w.containers[id]; ok && name != "" {
+		wc.info.Name = name
+	}
+}
@@ -0,0 +1,367 @@
+package updates
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aperture-science-network/internal/docker"
+	"aperture-science-network/internal/registry"
+)
+
+const (
+	// DefaultInterval is how often the registries are queried
+	DefaultInterval = 6 * time.Hour
+
+	// checkTimeout bounds one full check of all stacks
+	checkTimeout = 5 * time.Minute
+
+	eventRetryMin = time.Second
+	eventRetryMax = 30 * time.Second
+)
+
+// Status is the result of checking the image of one stack service's
+// container against its registry
+type Status struct {
+	ContainerID string `json:"containerId"`
+	Container   string `json:"container"`
+	Stack       string `json:"stack"`
+	Service     string `json:"service"`
+	Image       string `json:"image"`
+	// LocalDigest is the registry digest of the image the container runs,
+	// RemoteDigest the one its tag points to now
+	LocalDigest     string    `json:"localDigest,omitempty"`
+	RemoteDigest    string    `json:"remoteDigest,omitempty"`
+	UpdateAvailable bool      `json:"updateAvailable"`
+	Error           string    `json:"error,omitempty"`
+	CheckedAt       time.Time `json:"checkedAt"`
+}
+
+// remoteDigest is a cached registry lookup
+type remoteDigest struct {
+	digest    string
+	err       error
+	checkedAt time.Time
+}
+
+// Checker periodically compares the images of stack containers with their
+// registries and caches which have updates available. Between checks,
+// recreated containers are re-evaluated against the cached registry
+// digests, so applying an update clears its flag straight away.
+type Checker struct {
+	dockerClient docker.DockerClient
+	resolver     registry.Resolver
+	interval     time.Duration
+
+	// checkMu serializes registry checks
+	checkMu sync.Mutex
+
+	mu        sync.RWMutex
+	statuses  map[string]Status
+	remote    map[string]remoteDigest
+	lastCheck time.Time
+}
+
+// NewChecker creates a checker; call Run to start it. An interval of zero
+// disables periodic checks, leaving only checks requested through Check.
+func NewChecker(dockerClient docker.DockerClient, resolver registry.Resolver, interval time.Duration) *Checker {
+	return &Checker{
+		dockerClient: dockerClient,
+		resolver:     resolver,
+		interval:     interval,
+		statuses:     make(map[string]Status),
+		remote:       make(map[string]remoteDigest),
+	}
+}
+
+// Run checks for updates until the process exits
+func (c *Checker) Run() {
+	go c.followEvents()
+
+	if c.interval <= 0 {
+		return
+	}
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		if err := c.Check(ctx); err != nil {
+			log.Printf("Error checking for image updates: %v", err)
+		}
+		cancel()
+		time.Sleep(c.interval)
+	}
+}
+
+// Check queries the registries for every stack container's image and
+// replaces the cached results
+func (c *Checker) Check(ctx context.Context) error {
+	_, err := c.check(ctx, "")
+	return err
+}
+
+// CheckStack queries the registries for one stack's images, updates the
+// cached results for its containers and returns them
+func (c *Checker) CheckStack(ctx context.Context, stack string) ([]Status, error) {
+	return c.check(ctx, stack)
+}
+
+// check evaluates the containers of one stack, or of all stacks if stack is
+// empty, with fresh registry lookups
+func (c *Checker) check(ctx context.Context, stack string) ([]Status, error) {
+	c.checkMu.Lock()
+	defer c.checkMu.Unlock()
+
+	containers, err := c.dockerClient.ListContainers(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := make(map[string]remoteDigest)
+	statuses := make([]Status, 0)
+	for _, ctr := range containers {
+		project := ctr.Labels["com.docker.compose.project"]
+		if project == "" || (stack != "" && project != stack) {
+			continue
+		}
+		statuses = append(statuses, c.evaluate(ctx, ctr, func(ref registry.Reference) remoteDigest {
+			key := ref.Name + ":" + ref.Tag
+			if r, ok := remote[key]; ok {
+				return r
+			}
+			digest, err := c.resolver.Digest(ctx, key)
+			remote[key] = remoteDigest{digest: digest, err: err, checkedAt: time.Now().UTC()}
+			return remote[key]
+		}))
+	}
+
+	c.mu.Lock()
+	if stack == "" {
+		c.statuses = make(map[string]Status, len(statuses))
+		c.remote = remote
+		c.lastCheck = time.Now().UTC()
+	} else {
+		for id, s := range c.statuses {
+			if s.Stack == stack {
+				delete(c.statuses, id)
+			}
+		}
+		// Copy rather than write to the map refresh may be reading
+		merged := make(map[string]remoteDigest, len(c.remote)+len(remote))
+		for key, r := range c.remote {
+			merged[key] = r
+		}
+		for key, r := range remote {
+			merged[key] = r
+		}
+		c.remote = merged
+	}
+	for _, s := range statuses {
+		c.statuses[s.ContainerID] = s
+	}
+	c.mu.Unlock()
+
+	sortStatuses(statuses)
+	return statuses, nil
+}
+
+// Statuses returns the cached results, ordered by stack and service
+func (c *Checker) Statuses() []Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(c.statuses))
+	for _, s := range c.statuses {
+		statuses = append(statuses, s)
+	}
+	sortStatuses(statuses)
+	return statuses
+}
+
+func sortStatuses(statuses []Status) {
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Stack != statuses[j].Stack {
+			return statuses[i].Stack < statuses[j].Stack
+		}
+		if statuses[i].Service != statuses[j].Service {
+			return statuses[i].Service < statuses[j].Service
+		}
+		return statuses[i].Container < statuses[j].Container
+	})
+}
+
+// LastCheck returns when the registries were last queried, or the zero
+// time if they haven't been yet
+func (c *Checker) LastCheck() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastCheck
+}
+
+// ContainerUpdateAvailable reports whether a newer image was found for a
+// container
+func (c *Checker) ContainerUpdateAvailable(id string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.statuses[id].UpdateAvailable
+}
+
+// StackUpdatesAvailable reports whether a newer image was found for any of
+// a stack's containers
+func (c *Checker) StackUpdatesAvailable(stack string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, s := range c.statuses {
+		if s.Stack == stack && s.UpdateAvailable {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate compares a container's image with the registry digest returned
+// by lookup
+func (c *Checker) evaluate(ctx context.Context, ctr docker.ContainerInfo, lookup func(registry.Reference) remoteDigest) Status {
+	status := Status{
+		ContainerID: ctr.ID,
+		Container:   ctr.Name,
+		Stack:       ctr.Labels["com.docker.compose.project"],
+		Service:     ctr.Labels["com.docker.compose.service"],
+		Image:       ctr.Image,
+		CheckedAt:   time.Now().UTC(),
+	}
+
+	// The list shows the image ID instead of its name once the tag has
+	// moved to another image, as after a pull
+	if strings.HasPrefix(status.Image, "sha256:") {
+		if info, err := c.dockerClient.GetContainer(ctx, ctr.ID); err == nil {
+			status.Image = info.Image
+		}
+	}
+
+	ref, err := registry.ParseReference(status.Image)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if ref.Digest != "" {
+		// Pinned by digest, so pulling never changes it
+		status.LocalDigest = ref.Digest
+		status.RemoteDigest = ref.Digest
+		return status
+	}
+
+	local, err := c.localDigest(ctx, ctr.ImageID, ref)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.LocalDigest = local
+
+	remote := lookup(ref)
+	status.CheckedAt = remote.checkedAt
+	if remote.err != nil {
+		status.Error = remote.err.Error()
+		return status
+	}
+	status.RemoteDigest = remote.digest
+	status.UpdateAvailable = status.LocalDigest != status.RemoteDigest
+	return status
+}
+
+// localDigest returns the digest the image was pulled with from the
+// reference's repository
+func (c *Checker) localDigest(ctx context.Context, imageID string, ref registry.Reference) (string, error) {
+	image, err := c.dockerClient.InspectImage(ctx, imageID)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range image.Digests {
+		r, err := registry.ParseReference(d)
+		if err == nil && r.Name == ref.Name && r.Digest != "" {
+			return r.Digest, nil
+		}
+	}
+	return "", fmt.Errorf("image %s was not pulled from %s, it may have been built locally", image.ID, ref.Name)
+}
+
+// followEvents re-evaluates containers as they are created or removed,
+// reconnecting with backoff when the stream fails
+func (c *Checker) followEvents() {
+	delay := eventRetryMin
+	for {
+		connected := time.Now()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		events, errs := c.dockerClient.Events(ctx, time.Time{})
+		err := c.consume(events, errs)
+		cancel()
+
+		// Only back off further if the stream failed straight away
+		if time.Since(connected) > eventRetryMax {
+			delay = eventRetryMin
+		}
+		log.Printf("Update checker event stream interrupted: %v (retrying in %s)", err, delay)
+		time.Sleep(delay)
+		delay = min(delay*2, eventRetryMax)
+	}
+}
+
+func (c *Checker) consume(events <-chan docker.Event, errs <-chan error) error {
+	for {
+		select {
+		case event := <-events:
+			if event.Type != docker.EventContainer || event.Stack == "" {
+				continue
+			}
+			switch event.Action {
+			case "start":
+				c.refresh(event.ID)
+			case "destroy":
+				c.mu.Lock()
+				delete(c.statuses, event.ID)
+				c.mu.Unlock()
+			}
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// refresh re-evaluates one container against the cached registry digests.
+// Images not seen by the last check wait for the next one.
+func (c *Checker) refresh(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ctr, err := c.dockerClient.GetContainer(ctx, id)
+	if err != nil {
+		return
+	}
+
+	c.mu.RLock()
+	remote, lastCheck := c.remote, c.lastCheck
+	c.mu.RUnlock()
+
+	cached := true
+	var used remoteDigest
+	key := ""
+	status := c.evaluate(ctx, *ctr, func(ref registry.Reference) remoteDigest {
+		key = ref.Name + ":" + ref.Tag
+		used, cached = remote[key]
+		return used
+	})
+	if !cached {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// A check that finished meanwhile already stored a newer status
+	if !c.lastCheck.Equal(lastCheck) || (key != "" && !c.remote[key].checkedAt.Equal(used.checkedAt)) {
+		return
+	}
+	c.statuses[status.ContainerID] = status
+}
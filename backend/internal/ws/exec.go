@@ -0,0 +1,207 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"aperture-science-network/internal/auth"
+	"aperture-science-network/internal/docker"
+)
+
+const (
+	// maxExecMessageSize is the largest frame of terminal input, enough for
+	// a sizeable paste
+	maxExecMessageSize = 64 * 1024
+
+	execWriteWait  = 10 * time.Second
+	execPongWait   = 60 * time.Second
+	execPingPeriod = 54 * time.Second
+)
+
+// execRequest is a text frame sent by an exec client
+type execRequest struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+	Rows uint   `json:"rows"`
+	Cols uint   `json:"cols"`
+}
+
+// execSession serializes writes to an exec connection, which come from the
+// output copier, the pinger and the request handler
+type execSession struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+func (s *execSession) write(messageType int, data []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(execWriteWait))
+	return s.conn.WriteMessage(messageType, data)
+}
+
+func (s *execSession) send(msgType string, payload interface{}) error {
+	data, err := json.Marshal(Message{Type: msgType, Payload: payload})
+	if err != nil {
+		return err
+	}
+	return s.write(websocket.TextMessage, data)
+}
+
+func (s *execSession) sendError(err error) {
+	s.send("error", map[string]string{"error": err.Error()})
+}
+
+// HandleExec upgrades the request and bridges the connection to the terminal
+// of an exec created with a TTY, returning when the session ends.
+//
+// Binary frames carry raw terminal input and output. Clients may also send
+// text frames {"type":"input","data":"..."} with input and
+// {"type":"resize","rows":24,"cols":80} when their terminal changes size.
+// When the command exits the server sends {"type":"exit","payload":
+// {"exitCode":0}} and closes the connection; failures are reported as
+// {"type":"error","payload":{"error":"..."}}. Disconnecting hangs up the
+// terminal, as does any change to user's account.
+func HandleExec(hub *Hub, w http.ResponseWriter, r *http.Request, dockerClient docker.DockerClient, execID string, size docker.TerminalSize, user *auth.User) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 32 * 1024,
+		CheckOrigin:     hub.checkOrigin,
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx := r.Context()
+	session := &execSession{conn: conn}
+
+	hub.mutex.Lock()
+	hub.execs[session] = user.Username
+	hub.mutex.Unlock()
+	defer func() {
+		hub.mutex.Lock()
+		delete(hub.execs, session)
+		hub.mutex.Unlock()
+	}()
+
+	stream, err := dockerClient.AttachExec(ctx, execID, size)
+	if err != nil {
+		session.sendError(err)
+		session.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, ""))
+		return
+	}
+	defer stream.Close()
+
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := stream.Read(buf)
+			if n > 0 {
+				if session.write(websocket.BinaryMessage, buf[:n]) != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(execPingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-outputDone:
+				return
+			case <-ticker.C:
+				if session.write(websocket.PingMessage, nil) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		session.readInput(ctx, dockerClient, execID, stream)
+	}()
+
+	select {
+	case <-outputDone:
+		code, err := dockerClient.ExecExitCode(ctx, execID)
+		if err != nil {
+			session.sendError(err)
+		} else {
+			session.send("exit", map[string]int{"exitCode": code})
+		}
+		session.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+
+	case <-inputDone:
+		// The client went away; the deferred close hangs up the terminal
+	}
+}
+
+// readInput forwards the client's input to the terminal and applies resize
+// requests until the connection fails
+func (s *execSession) readInput(ctx context.Context, dockerClient docker.DockerClient, execID string, stream io.Writer) {
+	s.conn.SetReadLimit(maxExecMessageSize)
+	s.conn.SetReadDeadline(time.Now().Add(execPongWait))
+	s.conn.SetPongHandler(func(string) error {
+		s.conn.SetReadDeadline(time.Now().Add(execPongWait))
+		return nil
+	})
+
+	for {
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure, websocket.CloseAbnormalClosure) {
+				log.Printf("WebSocket error: %v", err)
+			}
+			return
+		}
+
+		if messageType == websocket.BinaryMessage {
+			if _, err := stream.Write(data); err != nil {
+				return
+			}
+			continue
+		}
+
+		var req execRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			s.sendError(ErrMalformedRequest)
+			continue
+		}
+		switch req.Type {
+		case "input":
+			if _, err := stream.Write([]byte(req.Data)); err != nil {
+				return
+			}
+		case "resize":
+			if req.Rows == 0 || req.Cols == 0 {
+				continue
+			}
+			size := docker.TerminalSize{Rows: req.Rows, Cols: req.Cols}
+			if err := dockerClient.ResizeExec(ctx, execID, size); err != nil {
+				s.sendError(err)
+			}
+		default:
+			s.sendError(ErrUnknownRequest)
+		}
+	}
+}
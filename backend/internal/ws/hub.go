@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 
+	"aperture-science-network/internal/auth"
 	"aperture-science-network/internal/docker"
 	"aperture-science-network/internal/stats"
 )
@@ -19,12 +23,9 @@ type StatsProvider interface {
 	GetSystemStats() (*stats.SystemStats, error)
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // In production, check origin properly
-	},
+// ContainerStatsSource provides the latest stats of running containers
+type ContainerStatsSource interface {
+	Snapshot() []stats.ContainerSample
 }
 
 type Message struct {
@@ -36,33 +37,88 @@ type Client struct {
 	hub  *Hub
 	conn *websocket.Conn
 	send chan []byte
+	user *auth.User
+
+	// topics and closed are guarded by the hub's mutex
+	topics map[string]bool
+	closed bool
+}
+
+// maxMessageSize is the largest request a client may send
+const maxMessageSize = 4096
+
+// outbound is a message queued for delivery. If filter is set, only clients
+// for which it returns true receive the message.
+type outbound struct {
+	data   []byte
+	filter func(*Client) bool
 }
 
 type Hub struct {
-	clients       map[*Client]bool
-	broadcast     chan []byte
-	register      chan *Client
-	unregister    chan *Client
-	mutex         sync.RWMutex
-	dockerClient  docker.DockerClient
-	statsProvider StatsProvider
+	clients        map[*Client]bool
+	broadcast      chan outbound
+	register       chan *Client
+	unregister     chan *Client
+	mutex          sync.RWMutex
+	dockerClient   docker.DockerClient
+	statsProvider  StatsProvider
+	containerStats ContainerStatsSource
+	origins        []string
+
+	// execs holds the open exec terminals with their user, guarded by mutex
+	execs map[*execSession]string
+
+	// dropped counts clients disconnected for not keeping up
+	dropped atomic.Uint64
 }
 
-func NewHub(dockerClient docker.DockerClient, statsProvider StatsProvider) *Hub {
+func NewHub(dockerClient docker.DockerClient, statsProvider StatsProvider, containerStats ContainerStatsSource) *Hub {
 	return &Hub{
-		clients:       make(map[*Client]bool),
-		broadcast:     make(chan []byte),
-		register:      make(chan *Client),
-		unregister:    make(chan *Client),
-		dockerClient:  dockerClient,
-		statsProvider: statsProvider,
+		clients:        make(map[*Client]bool),
+		execs:          make(map[*execSession]string),
+		broadcast:      make(chan outbound),
+		register:       make(chan *Client),
+		unregister:     make(chan *Client),
+		dockerClient:   dockerClient,
+		statsProvider:  statsProvider,
+		containerStats: containerStats,
 	}
 }
 
+// SetAllowedOrigins registers extra origins (besides the serving host) that
+// may open WebSocket connections, e.g. the frontend dev server
+func (h *Hub) SetAllowedOrigins(origins []string) {
+	h.origins = origins
+}
+
+// checkOrigin rejects cross-site upgrade requests, which would otherwise
+// ride on the browser's session cookie
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // Non-browser client
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if u.Host == r.Host {
+		return true
+	}
+	for _, allowed := range h.origins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *Hub) Run() {
 	// Start stats broadcasters
 	go h.broadcastSystemStats()
 	go h.broadcastContainerStats()
+	go h.broadcastDockerEvents()
 
 	for {
 		select {
@@ -74,38 +130,134 @@ func (h *Hub) Run() {
 
 		case client := <-h.unregister:
 			h.mutex.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
+			h.dropLocked(client)
 			h.mutex.Unlock()
 			log.Printf("Client disconnected. Total clients: %d", len(h.clients))
 
 		case message := <-h.broadcast:
-			h.mutex.RLock()
+			h.mutex.Lock()
 			for client := range h.clients {
+				if message.filter != nil && !message.filter(client) {
+					continue
+				}
 				select {
-				case client.send <- message:
+				case client.send <- message.data:
 				default:
-					close(client.send)
-					delete(h.clients, client)
+					h.dropped.Add(1)
+					h.dropLocked(client)
 				}
 			}
-			h.mutex.RUnlock()
+			h.mutex.Unlock()
 		}
 	}
 }
 
+// dropLocked disconnects a client. Callers must hold mutex.
+func (h *Hub) dropLocked(c *Client) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.send)
+	delete(h.clients, c)
+}
+
+// DisconnectUser closes the connections and exec terminals of username.
+// Clients capture their user when they connect, so after the account
+// changed they must reconnect to see it as it is now, or not at all if it
+// was deleted or logged out.
+func (h *Hub) DisconnectUser(username string) {
+	h.mutex.Lock()
+	for client := range h.clients {
+		if client.user != nil && client.user.Username == username {
+			h.dropLocked(client)
+		}
+	}
+	var execs []*execSession
+	for session, user := range h.execs {
+		if user == username {
+			execs = append(execs, session)
+		}
+	}
+	h.mutex.Unlock()
+
+	// Closing the connection ends the session and hangs up the terminal
+	for _, session := range execs {
+		session.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "account changed"), time.Now().Add(time.Second))
+		session.conn.Close()
+	}
+}
+
+// ClientCount returns the number of connected clients
+func (h *Hub) ClientCount() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.clients)
+}
+
+// DroppedClients returns the number of clients disconnected because their
+// send buffer was full
+func (h *Hub) DroppedClients() uint64 {
+	return h.dropped.Load()
+}
+
+// PublishToRole sends a message to clients subscribed to any of topics whose
+// user has at least role
+func (h *Hub) PublishToRole(msgType string, payload interface{}, role auth.Role, topics ...string) {
+	data, err := json.Marshal(Message{Type: msgType, Payload: payload})
+	if err != nil {
+		log.Printf("Error marshaling %s message: %v", msgType, err)
+		return
+	}
+
+	h.broadcast <- outbound{
+		data: data,
+		filter: func(c *Client) bool {
+			return c.subscribed(topics...) && c.user != nil && c.user.Role.Includes(role)
+		},
+	}
+}
+
+// PublishForStack sends a message to clients subscribed to any of topics
+// with access to stack
+func (h *Hub) PublishForStack(msgType string, payload interface{}, stack string, topics ...string) {
+	data, err := json.Marshal(Message{Type: msgType, Payload: payload})
+	if err != nil {
+		log.Printf("Error marshaling %s message: %v", msgType, err)
+		return
+	}
+
+	h.broadcast <- outbound{
+		data: data,
+		filter: func(c *Client) bool {
+			return c.subscribed(topics...) && c.user != nil && c.user.CanAccessStack(stack)
+		},
+	}
+}
+
+// PublishUnrestricted sends a message to clients subscribed to any of
+// topics whose user is not limited to some stacks
+func (h *Hub) PublishUnrestricted(msgType string, payload interface{}, topics ...string) {
+	data, err := json.Marshal(Message{Type: msgType, Payload: payload})
+	if err != nil {
+		log.Printf("Error marshaling %s message: %v", msgType, err)
+		return
+	}
+
+	h.broadcast <- outbound{
+		data: data,
+		filter: func(c *Client) bool {
+			return c.subscribed(topics...) && c.user != nil && !c.restricted()
+		},
+	}
+}
+
 func (h *Hub) broadcastSystemStats() {
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		h.mutex.RLock()
-		clientCount := len(h.clients)
-		h.mutex.RUnlock()
-
-		if clientCount == 0 {
+		if !h.watched(TopicStats) {
 			continue
 		}
 
@@ -126,14 +278,19 @@ func (h *Hub) broadcastSystemStats() {
 			continue
 		}
 
-		h.broadcast <- data
+		h.broadcast <- outbound{
+			data: data,
+			filter: func(c *Client) bool {
+				return c.subscribed(TopicStats)
+			},
+		}
 	}
 }
 
 // ContainerStatsPayload holds stats for all containers
 type ContainerStatsPayload struct {
 	Containers map[string]*docker.ContainerStats `json:"containers"`
-	Timestamp  int64                              `json:"timestamp"`
+	Timestamp  int64                             `json:"timestamp"`
 }
 
 func (h *Hub) broadcastContainerStats() {
@@ -141,58 +298,209 @@ func (h *Hub) broadcastContainerStats() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		h.mutex.RLock()
-		clientCount := len(h.clients)
-		h.mutex.RUnlock()
-
-		if clientCount == 0 || h.dockerClient == nil {
+		// Only send stats for containers someone is watching
+		watchAll, wanted := h.watchedContainers()
+		if !watchAll && len(wanted) == 0 {
 			continue
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		// Read the latest cached sample of each container
+		containerStats := make(map[string]*docker.ContainerStats)
+		projects := make(map[string]string)
+		names := make(map[string]string)
+		for _, sample := range h.containerStats.Snapshot() {
+			ctr := sample.Container
+			if !watchAll && !wanted[ctr.ID] && !wanted[ctr.Name] {
+				continue
+			}
+			containerStats[ctr.ID] = sample.Stats
+			projects[ctr.ID] = ctr.Labels["com.docker.compose.project"]
+			names[ctr.ID] = ctr.Name
+		}
 
-		// Get running containers
-		containers, err := h.dockerClient.ListContainers(ctx, false)
-		if err != nil {
-			cancel()
-			log.Printf("Error listing containers: %v", err)
+		if len(containerStats) == 0 {
 			continue
 		}
 
-		// Collect stats for each container
-		containerStats := make(map[string]*docker.ContainerStats)
-		for _, ctr := range containers {
-			ctrStats, err := h.dockerClient.GetContainerStats(ctx, ctr.ID)
-			if err != nil {
-				continue // Skip containers we can't get stats for
+		// Unrestricted clients watching every container share one payload
+		timestamp := time.Now().Unix()
+		h.sendContainerStats(containerStats, timestamp, func(c *Client) bool {
+			return c.subscribed(TopicContainerStats) && !c.restricted()
+		})
+
+		// Other clients get their own payload with the containers they
+		// watch and may see
+		type selection struct {
+			client *Client
+			all    bool
+			ids    map[string]bool
+		}
+		h.mutex.RLock()
+		selections := make([]selection, 0)
+		for client := range h.clients {
+			all := client.subscribed(TopicContainerStats)
+			if all && !client.restricted() {
+				continue
+			}
+			ids := make(map[string]bool)
+			for topic := range client.topics {
+				if id, ok := strings.CutPrefix(topic, containerStatsPrefix); ok {
+					ids[id] = true
+				}
+			}
+			if all || len(ids) > 0 {
+				selections = append(selections, selection{client: client, all: all, ids: ids})
 			}
-			containerStats[ctr.ID] = ctrStats
 		}
-		cancel()
+		h.mutex.RUnlock()
 
-		if len(containerStats) == 0 {
-			continue
+		for _, sel := range selections {
+			visible := make(map[string]*docker.ContainerStats)
+			for id, ctrStats := range containerStats {
+				if !sel.all && !sel.ids[id] && !sel.ids[names[id]] {
+					continue
+				}
+				if sel.client.restricted() && (projects[id] == "" || !sel.client.user.CanAccessStack(projects[id])) {
+					continue
+				}
+				visible[id] = ctrStats
+			}
+			if len(visible) == 0 {
+				continue
+			}
+			target := sel.client
+			h.sendContainerStats(visible, timestamp, func(c *Client) bool {
+				return c == target
+			})
 		}
+	}
+}
 
-		msg := Message{
-			Type: "container_stats",
-			Payload: ContainerStatsPayload{
-				Containers: containerStats,
-				Timestamp:  time.Now().Unix(),
-			},
+// watchedContainers returns whether any client watches every container,
+// and otherwise the IDs or names of the containers being watched
+func (h *Hub) watchedContainers() (bool, map[string]bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	wanted := make(map[string]bool)
+	for client := range h.clients {
+		for topic := range client.topics {
+			if topic == TopicContainerStats {
+				return true, nil
+			}
+			if id, ok := strings.CutPrefix(topic, containerStatsPrefix); ok {
+				wanted[id] = true
+			}
 		}
+	}
+	return false, wanted
+}
 
-		data, err := json.Marshal(msg)
-		if err != nil {
-			log.Printf("Error marshaling container stats: %v", err)
-			continue
+func (h *Hub) sendContainerStats(containerStats map[string]*docker.ContainerStats, timestamp int64, filter func(*Client) bool) {
+	msg := Message{
+		Type: "container_stats",
+		Payload: ContainerStatsPayload{
+			Containers: containerStats,
+			Timestamp:  timestamp,
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error marshaling container stats: %v", err)
+		return
+	}
+
+	h.broadcast <- outbound{data: data, filter: filter}
+}
+
+const (
+	// eventRetryMin and eventRetryMax bound the delay before resubscribing
+	// to Docker events after the stream drops
+	eventRetryMin = time.Second
+	eventRetryMax = 30 * time.Second
+)
+
+// broadcastDockerEvents forwards Docker events to clients as
+// "<type>_event" messages, resubscribing whenever the daemon stream drops.
+// Events missed while disconnected are replayed from the daemon.
+func (h *Hub) broadcastDockerEvents() {
+	if h.dockerClient == nil {
+		return
+	}
+
+	delay := eventRetryMin
+	var since time.Time
+	for {
+		if since.IsZero() {
+			since = time.Now()
 		}
+		connected := time.Now()
 
-		h.broadcast <- data
+		ctx, cancel := context.WithCancel(context.Background())
+		events, errs := h.dockerClient.Events(ctx, since)
+		err := h.forwardEvents(events, errs, &since)
+		cancel()
+
+		// Only back off further if the stream failed straight away
+		if time.Since(connected) > eventRetryMax {
+			delay = eventRetryMin
+		}
+		log.Printf("Docker event stream interrupted: %v (retrying in %s)", err, delay)
+		time.Sleep(delay)
+		delay = min(delay*2, eventRetryMax)
+	}
+}
+
+// forwardEvents broadcasts events until the stream fails, advancing since
+// past each event delivered
+func (h *Hub) forwardEvents(events <-chan docker.Event, errs <-chan error, since *time.Time) error {
+	for {
+		select {
+		case event := <-events:
+			*since = event.Time.Add(time.Nanosecond)
+			h.sendEvent(event)
+		case err := <-errs:
+			return err
+		}
 	}
 }
 
-func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
+func (h *Hub) sendEvent(event docker.Event) {
+	data, err := json.Marshal(Message{Type: event.Type + "_event", Payload: event})
+	if err != nil {
+		log.Printf("Error marshaling %s event: %v", event.Type, err)
+		return
+	}
+
+	// Container events follow the same visibility rules as containers and
+	// also go to the stack's subscribers; images, volumes and networks are
+	// listed for every user
+	filter := func(c *Client) bool {
+		return c.user != nil && c.subscribed(TopicEvents)
+	}
+	if event.Type == docker.EventContainer {
+		filter = func(c *Client) bool {
+			if c.user == nil || !c.subscribed(TopicEvents, StackTopic(event.Stack)) {
+				return false
+			}
+			if !c.user.Restricted() {
+				return true
+			}
+			return event.Stack != "" && c.user.CanAccessStack(event.Stack)
+		}
+	}
+
+	h.broadcast <- outbound{data: data, filter: filter}
+}
+
+func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request, user *auth.User) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     hub.checkOrigin,
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
@@ -200,9 +508,11 @@ func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, 256),
+		hub:    hub,
+		conn:   conn,
+		send:   make(chan []byte, 256),
+		user:   user,
+		topics: make(map[string]bool),
 	}
 
 	hub.register <- client
@@ -211,13 +521,18 @@ func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
 	go client.readPump()
 }
 
+// restricted reports whether the client's user only has access to some stacks
+func (c *Client) restricted() bool {
+	return c.user != nil && c.user.Restricted()
+}
+
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(512)
+	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -225,13 +540,14 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
+		c.handleRequest(data)
 	}
 }
 
@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"aperture-science-network/internal/auth"
+)
+
+func TestDisconnectUser(t *testing.T) {
+	hub := NewHub(nil, nil, nil)
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		HandleWebSocket(hub, w, r, &auth.User{Username: r.URL.Query().Get("user"), Role: auth.RoleAdmin})
+	}))
+	defer server.Close()
+
+	dial := func(username string) *websocket.Conn {
+		t.Helper()
+		url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?user=" + username
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	alice := dial("alice")
+	bob := dial("bob")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.ClientCount() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("ClientCount() = %d, want 2", hub.ClientCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	hub.DisconnectUser("alice")
+
+	alice.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := alice.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNoStatusReceived) {
+		t.Errorf("alice's connection read error = %v, want a close", err)
+	}
+
+	bob.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	var netErr net.Error
+	if _, _, err := bob.ReadMessage(); !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("bob's connection read error = %v, want a timeout", err)
+	}
+	if got := hub.ClientCount(); got != 1 {
+		t.Errorf("ClientCount() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,204 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"aperture-science-network/internal/auth"
+)
+
+// Topics clients can subscribe to. The hub only gathers and sends data for
+// topics at least one client is subscribed to.
+const (
+	// TopicStats carries "stats" messages with host statistics
+	TopicStats = "stats"
+	// TopicContainerStats carries "container_stats" messages for every
+	// running container the user can see. Use ContainerStatsTopic to watch
+	// a single container.
+	TopicContainerStats = "container_stats"
+	// TopicEvents carries every Docker event the user can see
+	TopicEvents = "events"
+	// TopicAudit carries "audit" entries and is limited to admins
+	TopicAudit = "audit"
+	// TopicAlerts carries "alert" messages as alerts fire and resolve
+	TopicAlerts = "alerts"
+)
+
+const (
+	containerStatsPrefix = TopicContainerStats + ":"
+	stackPrefix          = "stack:"
+	jobPrefix            = "job:"
+
+	// maxSubscriptions bounds the number of topics one client may hold
+	maxSubscriptions = 256
+)
+
+var (
+	ErrUnknownTopic      = errors.New("unknown topic")
+	ErrTopicForbidden    = errors.New("access to this topic is not granted")
+	ErrTooManyTopics     = errors.New("too many subscriptions")
+	ErrUnknownRequest    = errors.New("unknown request type")
+	ErrMalformedRequest  = errors.New("malformed request")
+	errEmptyTopicSubject = errors.New("topic is missing its subject")
+)
+
+// ContainerStatsTopic returns the topic carrying stats for one container,
+// given by ID or name
+func ContainerStatsTopic(id string) string {
+	return containerStatsPrefix + id
+}
+
+// StackTopic returns the topic carrying a stack's container events and job
+// status updates
+func StackTopic(name string) string {
+	return stackPrefix + name
+}
+
+// JobTopic returns the topic carrying a job's status updates and output
+func JobTopic(id string) string {
+	return jobPrefix + id
+}
+
+// Request is a message sent by a client, e.g.
+// {"id": "1", "type": "subscribe", "topic": "stack:web"}
+type Request struct {
+	ID    string `json:"id,omitempty"`
+	Type  string `json:"type"`
+	Topic string `json:"topic"`
+}
+
+// Response answers a Request and is sent as a "response" message. Error is
+// empty if the request succeeded.
+type Response struct {
+	ID    string `json:"id,omitempty"`
+	Type  string `json:"type"`
+	Topic string `json:"topic,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleRequest applies a request read from the client and sends the response
+func (c *Client) handleRequest(data []byte) {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		c.hub.sendTo(c, "response", Response{Error: ErrMalformedRequest.Error()})
+		return
+	}
+
+	var err error
+	switch req.Type {
+	case "subscribe":
+		err = c.hub.subscribe(c, req.Topic)
+	case "unsubscribe":
+		c.hub.unsubscribe(c, req.Topic)
+	default:
+		err = fmt.Errorf("%w: %q", ErrUnknownRequest, req.Type)
+	}
+
+	resp := Response{ID: req.ID, Type: req.Type, Topic: req.Topic}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	c.hub.sendTo(c, "response", resp)
+}
+
+// authorize checks that the client's user may subscribe to topic. Topics
+// whose messages concern a stack are filtered again on delivery, so only
+// topics that can be checked up front are rejected here.
+func (c *Client) authorize(topic string) error {
+	if c.user == nil {
+		return ErrTopicForbidden
+	}
+
+	switch topic {
+	case TopicStats, TopicContainerStats, TopicEvents, TopicAlerts:
+		return nil
+	case TopicAudit:
+		if !c.user.Role.Includes(auth.RoleAdmin) {
+			return ErrTopicForbidden
+		}
+		return nil
+	}
+
+	for _, prefix := range []string{containerStatsPrefix, stackPrefix, jobPrefix} {
+		subject, ok := strings.CutPrefix(topic, prefix)
+		if !ok {
+			continue
+		}
+		if subject == "" {
+			return errEmptyTopicSubject
+		}
+		if prefix == stackPrefix && !c.user.CanAccessStack(subject) {
+			return ErrTopicForbidden
+		}
+		return nil
+	}
+	return ErrUnknownTopic
+}
+
+// subscribed reports whether the client is subscribed to any of topics.
+// Callers must hold the hub's mutex.
+func (c *Client) subscribed(topics ...string) bool {
+	for _, topic := range topics {
+		if c.topics[topic] {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Hub) subscribe(c *Client, topic string) error {
+	if err := c.authorize(topic); err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !c.topics[topic] && len(c.topics) >= maxSubscriptions {
+		return ErrTooManyTopics
+	}
+	c.topics[topic] = true
+	return nil
+}
+
+func (h *Hub) unsubscribe(c *Client, topic string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(c.topics, topic)
+}
+
+// watched reports whether any client is subscribed to topic
+func (h *Hub) watched(topic string) bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for client := range h.clients {
+		if client.topics[topic] {
+			return true
+		}
+	}
+	return false
+}
+
+// sendTo queues a message for a single client, dropping the client if its
+// buffer is full
+func (h *Hub) sendTo(c *Client, msgType string, payload interface{}) {
+	data, err := json.Marshal(Message{Type: msgType, Payload: payload})
+	if err != nil {
+		log.Printf("Error marshaling %s message: %v", msgType, err)
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		h.dropped.Add(1)
+		h.dropLocked(c)
+	}
+}
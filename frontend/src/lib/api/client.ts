@@ -6,10 +6,55 @@ import type {
 	VolumeInfo,
 	NetworkInfo,
 	ImageInfo,
+	ImageDetails,
+	ImageLayer,
+	ImageRemoval,
+	ImagePruneReport,
+	PullProgress,
+	UpdatesResponse,
 	StatusResponse,
 	HealthResponse,
 	ComposeFileResponse,
+	ComposeIssue,
+	ComposeSaveResponse,
+	ComposeValidationResponse,
+	Revision,
+	RevisionDetailResponse,
+	RevisionDiffResponse,
+	RollbackResponse,
+	FileEntry,
+	FileContent,
 	LogsResponse,
+	LogEntry,
+	LogStreamOptions,
+	LogSearchQuery,
+	LogSearchResponse,
+	ExecOptions,
+	ExecMessage,
+	MetricsQuery,
+	MetricsResponse,
+	Alert,
+	AlertRule,
+	AlertRuleInput,
+	AlertChannel,
+	AlertChannelInput,
+	Silence,
+	SilenceInput,
+	SessionResponse,
+	LoginResponse,
+	SetupStatusResponse,
+	UpdatePolicy,
+	UpdatePolicyInput,
+	UpdatePolicyResponse,
+	UpdateRun,
+	UpdateRunResponse,
+	RegistryCredential,
+	RegistryCredentialInput,
+	JobAcceptedResponse,
+	RenameStackResponse,
+	JobDetailResponse,
+	JobInfo,
+	JobStatus,
 	ApiError as ApiErrorResponse
 } from './types';
 
@@ -21,10 +66,19 @@ const getApiBase = (): string => {
 
 const API_BASE = getApiBase();
 
+const getWsBase = (): string => {
+	if (typeof window === 'undefined') return 'ws://localhost:8080';
+	const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+	return `${protocol}//${window.location.host}`;
+};
+
+const WS_BASE = getWsBase();
+
 class ApiError extends Error {
 	constructor(
 		public status: number,
-		message: string
+		message: string,
+		public issues: ComposeIssue[] = []
 	) {
 		super(message);
 		this.name = 'ApiError';
@@ -43,7 +97,11 @@ async function request<T>(endpoint: string, options: RequestInit = {}): Promise<
 
 	if (!response.ok) {
 		const errorResponse = (await response.json().catch(() => ({ error: 'Unknown error' }))) as ApiErrorResponse;
-		throw new ApiError(response.status, errorResponse.error || `HTTP ${response.status}`);
+		throw new ApiError(
+			response.status,
+			errorResponse.error || `HTTP ${response.status}`,
+			errorResponse.issues
+		);
 	}
 
 	return response.json();
@@ -60,6 +118,35 @@ export async function getHealth(): Promise<HealthResponse> {
 	return response.json();
 }
 
+// Auth
+export async function getSetupStatus(): Promise<SetupStatusResponse> {
+	return request<SetupStatusResponse>('/auth/setup');
+}
+
+export async function setup(username: string, password: string): Promise<LoginResponse> {
+	return request<LoginResponse>('/auth/setup', {
+		method: 'POST',
+		body: JSON.stringify({ username, password })
+	});
+}
+
+export async function login(username: string, password: string): Promise<LoginResponse> {
+	return request<LoginResponse>('/auth/login', {
+		method: 'POST',
+		body: JSON.stringify({ username, password })
+	});
+}
+
+export async function logout(): Promise<StatusResponse> {
+	return request<StatusResponse>('/auth/logout', {
+		method: 'POST'
+	});
+}
+
+export async function getSession(): Promise<SessionResponse> {
+	return request<SessionResponse>('/auth/session');
+}
+
 // Stacks
 export async function listStacks(): Promise<StackInfo[]> {
 	return request<StackInfo[]>('/stacks');
@@ -69,26 +156,53 @@ export async function getStack(name: string): Promise<StackInfo> {
 	return request<StackInfo>(`/stacks/${encodeURIComponent(name)}`);
 }
 
-export async function startStack(name: string): Promise<StatusResponse> {
-	return request<StatusResponse>(`/stacks/${encodeURIComponent(name)}/start`, {
+export async function createStack(name: string, compose: string, env = ''): Promise<StackInfo> {
+	return request<StackInfo>('/stacks', {
+		method: 'POST',
+		body: JSON.stringify({ name, compose, env })
+	});
+}
+
+// Renaming a stack that owns named volumes fails with 409 unless
+// abandonVolumes is set, since its volumes stay with the old project name
+export async function renameStack(
+	name: string,
+	newName: string,
+	abandonVolumes = false
+): Promise<RenameStackResponse> {
+	return request<RenameStackResponse>(`/stacks/${encodeURIComponent(name)}/rename`, {
+		method: 'POST',
+		body: JSON.stringify({ newName, abandonVolumes })
+	});
+}
+
+export async function deleteStack(name: string, down = false): Promise<JobAcceptedResponse> {
+	return request<JobAcceptedResponse>(
+		`/stacks/${encodeURIComponent(name)}${down ? '?down=true' : ''}`,
+		{ method: 'DELETE' }
+	);
+}
+
+export async function startStack(name: string): Promise<JobAcceptedResponse> {
+	return request<JobAcceptedResponse>(`/stacks/${encodeURIComponent(name)}/start`, {
 		method: 'POST'
 	});
 }
 
-export async function stopStack(name: string): Promise<StatusResponse> {
-	return request<StatusResponse>(`/stacks/${encodeURIComponent(name)}/stop`, {
+export async function stopStack(name: string): Promise<JobAcceptedResponse> {
+	return request<JobAcceptedResponse>(`/stacks/${encodeURIComponent(name)}/stop`, {
 		method: 'POST'
 	});
 }
 
-export async function restartStack(name: string): Promise<StatusResponse> {
-	return request<StatusResponse>(`/stacks/${encodeURIComponent(name)}/restart`, {
+export async function restartStack(name: string): Promise<JobAcceptedResponse> {
+	return request<JobAcceptedResponse>(`/stacks/${encodeURIComponent(name)}/restart`, {
 		method: 'POST'
 	});
 }
 
-export async function pullStack(name: string): Promise<StatusResponse> {
-	return request<StatusResponse>(`/stacks/${encodeURIComponent(name)}/pull`, {
+export async function pullStack(name: string): Promise<JobAcceptedResponse> {
+	return request<JobAcceptedResponse>(`/stacks/${encodeURIComponent(name)}/pull`, {
 		method: 'POST'
 	});
 }
@@ -100,13 +214,113 @@ export async function getComposeFile(name: string): Promise<string> {
 	return response.content;
 }
 
-export async function updateComposeFile(name: string, content: string): Promise<StatusResponse> {
-	return request<StatusResponse>(`/stacks/${encodeURIComponent(name)}/compose`, {
+export async function updateComposeFile(
+	name: string,
+	content: string,
+	options: { message?: string; force?: boolean; verify?: boolean } = {}
+): Promise<ComposeSaveResponse> {
+	return request<ComposeSaveResponse>(`/stacks/${encodeURIComponent(name)}/compose`, {
+		method: 'PUT',
+		body: JSON.stringify({ content, ...options })
+	});
+}
+
+export async function validateComposeFile(
+	name: string,
+	content: string,
+	verify = false
+): Promise<ComposeValidationResponse> {
+	return request<ComposeValidationResponse>(`/stacks/${encodeURIComponent(name)}/compose/validate`, {
+		method: 'POST',
+		body: JSON.stringify({ content, verify })
+	});
+}
+
+// Compose history
+export async function listRevisions(name: string): Promise<Revision[]> {
+	return request<Revision[]>(`/stacks/${encodeURIComponent(name)}/history`);
+}
+
+export async function getRevision(name: string, id: number): Promise<RevisionDetailResponse> {
+	return request<RevisionDetailResponse>(`/stacks/${encodeURIComponent(name)}/history/${id}`);
+}
+
+export async function diffRevisions(
+	name: string,
+	from: number | 'current',
+	to: number | 'current' = 'current'
+): Promise<RevisionDiffResponse> {
+	const params = new URLSearchParams({ from: String(from), to: String(to) });
+	return request<RevisionDiffResponse>(`/stacks/${encodeURIComponent(name)}/history/diff?${params}`);
+}
+
+export async function rollbackRevision(
+	name: string,
+	id: number,
+	options: { up?: boolean; message?: string } = {}
+): Promise<RollbackResponse> {
+	return request<RollbackResponse>(`/stacks/${encodeURIComponent(name)}/history/${id}/rollback`, {
+		method: 'POST',
+		body: JSON.stringify(options)
+	});
+}
+
+// Stack files
+function stackFileUrl(name: string, path: string): string {
+	return `/stacks/${encodeURIComponent(name)}/file?path=${encodeURIComponent(path)}`;
+}
+
+export async function listStackFiles(name: string): Promise<FileEntry[]> {
+	return request<FileEntry[]>(`/stacks/${encodeURIComponent(name)}/files`);
+}
+
+export async function readStackFile(name: string, path: string): Promise<FileContent> {
+	return request<FileContent>(stackFileUrl(name, path));
+}
+
+export async function writeStackFile(name: string, path: string, content: string): Promise<FileEntry> {
+	return request<FileEntry>(stackFileUrl(name, path), {
 		method: 'PUT',
 		body: JSON.stringify({ content })
 	});
 }
 
+export async function createStackFile(
+	name: string,
+	path: string,
+	options: { content?: string; directory?: boolean } = {}
+): Promise<FileEntry> {
+	return request<FileEntry>(stackFileUrl(name, path), {
+		method: 'POST',
+		body: JSON.stringify(options)
+	});
+}
+
+export async function deleteStackFile(name: string, path: string): Promise<StatusResponse> {
+	return request<StatusResponse>(stackFileUrl(name, path), {
+		method: 'DELETE'
+	});
+}
+
+// Jobs
+export async function listJobs(filter: { stack?: string; status?: JobStatus } = {}): Promise<JobInfo[]> {
+	const params = new URLSearchParams();
+	if (filter.stack) params.set('stack', filter.stack);
+	if (filter.status) params.set('status', filter.status);
+	const query = params.toString();
+	return request<JobInfo[]>(`/jobs${query ? `?${query}` : ''}`);
+}
+
+export async function cancelJob(id: string): Promise<JobInfo> {
+	return request<JobInfo>(`/jobs/${encodeURIComponent(id)}/cancel`, {
+		method: 'POST'
+	});
+}
+
+export async function getJob(id: string): Promise<JobDetailResponse> {
+	return request<JobDetailResponse>(`/jobs/${encodeURIComponent(id)}`);
+}
+
 // Containers
 export async function listContainers(all = true): Promise<ContainerInfo[]> {
 	return request<ContainerInfo[]>(`/containers?all=${all}`);
@@ -134,17 +348,211 @@ export async function restartContainer(id: string): Promise<StatusResponse> {
 	});
 }
 
-export async function getContainerLogs(id: string, tail = '100'): Promise<string> {
-	const response = await request<LogsResponse>(
-		`/containers/${encodeURIComponent(id)}/logs?tail=${tail}`
-	);
-	return response.logs;
+export async function getContainerLogs(
+	id: string,
+	options: Omit<LogStreamOptions, 'follow' | 'services'> = {}
+): Promise<LogsResponse> {
+	const params = new URLSearchParams({ tail: String(options.tail ?? 100) });
+	if (options.since) params.set('since', options.since);
+	if (options.until) params.set('until', options.until);
+	return request<LogsResponse>(`/containers/${encodeURIComponent(id)}/logs?${params}`);
+}
+
+export interface LogStreamHandlers {
+	onEntry: (entry: LogEntry) => void;
+	// Lines skipped because the browser read too slowly
+	onDropped?: (count: number) => void;
+	onEnd?: (error?: string) => void;
+	onError?: (event: Event) => void;
+}
+
+// Open a Server-Sent Events log stream. Returns a function that closes it.
+function openLogStream(endpoint: string, options: LogStreamOptions, handlers: LogStreamHandlers): () => void {
+	const params = new URLSearchParams();
+	if (options.since) params.set('since', options.since);
+	if (options.until) params.set('until', options.until);
+	if (options.tail !== undefined) params.set('tail', String(options.tail));
+	if (options.follow === false) params.set('follow', 'false');
+	if (options.services?.length) params.set('services', options.services.join(','));
+
+	const source = new EventSource(`${API_BASE}${endpoint}?${params}`, { withCredentials: true });
+	source.addEventListener('log', (event) => {
+		handlers.onEntry(JSON.parse((event as MessageEvent).data) as LogEntry);
+	});
+	source.addEventListener('dropped', (event) => {
+		handlers.onDropped?.((JSON.parse((event as MessageEvent).data) as { count: number }).count);
+	});
+	source.addEventListener('end', (event) => {
+		// Close before the browser reconnects and replays the logs
+		source.close();
+		handlers.onEnd?.((JSON.parse((event as MessageEvent).data) as { error?: string }).error);
+	});
+	source.onerror = (event) => handlers.onError?.(event);
+
+	return () => source.close();
+}
+
+export function streamContainerLogs(
+	id: string,
+	options: LogStreamOptions,
+	handlers: LogStreamHandlers
+): () => void {
+	return openLogStream(`/containers/${encodeURIComponent(id)}/logs/stream`, options, handlers);
+}
+
+export function streamStackLogs(
+	name: string,
+	options: LogStreamOptions,
+	handlers: LogStreamHandlers
+): () => void {
+	return openLogStream(`/stacks/${encodeURIComponent(name)}/logs/stream`, options, handlers);
+}
+
+export async function searchLogs(query: LogSearchQuery): Promise<LogSearchResponse> {
+	return request<LogSearchResponse>('/logs/search', {
+		method: 'POST',
+		body: JSON.stringify(query)
+	});
+}
+
+export interface ExecHandlers {
+	onOutput: (data: Uint8Array) => void;
+	onExit?: (exitCode: number) => void;
+	onError?: (error: string) => void;
+	onClose?: (event: CloseEvent) => void;
+}
+
+export interface ExecSession {
+	send: (data: string) => void;
+	resize: (rows: number, cols: number) => void;
+	close: () => void;
+}
+
+// Open an interactive terminal in a running container
+export function execContainer(id: string, options: ExecOptions, handlers: ExecHandlers): ExecSession {
+	const params = new URLSearchParams();
+	if (options.shell) params.set('shell', options.shell);
+	if (options.user) params.set('user', options.user);
+	if (options.rows) params.set('rows', String(options.rows));
+	if (options.cols) params.set('cols', String(options.cols));
+
+	const socket = new WebSocket(`${WS_BASE}/ws/containers/${encodeURIComponent(id)}/exec?${params}`);
+	socket.binaryType = 'arraybuffer';
+	socket.onmessage = (event) => {
+		if (event.data instanceof ArrayBuffer) {
+			handlers.onOutput(new Uint8Array(event.data));
+			return;
+		}
+		const message = JSON.parse(event.data) as ExecMessage;
+		if (message.type === 'exit') handlers.onExit?.(message.payload.exitCode);
+		else if (message.type === 'error') handlers.onError?.(message.payload.error);
+	};
+	socket.onclose = (event) => handlers.onClose?.(event);
+
+	const encoder = new TextEncoder();
+	return {
+		send: (data) => {
+			if (socket.readyState === WebSocket.OPEN) socket.send(encoder.encode(data));
+		},
+		resize: (rows, cols) => {
+			if (socket.readyState === WebSocket.OPEN) socket.send(JSON.stringify({ type: 'resize', rows, cols }));
+		},
+		close: () => socket.close()
+	};
 }
 
 export async function getContainerStats(id: string): Promise<ContainerStats> {
 	return request<ContainerStats>(`/containers/${encodeURIComponent(id)}/stats`);
 }
 
+// Metrics history
+export async function getMetrics(query: MetricsQuery = {}): Promise<MetricsResponse> {
+	const params = new URLSearchParams();
+	for (const [key, value] of Object.entries(query)) {
+		if (value) params.set(key, value);
+	}
+	return request<MetricsResponse>(`/metrics?${params}`);
+}
+
+// Alerts
+export async function listAlerts(): Promise<Alert[]> {
+	return request<Alert[]>('/alerts');
+}
+
+export async function listAlertRules(): Promise<AlertRule[]> {
+	return request<AlertRule[]>('/alerts/rules');
+}
+
+export async function createAlertRule(rule: AlertRuleInput): Promise<AlertRule> {
+	return request<AlertRule>('/alerts/rules', {
+		method: 'POST',
+		body: JSON.stringify(rule)
+	});
+}
+
+export async function updateAlertRule(id: string, rule: AlertRuleInput): Promise<AlertRule> {
+	return request<AlertRule>(`/alerts/rules/${encodeURIComponent(id)}`, {
+		method: 'PUT',
+		body: JSON.stringify(rule)
+	});
+}
+
+export async function deleteAlertRule(id: string): Promise<StatusResponse> {
+	return request<StatusResponse>(`/alerts/rules/${encodeURIComponent(id)}`, {
+		method: 'DELETE'
+	});
+}
+
+export async function listAlertChannels(): Promise<AlertChannel[]> {
+	return request<AlertChannel[]>('/alerts/channels');
+}
+
+export async function createAlertChannel(channel: AlertChannelInput): Promise<AlertChannel> {
+	return request<AlertChannel>('/alerts/channels', {
+		method: 'POST',
+		body: JSON.stringify(channel)
+	});
+}
+
+export async function updateAlertChannel(
+	id: string,
+	channel: AlertChannelInput
+): Promise<AlertChannel> {
+	return request<AlertChannel>(`/alerts/channels/${encodeURIComponent(id)}`, {
+		method: 'PUT',
+		body: JSON.stringify(channel)
+	});
+}
+
+export async function deleteAlertChannel(id: string): Promise<StatusResponse> {
+	return request<StatusResponse>(`/alerts/channels/${encodeURIComponent(id)}`, {
+		method: 'DELETE'
+	});
+}
+
+export async function testAlertChannel(id: string): Promise<StatusResponse> {
+	return request<StatusResponse>(`/alerts/channels/${encodeURIComponent(id)}/test`, {
+		method: 'POST'
+	});
+}
+
+export async function listSilences(): Promise<Silence[]> {
+	return request<Silence[]>('/alerts/silences');
+}
+
+export async function createSilence(silence: SilenceInput): Promise<Silence> {
+	return request<Silence>('/alerts/silences', {
+		method: 'POST',
+		body: JSON.stringify(silence)
+	});
+}
+
+export async function deleteSilence(id: string): Promise<StatusResponse> {
+	return request<StatusResponse>(`/alerts/silences/${encodeURIComponent(id)}`, {
+		method: 'DELETE'
+	});
+}
+
 // Volumes
 export async function listVolumes(): Promise<VolumeInfo[]> {
 	return request<VolumeInfo[]>('/volumes');
@@ -190,30 +598,226 @@ export async function listImages(): Promise<ImageInfo[]> {
 	return request<ImageInfo[]>('/images');
 }
 
+export async function getImage(id: string): Promise<ImageDetails> {
+	return request<ImageDetails>(`/images/${encodeURIComponent(id)}`);
+}
+
+export async function getImageHistory(id: string): Promise<ImageLayer[]> {
+	return request<ImageLayer[]>(`/images/${encodeURIComponent(id)}/history`);
+}
+
+// Pull an image, reporting the daemon's progress messages. Resolves when the
+// pull completes and rejects with its error.
+export async function pullImage(
+	reference: string,
+	onProgress: (progress: PullProgress) => void,
+	signal?: AbortSignal
+): Promise<void> {
+	const response = await fetch(`${API_BASE}/images/pull`, {
+		method: 'POST',
+		headers: { 'Content-Type': 'application/json' },
+		body: JSON.stringify({ reference }),
+		signal
+	});
+	if (!response.ok || !response.body) {
+		const errorResponse = (await response.json().catch(() => ({ error: 'Unknown error' }))) as ApiErrorResponse;
+		throw new ApiError(response.status, errorResponse.error || `HTTP ${response.status}`);
+	}
+
+	// The stream is a POST, so read the server-sent events by hand
+	const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+	let buffer = '';
+	for (;;) {
+		const { value, done } = await reader.read();
+		if (done) throw new Error('Pull stream closed unexpectedly');
+		buffer += value;
+
+		let boundary: number;
+		while ((boundary = buffer.indexOf('\n\n')) >= 0) {
+			const block = buffer.slice(0, boundary);
+			buffer = buffer.slice(boundary + 2);
+
+			let event = 'message';
+			let data = '';
+			for (const line of block.split('\n')) {
+				if (line.startsWith('event:')) event = line.slice(6).trim();
+				else if (line.startsWith('data:')) data += line.slice(5);
+			}
+			if (event === 'progress') {
+				onProgress(JSON.parse(data) as PullProgress);
+			} else if (event === 'end') {
+				const end = JSON.parse(data) as { error?: string };
+				if (end.error) throw new Error(end.error);
+				return;
+			}
+		}
+	}
+}
+
+export async function deleteImage(
+	id: string,
+	options: { force?: boolean; tag?: string } = {}
+): Promise<ImageRemoval[]> {
+	const params = new URLSearchParams();
+	if (options.force) params.set('force', 'true');
+	if (options.tag) params.set('tag', options.tag);
+	return request<ImageRemoval[]>(`/images/${encodeURIComponent(id)}?${params}`, {
+		method: 'DELETE'
+	});
+}
+
+// Remove dangling images, or every unused one with all. dryRun only
+// previews what would be removed.
+export async function pruneImages(options: { all?: boolean; dryRun?: boolean } = {}): Promise<ImagePruneReport> {
+	const params = new URLSearchParams();
+	if (options.all) params.set('all', 'true');
+	if (options.dryRun) params.set('dryRun', 'true');
+	return request<ImagePruneReport>(`/images/prune?${params}`, { method: 'POST' });
+}
+
+// Image updates
+export async function listUpdates(): Promise<UpdatesResponse> {
+	return request<UpdatesResponse>('/updates');
+}
+
+// Query the registries now rather than waiting for the periodic check
+export async function checkUpdates(): Promise<UpdatesResponse> {
+	return request<UpdatesResponse>('/updates/check', { method: 'POST' });
+}
+
+// Update policies
+export async function listUpdatePolicies(): Promise<UpdatePolicy[]> {
+	return request<UpdatePolicy[]>('/update-policies');
+}
+
+export async function getUpdatePolicy(name: string): Promise<UpdatePolicyResponse> {
+	return request<UpdatePolicyResponse>(`/stacks/${encodeURIComponent(name)}/update-policy`);
+}
+
+export async function setUpdatePolicy(name: string, policy: UpdatePolicyInput): Promise<UpdatePolicy> {
+	return request<UpdatePolicy>(`/stacks/${encodeURIComponent(name)}/update-policy`, {
+		method: 'PUT',
+		body: JSON.stringify(policy)
+	});
+}
+
+export async function deleteUpdatePolicy(name: string): Promise<StatusResponse> {
+	return request<StatusResponse>(`/stacks/${encodeURIComponent(name)}/update-policy`, {
+		method: 'DELETE'
+	});
+}
+
+// Check for updates now and apply them even outside the maintenance window
+export async function runUpdatePolicy(name: string): Promise<UpdateRunResponse> {
+	return request<UpdateRunResponse>(`/stacks/${encodeURIComponent(name)}/update-policy/run`, {
+		method: 'POST'
+	});
+}
+
+export async function listUpdateRuns(name: string): Promise<UpdateRun[]> {
+	return request<UpdateRun[]>(`/stacks/${encodeURIComponent(name)}/update-policy/runs`);
+}
+
+// Registry credentials
+export async function listRegistryCredentials(): Promise<RegistryCredential[]> {
+	return request<RegistryCredential[]>('/registry-credentials');
+}
+
+export async function setRegistryCredential(
+	registry: string,
+	credential: RegistryCredentialInput
+): Promise<RegistryCredential> {
+	return request<RegistryCredential>(`/registry-credentials/${encodeURIComponent(registry)}`, {
+		method: 'PUT',
+		body: JSON.stringify(credential)
+	});
+}
+
+export async function deleteRegistryCredential(registry: string): Promise<StatusResponse> {
+	return request<StatusResponse>(`/registry-credentials/${encodeURIComponent(registry)}`, {
+		method: 'DELETE'
+	});
+}
+
 // Export all functions as api object for convenience
 export const api = {
 	getSystemStats,
 	getHealth,
+	getSetupStatus,
+	setup,
+	login,
+	logout,
+	getSession,
 	listStacks,
 	getStack,
+	createStack,
+	renameStack,
+	deleteStack,
 	startStack,
 	stopStack,
 	restartStack,
 	pullStack,
+	listJobs,
+	getJob,
+	cancelJob,
 	getComposeFile,
 	updateComposeFile,
+	validateComposeFile,
+	listRevisions,
+	getRevision,
+	diffRevisions,
+	rollbackRevision,
+	listStackFiles,
+	readStackFile,
+	writeStackFile,
+	createStackFile,
+	deleteStackFile,
 	listContainers,
 	getContainer,
 	startContainer,
 	stopContainer,
 	restartContainer,
 	getContainerLogs,
+	streamContainerLogs,
+	streamStackLogs,
+	searchLogs,
+	execContainer,
 	getContainerStats,
+	getMetrics,
+	listAlerts,
+	listAlertRules,
+	createAlertRule,
+	updateAlertRule,
+	deleteAlertRule,
+	listAlertChannels,
+	createAlertChannel,
+	updateAlertChannel,
+	deleteAlertChannel,
+	testAlertChannel,
+	listSilences,
+	createSilence,
+	deleteSilence,
 	listVolumes,
 	createVolume,
 	deleteVolume,
 	listNetworks,
 	createNetwork,
 	deleteNetwork,
-	listImages
+	listImages,
+	getImage,
+	getImageHistory,
+	pullImage,
+	deleteImage,
+	pruneImages,
+	listUpdates,
+	checkUpdates,
+	listUpdatePolicies,
+	getUpdatePolicy,
+	setUpdatePolicy,
+	deleteUpdatePolicy,
+	runUpdatePolicy,
+	listUpdateRuns,
+	listRegistryCredentials,
+	setRegistryCredential,
+	deleteRegistryCredential
 };
@@ -26,23 +26,56 @@ export interface ContainerInfo {
 	id: string;
 	name: string;
 	image: string;
+	imageId: string;
 	status: string;
 	state: string;
 	created: number;
 	ports: PortBinding[];
 	labels: Record<string, string>;
 	networkMode: string;
+	// A newer image is in the registry for the container's tag
+	updateAvailable: boolean;
+	// Last exit code of a stopped container, only set for a single container
+	exitCode?: number;
 }
 
 export interface ContainerStats {
+	// Relative to one CPU: a container saturating two CPUs reports 200
 	cpuPercent: number;
+	// Only reported on cgroup v1 hosts
+	perCpuPercent?: number[];
+	onlineCpus: number;
+	throttling: CpuThrottling;
+	// Excludes the page cache reported in memoryCache
 	memoryUsage: number;
+	memoryCache: number;
 	memoryLimit: number;
 	memoryPercent: number;
 	networkRx: number;
 	networkTx: number;
+	networks?: Record<string, InterfaceStats>;
 	blockRead: number;
 	blockWrite: number;
+	pids: number;
+	pidsLimit?: number;
+}
+
+export interface CpuThrottling {
+	periods: number;
+	throttledPeriods: number;
+	// Nanoseconds
+	throttledTime: number;
+}
+
+export interface InterfaceStats {
+	rxBytes: number;
+	txBytes: number;
+	rxPackets: number;
+	txPackets: number;
+	rxErrors: number;
+	txErrors: number;
+	rxDropped: number;
+	txDropped: number;
 }
 
 // Stack types
@@ -52,6 +85,54 @@ export interface StackInfo {
 	status: 'running' | 'partial' | 'stopped';
 	services: number;
 	runningServices: number;
+	composeFiles: string[];
+	// A newer image is in the registry for at least one service
+	updatesAvailable: boolean;
+}
+
+// Job types (background compose operations)
+export type JobStatus = 'queued' | 'running' | 'succeeded' | 'failed' | 'canceled';
+
+export interface JobInfo {
+	id: string;
+	stack: string;
+	action: string;
+	user: string;
+	params?: Record<string, string>;
+	status: JobStatus;
+	error?: string;
+	exitCode?: number;
+	createdAt: string;
+	startedAt?: string;
+	finishedAt?: string;
+}
+
+export interface JobLine {
+	seq: number;
+	stream: 'stdout' | 'stderr';
+	text: string;
+	time: string;
+}
+
+export interface JobAcceptedResponse {
+	status: string;
+	job: JobInfo;
+}
+
+export interface RenameStackResponse extends JobAcceptedResponse {
+	// Volumes left behind under the old project name
+	abandonedVolumes: string[];
+}
+
+export interface JobDetailResponse {
+	job: JobInfo;
+	output: JobLine[];
+}
+
+export interface JobOutputPayload {
+	jobId: string;
+	stack: string;
+	line: JobLine;
 }
 
 // Volume types
@@ -75,11 +156,175 @@ export interface NetworkInfo {
 }
 
 // Image types
+export interface ImageContainer {
+	id: string;
+	name: string;
+	state: string;
+	stack?: string;
+}
+
 export interface ImageInfo {
 	id: string;
 	tags: string[];
+	digests: string[];
 	size: number;
+	// Bytes in layers shared with other images, -1 if unknown
+	sharedSize: number;
+	created: number;
+	// Untagged, usually superseded by a newer pull
+	dangling: boolean;
+	containers: ImageContainer[];
+	stacks: string[];
+}
+
+export interface ImageDetails extends ImageInfo {
+	architecture: string;
+	variant?: string;
+	os: string;
+	author?: string;
+	env: string[];
+	entrypoint: string[];
+	cmd: string[];
+	workingDir?: string;
+	user?: string;
+	exposedPorts: string[];
+	volumes: string[];
+	labels: Record<string, string>;
+	layers: string[];
+}
+
+// One build step, newest first. id is only set for tagged images.
+export interface ImageLayer {
+	id?: string;
 	created: number;
+	createdBy: string;
+	size: number;
+	tags?: string[];
+	comment?: string;
+}
+
+export interface PullProgress {
+	id?: string;
+	status: string;
+	current?: number;
+	total?: number;
+}
+
+export interface ImageRemoval {
+	untagged?: string;
+	deleted?: string;
+}
+
+export interface ImagePruneReport {
+	images: ImageInfo[];
+	spaceReclaimed: number;
+	dryRun: boolean;
+}
+
+// Image update check for one stack service's container
+export interface UpdateStatus {
+	containerId: string;
+	container: string;
+	stack: string;
+	service: string;
+	image: string;
+	localDigest?: string;
+	remoteDigest?: string;
+	updateAvailable: boolean;
+	error?: string;
+	checkedAt: string;
+}
+
+export interface UpdatesResponse {
+	// null until the registries have been queried once
+	lastCheck: string | null;
+	services: UpdateStatus[];
+}
+
+// Automatic update policies
+export type UpdateMode = 'notify' | 'apply';
+
+export type UpdateOutcome = 'up-to-date' | 'notified' | 'deferred' | 'applied' | 'rolled-back' | 'failed';
+
+export interface MaintenanceWindow {
+	// "HH:MM" in the server's time zone; an end before the start spans midnight
+	start: string;
+	end: string;
+	// 'mon' to 'sun'; empty means every day
+	days?: string[];
+}
+
+export interface UpdatePolicy {
+	stack: string;
+	enabled: boolean;
+	mode: UpdateMode;
+	// Cron expression or shorthand such as '@daily'
+	schedule: string;
+	window?: MaintenanceWindow;
+	// Duration string, e.g. '2m0s'
+	healthTimeout: string;
+	// Alert notification channel IDs
+	channels: string[];
+	createdAt: string;
+	updatedAt: string;
+}
+
+export interface UpdatePolicyInput {
+	enabled: boolean;
+	mode: UpdateMode;
+	schedule: string;
+	window?: MaintenanceWindow;
+	healthTimeout?: string;
+	channels?: string[];
+}
+
+export interface ServiceUpdate {
+	service: string;
+	image: string;
+	fromDigest?: string;
+	toDigest?: string;
+}
+
+export interface UpdateRun {
+	id: string;
+	stack: string;
+	trigger: 'schedule' | 'window' | 'manual';
+	mode: UpdateMode;
+	// Empty while the update job runs
+	outcome: UpdateOutcome | '';
+	services: ServiceUpdate[];
+	jobId?: string;
+	error?: string;
+	startedAt: string;
+	finishedAt: string;
+}
+
+export interface UpdatePolicyResponse {
+	policy: UpdatePolicy;
+	// Updates waiting for the maintenance window
+	pending: ServiceUpdate[];
+}
+
+export interface UpdateRunResponse {
+	run: UpdateRun;
+	// Set when an update job was started
+	status?: string;
+	job?: JobInfo;
+}
+
+// Private registry credentials; passwords are never returned
+export interface RegistryCredential {
+	// Registry host, e.g. 'ghcr.io' or 'harbor.example.com:8443'; 'docker.io' for Docker Hub
+	registry: string;
+	username: string;
+	createdAt: string;
+	updatedAt: string;
+}
+
+export interface RegistryCredentialInput {
+	username: string;
+	// Omit to keep the stored password
+	password?: string;
 }
 
 // WebSocket message types
@@ -88,14 +333,208 @@ export interface WebSocketMessage<T = unknown> {
 	payload: T;
 }
 
+// Answer to a subscribe/unsubscribe request ('response' message)
+export interface WebSocketResponse {
+	id?: string;
+	type: string;
+	topic?: string;
+	error?: string;
+}
+
+// Docker events ('container_event', 'image_event', 'volume_event',
+// 'network_event' WebSocket messages)
+export type DockerEventType = 'container' | 'image' | 'volume' | 'network';
+
+export interface DockerEvent {
+	type: DockerEventType;
+	action: string;
+	id: string;
+	name?: string;
+	stack?: string;
+	attributes?: Record<string, string>;
+	time: string;
+}
+
+// Metrics history
+export type MetricsResolution = 'raw' | '1m' | '10m';
+
+export interface MetricsQuery {
+	// Container ID or name; with neither container nor stack the host's
+	// series is returned
+	container?: string;
+	stack?: string;
+	since?: string;
+	until?: string;
+	resolution?: MetricsResolution;
+}
+
+export interface MetricsPoint {
+	time: string;
+	values: Record<string, number>;
+}
+
+export interface MetricsSeries {
+	target: 'system' | 'container';
+	id: string;
+	name?: string;
+	stack?: string;
+	points: MetricsPoint[];
+}
+
+export interface MetricsResponse {
+	resolution: MetricsResolution;
+	since: string;
+	until: string;
+	series: MetricsSeries[];
+}
+
+// Alerting
+export type AlertCondition =
+	| 'container_exited'
+	| 'container_unhealthy'
+	| 'container_restart_loop'
+	| 'host_cpu_percent'
+	| 'host_memory_percent'
+	| 'host_disk_percent';
+
+// Durations are Go duration strings such as '90s' or '5m'
+export interface AlertRule {
+	id: string;
+	name: string;
+	enabled: boolean;
+	condition: AlertCondition;
+	// Percentage for host conditions, number of exits for restart loops
+	threshold?: number;
+	for?: string;
+	window?: string;
+	cooldown?: string;
+	// path.Match patterns limiting container conditions
+	stack?: string;
+	container?: string;
+	channels: string[];
+	createdAt: string;
+	updatedAt: string;
+}
+
+export type AlertRuleInput = Omit<AlertRule, 'id' | 'createdAt' | 'updatedAt'>;
+
+export type AlertChannelType = 'webhook' | 'smtp' | 'ntfy' | 'gotify';
+
+export interface SmtpSettings {
+	host: string;
+	port?: number;
+	username?: string;
+	password?: string;
+	from: string;
+	to: string[];
+	security?: 'starttls' | 'tls' | 'none';
+}
+
+// Secrets come back as '********'; send them back unchanged to keep them
+export interface AlertChannel {
+	id: string;
+	name: string;
+	type: AlertChannelType;
+	url?: string;
+	headers?: Record<string, string>;
+	token?: string;
+	priority?: number;
+	smtp?: SmtpSettings;
+	createdAt: string;
+	updatedAt: string;
+}
+
+export type AlertChannelInput = Omit<AlertChannel, 'id' | 'createdAt' | 'updatedAt'>;
+
+export interface Silence {
+	id: string;
+	ruleId?: string;
+	stack?: string;
+	container?: string;
+	comment?: string;
+	createdBy: string;
+	createdAt: string;
+	expiresAt: string;
+}
+
+export interface SilenceInput {
+	ruleId?: string;
+	stack?: string;
+	container?: string;
+	comment?: string;
+	// Either a duration such as '2h' or an expiry time
+	duration?: string;
+	expiresAt?: string;
+}
+
+export type AlertState = 'pending' | 'firing' | 'resolved';
+
+// Also sent as 'alert' messages on the 'alerts' WebSocket topic
+export interface Alert {
+	id: string;
+	ruleId: string;
+	ruleName: string;
+	condition: AlertCondition;
+	state: AlertState;
+	message: string;
+	value?: number;
+	containerId?: string;
+	container?: string;
+	stack?: string;
+	since: string;
+	firedAt?: string;
+	resolvedAt?: string;
+	silenced: boolean;
+}
+
+// Exec terminal: terminal output arrives as binary frames; these text
+// messages report how the session ended
+export interface ExecOptions {
+	// Command to run instead of the server's default shell
+	shell?: string;
+	user?: string;
+	rows?: number;
+	cols?: number;
+}
+
+export type ExecMessage =
+	| { type: 'exit'; payload: { exitCode: number } }
+	| { type: 'error'; payload: { error: string } };
+
 export interface ContainerStatsPayload {
 	containers: Record<string, ContainerStats>;
 	timestamp: number;
 }
 
+// Auth types
+export type Role = 'viewer' | 'operator' | 'admin';
+
+export interface User {
+	username: string;
+	role: Role;
+	teams: string[];
+	stacks: string[];
+	createdAt: string;
+}
+
+export interface SessionResponse {
+	user: User;
+}
+
+export interface LoginResponse {
+	user: User;
+	token: string;
+	expiresAt: number;
+}
+
+export interface SetupStatusResponse {
+	setupRequired: boolean;
+}
+
 // API response types
 export interface ApiError {
 	error: string;
+	issues?: ComposeIssue[];
 }
 
 export interface HealthResponse {
@@ -111,6 +550,123 @@ export interface ComposeFileResponse {
 	content: string;
 }
 
+// Compose validation
+export interface ComposeIssue {
+	line: number;
+	column: number;
+	path?: string;
+	message: string;
+	severity: 'error' | 'warning';
+}
+
+export interface ComposeSaveResponse {
+	status: string;
+	issues: ComposeIssue[];
+	revision?: Revision;
+}
+
+// Compose history
+export interface Revision {
+	id: number;
+	stack: string;
+	author: string;
+	message?: string;
+	time: string;
+	size: number;
+	hash: string;
+}
+
+export interface RevisionDetailResponse {
+	revision: Revision;
+	content: string;
+}
+
+export interface RevisionDiffResponse {
+	from: string;
+	to: string;
+	diff: string;
+}
+
+export interface RollbackResponse {
+	status: string;
+	revision?: Revision;
+	job?: JobInfo;
+}
+
+// Stack files
+export interface FileEntry {
+	path: string;
+	dir: boolean;
+	symlink?: boolean;
+	size: number;
+	mode: string;
+	modTime: string;
+}
+
+export interface FileContent extends FileEntry {
+	binary: boolean;
+	content?: string;
+}
+
+export interface ComposeValidationResponse {
+	valid: boolean;
+	issues: ComposeIssue[];
+}
+
+
+// Log streaming ('log' Server-Sent Events)
+export interface LogEntry {
+	time: string;
+	stream: 'stdout' | 'stderr';
+	message: string;
+	container?: string;
+	service?: string;
+}
+
+// Container logs; tty containers report stdout and stderr together as stdout
 export interface LogsResponse {
-	logs: string;
+	tty: boolean;
+	entries: LogEntry[];
+}
+
+// Log search
+export type LogLevel = 'trace' | 'debug' | 'info' | 'warn' | 'error' | 'fatal';
+
+export interface LogSearchQuery {
+	// Either a stack (optionally limited to services) or a list of containers
+	stack?: string;
+	services?: string[];
+	containers?: string[];
+	query?: string;
+	regex?: boolean;
+	caseSensitive?: boolean;
+	levels?: LogLevel[];
+	since?: string;
+	until?: string;
+	context?: number;
+	offset?: number;
+	limit?: number;
+}
+
+export interface LogMatch extends LogEntry {
+	level?: LogLevel;
+	before?: LogEntry[];
+	after?: LogEntry[];
+}
+
+export interface LogSearchResponse {
+	matches: LogMatch[];
+	total: number;
+	offset: number;
+	limit: number;
+	truncated: boolean;
+}
+
+export interface LogStreamOptions {
+	since?: string;
+	until?: string;
+	tail?: number | 'all';
+	follow?: boolean;
+	// Stack streams only
+	services?: string[];
 }
@@ -1,4 +1,4 @@
-import type { WebSocketMessage } from './types';
+import type { WebSocketMessage, WebSocketResponse } from './types';
 
 type MessageHandler<T = unknown> = (payload: T) => void;
 
@@ -17,6 +17,9 @@ const MAX_RECONNECT_ATTEMPTS = 10;
 class WebSocketClient {
 	private ws: WebSocket | null = null;
 	private handlers: Map<string, Set<MessageHandler>> = new Map();
+	// Topic subscriptions with the number of callers holding each
+	private topics: Map<string, number> = new Map();
+	private requestId = 0;
 	private reconnectAttempts = 0;
 	private reconnectTimeout: ReturnType<typeof setTimeout> | null = null;
 	private url: string;
@@ -42,6 +45,8 @@ class WebSocketClient {
 				console.log('[WS] Connected');
 				this._connected = true;
 				this.reconnectAttempts = 0;
+				// Subscriptions don't survive a reconnect
+				this.topics.forEach((_, topic) => this.request('subscribe', topic));
 			};
 
 			this.ws.onclose = (event) => {
@@ -95,6 +100,37 @@ class WebSocketClient {
 		};
 	}
 
+	// Ask the server to send messages for a topic, e.g. 'stats',
+	// 'container_stats', 'events', 'alerts', 'stack:<name>' or 'job:<id>'.
+	// Returns a function that releases the subscription.
+	subscribe(topic: string): () => void {
+		const count = this.topics.get(topic) ?? 0;
+		this.topics.set(topic, count + 1);
+		if (count === 0) {
+			this.request('subscribe', topic);
+		}
+
+		let released = false;
+		return () => {
+			if (released) return;
+			released = true;
+			const remaining = (this.topics.get(topic) ?? 1) - 1;
+			if (remaining > 0) {
+				this.topics.set(topic, remaining);
+			} else {
+				this.topics.delete(topic);
+				this.request('unsubscribe', topic);
+			}
+		};
+	}
+
+	private request(type: 'subscribe' | 'unsubscribe', topic: string): void {
+		// Requests made while disconnected are sent on the next connect
+		if (this.ws?.readyState !== WebSocket.OPEN) return;
+		this.requestId++;
+		this.ws.send(JSON.stringify({ id: String(this.requestId), type, topic }));
+	}
+
 	off(type: string, handler?: MessageHandler): void {
 		if (handler) {
 			const typeHandlers = this.handlers.get(type);
@@ -109,6 +145,12 @@ class WebSocketClient {
 	private handleMessage(event: MessageEvent): void {
 		try {
 			const message: WebSocketMessage = JSON.parse(event.data);
+			if (message.type === 'response') {
+				const response = message.payload as WebSocketResponse;
+				if (response.error) {
+					console.error(`[WS] ${response.type} ${response.topic ?? ''} failed:`, response.error);
+				}
+			}
 			const handlers = this.handlers.get(message.type);
 
 			if (handlers) {
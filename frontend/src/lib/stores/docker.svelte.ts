@@ -7,9 +7,56 @@ import type {
 	VolumeInfo,
 	NetworkInfo,
 	ImageInfo,
-	ContainerStatsPayload
+	ContainerStatsPayload,
+	DockerEvent,
+	JobInfo
 } from '$lib/api/types';
 
+const JOB_POLL_INTERVAL = 5000;
+const EVENT_REFRESH_DELAY = 500;
+
+// Resolve once a background job finishes, using WebSocket updates with a
+// polling fallback in case the socket is disconnected
+function jobPending(job: JobInfo): boolean {
+	return job.status === 'queued' || job.status === 'running';
+}
+
+function waitForJob(job: JobInfo): Promise<JobInfo> {
+	return new Promise((resolve) => {
+		if (!jobPending(job)) {
+			resolve(job);
+			return;
+		}
+
+		let poll: ReturnType<typeof setInterval> | null = null;
+		const finish = (result: JobInfo) => {
+			off();
+			if (poll) clearInterval(poll);
+			resolve(result);
+		};
+
+		const offUpdates = wsClient.on<JobInfo>('job', (update) => {
+			if (update.id === job.id && !jobPending(update)) {
+				finish(update);
+			}
+		});
+		const release = wsClient.subscribe(`job:${job.id}`);
+		const off = () => {
+			offUpdates();
+			release();
+		};
+
+		poll = setInterval(async () => {
+			try {
+				const { job: current } = await api.getJob(job.id);
+				if (!jobPending(current)) finish(current);
+			} catch {
+				// Keep waiting for the WebSocket update
+			}
+		}, JOB_POLL_INTERVAL);
+	});
+}
+
 function createDockerStore() {
 	let containers = $state<ContainerInfo[]>([]);
 	let stacks = $state<StackInfo[]>([]);
@@ -20,6 +67,7 @@ function createDockerStore() {
 	let loading = $state(false);
 	let error = $state<string | null>(null);
 	let unsubscribe: (() => void) | null = null;
+	let eventUnsubscribers: (() => void)[] = [];
 
 	// Fetch functions
 	async function fetchContainers(all = true) {
@@ -90,9 +138,14 @@ function createDockerStore() {
 
 	// Subscribe to container stats from WebSocket
 	function subscribeToContainerStats() {
-		unsubscribe = wsClient.on<ContainerStatsPayload>('container_stats', (payload) => {
+		const off = wsClient.on<ContainerStatsPayload>('container_stats', (payload) => {
 			containerStats = new Map(Object.entries(payload.containers));
 		});
+		const release = wsClient.subscribe('container_stats');
+		unsubscribe = () => {
+			off();
+			release();
+		};
 	}
 
 	function unsubscribeFromContainerStats() {
@@ -102,10 +155,54 @@ function createDockerStore() {
 		}
 	}
 
+	// Refresh lists when Docker reports changes. Events tend to arrive in
+	// bursts (a stop emits kill, die and stop), so refreshes are debounced.
+	function debounced(fn: () => void): () => void {
+		let timeout: ReturnType<typeof setTimeout> | null = null;
+		return () => {
+			if (timeout) clearTimeout(timeout);
+			timeout = setTimeout(() => {
+				timeout = null;
+				fn();
+			}, EVENT_REFRESH_DELAY);
+		};
+	}
+
+	function subscribeToDockerEvents() {
+		const refreshContainers = debounced(() => {
+			fetchContainers();
+			fetchStacks();
+		});
+		const refreshImages = debounced(fetchImages);
+		const refreshVolumes = debounced(fetchVolumes);
+		const refreshNetworks = debounced(fetchNetworks);
+
+		eventUnsubscribers = [
+			wsClient.on<DockerEvent>('container_event', (event) => {
+				// Exec and health probe events don't change the container list
+				if (event.action.startsWith('exec_') || event.action.startsWith('health_status')) return;
+				refreshContainers();
+			}),
+			wsClient.on<DockerEvent>('image_event', refreshImages),
+			wsClient.on<DockerEvent>('volume_event', refreshVolumes),
+			wsClient.on<DockerEvent>('network_event', refreshNetworks),
+			wsClient.subscribe('events')
+		];
+	}
+
+	function unsubscribeFromDockerEvents() {
+		eventUnsubscribers.forEach((off) => off());
+		eventUnsubscribers = [];
+	}
+
 	// Stack operations
 	async function startStack(name: string) {
 		try {
-			await api.startStack(name);
+			const { job } = await api.startStack(name);
+			const result = await waitForJob(job);
+			if (result.status !== 'succeeded') {
+				error = result.error ?? 'Failed to start stack';
+			}
 			await fetchStacks();
 			await fetchContainers();
 		} catch (e) {
@@ -116,7 +213,11 @@ function createDockerStore() {
 
 	async function stopStack(name: string) {
 		try {
-			await api.stopStack(name);
+			const { job } = await api.stopStack(name);
+			const result = await waitForJob(job);
+			if (result.status !== 'succeeded') {
+				error = result.error ?? 'Failed to stop stack';
+			}
 			await fetchStacks();
 			await fetchContainers();
 		} catch (e) {
@@ -127,7 +228,11 @@ function createDockerStore() {
 
 	async function restartStack(name: string) {
 		try {
-			await api.restartStack(name);
+			const { job } = await api.restartStack(name);
+			const result = await waitForJob(job);
+			if (result.status !== 'succeeded') {
+				error = result.error ?? 'Failed to restart stack';
+			}
 			await fetchStacks();
 			await fetchContainers();
 		} catch (e) {
@@ -138,7 +243,11 @@ function createDockerStore() {
 
 	async function pullStack(name: string) {
 		try {
-			await api.pullStack(name);
+			const { job } = await api.pullStack(name);
+			const result = await waitForJob(job);
+			if (result.status !== 'succeeded') {
+				error = result.error ?? 'Failed to pull stack';
+			}
 		} catch (e) {
 			error = e instanceof Error ? e.message : 'Failed to pull stack';
 			throw e;
@@ -268,6 +377,8 @@ function createDockerStore() {
 		// WebSocket subscriptions
 		subscribeToContainerStats,
 		unsubscribeFromContainerStats,
+		subscribeToDockerEvents,
+		unsubscribeFromDockerEvents,
 
 		// Stack operations
 		startStack,
@@ -26,10 +26,15 @@ function createSystemStore() {
 		wsClient.connect();
 
 		// Subscribe to system stats
-		unsubscribe = wsClient.on<SystemStats>('stats', (payload) => {
+		const off = wsClient.on<SystemStats>('stats', (payload) => {
 			stats = payload;
 			error = null;
 		});
+		const release = wsClient.subscribe('stats');
+		unsubscribe = () => {
+			off();
+			release();
+		};
 	}
 
 	function disconnect() {